@@ -0,0 +1,127 @@
+package leo
+
+import (
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+)
+
+// AdaptiveConcurrency configures an AIMD controller for an executor's
+// concurrency limit, for tasks that share a backend whose capacity varies,
+// see Executor.SetAdaptiveConcurrency. Like TCP congestion control, the
+// limit grows by one for every limit's worth of tasks that succeed in time,
+// and is multiplied by Backoff when a task fails or takes longer than
+// Latency.
+type AdaptiveConcurrency struct {
+    // Min and Max bound the limit. Min defaults to 1.
+    Min int
+    Max int
+    // Initial is the limit the controller starts at. It defaults to Min.
+    Initial int
+    // Latency is the duration above which a successful task counts as a
+    // sign of an overloaded backend. If it is 0, only failures do.
+    Latency time.Duration
+    // Backoff is the factor the limit is multiplied by when the backend is
+    // overloaded, between 0 and 1. It defaults to 0.5.
+    Backoff float64
+}
+
+// SetAdaptiveConcurrency replaces the executor's concurrency limit, see
+// SetConcurrency, with one adjusted by an AIMD controller as tasks finish.
+// The limit is kept from run to run, so that later runs start where earlier
+// ones left off, and applies to each run separately. Skipped and cached
+// tasks do not affect it. After a decrease, tasks that had already started
+// are not counted again, so that a burst of failures cuts the limit once.
+// Runs on a worker pool are limited by the pool instead.
+func (e *Executor) SetAdaptiveConcurrency(c AdaptiveConcurrency) error {
+    if c.Min == 0 {
+        c.Min = 1
+    }
+    if c.Initial == 0 {
+        c.Initial = c.Min
+    }
+    if c.Backoff == 0 {
+        c.Backoff = 0.5
+    }
+    switch {
+    case c.Min < 1:
+        return errors.New("adaptive concurrency needs a minimum of at least 1")
+    case c.Max < c.Min:
+        return fmt.Errorf("adaptive concurrency maximum %d is below its minimum %d", c.Max, c.Min)
+    case c.Initial < c.Min || c.Initial > c.Max:
+        return fmt.Errorf("adaptive concurrency initial limit %d is not between %d and %d", c.Initial, c.Min, c.Max)
+    case c.Backoff <= 0 || c.Backoff >= 1:
+        return fmt.Errorf("adaptive concurrency backoff %v is not between 0 and 1", c.Backoff)
+    }
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.adaptive = &aimd{config: c, limit: float64(c.Initial)}
+    return nil
+}
+
+// ConcurrencyLimit returns the current limit of the executor's adaptive
+// concurrency controller, or 0 if it has none.
+func (e *Executor) ConcurrencyLimit() int {
+    if a := e.getAdaptive(); a != nil {
+        return a.current()
+    }
+    return 0
+}
+
+func (e *Executor) getAdaptive() *aimd {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.adaptive
+}
+
+// aimd is an additive increase, multiplicative decrease controller.
+type aimd struct {
+    config AdaptiveConcurrency
+
+    mu    sync.Mutex
+    limit float64
+    // cut is when the limit was last decreased.
+    cut   time.Time
+}
+
+func (a *aimd) current() int {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    return int(a.limit)
+}
+
+// observe adjusts the limit for a task that started at start and took d,
+// failing if failed is set.
+func (a *aimd) observe(start time.Time, d time.Duration, failed bool, now time.Time) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    c := a.config
+    if failed || c.Latency > 0 && d > c.Latency {
+        if start.Before(a.cut) {
+            return
+        }
+        a.limit *= c.Backoff
+        if a.limit < float64(c.Min) {
+            a.limit = float64(c.Min)
+        }
+        a.cut = now
+        return
+    }
+    a.limit += 1 / a.limit
+    if a.limit > float64(c.Max) {
+        a.limit = float64(c.Max)
+    }
+}
+
+// adapt feeds the outcome of n, which has just been executed, to the
+// controller a.
+func (r *Run) adapt(a *aimd, n *Node) {
+    r.report.mu.Lock()
+    nr := r.report.Nodes[n.name]
+    r.report.mu.Unlock()
+    if nr == nil || nr.Cached || nr.State != StateSucceeded && nr.State != StateFailed {
+        return
+    }
+    a.observe(nr.Start, nr.Duration, nr.State == StateFailed, r.executor.now())
+}
@@ -0,0 +1,113 @@
+package leo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAIMD(t *testing.T) {
+    a := &aimd{config: AdaptiveConcurrency{Min: 1, Max: 4, Latency: time.Second, Backoff: 0.5}, limit: 4}
+    base := time.Unix(0, 0)
+    at := func(s int) time.Time { return base.Add(time.Duration(s) * time.Second) }
+
+    a.observe(at(1), time.Millisecond, true, at(2))
+    if got := a.current(); got != 2 {
+        t.Errorf("expected a failure to halve the limit to 2, got %d", got)
+    }
+    // A task that started before the cut is part of the same burst.
+    a.observe(at(1), 2*time.Second, false, at(3))
+    if got := a.current(); got != 2 {
+        t.Errorf("expected the limit to stay at 2, got %d", got)
+    }
+    a.observe(at(3), 2*time.Second, false, at(5))
+    if got := a.current(); got != 1 {
+        t.Errorf("expected a slow task to halve the limit to 1, got %d", got)
+    }
+    a.observe(at(5), time.Millisecond, true, at(6))
+    if got := a.current(); got != 1 {
+        t.Errorf("expected the limit to stay at its minimum, got %d", got)
+    }
+    for i := 0; i < 20; i++ {
+        a.observe(at(7), time.Millisecond, false, at(8))
+    }
+    if got := a.current(); got != 4 {
+        t.Errorf("expected successes to raise the limit to its maximum, got %d", got)
+    }
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+    // The backend fails requests beyond two at once.
+    var mu sync.Mutex
+    inFlight, peak, capacity := 0, 0, 2
+    call := func() error {
+        mu.Lock()
+        inFlight++
+        over := inFlight > capacity
+        if inFlight > peak {
+            peak = inFlight
+        }
+        mu.Unlock()
+        time.Sleep(2 * time.Millisecond)
+        mu.Lock()
+        inFlight--
+        mu.Unlock()
+        if over {
+            return errors.New("overloaded")
+        }
+        return nil
+    }
+
+    graph := TaskGraph()
+    for i := 0; i < 40; i++ {
+        graph.Add(fmt.Sprintf("req%02d", i), call)
+    }
+    executor := NewExecutor(graph,
+        WithFailureMode(FailContinue),
+        WithAdaptiveConcurrency(AdaptiveConcurrency{Max: 8, Initial: 8}),
+    )
+    executor.Execute()
+    if got := executor.ConcurrencyLimit(); got < 1 || got > 4 {
+        t.Errorf("expected the limit to back off towards 2, got %d", got)
+    }
+    if peak > 8 {
+        t.Errorf("expected at most 8 tasks at once, got %d", peak)
+    }
+
+    // Once the backend has capacity again, the limit grows back towards
+    // the maximum from where the previous run left it.
+    capacity = 100
+    before := executor.ConcurrencyLimit()
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if got := executor.ConcurrencyLimit(); got <= before {
+        t.Errorf("expected the limit to grow from %d, got %d", before, got)
+    }
+}
+
+func TestAdaptiveConcurrencyErrors(t *testing.T) {
+    executor := NewExecutor(TaskGraph())
+    if got := executor.ConcurrencyLimit(); got != 0 {
+        t.Errorf("expected no limit without a controller, got %d", got)
+    }
+    for _, tc := range []struct {
+        config AdaptiveConcurrency
+        want   string
+    }{
+        {AdaptiveConcurrency{Min: 4, Max: 2}, "below its minimum"},
+        {AdaptiveConcurrency{Max: 2, Initial: 3}, "initial limit 3"},
+        {AdaptiveConcurrency{Max: 2, Backoff: 1}, "backoff"},
+        {AdaptiveConcurrency{Min: -1, Max: 2}, "at least 1"},
+    } {
+        if err := executor.SetAdaptiveConcurrency(tc.config); err == nil || !strings.Contains(err.Error(), tc.want) {
+            t.Errorf("%+v: expected an error containing %q, got %v", tc.config, tc.want, err)
+        }
+        if err := NewExecutor(TaskGraph(), WithAdaptiveConcurrency(tc.config)).Execute(); err == nil || !strings.Contains(err.Error(), tc.want) {
+            t.Errorf("%+v: expected Execute to return an error containing %q, got %v", tc.config, tc.want, err)
+        }
+    }
+}
@@ -0,0 +1,221 @@
+package leo
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// ExportAirflow writes g as an Airflow DAG definition in Python. Nodes with a
+// recorded shell command (see AddShell and ParseMakefile) become
+// BashOperators; all other nodes become EmptyOperator placeholders for the
+// receiving team to replace. Node names are used as task IDs, with characters
+// Airflow does not allow replaced by underscores. Edge policies and fallback
+// edges have no direct Airflow equivalent and are not exported.
+func ExportAirflow(w io.Writer, g *Graph, dagID string) error {
+    names := make([]string, 0, len(g.nodes))
+    for name := range g.nodes {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    taskIDs := make(map[string]string, len(names))
+    vars := make(map[string]string, len(names))
+    usedIDs := make(map[string]string)
+    usedVars := make(map[string]bool)
+    for _, name := range names {
+        id := airflowTaskID(name)
+        if other, exists := usedIDs[id]; exists {
+            return fmt.Errorf("nodes %s and %s both map to Airflow task ID %s", other, name, id)
+        }
+        usedIDs[id] = name
+        taskIDs[name] = id
+
+        v := pythonIdentifier(id)
+        for i := 2; usedVars[v]; i++ {
+            v = fmt.Sprintf("%s_%d", pythonIdentifier(id), i)
+        }
+        usedVars[v] = true
+        vars[name] = v
+    }
+
+    bw := bufio.NewWriter(w)
+    fmt.Fprintf(bw, "# Generated by leo. Placeholder tasks use EmptyOperator.\n")
+    fmt.Fprintf(bw, "from datetime import datetime\n\n")
+    fmt.Fprintf(bw, "from airflow import DAG\n")
+    fmt.Fprintf(bw, "from airflow.operators.bash import BashOperator\n")
+    fmt.Fprintf(bw, "from airflow.operators.empty import EmptyOperator\n\n")
+    fmt.Fprintf(bw, "with DAG(dag_id=%s, start_date=datetime(2024, 1, 1), schedule=None, catchup=False) as dag:\n", strconv.Quote(dagID))
+
+    for _, name := range names {
+        node := g.nodes[name]
+        if node.command != "" {
+            fmt.Fprintf(bw, "    %s = BashOperator(task_id=%s, bash_command=%s)\n", vars[name], strconv.Quote(taskIDs[name]), strconv.Quote(node.command))
+        } else {
+            fmt.Fprintf(bw, "    %s = EmptyOperator(task_id=%s)\n", vars[name], strconv.Quote(taskIDs[name]))
+        }
+    }
+
+    edges := edgeSet(g)
+    sorted := make([]Edge, 0, len(edges))
+    for e := range edges {
+        if !e.Fallback {
+            sorted = append(sorted, e)
+        }
+    }
+    sortEdges(sorted)
+    if len(sorted) > 0 {
+        fmt.Fprintln(bw)
+    }
+    for _, e := range sorted {
+        fmt.Fprintf(bw, "    %s >> %s\n", vars[e.From], vars[e.To])
+    }
+
+    return bw.Flush()
+}
+
+var (
+    airflowOperator = regexp.MustCompile(`^(\w+)\s*=\s*(\w+)\((.*)\)\s*$`)
+    airflowBitshift = regexp.MustCompile(`>>|<<`)
+    airflowKeyword  = regexp.MustCompile(`(\w+)\s*=\s*("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`)
+)
+
+// ImportAirflow reads a restricted subset of an Airflow DAG file back into a
+// graph: single-line operator assignments such as
+//
+//    extract = BashOperator(task_id="extract", bash_command="./extract.sh")
+//
+// and dependencies written with >> or <<, including lists on either side,
+// such as "extract >> [transform, validate]". BashOperators become shell
+// tasks; any other operator becomes a no-op task for the caller to replace.
+// Other lines are ignored.
+func ImportAirflow(r io.Reader) (*Graph, error) {
+    graph := TaskGraph()
+    names := make(map[string]string)
+
+    var deps []string
+    scanner := bufio.NewScanner(r)
+    lineNo := 0
+    for scanner.Scan() {
+        lineNo++
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+
+        if m := airflowOperator.FindStringSubmatch(line); m != nil && strings.HasSuffix(m[2], "Operator") {
+            args := make(map[string]string)
+            for _, kw := range airflowKeyword.FindAllStringSubmatch(m[3], -1) {
+                value, err := pythonString(kw[2])
+                if err != nil {
+                    return nil, fmt.Errorf("airflow line %d: %s: %w", lineNo, kw[1], err)
+                }
+                args[kw[1]] = value
+            }
+            id, ok := args["task_id"]
+            if !ok {
+                return nil, fmt.Errorf("airflow line %d: operator without a task_id", lineNo)
+            }
+            if _, exists := graph.nodes[id]; exists {
+                return nil, fmt.Errorf("airflow line %d: duplicate task_id %s", lineNo, id)
+            }
+            if cmd, ok := args["bash_command"]; ok && m[2] == "BashOperator" {
+                graph.AddShell(id, cmd)
+            } else {
+                graph.Add(id, func() error { return nil })
+            }
+            names[m[1]] = id
+            continue
+        }
+
+        if strings.Contains(line, ">>") || strings.Contains(line, "<<") {
+            deps = append(deps, line)
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+
+    for _, line := range deps {
+        if err := airflowDependencies(graph, names, line); err != nil {
+            return nil, err
+        }
+    }
+
+    return graph, nil
+}
+
+// airflowDependencies adds the edges described by a chain such as
+// "a >> [b, c] >> d" or "d << c".
+func airflowDependencies(graph *Graph, names map[string]string, line string) error {
+    tokens := airflowBitshift.Split(line, -1)
+    ops := airflowBitshift.FindAllString(line, -1)
+
+    groups := make([][]string, len(tokens))
+    for i, token := range tokens {
+        token = strings.Trim(strings.TrimSpace(token), "[]")
+        for _, v := range strings.Split(token, ",") {
+            v = strings.TrimSpace(v)
+            if v == "" {
+                continue
+            }
+            name, ok := names[v]
+            if !ok {
+                return fmt.Errorf("airflow: unknown task %s in %q", v, line)
+            }
+            groups[i] = append(groups[i], name)
+        }
+    }
+
+    for i, op := range ops {
+        upstream, downstream := groups[i], groups[i+1]
+        if op == "<<" {
+            upstream, downstream = downstream, upstream
+        }
+        for _, from := range upstream {
+            for _, to := range downstream {
+                if err := graph.Precede(from, to); err != nil {
+                    return fmt.Errorf("airflow: %s >> %s: %w", from, to, err)
+                }
+            }
+        }
+    }
+    return nil
+}
+
+// airflowTaskID replaces characters that are not valid in an Airflow task ID.
+func airflowTaskID(name string) string {
+    return strings.Map(func(r rune) rune {
+        switch {
+        case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.', r == '_':
+            return r
+        }
+        return '_'
+    }, name)
+}
+
+func pythonIdentifier(id string) string {
+    ident := strings.Map(func(r rune) rune {
+        if r == '-' || r == '.' {
+            return '_'
+        }
+        return r
+    }, strings.ToLower(id))
+    // The prefix keeps identifiers valid and clear of Python keywords.
+    return "task_" + ident
+}
+
+// pythonString decodes a quoted Python string literal without prefixes.
+func pythonString(lit string) (string, error) {
+    if strings.HasPrefix(lit, "'") {
+        inner := lit[1 : len(lit)-1]
+        inner = strings.ReplaceAll(inner, `\'`, `'`)
+        inner = strings.ReplaceAll(inner, `"`, `\"`)
+        lit = `"` + inner + `"`
+    }
+    return strconv.Unquote(lit)
+}
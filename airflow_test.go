@@ -0,0 +1,83 @@
+package leo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExportAirflow(t *testing.T) {
+    graph := TaskGraph()
+    graph.AddShell("extract", "./extract.sh --all")
+    graph.Add("Task B", func() error { return nil })
+    graph.Precede("extract", "Task B")
+
+    var buf bytes.Buffer
+    if err := ExportAirflow(&buf, graph, "etl"); err != nil {
+        t.Fatalf("ExportAirflow failed: %v", err)
+    }
+    out := buf.String()
+
+    for _, want := range []string{
+        `with DAG(dag_id="etl"`,
+        `task_extract = BashOperator(task_id="extract", bash_command="./extract.sh --all")`,
+        `task_task_b = EmptyOperator(task_id="Task_B")`,
+        `task_extract >> task_task_b`,
+    } {
+        if !strings.Contains(out, want) {
+            t.Errorf("export missing %q:\n%s", want, out)
+        }
+    }
+}
+
+func TestAirflowRoundTrip(t *testing.T) {
+    graph := TaskGraph()
+    graph.AddShell("extract", `echo "it's done"`)
+    graph.AddShell("transform", "true")
+    graph.Add("load", func() error { return nil })
+    graph.Precede("extract", "transform")
+    graph.Precede("transform", "load")
+
+    var buf bytes.Buffer
+    if err := ExportAirflow(&buf, graph, "etl"); err != nil {
+        t.Fatalf("ExportAirflow failed: %v", err)
+    }
+
+    imported, err := ImportAirflow(&buf)
+    if err != nil {
+        t.Fatalf("ImportAirflow failed: %v", err)
+    }
+    if d := Diff(graph, imported); !d.Empty() {
+        t.Errorf("round trip changed the graph:\n%s", d)
+    }
+    if err := NewExecutor(imported).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+}
+
+func TestImportAirflowChains(t *testing.T) {
+    dag := `
+from airflow import DAG
+with DAG("x") as dag:
+    a = EmptyOperator(task_id='a')
+    b = EmptyOperator(task_id="b")
+    c = EmptyOperator(task_id="c")
+    d = BashOperator(task_id="d", bash_command='echo d')
+    a >> [b, c] >> d
+    d << a
+`
+    graph, err := ImportAirflow(strings.NewReader(dag))
+    if err != nil {
+        t.Fatalf("ImportAirflow failed: %v", err)
+    }
+    if got := len(graph.Edges()); got != 5 {
+        t.Errorf("expected 5 edges, got %d: %v", got, graph.Edges())
+    }
+    if graph.nodes["d"].command != "echo d" {
+        t.Errorf("expected d to keep its bash command, got %q", graph.nodes["d"].command)
+    }
+
+    if _, err := ImportAirflow(strings.NewReader("a = EmptyOperator(task_id='a')\na >> missing\n")); err == nil {
+        t.Errorf("expected an error for an unknown task")
+    }
+}
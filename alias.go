@@ -0,0 +1,90 @@
+package leo
+
+import (
+    "fmt"
+    "sort"
+)
+
+// Alias registers alias as an alternate name for the node called name, so
+// that a renamed task can still be referred to by its old name while
+// pipelines and invocations that use it are migrated. Methods that look up
+// existing nodes by name, such as Precede, OnFailure, Bind, Ancestors,
+// Subgraph and Run.Disable, accept aliases; adding a node under an alias
+// finds the aliased node, which the graph's DuplicatePolicy then governs.
+// Nodes keep their own name in reports and events.
+//
+// name may itself be an alias. It is an error for alias to be taken by a
+// node or by an alias of another node. It returns ErrFrozen if the graph is
+// frozen.
+func (g *Graph) Alias(alias, name string) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    n, exists := g.lookup(name)
+    if !exists {
+        return fmt.Errorf("node %s does not exist", name)
+    }
+    if alias == "" {
+        return fmt.Errorf("alias of %s is empty", n.name)
+    }
+    if taken, exists := g.lookup(alias); exists && (taken != n || taken.name == alias) {
+        return fmt.Errorf("alias %s is already the name of %s: %w", alias, taken.name, ErrNodeExists)
+    }
+    if g.aliases == nil {
+        g.aliases = make(map[string]string)
+    }
+    g.aliases[alias] = n.name
+    return nil
+}
+
+// Aliases returns the graph's aliases, mapping each to the name of its node.
+func (g *Graph) Aliases() map[string]string {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    out := make(map[string]string, len(g.aliases))
+    for alias, name := range g.aliases {
+        out[alias] = name
+    }
+    return out
+}
+
+// Resolve returns the name of the node called name or aliased by it, and
+// whether there is one.
+func (g *Graph) Resolve(name string) (string, bool) {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    n, exists := g.lookup(name)
+    if !exists {
+        return "", false
+    }
+    return n.name, true
+}
+
+// AliasesOf returns the sorted aliases of the node called name.
+func (g *Graph) AliasesOf(name string) []string {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    var aliases []string
+    for alias, target := range g.aliases {
+        if target == name {
+            aliases = append(aliases, alias)
+        }
+    }
+    sort.Strings(aliases)
+    return aliases
+}
+
+// lookup returns the node called name or aliased by it. The caller must
+// hold g.mu, unless the graph is no longer modified.
+func (g *Graph) lookup(name string) (*Node, bool) {
+    if n, exists := g.nodes[name]; exists {
+        return n, true
+    }
+    if target, exists := g.aliases[name]; exists {
+        n, exists := g.nodes[target]
+        return n, exists
+    }
+    return nil, false
+}
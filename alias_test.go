@@ -0,0 +1,85 @@
+package leo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+    graph := TaskGraph()
+    var ran []string
+    record := func(name string) TaskFunc {
+        return func() error { ran = append(ran, name); return nil }
+    }
+    graph.Add("fetch", record("fetch"))
+    graph.Add("compile", record("compile"))
+    graph.Add("test", record("test"))
+    if err := graph.Alias("build", "compile"); err != nil {
+        t.Fatalf("Alias failed: %v", err)
+    }
+    if err := graph.Alias("make", "build"); err != nil {
+        t.Fatalf("aliasing an alias failed: %v", err)
+    }
+    if err := graph.Precede("fetch", "build"); err != nil {
+        t.Fatalf("Precede through an alias failed: %v", err)
+    }
+    if err := graph.Succeed("test", "make"); err != nil {
+        t.Fatalf("Succeed through an alias failed: %v", err)
+    }
+    if name, ok := graph.Resolve("make"); !ok || name != "compile" {
+        t.Errorf("expected make to resolve to compile, got %q, %v", name, ok)
+    }
+    if got := graph.AliasesOf("compile"); fmt.Sprint(got) != "[build make]" {
+        t.Errorf("unexpected aliases %v", got)
+    }
+    if ancestors, err := graph.Ancestors("make"); err != nil || fmt.Sprint(ancestors) != "[fetch]" {
+        t.Errorf("unexpected ancestors %v, %v", ancestors, err)
+    }
+
+    for alias, name := range map[string]string{"test": "compile", "build": "fetch", "": "fetch"} {
+        if err := graph.Alias(alias, name); err == nil {
+            t.Errorf("expected aliasing %q to %s to fail", alias, name)
+        }
+    }
+    if err := graph.Alias("build", "compile"); err != nil {
+        t.Errorf("expected repeating an alias to succeed, got %v", err)
+    }
+    graph.SetDuplicatePolicy(DuplicateError)
+    if _, err := graph.Add("build", nil); !errors.Is(err, ErrNodeExists) {
+        t.Errorf("expected adding under an alias to find the node, got %v", err)
+    }
+
+    run := NewExecutor(graph).NewRun()
+    if err := run.Disable("build"); err != nil {
+        t.Fatalf("Disable through an alias failed: %v", err)
+    }
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    // Disabling build skips test too, along its default edge.
+    if fmt.Sprint(ran) != "[fetch]" {
+        t.Errorf("unexpected tasks %v", ran)
+    }
+    if _, ok := run.Report().Nodes["compile"]; !ok {
+        t.Errorf("expected the report to use the node's own name")
+    }
+
+    sub, err := graph.Subgraph("build", "test")
+    if err != nil {
+        t.Fatalf("Subgraph through an alias failed: %v", err)
+    }
+    if len(sub.Aliases()) != 2 {
+        t.Errorf("expected the subgraph to keep the aliases, got %v", sub.Aliases())
+    }
+    merged := TaskGraph()
+    if err := merged.Merge(graph, Namespace("v2")); err != nil {
+        t.Fatalf("Merge failed: %v", err)
+    }
+    if name, _ := merged.Resolve("v2/build"); name != "v2/compile" {
+        t.Errorf("expected merged aliases to be namespaced, got %q", name)
+    }
+    if err := merged.Merge(sub, Namespace("v2")); !errors.Is(err, ErrNodeExists) {
+        t.Errorf("expected aliases to collide, got %v", err)
+    }
+}
@@ -0,0 +1,111 @@
+package leo
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "io"
+    "net/url"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// Artifact is something a run produced, such as a binary, an image or a
+// report, recorded so that downstream systems can find it. Artifacts are
+// kept in the run's Report and saved with it to the history store, see
+// FindArtifacts.
+type Artifact struct {
+    Name string `json:"name"`
+    // URI locates the artifact, such as file:///build/app or
+    // oci://ghcr.io/acme/app.
+    URI string `json:"uri"`
+    // Digest identifies the artifact's content, such as "sha256:...".
+    Digest string `json:"digest,omitempty"`
+    // Node and RunID identify the producer, and Time when it was recorded.
+    Node  string    `json:"node"`
+    RunID string    `json:"run_id"`
+    Time  time.Time `json:"time"`
+}
+
+// RecordArtifact records an artifact produced by the task that received ctx,
+// filling in its Node, RunID and Time. It returns an error if ctx did not
+// come from a task.
+func RecordArtifact(ctx context.Context, a Artifact) error {
+    r, n := runNode(ctx)
+    if r == nil || n == nil {
+        return errors.New("RecordArtifact called outside a task")
+    }
+    r.addArtifact(n, a)
+    return nil
+}
+
+// FileArtifact returns an artifact for the file at path, named after it,
+// with a file URI and the SHA-256 digest of its content.
+func FileArtifact(path string) (Artifact, error) {
+    abs, err := filepath.Abs(path)
+    if err != nil {
+        return Artifact{}, err
+    }
+    f, err := os.Open(abs)
+    if err != nil {
+        return Artifact{}, err
+    }
+    defer f.Close()
+    h := sha256.New()
+    if _, err := io.Copy(h, f); err != nil {
+        return Artifact{}, err
+    }
+    return Artifact{
+        Name:   filepath.Base(abs),
+        URI:    (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(),
+        Digest: "sha256:" + hex.EncodeToString(h.Sum(nil)),
+    }, nil
+}
+
+func (r *Run) addArtifact(n *Node, a Artifact) {
+    a.Node = n.name
+    a.RunID = r.id
+    a.Time = r.executor.now()
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    r.artifacts = append(r.artifacts, a)
+}
+
+// recordOutputs records the output files of n (see WithOutputs), once its
+// task has succeeded. Outputs that cannot be read are left out.
+func (r *Run) recordOutputs(n *Node) {
+    for _, path := range n.outputFiles {
+        if a, err := FileArtifact(path); err == nil {
+            r.addArtifact(n, a)
+        }
+    }
+}
+
+// Artifacts returns the artifacts recorded so far by the run's tasks, in the
+// order they were recorded. Nodes' output files (see WithOutputs) are
+// recorded as file artifacts when their tasks succeed.
+func (r *Run) Artifacts() []Artifact {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    return append([]Artifact(nil), r.artifacts...)
+}
+
+// FindArtifacts returns the artifacts named name in the runs kept by h,
+// newest first.
+func FindArtifacts(h HistoryStore, name string) ([]Artifact, error) {
+    runs, err := h.Runs(0)
+    if err != nil {
+        return nil, err
+    }
+    var out []Artifact
+    for _, run := range runs {
+        for i := len(run.Artifacts) - 1; i >= 0; i-- {
+            if a := run.Artifacts[i]; a.Name == name {
+                out = append(out, a)
+            }
+        }
+    }
+    return out, nil
+}
@@ -0,0 +1,61 @@
+package leo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArtifacts(t *testing.T) {
+    dir := t.TempDir()
+    bin := filepath.Join(dir, "app")
+
+    graph := TaskGraph()
+    graph.Add("build", func() error { return os.WriteFile(bin, []byte("binary"), 0o755) }, WithOutputs(bin))
+    graph.AddCtx("push", func(ctx context.Context) error {
+        return RecordArtifact(ctx, Artifact{Name: "image", URI: "oci://ghcr.io/acme/app:1", Digest: "sha256:abc"})
+    })
+    graph.Precede("build", "push")
+
+    history := NewMemoryHistory(0)
+    executor := NewExecutor(graph)
+    executor.SetHistory(history)
+    run := executor.NewRun()
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    artifacts := run.Artifacts()
+    if len(artifacts) != 2 {
+        t.Fatalf("expected two artifacts, got %+v", artifacts)
+    }
+    file, image := artifacts[0], artifacts[1]
+    sum := sha256.Sum256([]byte("binary"))
+    if file.Name != "app" || file.Node != "build" || !strings.HasPrefix(file.URI, "file://") || !strings.HasSuffix(file.URI, "/app") ||
+        file.Digest != "sha256:"+hex.EncodeToString(sum[:]) {
+        t.Errorf("unexpected file artifact %+v", file)
+    }
+    if image.Node != "push" || image.RunID != run.ID() || image.Time.IsZero() {
+        t.Errorf("unexpected recorded artifact %+v", image)
+    }
+    if len(run.Report().Artifacts) != 2 {
+        t.Errorf("expected the report to list the artifacts, got %+v", run.Report().Artifacts)
+    }
+
+    executor.Execute()
+    found, err := FindArtifacts(history, "image")
+    if err != nil {
+        t.Fatalf("FindArtifacts failed: %v", err)
+    }
+    if len(found) != 2 || found[0].RunID != executor.Report().ID || found[1].RunID != run.ID() {
+        t.Errorf("expected both runs' images, newest first, got %+v", found)
+    }
+
+    if err := RecordArtifact(context.Background(), Artifact{Name: "stray"}); err == nil {
+        t.Errorf("expected recording outside a task to fail")
+    }
+}
@@ -0,0 +1,110 @@
+package leo
+
+import (
+    "context"
+    "errors"
+)
+
+// ErrRunCancelled is the cause of the context passed to a started run's
+// tasks when the run is cancelled, see Run.Cancel.
+var ErrRunCancelled = errors.New("run cancelled")
+
+// async is the state of a run started with Start.
+type async struct {
+    done   chan struct{}
+    cancel context.CancelCauseFunc
+    err    error
+}
+
+// Start starts a new run of the graph and returns it without waiting for it
+// to finish, see Run.Start.
+func (e *Executor) Start(ctx context.Context) *Run {
+    r := e.NewRun()
+    r.Start(ctx)
+    return r
+}
+
+// Start executes the run in the background, passing ctx to context-aware
+// tasks, and returns immediately. While the run executes, Status, ETA and
+// Results may be polled, and Cancel stops it; Wait and Done report when it
+// has returned. Subscribe before calling Start to receive every event of the
+// run. Start does nothing if the run has already been started.
+func (r *Run) Start(ctx context.Context) {
+    ctx, cancel := context.WithCancelCause(ctx)
+    r.mu.Lock()
+    if r.async != nil {
+        r.mu.Unlock()
+        cancel(nil)
+        return
+    }
+    a := &async{done: make(chan struct{}), cancel: cancel}
+    r.async = a
+    r.mu.Unlock()
+
+    go func() {
+        defer cancel(nil)
+        err := r.ExecuteContext(ctx)
+        if err != nil && errors.Is(context.Cause(ctx), ErrRunCancelled) && errors.Is(err, context.Canceled) {
+            err = ErrRunCancelled
+        }
+        a.err = err
+        close(a.done)
+    }()
+}
+
+// background returns the run's background execution, or nil if Start has not
+// been called.
+func (r *Run) background() *async {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.async
+}
+
+// Done returns a channel that is closed once a started run has returned. It
+// returns nil, which blocks forever, if Start has not been called.
+func (r *Run) Done() <-chan struct{} {
+    if a := r.background(); a != nil {
+        return a.done
+    }
+    return nil
+}
+
+// Wait waits for a started run to return and returns its error, which is
+// ErrRunCancelled if Cancel stopped it. It returns an error immediately if
+// Start has not been called.
+func (r *Run) Wait() error {
+    a := r.background()
+    if a == nil {
+        return errors.New("run has not been started")
+    }
+    <-a.done
+    return a.err
+}
+
+// Cancel stops a started run, cancelling the context of its running tasks
+// with ErrRunCancelled. It does not wait for the run to return; call Wait
+// for that. Cancel does nothing if the run has not been started or has
+// already returned.
+func (r *Run) Cancel() {
+    if a := r.background(); a != nil {
+        a.cancel(ErrRunCancelled)
+    }
+}
+
+// Subscribe returns a subscription to the events of this run only. C is
+// closed once the run's EventRunFinished has been received, or immediately
+// if a started run has already returned.
+func (r *Run) Subscribe() *Subscription {
+    s := r.executor.Subscribe()
+    s.mu.Lock()
+    s.run = r
+    s.mu.Unlock()
+    if a := r.background(); a != nil {
+        select {
+        case <-a.done:
+            s.Close()
+        default:
+        }
+    }
+    return s
+}
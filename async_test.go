@@ -0,0 +1,115 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+    started, release := make(chan struct{}), make(chan struct{})
+    graph := TaskGraph()
+    graph.Add("build", func() error {
+        if started != nil {
+            close(started)
+            started = nil
+            <-release
+        }
+        return nil
+    })
+    graph.Add("test", func() error { return nil })
+    graph.Precede("build", "test")
+
+    executor := NewExecutor(graph)
+    run := executor.NewRun()
+    sub := run.Subscribe()
+    other := executor.Subscribe()
+    defer other.Close()
+    wait := started
+    run.Start(context.Background())
+
+    <-wait
+    if st := run.Status()["build"]; st.State != StateRunning {
+        t.Errorf("expected build to be running, got %s", st.State)
+    }
+    select {
+    case <-run.Done():
+        t.Fatalf("expected the run to still be executing")
+    default:
+    }
+    close(release)
+    if err := run.Wait(); err != nil {
+        t.Fatalf("Wait failed: %v", err)
+    }
+    if st := run.Status()["test"]; st.State != StateSucceeded {
+        t.Errorf("expected test to have succeeded, got %s", st.State)
+    }
+
+    var types []EventType
+    for ev := range sub.C {
+        if ev.Run != run {
+            t.Errorf("expected only the run's events, got %v", ev)
+        }
+        types = append(types, ev.Type)
+    }
+    if len(types) == 0 || types[len(types)-1] != EventRunFinished {
+        t.Errorf("expected the subscription to end with the run, got %v", types)
+    }
+
+    // A second run of the executor is not delivered to the first's
+    // subscription, which has closed.
+    if err := executor.Start(context.Background()).Wait(); err != nil {
+        t.Fatalf("second run failed: %v", err)
+    }
+    if _, ok := <-sub.C; ok {
+        t.Errorf("expected the run's subscription to stay closed")
+    }
+}
+
+func TestStartReport(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("build", func() error { return nil })
+
+    run := NewExecutor(graph, WithConcurrency(1)).NewRun()
+    run.Start(context.Background())
+    // The report may be polled while the run starts.
+    for run.Report() == nil {
+        runtime.Gosched()
+    }
+    if err := run.Wait(); err != nil {
+        t.Fatalf("Wait failed: %v", err)
+    }
+    if rep := run.Report(); rep.ID != run.ID() || rep.Scheduling != ScheduleFIFO {
+        t.Errorf("unexpected report %+v", rep)
+    }
+}
+
+func TestStartCancel(t *testing.T) {
+    graph := TaskGraph()
+    cause := make(chan error, 1)
+    started := make(chan struct{})
+    graph.AddCtx("wait", func(ctx context.Context) error {
+        close(started)
+        <-ctx.Done()
+        cause <- context.Cause(ctx)
+        return ctx.Err()
+    })
+    graph.Add("after", func() error { return nil })
+    graph.Precede("wait", "after")
+
+    run := NewExecutor(graph).Start(context.Background())
+    <-started
+    run.Cancel()
+    if err := run.Wait(); !errors.Is(err, ErrRunCancelled) {
+        t.Fatalf("expected ErrRunCancelled, got %v", err)
+    }
+    if err := <-cause; !errors.Is(err, ErrRunCancelled) {
+        t.Errorf("expected the task's context to be cancelled by the run, got %v", err)
+    }
+    run.Cancel()
+
+    if err := NewExecutor(graph).NewRun().Wait(); err == nil {
+        t.Errorf("expected Wait to fail for a run that was not started")
+    }
+}
@@ -0,0 +1,137 @@
+package leo
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+)
+
+// AddAll adds a task for each name in tasks, for graphs generated by code.
+// Existing names are handled according to the graph's DuplicatePolicy, but
+// either every task is added or none is: with DuplicateError, AddAll returns
+// an error wrapping ErrNodeExists without changing the graph if any name is
+// taken. It returns ErrFrozen if the graph is frozen.
+func (g *Graph) AddAll(tasks map[string]TaskFunc) error {
+    names := make([]string, 0, len(tasks))
+    for name := range tasks {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    if g.duplicates == DuplicateError {
+        for _, name := range names {
+            if n, exists := g.lookup(name); exists && !n.unbound {
+                return fmt.Errorf("node %s: %w", name, ErrNodeExists)
+            }
+        }
+    }
+    for _, name := range names {
+        var task TaskCtxFunc
+        if t := tasks[name]; t != nil {
+            task = func(context.Context) error { return t() }
+        }
+        if n, exists := g.lookup(name); exists {
+            if n.unbound || g.duplicates == DuplicateReplace {
+                n.bind(task, nil)
+            }
+            continue
+        }
+        g.addNode(name, task, nil)
+    }
+    return nil
+}
+
+// AddEdges adds an edge from edges[i][0] to edges[i][1] for each i, like
+// Precede, but checks the graph for cycles once after adding them all
+// instead of once per edge, which is faster for large generated graphs.
+// Either every edge is added or none is: if a node does not exist or the
+// edges would create a cycle, AddEdges returns an error, a *CycleError for
+// a cycle, and leaves the graph unchanged. It returns ErrFrozen if the graph
+// is frozen.
+func (g *Graph) AddEdges(edges [][2]string) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    for _, e := range edges {
+        for _, name := range e {
+            if _, exists := g.lookup(name); !exists {
+                return fmt.Errorf("edge %s → %s: node %s does not exist", e[0], e[1], name)
+            }
+        }
+    }
+
+    children := make(map[*Node]int)
+    parents := make(map[*Node]int)
+    for _, e := range edges {
+        from, _ := g.lookup(e[0])
+        to, _ := g.lookup(e[1])
+        if _, seen := children[from]; !seen {
+            children[from] = len(from.children)
+        }
+        if _, seen := parents[to]; !seen {
+            parents[to] = len(to.parents)
+        }
+        from.children = append(from.children, to)
+        to.parents = append(to.parents, from)
+    }
+    if !g.hasCycle() {
+        return nil
+    }
+
+    rollback := func() {
+        for n, size := range children {
+            n.children = n.children[:size]
+        }
+        for n, size := range parents {
+            n.parents = n.parents[:size]
+        }
+    }
+    // Find the edge that closes a cycle, to report it like Precede would.
+    rollback()
+    for _, e := range edges {
+        from, _ := g.lookup(e[0])
+        to, _ := g.lookup(e[1])
+        if cycle := g.cycleThrough(from, to); cycle != nil {
+            rollback()
+            return &CycleError{Path: cycle}
+        }
+        from.children = append(from.children, to)
+        to.parents = append(to.parents, from)
+    }
+    rollback()
+    return &CycleError{}
+}
+
+// FromEdges returns a graph of tasks with an edge from edges[i][0] to
+// edges[i][1] for each i, for graphs whose topology comes from data. Every
+// edge that names a node missing from tasks is reported, by its index, in
+// the returned error; a cycle is reported as a *CycleError.
+func FromEdges(tasks map[string]TaskFunc, edges [][2]string) (*Graph, error) {
+    var errs []error
+    for i, e := range edges {
+        for _, name := range e {
+            if _, ok := tasks[name]; !ok {
+                errs = append(errs, fmt.Errorf("edge %d (%s → %s): unknown node %q", i, e[0], e[1], name))
+            }
+        }
+    }
+    if len(errs) > 0 {
+        return nil, errors.Join(errs...)
+    }
+    g := TaskGraph()
+    if err := g.AddAll(tasks); err != nil {
+        return nil, err
+    }
+    if err := g.AddEdges(edges); err != nil {
+        return nil, err
+    }
+    return g, nil
+}
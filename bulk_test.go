@@ -0,0 +1,89 @@
+package leo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAddAll(t *testing.T) {
+    graph := TaskGraph()
+    var ran []string
+    tasks := make(map[string]TaskFunc)
+    for i := 0; i < 5; i++ {
+        name := fmt.Sprintf("shard-%d", i)
+        tasks[name] = func() error { ran = append(ran, name); return nil }
+    }
+    if err := graph.AddAll(tasks); err != nil {
+        t.Fatalf("AddAll failed: %v", err)
+    }
+    edges := [][2]string{{"shard-0", "shard-1"}, {"shard-1", "shard-2"}, {"shard-2", "shard-3"}, {"shard-3", "shard-4"}}
+    if err := graph.AddEdges(edges); err != nil {
+        t.Fatalf("AddEdges failed: %v", err)
+    }
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if fmt.Sprint(ran) != "[shard-0 shard-1 shard-2 shard-3 shard-4]" {
+        t.Errorf("unexpected order %v", ran)
+    }
+
+    graph.SetDuplicatePolicy(DuplicateError)
+    err := graph.AddAll(map[string]TaskFunc{"new": nil, "shard-2": nil})
+    if !errors.Is(err, ErrNodeExists) {
+        t.Errorf("expected ErrNodeExists, got %v", err)
+    }
+    if len(graph.nodes) != 5 {
+        t.Errorf("expected a failed AddAll to add nothing, got %d nodes", len(graph.nodes))
+    }
+}
+
+func TestAddEdgesIsAllOrNothing(t *testing.T) {
+    graph := TaskGraph()
+    graph.AddAll(map[string]TaskFunc{"a": nil, "b": nil, "c": nil})
+    graph.Precede("a", "b")
+
+    err := graph.AddEdges([][2]string{{"b", "c"}, {"c", "a"}})
+    var cycle *CycleError
+    if !errors.As(err, &cycle) {
+        t.Fatalf("expected a *CycleError, got %v", err)
+    }
+    if fmt.Sprint(cycle.Path) != "[c a b c]" {
+        t.Errorf("unexpected cycle %v", cycle.Path)
+    }
+    if err := graph.AddEdges([][2]string{{"b", "c"}, {"c", "missing"}}); err == nil {
+        t.Errorf("expected an edge to a missing node to fail")
+    }
+    if edges := graph.Edges(); len(edges) != 1 {
+        t.Errorf("expected the failed calls to leave one edge, got %v", edges)
+    }
+    for _, n := range graph.nodes {
+        if len(n.parents) > 1 || len(n.children) > 1 {
+            t.Errorf("%s was left with edges %d/%d", n.name, len(n.parents), len(n.children))
+        }
+    }
+}
+
+func TestFromEdges(t *testing.T) {
+    tasks := map[string]TaskFunc{"fetch": nil, "build": nil, "test": nil}
+    graph, err := FromEdges(tasks, [][2]string{{"fetch", "build"}, {"build", "test"}})
+    if err != nil {
+        t.Fatalf("FromEdges failed: %v", err)
+    }
+    if len(graph.Edges()) != 2 {
+        t.Errorf("expected 2 edges, got %v", graph.Edges())
+    }
+
+    _, err = FromEdges(tasks, [][2]string{{"fetch", "biuld"}, {"build", "test"}, {"tset", "fetch"}})
+    if err == nil || !strings.Contains(err.Error(), `edge 0 (fetch → biuld): unknown node "biuld"`) ||
+        !strings.Contains(err.Error(), `edge 2 (tset → fetch): unknown node "tset"`) {
+        t.Errorf("expected every unknown node to be reported, got %v", err)
+    }
+
+    _, err = FromEdges(tasks, [][2]string{{"fetch", "build"}, {"build", "fetch"}})
+    var cycle *CycleError
+    if !errors.As(err, &cycle) {
+        t.Errorf("expected a *CycleError, got %v", err)
+    }
+}
@@ -0,0 +1,181 @@
+package leo
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sync"
+)
+
+// Cache stores the results of cached nodes, see WithCacheKey. Keys are
+// hex-encoded SHA-256 digests, safe to use as file or object names.
+// Implementations may be shared by several executors and machines.
+type Cache interface {
+    // Get returns the entry for key, reporting false if there is none.
+    Get(ctx context.Context, key string) ([]byte, bool, error)
+    // Put stores an entry.
+    Put(ctx context.Context, key string, data []byte) error
+}
+
+// SetCache sets the cache used by nodes added with WithCacheKey.
+func (e *Executor) SetCache(c Cache) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.cache = c
+}
+
+func (e *Executor) getCache() Cache {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.cache
+}
+
+// WithCacheKey memoizes a node: key is computed from the run's context when
+// the node is ready, and if the executor's cache (see SetCache) holds an
+// entry for the node and key, the node is reported as succeeded and cached
+// without calling its task. Otherwise the task runs and, if it succeeds, an
+// entry is stored. For nodes added with AddFunc, the entry holds the
+// function's result encoded as JSON, which consumers receive on a hit. An
+// empty key disables caching for that run.
+//
+// Caching is best effort: a cache that cannot be read counts as a miss, and
+// an entry that cannot be stored is dropped.
+func WithCacheKey(key func(ctx context.Context) string) NodeOption {
+    return func(n *Node) {
+        n.cacheKey = key
+    }
+}
+
+// cacheDigest returns the cache key of n for key.
+func cacheDigest(n *Node, key string) string {
+    sum := sha256.Sum256([]byte(n.name + "\x00" + key))
+    return hex.EncodeToString(sum[:])
+}
+
+// fromCache looks n up in the cache, restoring its result on a hit. It
+// returns the digest to store n's result under after a miss, or "" if n is
+// not cached in this run.
+func (r *Run) fromCache(n *Node) (hit bool, digest string) {
+    c := r.executor.getCache()
+    if c == nil || n.cacheKey == nil {
+        return false, ""
+    }
+    key := n.cacheKey(r.ctx)
+    if key == "" {
+        return false, ""
+    }
+    digest = cacheDigest(n, key)
+
+    data, ok, err := c.Get(r.ctx, digest)
+    if err != nil || !ok {
+        return false, digest
+    }
+    if n.produces != nil {
+        v := reflect.New(n.produces)
+        if err := json.Unmarshal(data, v.Interface()); err != nil {
+            return false, digest
+        }
+        r.setResult(n.name, v.Elem().Interface())
+    }
+    return true, digest
+}
+
+// toCache stores n's result under digest.
+func (r *Run) toCache(n *Node, digest string) {
+    c := r.executor.getCache()
+    if c == nil {
+        return
+    }
+    data := []byte("{}")
+    if n.produces != nil {
+        v, _ := r.Result(n.name)
+        encoded, err := json.Marshal(v)
+        if err != nil {
+            return
+        }
+        data = encoded
+    }
+    c.Put(r.ctx, digest, data)
+}
+
+// MemoryCache is a Cache held in memory, for a single process.
+type MemoryCache struct {
+    mu      sync.Mutex
+    entries map[string][]byte
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    data, ok := c.entries[key]
+    return data, ok, nil
+}
+
+func (c *MemoryCache) Put(_ context.Context, key string, data []byte) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.entries == nil {
+        c.entries = make(map[string][]byte)
+    }
+    c.entries[key] = append([]byte(nil), data...)
+    return nil
+}
+
+// DirCache is a Cache storing each entry as a file in Dir.
+type DirCache struct {
+    Dir string
+}
+
+func (c DirCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+    data, err := os.ReadFile(filepath.Join(c.Dir, key))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, false, nil
+    }
+    if err != nil {
+        return nil, false, err
+    }
+    return data, true, nil
+}
+
+func (c DirCache) Put(_ context.Context, key string, data []byte) error {
+    if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+        return err
+    }
+    // Write to a temporary file first so that readers never see a partial
+    // entry.
+    tmp, err := os.CreateTemp(c.Dir, ".tmp-*")
+    if err != nil {
+        return err
+    }
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        os.Remove(tmp.Name())
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        os.Remove(tmp.Name())
+        return err
+    }
+    return os.Rename(tmp.Name(), filepath.Join(c.Dir, key))
+}
+
+// setCached records that n's result came from the cache.
+func (r *Run) setCached(n *Node) {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    if r.cached == nil {
+        r.cached = make(map[*Node]bool)
+    }
+    r.cached[n] = true
+}
+
+func (r *Run) isCached(n *Node) bool {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    return r.cached[n]
+}
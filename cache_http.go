@@ -0,0 +1,72 @@
+package leo
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+)
+
+// HTTPCache is a Cache on an HTTP server that stores entries with PUT and
+// serves them with GET, such as bazel-remote or a WebDAV share. Entries live
+// at URL/key.
+type HTTPCache struct {
+    URL string
+    // Header is added to every request, for example for authorization.
+    Header http.Header
+    // Client sends requests. Defaults to http.DefaultClient.
+    Client *http.Client
+}
+
+func (c *HTTPCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+    resp, err := c.do(ctx, http.MethodGet, key, nil)
+    if err != nil {
+        return nil, false, err
+    }
+    defer resp.Body.Close()
+    switch {
+    case resp.StatusCode == http.StatusNotFound:
+        return nil, false, nil
+    case resp.StatusCode != http.StatusOK:
+        return nil, false, fmt.Errorf("cache: GET %s: %s", key, resp.Status)
+    }
+    data, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, false, err
+    }
+    return data, true, nil
+}
+
+func (c *HTTPCache) Put(ctx context.Context, key string, data []byte) error {
+    resp, err := c.do(ctx, http.MethodPut, key, data)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, resp.Body)
+    if resp.StatusCode/100 != 2 {
+        return fmt.Errorf("cache: PUT %s: %s", key, resp.Status)
+    }
+    return nil
+}
+
+func (c *HTTPCache) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+    var r io.Reader
+    if body != nil {
+        r = bytes.NewReader(body)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.URL, "/")+"/"+key, r)
+    if err != nil {
+        return nil, err
+    }
+    for name, values := range c.Header {
+        req.Header[name] = values
+    }
+    client := c.Client
+    if client == nil {
+        client = http.DefaultClient
+    }
+    return client.Do(req)
+}
@@ -0,0 +1,55 @@
+package leo
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestHTTPCache(t *testing.T) {
+    var mu sync.Mutex
+    store := map[string][]byte{}
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("Authorization") != "Bearer t" {
+            http.Error(w, "denied", http.StatusUnauthorized)
+            return
+        }
+        mu.Lock()
+        defer mu.Unlock()
+        switch r.Method {
+        case http.MethodPut:
+            store[r.URL.Path], _ = io.ReadAll(r.Body)
+        case http.MethodGet:
+            data, ok := store[r.URL.Path]
+            if !ok {
+                http.NotFound(w, r)
+                return
+            }
+            w.Write(data)
+        }
+    }))
+    defer server.Close()
+
+    ctx := context.Background()
+    cache := &HTTPCache{URL: server.URL + "/cache/", Header: http.Header{"Authorization": {"Bearer t"}}}
+    if _, ok, err := cache.Get(ctx, "abc"); ok || err != nil {
+        t.Errorf("expected a miss, got %v, %v", ok, err)
+    }
+    if err := cache.Put(ctx, "abc", []byte("data")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if data, ok, err := cache.Get(ctx, "abc"); !ok || err != nil || string(data) != "data" {
+        t.Errorf("expected a hit, got %q, %v, %v", data, ok, err)
+    }
+    if _, ok := store["/cache/abc"]; !ok {
+        t.Errorf("expected the entry under the cache URL, got %v", store)
+    }
+
+    unauthorized := &HTTPCache{URL: server.URL}
+    if _, _, err := unauthorized.Get(ctx, "abc"); err == nil {
+        t.Errorf("expected an error for a rejected request")
+    }
+}
@@ -0,0 +1,144 @@
+package leo
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+)
+
+// S3Cache is a Cache in a bucket of Amazon S3 or an S3-compatible store such
+// as MinIO or Ceph. Requests use path-style URLs, Endpoint/Bucket/Prefix+key,
+// and are signed with AWS Signature Version 4.
+type S3Cache struct {
+    // Endpoint is the service URL, such as https://s3.us-east-1.amazonaws.com.
+    Endpoint string
+    Region   string
+    Bucket   string
+    // Prefix is prepended to every key, for example "leo-cache/".
+    Prefix string
+
+    AccessKeyID     string
+    SecretAccessKey string
+    // SessionToken is set for temporary credentials.
+    SessionToken string
+
+    // Client sends requests. Defaults to http.DefaultClient.
+    Client *http.Client
+}
+
+func (c *S3Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+    resp, err := c.do(ctx, http.MethodGet, key, nil)
+    if err != nil {
+        return nil, false, err
+    }
+    defer resp.Body.Close()
+    switch {
+    case resp.StatusCode == http.StatusNotFound:
+        return nil, false, nil
+    case resp.StatusCode != http.StatusOK:
+        return nil, false, fmt.Errorf("cache: s3 GET %s: %s", key, resp.Status)
+    }
+    data, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, false, err
+    }
+    return data, true, nil
+}
+
+func (c *S3Cache) Put(ctx context.Context, key string, data []byte) error {
+    resp, err := c.do(ctx, http.MethodPut, key, data)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, resp.Body)
+    if resp.StatusCode/100 != 2 {
+        return fmt.Errorf("cache: s3 PUT %s: %s", key, resp.Status)
+    }
+    return nil
+}
+
+func (c *S3Cache) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+    url := strings.TrimSuffix(c.Endpoint, "/") + "/" + c.Bucket + "/" + c.Prefix + key
+    req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    sum := sha256.Sum256(body)
+    req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+    if c.SessionToken != "" {
+        req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+    }
+    signV4(req, c.AccessKeyID, c.SecretAccessKey, c.Region, "s3", time.Now())
+
+    client := c.Client
+    if client == nil {
+        client = http.DefaultClient
+    }
+    return client.Do(req)
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req, signing
+// the host and every header already set. The X-Amz-Content-Sha256 header must
+// hold the payload hash.
+func signV4(req *http.Request, accessKey, secretKey, region, service string, now time.Time) {
+    now = now.UTC()
+    amzDate := now.Format("20060102T150405Z")
+    date := now.Format("20060102")
+    req.Header.Set("X-Amz-Date", amzDate)
+
+    headers := map[string]string{"host": req.URL.Host}
+    for name, values := range req.Header {
+        headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+    }
+    names := make([]string, 0, len(headers))
+    for name := range headers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    var canonicalHeaders strings.Builder
+    for _, name := range names {
+        canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+    }
+    signedHeaders := strings.Join(names, ";")
+
+    path := req.URL.EscapedPath()
+    if path == "" {
+        path = "/"
+    }
+    canonicalRequest := strings.Join([]string{
+        req.Method,
+        path,
+        req.URL.RawQuery,
+        canonicalHeaders.String(),
+        signedHeaders,
+        req.Header.Get("X-Amz-Content-Sha256"),
+    }, "\n")
+
+    scope := date + "/" + region + "/" + service + "/aws4_request"
+    hash := sha256.Sum256([]byte(canonicalRequest))
+    stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+    key := hmacSHA256([]byte("AWS4"+secretKey), date)
+    key = hmacSHA256(key, region)
+    key = hmacSHA256(key, service)
+    key = hmacSHA256(key, "aws4_request")
+    signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+    req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+        ", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(data))
+    return mac.Sum(nil)
+}
@@ -0,0 +1,75 @@
+package leo
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+type buildOutput struct {
+    Artifact string
+    Size     int
+}
+
+func TestCacheKey(t *testing.T) {
+    for _, cache := range []Cache{&MemoryCache{}, DirCache{Dir: t.TempDir()}} {
+        var builds, uploads atomic.Int32
+        var uploaded buildOutput
+
+        graph := TaskGraph()
+        graph.AddFunc("build", func() buildOutput {
+            builds.Add(1)
+            return buildOutput{Artifact: "app.tar", Size: 42}
+        }, WithCacheKey(func(ctx context.Context) string { return Params(ctx)["rev"] }))
+        graph.AddFunc("upload", func(out buildOutput) {
+            uploads.Add(1)
+            uploaded = out
+        })
+        graph.AutoWire()
+
+        executor := NewExecutor(graph)
+        executor.SetCache(cache)
+
+        for _, rev := range []string{"r1", "r1", "r2"} {
+            run := executor.NewRun()
+            run.SetParams(map[string]string{"rev": rev})
+            if err := run.Execute(); err != nil {
+                t.Fatalf("%T: Execute failed: %v", cache, err)
+            }
+            if uploaded != (buildOutput{Artifact: "app.tar", Size: 42}) {
+                t.Errorf("%T: upload received %+v", cache, uploaded)
+            }
+        }
+        if builds.Load() != 2 || uploads.Load() != 3 {
+            t.Errorf("%T: expected 2 builds and 3 uploads, got %d and %d", cache, builds.Load(), uploads.Load())
+        }
+        if executor.Report().Nodes["build"].Cached {
+            t.Errorf("%T: the last run used a new revision and should not be cached", cache)
+        }
+    }
+}
+
+func TestCacheFailuresNotStored(t *testing.T) {
+    var calls atomic.Int32
+    graph := TaskGraph()
+    graph.AddCtx("flaky", func(ctx context.Context) error {
+        if calls.Add(1) == 1 {
+            return context.DeadlineExceeded
+        }
+        return nil
+    }, WithCacheKey(func(context.Context) string { return "k" }))
+
+    executor := NewExecutor(graph)
+    executor.SetCache(&MemoryCache{})
+    executor.Execute()
+    executor.Execute()
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if calls.Load() != 2 {
+        t.Errorf("expected the failed run not to be cached, got %d calls", calls.Load())
+    }
+    if !executor.Report().Nodes["flaky"].Cached {
+        t.Errorf("expected the third run to be served from the cache")
+    }
+}
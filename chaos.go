@@ -0,0 +1,126 @@
+package leo
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/rand"
+    "sync"
+    "time"
+)
+
+// ErrInjectedFault is the error returned by tasks that Chaos makes fail,
+// unless the fault sets its own.
+var ErrInjectedFault = errors.New("injected fault")
+
+// FaultKind is the kind of a Fault.
+type FaultKind int
+
+const (
+    // FaultError makes the task fail without running it.
+    FaultError FaultKind = iota
+    // FaultDelay delays the start of the task.
+    FaultDelay
+    // FaultPanic makes the task panic without running it.
+    FaultPanic
+)
+
+func (k FaultKind) String() string {
+    switch k {
+    case FaultError:
+        return "error"
+    case FaultDelay:
+        return "delay"
+    case FaultPanic:
+        return "panic"
+    }
+    return "unknown"
+}
+
+// Fault describes a fault for Chaos to inject. It applies to tasks whose
+// name matches one of the Nodes glob patterns, in path.Match syntax, or that
+// have one of Tags; with neither set it applies to every task. Each time an
+// applicable task runs, the fault is injected with the given Probability,
+// from 0 to 1.
+type Fault struct {
+    Kind        FaultKind
+    Nodes       []string
+    Tags        []string
+    Probability float64
+    // Delay is how long a FaultDelay holds the task back. The delay ends
+    // early if the task's context is cancelled.
+    Delay time.Duration
+    // Err is the error a FaultError returns, ErrInjectedFault if nil.
+    Err error
+}
+
+func (f Fault) appliesTo(node NodeInfo) bool {
+    if len(f.Nodes) == 0 && len(f.Tags) == 0 {
+        return true
+    }
+    return matchAny(f.Nodes, node.Name) || anyTag(f.Tags, node.Tags)
+}
+
+// Chaos returns middleware that injects faults into tasks, for testing that
+// a graph's fallbacks, retries and compensation actually handle failures:
+//
+//    executor.Use(leo.RecoverPanics(), leo.Chaos(1, leo.Fault{
+//        Kind:        leo.FaultError,
+//        Nodes:       []string{"push-*"},
+//        Probability: 0.3,
+//    }))
+//
+// Delays are applied first, then the first error or panic that fires. The
+// random choices come from seed, so a failing combination can be replayed,
+// though which task draws which number depends on the order tasks start (see
+// Executor.SetSeed). Add RecoverPanics before Chaos if faults include
+// FaultPanic, or the panic crashes the process.
+func Chaos(seed int64, faults ...Fault) Middleware {
+    var mu sync.Mutex
+    rng := rand.New(rand.NewSource(seed))
+    fires := func(f Fault) bool {
+        mu.Lock()
+        defer mu.Unlock()
+        return rng.Float64() < f.Probability
+    }
+
+    return func(next TaskCtxFunc, node NodeInfo) TaskCtxFunc {
+        var applicable []Fault
+        for _, f := range faults {
+            if f.appliesTo(node) {
+                applicable = append(applicable, f)
+            }
+        }
+        if len(applicable) == 0 {
+            return next
+        }
+        return func(ctx context.Context) error {
+            var fault *Fault
+            for i, f := range applicable {
+                if !fires(f) {
+                    continue
+                }
+                if f.Kind == FaultDelay {
+                    timer := time.NewTimer(f.Delay)
+                    select {
+                    case <-timer.C:
+                    case <-ctx.Done():
+                        timer.Stop()
+                        return ctx.Err()
+                    }
+                } else if fault == nil {
+                    fault = &applicable[i]
+                }
+            }
+            switch {
+            case fault == nil:
+                return next(ctx)
+            case fault.Kind == FaultPanic:
+                panic(fmt.Sprintf("injected panic in %s", node.Name))
+            case fault.Err != nil:
+                return fault.Err
+            }
+            return ErrInjectedFault
+        }
+    }
+}
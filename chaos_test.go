@@ -0,0 +1,81 @@
+package leo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChaosTargetedFaults(t *testing.T) {
+    var ran sync.Map
+    graph := TaskGraph()
+    for _, name := range []string{"push", "rollback", "notify", "audit"} {
+        name := name
+        graph.Add(name, func() error {
+            ran.Store(name, true)
+            return nil
+        })
+    }
+    graph.Add("cleanup", func() error { return nil }, WithTags("slow"))
+    graph.OnFailure("push", "rollback")
+    graph.Precede("rollback", "notify")
+    // The panic fails the run, so audit runs last to let the rest finish.
+    graph.Precede("notify", "audit")
+    graph.Precede("cleanup", "audit")
+
+    executor := NewExecutor(graph)
+    executor.Use(RecoverPanics(), Chaos(1,
+        Fault{Kind: FaultError, Nodes: []string{"pu*"}, Probability: 1},
+        Fault{Kind: FaultPanic, Nodes: []string{"audit"}, Probability: 1},
+        Fault{Kind: FaultDelay, Tags: []string{"slow"}, Probability: 1, Delay: 30 * time.Millisecond},
+        Fault{Kind: FaultError, Nodes: []string{"notify"}, Probability: 0},
+    ))
+    err := executor.Execute()
+    var panicErr *PanicError
+    if !errors.As(err, &panicErr) {
+        t.Fatalf("Execute = %v, want the injected panic", err)
+    }
+
+    report := executor.Report()
+    if _, pushed := ran.Load("push"); pushed {
+        t.Error("push ran despite its injected fault")
+    }
+    if nr := report.Nodes["push"]; !errors.Is(nr.Err, ErrInjectedFault) || !nr.Handled {
+        t.Errorf("push = %+v, want an injected, handled failure", nr)
+    }
+    if _, notified := ran.Load("notify"); !notified {
+        t.Error("the fallback chain did not run")
+    }
+    if d := report.Nodes["cleanup"].Duration; d < 30*time.Millisecond {
+        t.Errorf("cleanup took %s, want the injected delay", d)
+    }
+}
+
+func TestChaosProbabilityIsSeeded(t *testing.T) {
+    failures := func(seed int64) []bool {
+        graph := TaskGraph()
+        graph.Add("flaky", func() error { return nil })
+        executor := NewExecutor(graph)
+        executor.Use(Chaos(seed, Fault{Kind: FaultError, Probability: 0.5}))
+        var out []bool
+        for i := 0; i < 50; i++ {
+            out = append(out, executor.Execute() != nil)
+        }
+        return out
+    }
+
+    first, second := failures(3), failures(3)
+    failed := 0
+    for i := range first {
+        if first[i] != second[i] {
+            t.Fatalf("runs with the same seed differ at %d", i)
+        }
+        if first[i] {
+            failed++
+        }
+    }
+    if failed < 10 || failed > 40 {
+        t.Errorf("%d of 50 runs failed with probability 0.5", failed)
+    }
+}
@@ -0,0 +1,75 @@
+package leo
+
+import (
+    "context"
+    "errors"
+)
+
+// permanentError marks an error that retrying cannot fix.
+type permanentError struct {
+    err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that the task returning it is not retried, see
+// WithRetry, whatever the task's or executor's RetryIf says. errors.Is and
+// errors.As see through the wrapper. Permanent(nil) is nil.
+func Permanent(err error) error {
+    if err == nil {
+        return nil
+    }
+    return &permanentError{err}
+}
+
+// IsPermanent reports whether err, or an error it wraps, was marked with
+// Permanent.
+func IsPermanent(err error) bool {
+    var p *permanentError
+    return errors.As(err, &p)
+}
+
+// RetryFunc reports whether a task that failed with err may be retried.
+type RetryFunc func(err error) bool
+
+// RetryIf sets which of the task's errors are retried, overriding the
+// executor's RetryFunc (see SetRetryIf). Errors marked with Permanent are
+// never retried.
+func RetryIf(retryable RetryFunc) NodeOption {
+    return func(n *Node) {
+        n.retryIf = retryable
+    }
+}
+
+// SetRetryIf sets which errors of tasks without their own RetryIf are
+// retried. By default every error not marked with Permanent is.
+func (e *Executor) SetRetryIf(retryable RetryFunc) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.retryIf = retryable
+}
+
+func (e *Executor) getRetryIf() RetryFunc {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.retryIf
+}
+
+// retryable reports whether n may be retried after failing with err, in the
+// run that ctx belongs to.
+func (n *Node) retryable(ctx context.Context, err error) bool {
+    if IsPermanent(err) {
+        return false
+    }
+    if n.retryIf != nil {
+        return n.retryIf(err)
+    }
+    if r := RunFromContext(ctx); r != nil {
+        if retryable := r.executor.getRetryIf(); retryable != nil {
+            return retryable(err)
+        }
+    }
+    return true
+}
@@ -0,0 +1,65 @@
+package leo
+
+import (
+	"errors"
+	"testing"
+)
+
+var errThrottled = errors.New("throttled")
+
+func TestPermanent(t *testing.T) {
+    if Permanent(nil) != nil {
+        t.Errorf("Permanent(nil) should be nil")
+    }
+    denied := errors.New("access denied")
+    err := Permanent(denied)
+    if !errors.Is(err, denied) || !IsPermanent(err) || err.Error() != "access denied" {
+        t.Errorf("Permanent(%v) = %v", denied, err)
+    }
+    if IsPermanent(denied) {
+        t.Errorf("expected an unmarked error not to be permanent")
+    }
+
+    var attempts int
+    graph := TaskGraph()
+    graph.Add("upload", func() error {
+        attempts++
+        return Permanent(denied)
+    }, WithRetry(3, 0))
+    if err := NewExecutor(graph).Execute(); !errors.Is(err, denied) {
+        t.Errorf("expected the permanent error, got %v", err)
+    }
+    if attempts != 1 {
+        t.Errorf("expected a permanent error not to be retried, got %d attempts", attempts)
+    }
+}
+
+func TestRetryIf(t *testing.T) {
+    onlyThrottled := WithRetryIf(func(err error) bool {
+        return errors.Is(err, errThrottled)
+    })
+    attempts := func(err error, opts ...NodeOption) int {
+        n := 0
+        graph := TaskGraph()
+        graph.Add("task", func() error {
+            n++
+            return err
+        }, append([]NodeOption{WithRetry(2, 0)}, opts...)...)
+        NewExecutor(graph, onlyThrottled).Execute()
+        return n
+    }
+
+    if got := attempts(errThrottled); got != 3 {
+        t.Errorf("expected a retryable error to be retried twice, got %d attempts", got)
+    }
+    if got := attempts(errors.New("syntax error")); got != 1 {
+        t.Errorf("expected other errors not to be retried, got %d attempts", got)
+    }
+    always := RetryIf(func(err error) bool { return true })
+    if got := attempts(errors.New("syntax error"), always); got != 3 {
+        t.Errorf("expected the task's RetryIf to override the executor's, got %d attempts", got)
+    }
+    if got := attempts(Permanent(errThrottled), always); got != 1 {
+        t.Errorf("expected a permanent error not to be retried, got %d attempts", got)
+    }
+}
@@ -0,0 +1,32 @@
+package leo
+
+import "time"
+
+// Clock tells the time. Executors read it for the timestamps and durations
+// they record in reports, events, journals and snapshots, and for ETAs, so
+// that tests can control them. Timeouts and delays still use real time.
+type Clock interface {
+    Now() time.Time
+}
+
+// WithClock sets the clock the executor reads timestamps from. The default
+// is the system clock.
+func WithClock(c Clock) ExecutorOption {
+    return func(e *Executor) {
+        e.clock = c
+    }
+}
+
+// now returns the time according to the executor's clock. The clock is only
+// set when the executor is created, so it is read without locking.
+func (e *Executor) now() time.Time {
+    if e.clock == nil {
+        return time.Now()
+    }
+    return e.clock.Now()
+}
+
+// since returns the time elapsed since t according to the executor's clock.
+func (e *Executor) since(t time.Time) time.Duration {
+    return e.now().Sub(t)
+}
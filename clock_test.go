@@ -0,0 +1,53 @@
+package leo
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock advances by step each time it is read.
+type fakeClock struct {
+    mu   sync.Mutex
+    now  time.Time
+    step time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.now = c.now.Add(c.step)
+    return c.now
+}
+
+func TestWithClock(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil })
+
+    epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    executor := NewExecutor(graph, WithClock(&fakeClock{now: epoch, step: time.Second}))
+    sub := executor.Subscribe()
+    defer sub.Close()
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    report := executor.Report()
+    if !report.Start.After(epoch) || report.Start.Sub(epoch) > time.Minute {
+        t.Errorf("expected the report to start by the fake clock, got %v", report.Start)
+    }
+    if d := report.Nodes["A"].Duration; d%time.Second != 0 || d <= 0 {
+        t.Errorf("expected a whole number of fake seconds, got %v", d)
+    }
+    if d := report.Duration; d%time.Second != 0 || d <= report.Nodes["A"].Duration {
+        t.Errorf("unexpected run duration %v", d)
+    }
+    for ev := range sub.C {
+        if ev.Time.Before(epoch) || ev.Time.Sub(epoch) > time.Minute {
+            t.Errorf("event %s at %v, not by the fake clock", ev, ev.Time)
+        }
+        if ev.Type == EventRunFinished {
+            break
+        }
+    }
+}
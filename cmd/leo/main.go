@@ -0,0 +1,120 @@
+// Command leo works with declarative leo pipeline files.
+//
+// Usage:
+//
+//    leo schema              print the JSON Schema for pipeline files
+//    leo validate [-toml-table KEY] FILE...
+//                            check JSON or TOML pipeline files against the schema
+//    leo shell [-toml-table KEY] [-profile NAME] FILE
+//                            inspect and run a pipeline interactively
+//    leo trace [-width N] FILE
+//                            show a trace written by leo.Tracer as a timeline
+package main
+
+import (
+    "flag"
+    "fmt"
+    "io"
+    "os"
+
+    "github.com/mips171/leo"
+    "github.com/mips171/leo/pipeline"
+)
+
+func main() {
+    os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+    if len(args) == 0 {
+        usage(stderr)
+        return 2
+    }
+
+    switch args[0] {
+    case "schema":
+        stdout.Write(pipeline.Schema())
+        return 0
+    case "validate":
+        flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+        flags.SetOutput(stderr)
+        table := flags.String("toml-table", "", "dotted `key` of the pipeline table in TOML files")
+        if err := flags.Parse(args[1:]); err != nil {
+            return 2
+        }
+        if flags.NArg() == 0 {
+            usage(stderr)
+            return 2
+        }
+        status := 0
+        for _, path := range flags.Args() {
+            data, err := pipeline.ReadFile(path, *table)
+            if err == nil {
+                err = pipeline.Validate(data)
+            }
+            if err != nil {
+                fmt.Fprintf(stderr, "%s: %v\n", path, err)
+                status = 1
+                continue
+            }
+            fmt.Fprintf(stdout, "%s: ok\n", path)
+        }
+        return status
+    case "shell":
+        flags := flag.NewFlagSet("shell", flag.ContinueOnError)
+        flags.SetOutput(stderr)
+        table := flags.String("toml-table", "", "dotted `key` of the pipeline table in a TOML file")
+        profile := flags.String("profile", "", "`name` of the pipeline profile to apply")
+        if err := flags.Parse(args[1:]); err != nil {
+            return 2
+        }
+        if flags.NArg() != 1 {
+            usage(stderr)
+            return 2
+        }
+        if err := runShell(flags.Arg(0), *table, *profile, stdin, stdout); err != nil {
+            fmt.Fprintf(stderr, "%s: %v\n", flags.Arg(0), err)
+            return 1
+        }
+        return 0
+    case "trace":
+        flags := flag.NewFlagSet("trace", flag.ContinueOnError)
+        flags.SetOutput(stderr)
+        width := flags.Int("width", 60, "width of the timeline in `columns`")
+        if err := flags.Parse(args[1:]); err != nil {
+            return 2
+        }
+        if flags.NArg() != 1 {
+            usage(stderr)
+            return 2
+        }
+        f, err := os.Open(flags.Arg(0))
+        if err == nil {
+            var spans []leo.Span
+            spans, err = leo.ReadTrace(f)
+            f.Close()
+            if err == nil {
+                err = leo.WriteTimeline(stdout, spans, *width)
+            }
+        }
+        if err != nil {
+            fmt.Fprintf(stderr, "%s: %v\n", flags.Arg(0), err)
+            return 1
+        }
+        return 0
+    default:
+        usage(stderr)
+        return 2
+    }
+}
+
+func usage(w io.Writer) {
+    fmt.Fprintln(w, "usage:")
+    fmt.Fprintln(w, "    leo schema              print the JSON Schema for pipeline files")
+    fmt.Fprintln(w, "    leo validate [-toml-table KEY] FILE...")
+    fmt.Fprintln(w, "                            check JSON or TOML pipeline files against the schema")
+    fmt.Fprintln(w, "    leo shell [-toml-table KEY] [-profile NAME] FILE")
+    fmt.Fprintln(w, "                            inspect and run a pipeline interactively")
+    fmt.Fprintln(w, "    leo trace [-width N] FILE")
+    fmt.Fprintln(w, "                            show a trace written by leo.Tracer as a timeline")
+}
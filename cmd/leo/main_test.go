@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateCommand(t *testing.T) {
+    dir := t.TempDir()
+    good := filepath.Join(dir, "good.json")
+    bad := filepath.Join(dir, "bad.json")
+    os.WriteFile(good, []byte(`{"tasks": [{"name": "a"}]}`), 0o644)
+    os.WriteFile(bad, []byte(`{"tasks": [{"nam": "a"}]}`), 0o644)
+
+    var stdout, stderr bytes.Buffer
+    if status := run([]string{"validate", good}, nil, &stdout, &stderr); status != 0 {
+        t.Errorf("validate good.json: status %d, stderr %q", status, stderr.String())
+    }
+
+    stdout.Reset()
+    stderr.Reset()
+    if status := run([]string{"validate", good, bad}, nil, &stdout, &stderr); status != 1 {
+        t.Errorf("validate bad.json: expected status 1, got %d", status)
+    }
+    if !strings.Contains(stderr.String(), `unknown property "nam"`) {
+        t.Errorf("expected the schema error on stderr, got %q", stderr.String())
+    }
+}
+
+func TestSchemaCommand(t *testing.T) {
+    var stdout, stderr bytes.Buffer
+    if status := run([]string{"schema"}, nil, &stdout, &stderr); status != 0 {
+        t.Fatalf("schema: status %d", status)
+    }
+    if !strings.Contains(stdout.String(), `"$id"`) {
+        t.Errorf("expected a JSON Schema, got %q", stdout.String())
+    }
+}
+
+func TestValidateTOML(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "infra.toml")
+    os.WriteFile(path, []byte("[app]\nport = 80\n\n[[app.pipeline.tasks]]\nname = \"a\"\n"), 0o644)
+
+    var stdout, stderr bytes.Buffer
+    if status := run([]string{"validate", "-toml-table", "app.pipeline", path}, nil, &stdout, &stderr); status != 0 {
+        t.Errorf("validate infra.toml: status %d, stderr %q", status, stderr.String())
+    }
+}
+
+func TestTraceCommand(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "trace.jsonl")
+    os.WriteFile(path, []byte(
+        `{"trace_id":"r1","span_id":"r1/build","parent_id":"r1","name":"build","start":"2024-01-01T00:00:00Z","end":"2024-01-01T00:00:01Z","status":"ok"}`+"\n"+
+            `{"trace_id":"r1","span_id":"r1","name":"ci","start":"2024-01-01T00:00:00Z","end":"2024-01-01T00:00:01Z","status":"ok"}`+"\n"), 0o644)
+
+    var stdout, stderr bytes.Buffer
+    if status := run([]string{"trace", "-width", "12", path}, nil, &stdout, &stderr); status != 0 {
+        t.Fatalf("trace: status %d, stderr %q", status, stderr.String())
+    }
+    if want := "ci r1, 1s, ok\nbuild |████████████| 1s\n\n"; stdout.String() != want {
+        t.Errorf("trace printed %q, want %q", stdout.String(), want)
+    }
+}
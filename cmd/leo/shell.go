@@ -0,0 +1,208 @@
+package main
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+    "sync"
+
+    "github.com/mips171/leo"
+    "github.com/mips171/leo/pipeline"
+)
+
+const shellHelp = `commands:
+    nodes                   list the tasks
+    ancestors NAME          list the tasks NAME depends on
+    descendants NAME        list the tasks that depend on NAME
+    levels                  list the tasks by topological level
+    critical                show the critical path
+    lint                    report anti-patterns in the pipeline
+    set KEY=VALUE           set a run parameter
+    params                  list the run parameters
+    run [NAME...]           run the named tasks and their dependencies, or every task
+    help                    show this help
+    quit                    leave the shell`
+
+// shell is an interactive session on a loaded pipeline.
+type shell struct {
+    graph  *leo.Graph
+    opts   []leo.ExecutorOption
+    params map[string]string
+    out    io.Writer
+}
+
+// runShell loads the pipeline at path, with the named profile applied, and
+// runs the commands read from in until it is exhausted or a quit command is
+// read.
+func runShell(path, table, profile string, in io.Reader, out io.Writer) error {
+    data, err := pipeline.ReadFile(path, table)
+    if err != nil {
+        return err
+    }
+    f, err := pipeline.ParseProfile(data, profile)
+    if err != nil {
+        return err
+    }
+    graph, err := f.Graph()
+    if err != nil {
+        return err
+    }
+    sh := &shell{graph: graph, opts: f.ExecutorOptions(), params: make(map[string]string), out: out}
+
+    fmt.Fprintf(out, "loaded %s, type help for commands\n", path)
+    scanner := bufio.NewScanner(in)
+    for {
+        fmt.Fprint(out, "leo> ")
+        if !scanner.Scan() {
+            fmt.Fprintln(out)
+            return scanner.Err()
+        }
+        fields := strings.Fields(scanner.Text())
+        if len(fields) == 0 {
+            continue
+        }
+        if fields[0] == "quit" || fields[0] == "exit" {
+            return nil
+        }
+        if err := sh.exec(fields[0], fields[1:]); err != nil {
+            fmt.Fprintf(out, "error: %v\n", err)
+        }
+    }
+}
+
+// exec runs a single shell command.
+func (sh *shell) exec(cmd string, args []string) error {
+    switch cmd {
+    case "help":
+        fmt.Fprintln(sh.out, shellHelp)
+    case "nodes":
+        levels := sh.graph.Levels()
+        var names []string
+        for _, level := range levels {
+            names = append(names, level...)
+        }
+        sort.Strings(names)
+        sh.list(names)
+    case "ancestors", "descendants":
+        if len(args) != 1 {
+            return fmt.Errorf("usage: %s NAME", cmd)
+        }
+        query := sh.graph.Ancestors
+        if cmd == "descendants" {
+            query = sh.graph.Descendants
+        }
+        names, err := query(args[0])
+        if err != nil {
+            return err
+        }
+        sh.list(names)
+    case "levels":
+        for i, level := range sh.graph.Levels() {
+            fmt.Fprintf(sh.out, "%d: %s\n", i, strings.Join(level, ", "))
+        }
+    case "critical":
+        path, total := sh.graph.CriticalPath()
+        fmt.Fprintf(sh.out, "%s (%s)\n", strings.Join(path, " → "), total)
+    case "lint":
+        warnings := leo.Lint(sh.graph)
+        for _, w := range warnings {
+            fmt.Fprintln(sh.out, w)
+        }
+        if len(warnings) == 0 {
+            fmt.Fprintln(sh.out, "no warnings")
+        }
+    case "set":
+        for _, arg := range args {
+            key, value, ok := strings.Cut(arg, "=")
+            if !ok || key == "" {
+                return fmt.Errorf("usage: set KEY=VALUE")
+            }
+            sh.params[key] = value
+        }
+    case "params":
+        keys := make([]string, 0, len(sh.params))
+        for k := range sh.params {
+            keys = append(keys, k)
+        }
+        sort.Strings(keys)
+        for _, k := range keys {
+            fmt.Fprintf(sh.out, "%s=%s\n", k, sh.params[k])
+        }
+    case "run":
+        return sh.run(args)
+    default:
+        return fmt.Errorf("unknown command %q, type help for commands", cmd)
+    }
+    return nil
+}
+
+func (sh *shell) list(names []string) {
+    if len(names) == 0 {
+        fmt.Fprintln(sh.out, "(none)")
+        return
+    }
+    for _, name := range names {
+        fmt.Fprintln(sh.out, name)
+    }
+}
+
+// run executes the targets and their ancestors, or the whole graph, printing
+// the tasks' output as it arrives and a summary when the run finishes.
+func (sh *shell) run(targets []string) error {
+    graph := sh.graph
+    if len(targets) > 0 {
+        include := append([]string(nil), targets...)
+        for _, target := range targets {
+            ancestors, err := sh.graph.Ancestors(target)
+            if err != nil {
+                return err
+            }
+            include = append(include, ancestors...)
+        }
+        sub, err := sh.graph.Subgraph(include...)
+        if err != nil {
+            return err
+        }
+        graph = sub
+    }
+
+    var mu sync.Mutex
+    executor := leo.NewExecutor(graph, sh.opts...)
+    executor.SetHooks(leo.Hooks{
+        OnOutput: func(line leo.OutputLine) {
+            mu.Lock()
+            defer mu.Unlock()
+            fmt.Fprintf(sh.out, "[%s] %s\n", line.Node, line.Text)
+        },
+    })
+    run := executor.NewRun()
+    run.SetParams(sh.params)
+    err := run.Execute()
+
+    mu.Lock()
+    defer mu.Unlock()
+    report := run.Report()
+    names := make([]string, 0, len(report.Nodes))
+    for name := range report.Nodes {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        nr := report.Nodes[name]
+        switch {
+        case nr.Err != nil:
+            fmt.Fprintf(sh.out, "%-20s %-10s %s: %v\n", name, nr.State, nr.Duration, nr.Err)
+        case nr.SkipReason != "":
+            fmt.Fprintf(sh.out, "%-20s %-10s %s\n", name, nr.State, nr.SkipReason)
+        default:
+            fmt.Fprintf(sh.out, "%-20s %-10s %s\n", name, nr.State, nr.Duration)
+        }
+    }
+    if err != nil {
+        return fmt.Errorf("run failed: %w", err)
+    }
+    fmt.Fprintf(sh.out, "run succeeded in %s\n", report.Duration)
+    return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShellCommand(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "deploy.json")
+    os.WriteFile(path, []byte(`{"tasks": [
+        {"name": "build", "shell": "echo built {{ version }}", "expected_duration": "2s"},
+        {"name": "test", "depends_on": ["build"], "expected_duration": "1s"},
+        {"name": "docs"},
+        {"name": "push", "shell": "echo pushed", "depends_on": ["test"]}
+    ]}`), 0o644)
+
+    commands := strings.Join([]string{
+        "descendants build",
+        "ancestors push",
+        "critical",
+        "set version=1.2",
+        "run test",
+        "bogus",
+        "quit",
+        "nodes",
+    }, "\n")
+    var stdout, stderr bytes.Buffer
+    if status := run([]string{"shell", path}, strings.NewReader(commands), &stdout, &stderr); status != 0 {
+        t.Fatalf("shell: status %d, stderr %q", status, stderr.String())
+    }
+    out := stdout.String()
+    for _, want := range []string{
+        "leo> push\ntest\n",
+        "leo> build\ntest\n",
+        "build → test → push (3s)",
+        "[build] built 1.2",
+        "run succeeded",
+        `error: unknown command "bogus"`,
+    } {
+        if !strings.Contains(out, want) {
+            t.Errorf("output does not contain %q:\n%s", want, out)
+        }
+    }
+    if strings.Contains(out, "pushed") || strings.Contains(out, "docs") {
+        t.Errorf("run test ran more than test and its dependencies, or commands after quit ran:\n%s", out)
+    }
+}
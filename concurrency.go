@@ -0,0 +1,27 @@
+package leo
+
+import "runtime"
+
+// ioBoundFactor is the number of tasks per processor that executors created
+// WithIOBound run at once by default.
+const ioBoundFactor = 8
+
+// WithIOBound tells the executor that its tasks mostly wait, on the network,
+// disks or other processes, rather than compute, so that its default
+// concurrency limit allows more of them to run at once, see SetConcurrency.
+func WithIOBound() ExecutorOption {
+    return func(e *Executor) {
+        e.ioBound = true
+    }
+}
+
+// defaultConcurrency returns the limit for runs of an executor that has none
+// set: runtime.GOMAXPROCS(0), the number of tasks that can compute at once,
+// or ioBoundFactor times that for IO-bound executors.
+func defaultConcurrency(ioBound bool) int {
+    n := runtime.GOMAXPROCS(0)
+    if ioBound {
+        n *= ioBoundFactor
+    }
+    return n
+}
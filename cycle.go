@@ -0,0 +1,45 @@
+package leo
+
+import "strings"
+
+// CycleError is returned by Precede, Succeed and OnFailure when the edge
+// would create a cycle. Path is the cycle the edge would close, starting and
+// ending with the edge's source node: for an edge from C to A in a graph
+// with edges A → B → C, it is [C A B C].
+type CycleError struct {
+    Path []string
+}
+
+func (e *CycleError) Error() string {
+    return "adding this edge would create a cycle: " + strings.Join(e.Path, " → ")
+}
+
+// cycleThrough returns the cycle that an edge of any kind from `from` to
+// `to` would close, or nil if it would not close one. The caller must hold
+// g.mu.
+func (g *Graph) cycleThrough(from, to *Node) []string {
+    visited := make(map[*Node]bool)
+    var path []string
+    var visit func(n *Node) bool
+    visit = func(n *Node) bool {
+        if visited[n] {
+            return false
+        }
+        visited[n] = true
+        path = append(path, n.name)
+        if n == from {
+            return true
+        }
+        for _, s := range n.successors() {
+            if visit(s) {
+                return true
+            }
+        }
+        path = path[:len(path)-1]
+        return false
+    }
+    if !visit(to) {
+        return nil
+    }
+    return append([]string{from.name}, path...)
+}
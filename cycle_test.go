@@ -0,0 +1,43 @@
+package leo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCycleErrorPath(t *testing.T) {
+    graph := TaskGraph()
+    for _, name := range []string{"A", "B", "C", "D"} {
+        graph.Add(name, func() error { return nil })
+    }
+    graph.Precede("A", "B")
+    graph.Precede("B", "C")
+    graph.Precede("A", "D")
+
+    err := graph.Precede("C", "A")
+    var cycle *CycleError
+    if !errors.As(err, &cycle) {
+        t.Fatalf("Precede(C, A) = %v, want a *CycleError", err)
+    }
+    if want := []string{"C", "A", "B", "C"}; !reflect.DeepEqual(cycle.Path, want) {
+        t.Errorf("Path = %v, want %v", cycle.Path, want)
+    }
+    if want := "adding this edge would create a cycle: C → A → B → C"; err.Error() != want {
+        t.Errorf("Error() = %q, want %q", err.Error(), want)
+    }
+    if edges := graph.Edges(); len(edges) != 3 {
+        t.Errorf("rejected edge was added: %v", edges)
+    }
+
+    err = graph.Precede("B", "B")
+    if !errors.As(err, &cycle) || !reflect.DeepEqual(cycle.Path, []string{"B", "B"}) {
+        t.Errorf("Precede(B, B) = %v, want the cycle B → B", err)
+    }
+
+    graph.OnFailure("C", "D")
+    err = graph.OnFailure("D", "A")
+    if !errors.As(err, &cycle) || !reflect.DeepEqual(cycle.Path, []string{"D", "A", "B", "C", "D"}) {
+        t.Errorf("OnFailure(D, A) = %v, want the cycle D → A → B → C → D", err)
+    }
+}
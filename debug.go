@@ -0,0 +1,126 @@
+package leo
+
+import (
+    "context"
+    "errors"
+)
+
+// ErrStepAborted is returned by the StepFunc from StepChannel when its
+// channel is closed, which stops stepping through the run.
+var ErrStepAborted = errors.New("stepping aborted")
+
+// StepFunc is called before each task of a run starts, for stepping through
+// a run interactively: the task waits until StepFunc returns, and fails with
+// its error if it returns one. Calls are serialized, so StepFunc sees one
+// task at a time even when several become ready together, but a task it has
+// let start runs concurrently with the next call.
+type StepFunc func(ctx context.Context, node NodeInfo) error
+
+// SetStepper puts the executor in debug mode: step is called before each of
+// a run's tasks starts, or only before those at a breakpoint if there are
+// any (see AddBreakpoint), and the task waits for it, see StepFunc. A nil
+// step, the default, disables debug mode. The time a task spends waiting
+// for step is not part of its duration.
+func (e *Executor) SetStepper(step StepFunc) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.stepper = step
+}
+
+func (e *Executor) getStepper() StepFunc {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.stepper
+}
+
+// StepChannel returns a StepFunc that lets one task start for each value
+// received from ch:
+//
+//    next := make(chan struct{})
+//    executor.SetStepper(leo.StepChannel(next))
+//    go executor.Execute()
+//    next <- struct{}{} // start the first task
+//
+// A task waiting for ch fails with the context's error if the run is
+// cancelled, and with ErrStepAborted if ch is closed.
+func StepChannel(ch <-chan struct{}) StepFunc {
+    return func(ctx context.Context, _ NodeInfo) error {
+        select {
+        case _, ok := <-ch:
+            if !ok {
+                return ErrStepAborted
+            }
+            return nil
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+}
+
+// Breakpoint selects nodes that a run in debug mode halts before: those
+// whose name matches one of the Nodes glob patterns, in path.Match syntax,
+// and those with one of Tags. The zero Breakpoint selects no nodes.
+type Breakpoint struct {
+    Nodes []string
+    Tags  []string
+}
+
+func (b Breakpoint) match(n *Node) bool {
+    return matchAny(b.Nodes, n.name) || anyTag(b.Tags, n.tags)
+}
+
+// AddBreakpoint makes runs in debug mode (see SetStepper) halt only before
+// the nodes that b or another added breakpoint selects, instead of before
+// every node. The stepper is called for those nodes as usual; other nodes
+// run without stopping. The stepper's context carries the run, so it can
+// inspect the run's state at a breakpoint:
+//
+//    executor.SetStepper(func(ctx context.Context, node leo.NodeInfo) error {
+//        run := leo.RunFromContext(ctx)
+//        cfg, _ := run.Result("load-config")
+//        log.Printf("halted before %s with config %v", node.Name, cfg)
+//        return nil
+//    })
+//    executor.AddBreakpoint(leo.Breakpoint{Tags: []string{"deploy"}})
+//
+// Breakpoints may be added while a run is in progress, and apply to the
+// nodes that have not started yet.
+func (e *Executor) AddBreakpoint(b Breakpoint) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.breakpoints = append(e.breakpoints, b)
+}
+
+// ClearBreakpoints removes every breakpoint, so that runs in debug mode halt
+// before every node again.
+func (e *Executor) ClearBreakpoints() {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.breakpoints = nil
+}
+
+// halts reports whether a run in debug mode halts before n.
+func (e *Executor) halts(n *Node) bool {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    if len(e.breakpoints) == 0 {
+        return true
+    }
+    for _, b := range e.breakpoints {
+        if b.match(n) {
+            return true
+        }
+    }
+    return false
+}
+
+// step waits for the executor's stepper, if any, to let n start.
+func (r *Run) step(n *Node) error {
+    step := r.executor.getStepper()
+    if step == nil || !r.executor.halts(n) {
+        return nil
+    }
+    r.stepMu.Lock()
+    defer r.stepMu.Unlock()
+    return step(r.ctx, n.info())
+}
@@ -0,0 +1,124 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStepThrough(t *testing.T) {
+    var mu sync.Mutex
+    var order []string
+    record := func(name string) TaskFunc {
+        return func() error {
+            mu.Lock()
+            defer mu.Unlock()
+            order = append(order, name)
+            return nil
+        }
+    }
+    ran := func() []string {
+        mu.Lock()
+        defer mu.Unlock()
+        return append([]string(nil), order...)
+    }
+
+    graph := TaskGraph()
+    graph.Add("A", record("A"))
+    graph.Add("B", record("B"))
+    graph.Add("C", record("C"))
+    graph.Precede("A", "B")
+    graph.Precede("B", "C")
+
+    next := make(chan struct{})
+    executor := NewExecutor(graph)
+    executor.SetStepper(StepChannel(next))
+    done := make(chan error, 1)
+    go func() { done <- executor.Execute() }()
+
+    for i, want := range [][]string{{"A"}, {"A", "B"}, {"A", "B", "C"}} {
+        time.Sleep(10 * time.Millisecond)
+        if got := ran(); len(got) != i {
+            t.Fatalf("before step %d, ran %v", i+1, got)
+        }
+        next <- struct{}{}
+        deadline := time.Now().Add(time.Second)
+        for len(ran()) < len(want) && time.Now().Before(deadline) {
+            time.Sleep(time.Millisecond)
+        }
+        if got := ran(); !reflect.DeepEqual(got, want) {
+            t.Fatalf("after step %d, ran %v, want %v", i+1, got, want)
+        }
+    }
+    if err := <-done; err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+}
+
+func TestStepAborted(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil })
+    graph.Add("B", func() error { return nil })
+    graph.Precede("A", "B")
+
+    var seen []string
+    executor := NewExecutor(graph)
+    executor.SetStepper(func(ctx context.Context, node NodeInfo) error {
+        seen = append(seen, node.Name)
+        if node.Name == "B" {
+            return ErrStepAborted
+        }
+        return nil
+    })
+    if err := executor.Execute(); !errors.Is(err, ErrStepAborted) {
+        t.Errorf("Execute = %v, want ErrStepAborted", err)
+    }
+    if !reflect.DeepEqual(seen, []string{"A", "B"}) {
+        t.Errorf("stepper saw %v", seen)
+    }
+    if got := executor.Report().Nodes["B"].State; got != StateFailed {
+        t.Errorf("B is %v, want failed", got)
+    }
+}
+
+func TestBreakpoints(t *testing.T) {
+    graph := TaskGraph()
+    graph.AddFunc("load-config", func() string { return "prod" })
+    graph.AddFunc("build", func(string) error { return nil })
+    graph.Add("push", func() error { return nil }, WithTags("deploy"))
+    graph.Add("migrate-db", func() error { return nil })
+    graph.AutoWire()
+    graph.Precede("build", "push")
+    graph.Precede("build", "migrate-db")
+
+    var mu sync.Mutex
+    halted := make(map[string]any)
+    executor := NewExecutor(graph)
+    executor.SetStepper(func(ctx context.Context, node NodeInfo) error {
+        cfg, _ := RunFromContext(ctx).Result("load-config")
+        mu.Lock()
+        halted[node.Name] = cfg
+        mu.Unlock()
+        return nil
+    })
+    executor.AddBreakpoint(Breakpoint{Tags: []string{"deploy"}})
+    executor.AddBreakpoint(Breakpoint{Nodes: []string{"migrate-*"}})
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if want := map[string]any{"push": "prod", "migrate-db": "prod"}; !reflect.DeepEqual(halted, want) {
+        t.Errorf("halted at %v, want %v", halted, want)
+    }
+
+    executor.ClearBreakpoints()
+    halted = make(map[string]any)
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if len(halted) != 4 {
+        t.Errorf("without breakpoints, halted at %v, want every node", halted)
+    }
+}
@@ -0,0 +1,208 @@
+package leo
+
+import (
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Change describes a changed setting on a node or edge.
+type Change struct {
+    // Node is set for node changes, Edge for edge changes.
+    Node string
+    Edge *Edge
+    Key  string
+    Old  string
+    New  string
+}
+
+func (c Change) String() string {
+    subject := "node " + c.Node
+    if c.Edge != nil {
+        subject = "edge " + c.Edge.String()
+    }
+    return fmt.Sprintf("%s: %s %q -> %q", subject, c.Key, c.Old, c.New)
+}
+
+// GraphDiff lists the differences between two graphs. All slices are sorted.
+type GraphDiff struct {
+    AddedNodes   []string
+    RemovedNodes []string
+    AddedEdges   []Edge
+    RemovedEdges []Edge
+    Changed      []Change
+}
+
+// Empty reports whether the graphs are equivalent.
+func (d *GraphDiff) Empty() bool {
+    return len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 &&
+        len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0 &&
+        len(d.Changed) == 0
+}
+
+// String formats the diff one change per line, prefixed with "+", "-" or "~".
+func (d *GraphDiff) String() string {
+    var b strings.Builder
+    for _, name := range d.AddedNodes {
+        fmt.Fprintf(&b, "+ node %s\n", name)
+    }
+    for _, name := range d.RemovedNodes {
+        fmt.Fprintf(&b, "- node %s\n", name)
+    }
+    for _, e := range d.AddedEdges {
+        fmt.Fprintf(&b, "+ edge %s\n", e)
+    }
+    for _, e := range d.RemovedEdges {
+        fmt.Fprintf(&b, "- edge %s\n", e)
+    }
+    for _, c := range d.Changed {
+        fmt.Fprintf(&b, "~ %s\n", c)
+    }
+    return b.String()
+}
+
+// Diff compares two graphs by node name and reports added and removed nodes
+// and edges, and changed node and edge settings. Tasks themselves cannot be
+// compared and are ignored.
+func Diff(old, new *Graph) *GraphDiff {
+    d := &GraphDiff{}
+
+    for name, n := range new.nodes {
+        o, exists := old.nodes[name]
+        if !exists {
+            d.AddedNodes = append(d.AddedNodes, name)
+            continue
+        }
+        d.Changed = append(d.Changed, diffMetadata(o.metadata(), n.metadata(), func(c *Change) { c.Node = name })...)
+    }
+    for name := range old.nodes {
+        if _, exists := new.nodes[name]; !exists {
+            d.RemovedNodes = append(d.RemovedNodes, name)
+        }
+    }
+
+    oldEdges := edgeSet(old)
+    newEdges := edgeSet(new)
+    for e, md := range newEdges {
+        o, exists := oldEdges[e]
+        if !exists {
+            d.AddedEdges = append(d.AddedEdges, e)
+            continue
+        }
+        e := e
+        d.Changed = append(d.Changed, diffMetadata(o, md, func(c *Change) { c.Edge = &e })...)
+    }
+    for e := range oldEdges {
+        if _, exists := newEdges[e]; !exists {
+            d.RemovedEdges = append(d.RemovedEdges, e)
+        }
+    }
+
+    sort.Strings(d.AddedNodes)
+    sort.Strings(d.RemovedNodes)
+    sortEdges(d.AddedEdges)
+    sortEdges(d.RemovedEdges)
+    sort.SliceStable(d.Changed, func(i, j int) bool {
+        return changeSubject(d.Changed[i]) < changeSubject(d.Changed[j])
+    })
+    return d
+}
+
+// metadata returns the node's non-default settings as strings, keyed by
+// setting name. Settings that affect scheduling or reporting belong here so
+// that Diff picks them up.
+func (n *Node) metadata() map[string]string {
+    md := make(map[string]string)
+    if n.expected > 0 {
+        md["expected_duration"] = n.expected.String()
+    }
+    if n.cost > 0 {
+        md["cost"] = n.cost.String()
+    }
+    if n.hedge > 0 {
+        md["hedge"] = n.hedge.String()
+    }
+    if n.retries > 0 {
+        md["retries"] = strconv.Itoa(n.retries)
+        md["retry_delay"] = n.retryDelay.String()
+    }
+    if n.notIdempotent {
+        md["idempotent"] = "false"
+    }
+    if n.command != "" {
+        md["command"] = n.command
+    }
+    if len(n.tags) > 0 {
+        md["tags"] = n.tagList()
+    }
+    if len(n.requires) > 0 {
+        md["requires"] = strings.Join(n.requires, ",")
+    }
+    if n.resources.CPU > 0 {
+        md["cpu"] = strconv.FormatFloat(n.resources.CPU, 'g', -1, 64)
+    }
+    if n.resources.Memory > 0 {
+        md["memory"] = strconv.FormatInt(n.resources.Memory, 10)
+    }
+    if n.stage != "" {
+        md["stage"] = n.stage
+    }
+    if n.group != "" {
+        md["group"] = n.group
+    }
+    if n.disabled {
+        md["disabled"] = "true"
+    }
+    if len(n.inputFiles) > 0 {
+        md["inputs"] = strings.Join(n.inputFiles, ",")
+    }
+    if len(n.outputFiles) > 0 {
+        md["outputs"] = strings.Join(n.outputFiles, ",")
+    }
+    return md
+}
+
+// edgeSet returns the graph's distinct edges and their settings.
+func edgeSet(g *Graph) map[Edge]map[string]string {
+    set := make(map[Edge]map[string]string)
+    for _, node := range g.nodes {
+        for _, child := range node.children {
+            e := Edge{From: node.name, To: child.name}
+            if _, exists := set[e]; !exists {
+                set[e] = node.edgeTo(child).metadata()
+            }
+        }
+        for _, fallback := range node.fallbacks {
+            set[Edge{From: node.name, To: fallback.name, Fallback: true}] = map[string]string{}
+        }
+    }
+    return set
+}
+
+func diffMetadata(old, new map[string]string, subject func(*Change)) []Change {
+    var changes []Change
+    for key, value := range new {
+        if old[key] != value {
+            c := Change{Key: key, Old: old[key], New: value}
+            subject(&c)
+            changes = append(changes, c)
+        }
+    }
+    for key, value := range old {
+        if _, exists := new[key]; !exists {
+            c := Change{Key: key, Old: value}
+            subject(&c)
+            changes = append(changes, c)
+        }
+    }
+    sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+    return changes
+}
+
+func changeSubject(c Change) string {
+    if c.Edge != nil {
+        return "edge " + c.Edge.String()
+    }
+    return "node " + c.Node
+}
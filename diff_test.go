@@ -0,0 +1,62 @@
+package leo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+    noop := func() error { return nil }
+
+    old := TaskGraph()
+    old.Add("A", noop)
+    old.Add("B", noop, WithExpectedDuration(time.Second))
+    old.Add("C", noop)
+    old.Precede("A", "B")
+    old.Precede("B", "C")
+
+    new := TaskGraph()
+    new.Add("A", noop)
+    new.Add("B", noop, WithExpectedDuration(2*time.Second))
+    new.Add("D", noop)
+    new.Precede("A", "B", OnParentFailure(EdgeRelease))
+    new.Precede("B", "D")
+
+    d := Diff(old, new)
+
+    if want := []string{"D"}; !reflect.DeepEqual(d.AddedNodes, want) {
+        t.Errorf("AddedNodes = %v, want %v", d.AddedNodes, want)
+    }
+    if want := []string{"C"}; !reflect.DeepEqual(d.RemovedNodes, want) {
+        t.Errorf("RemovedNodes = %v, want %v", d.RemovedNodes, want)
+    }
+    if want := []Edge{{From: "B", To: "D"}}; !reflect.DeepEqual(d.AddedEdges, want) {
+        t.Errorf("AddedEdges = %v, want %v", d.AddedEdges, want)
+    }
+    if want := []Edge{{From: "B", To: "C"}}; !reflect.DeepEqual(d.RemovedEdges, want) {
+        t.Errorf("RemovedEdges = %v, want %v", d.RemovedEdges, want)
+    }
+    if len(d.Changed) != 2 {
+        t.Fatalf("expected 2 changes, got %v", d.Changed)
+    }
+
+    out := d.String()
+    for _, line := range []string{
+        "+ node D",
+        "- node C",
+        "+ edge B -> D",
+        "- edge B -> C",
+        `~ edge A -> B: on_parent_failure "" -> "release"`,
+        `~ node B: expected_duration "1s" -> "2s"`,
+    } {
+        if !strings.Contains(out, line+"\n") {
+            t.Errorf("diff output missing %q:\n%s", line, out)
+        }
+    }
+
+    if !Diff(old, old).Empty() {
+        t.Errorf("a graph should not differ from itself")
+    }
+}
@@ -0,0 +1,432 @@
+package leo
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "sort"
+    "strconv"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+// ExportDOT writes g in the Graphviz DOT language, for rendering with tools
+// such as dot. Edge labels and weights (see EdgeLabel and EdgeWeight) become
+// the label and weight attributes; Graphviz's dot layout expects integer
+// weights. Fallback edges are drawn dashed and labelled "on failure".
+func ExportDOT(w io.Writer, g *Graph) error {
+    bw := bufio.NewWriter(w)
+    fmt.Fprintf(bw, "digraph leo {\n")
+    for _, name := range sortedNodeNames(g) {
+        fmt.Fprintf(bw, "    %s;\n", dotQuote(name))
+    }
+    for _, e := range exportEdges(g) {
+        var attrs []string
+        if e.Fallback {
+            attrs = append(attrs, "style=dashed", `label="on failure"`)
+        }
+        if e.Label != "" {
+            attrs = append(attrs, "label="+dotQuote(e.Label))
+        }
+        if e.Weight != 0 {
+            attrs = append(attrs, "weight="+strconv.FormatFloat(e.Weight, 'g', -1, 64))
+        }
+        fmt.Fprintf(bw, "    %s -> %s", dotQuote(e.From), dotQuote(e.To))
+        if len(attrs) > 0 {
+            fmt.Fprintf(bw, " [%s]", strings.Join(attrs, ", "))
+        }
+        fmt.Fprintf(bw, ";\n")
+    }
+    fmt.Fprintf(bw, "}\n")
+    return bw.Flush()
+}
+
+// dotQuote returns s as a DOT string literal.
+func dotQuote(s string) string {
+    s = strings.ReplaceAll(s, `\`, `\\`)
+    s = strings.ReplaceAll(s, `"`, `\"`)
+    s = strings.ReplaceAll(s, "\n", `\n`)
+    return `"` + s + `"`
+}
+
+func sortedNodeNames(g *Graph) []string {
+    names := make([]string, 0, len(g.nodes))
+    for name := range g.nodes {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// exportEdges returns the graph's distinct edges with their labels and
+// weights, sorted.
+func exportEdges(g *Graph) []Edge {
+    var edges []Edge
+    seen := make(map[Edge]bool)
+    for _, node := range g.nodes {
+        for _, child := range node.children {
+            key := Edge{From: node.name, To: child.name}
+            if seen[key] {
+                continue
+            }
+            seen[key] = true
+            ed := node.edgeTo(child)
+            key.Label, key.Weight = ed.label, ed.weight
+            edges = append(edges, key)
+        }
+        for _, fallback := range node.fallbacks {
+            key := Edge{From: node.name, To: fallback.name, Fallback: true}
+            if !seen[key] {
+                seen[key] = true
+                edges = append(edges, key)
+            }
+        }
+    }
+    sortEdges(edges)
+    return edges
+}
+
+// ImportDOT reads a graph written in a restricted subset of the Graphviz DOT
+// language, such as the output of ExportDOT. Every node becomes a
+// placeholder with no task, to be bound by name with Graph.Bind, and every
+// edge a dependency. The label and weight attributes of edges become
+// EdgeLabel and EdgeWeight, and dashed edges become fallbacks (see
+// Graph.OnFailure), as ExportDOT draws them.
+//
+// The subset is a single digraph of node statements, edge statements
+// chaining two or more nodes with ->, attribute lists, and comments.
+// Graph, node and edge defaults and graph attributes such as "rankdir=LR"
+// are ignored; undirected graphs, subgraphs and ports are not supported.
+func ImportDOT(r io.Reader) (*Graph, error) {
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return nil, err
+    }
+    p := &dotParser{lex: &dotLexer{src: string(data), line: 1}}
+    if err := p.next(); err != nil {
+        return nil, err
+    }
+    graph := TaskGraph()
+    if err := p.parse(graph); err != nil {
+        return nil, fmt.Errorf("dot line %d: %w", p.tok.line, err)
+    }
+    return graph, nil
+}
+
+// dotToken is a token of the DOT language. Kind is dotID for identifiers,
+// numerals and quoted strings, whose unquoted value is text, and otherwise
+// the punctuation itself, such as "->" or "[".
+type dotToken struct {
+    kind string
+    text string
+    line int
+}
+
+const (
+    dotID  = "ID"
+    dotEOF = "EOF"
+)
+
+type dotLexer struct {
+    src  string
+    pos  int
+    line int
+}
+
+// skip skips whitespace and comments.
+func (l *dotLexer) skip() {
+    for l.pos < len(l.src) {
+        switch c := l.src[l.pos]; {
+        case c == '\n':
+            l.line++
+            l.pos++
+        case c == ' ' || c == '\t' || c == '\r':
+            l.pos++
+        case strings.HasPrefix(l.src[l.pos:], "//"), c == '#' && (l.pos == 0 || l.src[l.pos-1] == '\n'):
+            for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+                l.pos++
+            }
+        case strings.HasPrefix(l.src[l.pos:], "/*"):
+            end := strings.Index(l.src[l.pos+2:], "*/")
+            if end < 0 {
+                l.pos = len(l.src)
+                return
+            }
+            l.line += strings.Count(l.src[l.pos:l.pos+end+4], "\n")
+            l.pos += end + 4
+        default:
+            return
+        }
+    }
+}
+
+func (l *dotLexer) next() (dotToken, error) {
+    l.skip()
+    tok := dotToken{line: l.line}
+    if l.pos >= len(l.src) {
+        tok.kind = dotEOF
+        return tok, nil
+    }
+    rest := l.src[l.pos:]
+    switch {
+    case strings.HasPrefix(rest, "->"), strings.HasPrefix(rest, "--"):
+        tok.kind = rest[:2]
+        l.pos += 2
+    case strings.ContainsRune("{}[];,=:", rune(rest[0])):
+        tok.kind = rest[:1]
+        l.pos++
+    case rest[0] == '"':
+        var b strings.Builder
+        i := 1
+        for ; i < len(rest) && rest[i] != '"'; i++ {
+            switch {
+            case rest[i] == '\\' && i+1 < len(rest) && rest[i+1] == '\n':
+                // A line continuation.
+                i++
+                l.line++
+            case rest[i] == '\\' && i+1 < len(rest) && rest[i+1] == '"':
+                i++
+                b.WriteByte('"')
+            case rest[i] == '\\' && i+1 < len(rest) && rest[i+1] == 'n':
+                i++
+                b.WriteByte('\n')
+            case rest[i] == '\\' && i+1 < len(rest) && rest[i+1] == '\\':
+                i++
+                b.WriteByte('\\')
+            default:
+                if rest[i] == '\n' {
+                    l.line++
+                }
+                b.WriteByte(rest[i])
+            }
+        }
+        if i == len(rest) {
+            return tok, fmt.Errorf("unterminated string")
+        }
+        tok.kind, tok.text = dotID, b.String()
+        l.pos += i + 1
+    default:
+        // An identifier or numeral; only a numeral may start with a minus.
+        i := 0
+        if rest[0] == '-' {
+            i++
+        }
+        for _, r := range rest[i:] {
+            if r != '_' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+                break
+            }
+            i += utf8.RuneLen(r)
+        }
+        if i == 1 && rest[0] == '-' {
+            i = 0
+        }
+        if i == 0 {
+            return tok, fmt.Errorf("unexpected %q", rest[:1])
+        }
+        tok.kind, tok.text = dotID, rest[:i]
+        l.pos += i
+    }
+    return tok, nil
+}
+
+type dotParser struct {
+    lex *dotLexer
+    tok dotToken
+}
+
+func (p *dotParser) next() error {
+    tok, err := p.lex.next()
+    if err != nil {
+        return err
+    }
+    p.tok = tok
+    return nil
+}
+
+// keyword reports whether the current token is the DOT keyword kw, which is
+// case-insensitive.
+func (p *dotParser) keyword(kw string) bool {
+    return p.tok.kind == dotID && strings.EqualFold(p.tok.text, kw)
+}
+
+func (p *dotParser) expect(kind string) error {
+    if p.tok.kind != kind {
+        return fmt.Errorf("expected %s, found %s", kind, p.describe())
+    }
+    return p.next()
+}
+
+func (p *dotParser) describe() string {
+    if p.tok.kind == dotID {
+        return strconv.Quote(p.tok.text)
+    }
+    return p.tok.kind
+}
+
+func (p *dotParser) parse(graph *Graph) error {
+    if p.keyword("strict") {
+        if err := p.next(); err != nil {
+            return err
+        }
+    }
+    if p.keyword("graph") {
+        return fmt.Errorf("undirected graphs are not supported")
+    }
+    if !p.keyword("digraph") {
+        return fmt.Errorf("expected digraph, found %s", p.describe())
+    }
+    if err := p.next(); err != nil {
+        return err
+    }
+    if p.tok.kind == dotID {
+        if err := p.next(); err != nil {
+            return err
+        }
+    }
+    if err := p.expect("{"); err != nil {
+        return err
+    }
+    for p.tok.kind != "}" {
+        if p.tok.kind == dotEOF {
+            return fmt.Errorf("expected }, found end of file")
+        }
+        if p.tok.kind == ";" {
+            if err := p.next(); err != nil {
+                return err
+            }
+            continue
+        }
+        if err := p.statement(graph); err != nil {
+            return err
+        }
+    }
+    if err := p.next(); err != nil {
+        return err
+    }
+    if p.tok.kind != dotEOF {
+        return fmt.Errorf("unexpected %s after the graph", p.describe())
+    }
+    return nil
+}
+
+// statement parses a node, edge or attribute statement.
+func (p *dotParser) statement(graph *Graph) error {
+    if p.keyword("subgraph") || p.tok.kind == "{" {
+        return fmt.Errorf("subgraphs are not supported")
+    }
+    if p.keyword("graph") || p.keyword("node") || p.keyword("edge") {
+        if err := p.next(); err != nil {
+            return err
+        }
+        _, err := p.attributes()
+        return err
+    }
+    if p.tok.kind != dotID {
+        return fmt.Errorf("expected a node, found %s", p.describe())
+    }
+    names := []string{p.tok.text}
+    if err := p.next(); err != nil {
+        return err
+    }
+    if p.tok.kind == "=" {
+        // A graph attribute.
+        if err := p.next(); err != nil {
+            return err
+        }
+        return p.expect(dotID)
+    }
+    if p.tok.kind == ":" {
+        return fmt.Errorf("ports are not supported")
+    }
+    for p.tok.kind == "->" || p.tok.kind == "--" {
+        if p.tok.kind == "--" {
+            return fmt.Errorf("undirected edges are not supported")
+        }
+        if err := p.next(); err != nil {
+            return err
+        }
+        if p.keyword("subgraph") || p.tok.kind == "{" {
+            return fmt.Errorf("subgraphs are not supported")
+        }
+        if p.tok.kind != dotID {
+            return fmt.Errorf("expected a node, found %s", p.describe())
+        }
+        names = append(names, p.tok.text)
+        if err := p.next(); err != nil {
+            return err
+        }
+    }
+    attrs, err := p.attributes()
+    if err != nil {
+        return err
+    }
+
+    for _, name := range names {
+        graph.AddCtx(name, nil)
+    }
+    if len(names) == 1 {
+        return nil
+    }
+    var opts []EdgeOption
+    if label, ok := attrs["label"]; ok && attrs["style"] != "dashed" {
+        opts = append(opts, EdgeLabel(label))
+    }
+    if weight, ok := attrs["weight"]; ok {
+        w, err := strconv.ParseFloat(weight, 64)
+        if err != nil {
+            return fmt.Errorf("weight %q is not a number", weight)
+        }
+        opts = append(opts, EdgeWeight(w))
+    }
+    for i := 1; i < len(names); i++ {
+        from, to := names[i-1], names[i]
+        if attrs["style"] == "dashed" {
+            err = graph.OnFailure(from, to)
+        } else {
+            err = graph.Precede(from, to, opts...)
+        }
+        if err != nil {
+            return fmt.Errorf("%s -> %s: %w", from, to, err)
+        }
+    }
+    return nil
+}
+
+// attributes parses any attribute lists, such as [label="x", weight=2].
+func (p *dotParser) attributes() (map[string]string, error) {
+    attrs := make(map[string]string)
+    for p.tok.kind == "[" {
+        if err := p.next(); err != nil {
+            return nil, err
+        }
+        for p.tok.kind != "]" {
+            if p.tok.kind != dotID {
+                return nil, fmt.Errorf("expected an attribute, found %s", p.describe())
+            }
+            key := p.tok.text
+            if err := p.next(); err != nil {
+                return nil, err
+            }
+            if err := p.expect("="); err != nil {
+                return nil, err
+            }
+            if p.tok.kind != dotID {
+                return nil, fmt.Errorf("expected a value for %s, found %s", key, p.describe())
+            }
+            attrs[key] = p.tok.text
+            if err := p.next(); err != nil {
+                return nil, err
+            }
+            if p.tok.kind == "," || p.tok.kind == ";" {
+                if err := p.next(); err != nil {
+                    return nil, err
+                }
+            }
+        }
+        if err := p.next(); err != nil {
+            return nil, err
+        }
+    }
+    return attrs, nil
+}
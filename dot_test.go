@@ -0,0 +1,114 @@
+package leo
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestExportDOT(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("build", func() error { return nil })
+    graph.Add("deploy", func() error { return nil })
+    graph.Add("say \"hi\"", func() error { return nil })
+    graph.Add("rollback", func() error { return nil })
+    graph.Precede("build", "deploy", EdgeLabel("image"), EdgeWeight(3))
+    graph.Precede("build", "say \"hi\"")
+    graph.OnFailure("deploy", "rollback")
+
+    var buf bytes.Buffer
+    if err := ExportDOT(&buf, graph); err != nil {
+        t.Fatalf("ExportDOT failed: %v", err)
+    }
+    want := `digraph leo {
+    "build";
+    "deploy";
+    "rollback";
+    "say \"hi\"";
+    "build" -> "deploy" [label="image", weight=3];
+    "build" -> "say \"hi\"";
+    "deploy" -> "rollback" [style=dashed, label="on failure"];
+}
+`
+    if buf.String() != want {
+        t.Errorf("unexpected DOT output:\n%s", buf.String())
+    }
+}
+
+func TestImportDOT(t *testing.T) {
+    src := `// Deploy pipeline.
+strict digraph "deploy" {
+    rankdir=LR;
+    node [shape=box];
+    "build";
+    fetch -> build -> "say \"hi\"";
+    build -> deploy [label="image", weight=3]
+    /* Roll back if the deploy fails. */
+    deploy -> rollback [style=dashed, label="on failure"];
+}
+`
+    graph, err := ImportDOT(strings.NewReader(src))
+    if err != nil {
+        t.Fatalf("ImportDOT failed: %v", err)
+    }
+    var buf bytes.Buffer
+    ExportDOT(&buf, graph)
+    want := `digraph leo {
+    "build";
+    "deploy";
+    "fetch";
+    "rollback";
+    "say \"hi\"";
+    "build" -> "deploy" [label="image", weight=3];
+    "build" -> "say \"hi\"";
+    "deploy" -> "rollback" [style=dashed, label="on failure"];
+    "fetch" -> "build";
+}
+`
+    if buf.String() != want {
+        t.Errorf("unexpected imported graph:\n%s", buf.String())
+    }
+
+    // The placeholders are bound by name.
+    var mu sync.Mutex
+    var ran []string
+    for _, name := range sortedNodeNames(graph) {
+        name := name
+        if err := graph.Bind(name, func(context.Context) error {
+            mu.Lock()
+            defer mu.Unlock()
+            ran = append(ran, name)
+            return nil
+        }); err != nil {
+            t.Fatalf("Bind failed: %v", err)
+        }
+    }
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if len(ran) != 4 || ran[0] != "fetch" {
+        t.Errorf("unexpected tasks run: %v", ran)
+    }
+    if err := graph.Bind("missing", nil); err == nil {
+        t.Errorf("expected binding a missing node to fail")
+    }
+}
+
+func TestImportDOTErrors(t *testing.T) {
+    for _, src := range []string{
+        "graph { a -- b }",
+        "digraph { a -> b",
+        "digraph { subgraph cluster { a } }",
+        "digraph { a -> { b c } }",
+        "digraph { a:n -> b }",
+        `digraph { a -> b [weight=heavy] }`,
+        `digraph { "a -> b }`,
+        "digraph { a -> b -> a }",
+    } {
+        if _, err := ImportDOT(strings.NewReader(src)); err == nil {
+            t.Errorf("expected %q to fail", src)
+        }
+    }
+}
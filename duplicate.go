@@ -0,0 +1,82 @@
+package leo
+
+import (
+    "errors"
+    "fmt"
+)
+
+// ErrNodeExists is returned when a node is added under a name that is
+// already taken and the graph's DuplicatePolicy does not allow it.
+var ErrNodeExists = errors.New("node already exists")
+
+// DuplicatePolicy controls what Add, AddCtx and AddShell do when a node with
+// the same name already exists.
+type DuplicatePolicy int
+
+const (
+    // DuplicateKeep keeps the existing node and ignores the new task and
+    // options. This is the default.
+    DuplicateKeep DuplicatePolicy = iota
+    // DuplicateError keeps the existing node and returns an error wrapping
+    // ErrNodeExists.
+    DuplicateError
+    // DuplicateReplace replaces the existing node's task and applies the new
+    // options to it. The node's edges and other settings are kept, except
+    // for the command recorded by AddShell, which belongs to the old task.
+    DuplicateReplace
+)
+
+func (p DuplicatePolicy) String() string {
+    switch p {
+    case DuplicateKeep:
+        return "keep"
+    case DuplicateError:
+        return "error"
+    case DuplicateReplace:
+        return "replace"
+    }
+    return fmt.Sprintf("DuplicatePolicy(%d)", int(p))
+}
+
+// SetDuplicatePolicy sets how the graph handles nodes added under a name that
+// is already taken. It returns ErrFrozen if the graph is frozen.
+func (g *Graph) SetDuplicatePolicy(p DuplicatePolicy) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    g.duplicates = p
+    return nil
+}
+
+// AddResult reports what Add, AddCtx or AddShell did.
+type AddResult int
+
+const (
+    // NodeAdded means a new node was added.
+    NodeAdded AddResult = iota
+    // NodeKept means a node with the name already existed and was left
+    // unchanged, see DuplicateKeep.
+    NodeKept
+    // NodeReplaced means an existing node's task was replaced, see
+    // DuplicateReplace.
+    NodeReplaced
+    // NodeRejected means the call returned an error and the graph is
+    // unchanged.
+    NodeRejected
+)
+
+func (r AddResult) String() string {
+    switch r {
+    case NodeAdded:
+        return "added"
+    case NodeKept:
+        return "kept"
+    case NodeReplaced:
+        return "replaced"
+    case NodeRejected:
+        return "rejected"
+    }
+    return fmt.Sprintf("AddResult(%d)", int(r))
+}
@@ -0,0 +1,40 @@
+package leo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDuplicatePolicy(t *testing.T) {
+    var ran string
+    graph := TaskGraph()
+    if res, err := graph.Add("a", func() error { ran = "first"; return nil }); res != NodeAdded || err != nil {
+        t.Fatalf("expected the node to be added, got %v, %v", res, err)
+    }
+    graph.Add("b", func() error { return nil })
+    graph.Precede("a", "b")
+
+    if res, err := graph.Add("a", func() error { ran = "second"; return nil }); res != NodeKept || err != nil {
+        t.Errorf("expected the first node to be kept, got %v, %v", res, err)
+    }
+
+    graph.SetDuplicatePolicy(DuplicateError)
+    if res, err := graph.Add("a", func() error { return nil }); res != NodeRejected || !errors.Is(err, ErrNodeExists) {
+        t.Errorf("expected ErrNodeExists, got %v, %v", res, err)
+    }
+
+    graph.SetDuplicatePolicy(DuplicateReplace)
+    if res, err := graph.Add("a", func() error { ran = "third"; return nil }, WithTags("replaced")); res != NodeReplaced || err != nil {
+        t.Errorf("expected the task to be replaced, got %v, %v", res, err)
+    }
+
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if ran != "third" {
+        t.Errorf("expected the replacement task to run, got %q", ran)
+    }
+    if len(graph.Edges()) != 1 || len(graph.Tagged("replaced")) != 1 {
+        t.Errorf("expected the replaced node to keep its edges and take the new options")
+    }
+}
@@ -0,0 +1,150 @@
+package leo
+
+import (
+    "sort"
+    "strconv"
+)
+
+// EdgePolicy controls what happens to a child when its parent fails or is
+// skipped.
+type EdgePolicy int
+
+const (
+    // EdgeSkip skips the child and propagates the skip along the child's own
+    // edges. This is the default.
+    EdgeSkip EdgePolicy = iota
+    // EdgeRelease releases the child as if the parent had succeeded, for
+    // tasks such as cleanup or notification that must run regardless.
+    EdgeRelease
+    // EdgeBlock aborts the run: the child is skipped, no further tasks are
+    // started anywhere in the graph, and the run fails.
+    EdgeBlock
+)
+
+func (p EdgePolicy) String() string {
+    switch p {
+    case EdgeSkip:
+        return "skip"
+    case EdgeRelease:
+        return "release"
+    case EdgeBlock:
+        return "block"
+    }
+    return "unknown"
+}
+
+type edgeConfig struct {
+    policy EdgePolicy
+    stream bool
+    buffer int
+    label  string
+    weight float64
+}
+
+// EdgeOption configures an edge added with Precede or Succeed.
+type EdgeOption func(*edgeConfig)
+
+// OnParentFailure sets how the child reacts when the parent fails or is
+// skipped.
+func OnParentFailure(policy EdgePolicy) EdgeOption {
+    return func(e *edgeConfig) {
+        e.policy = policy
+    }
+}
+
+// EdgeLabel describes an edge, for example the artifact passed along it. The
+// label appears in Edges and in the DOT and Mermaid exports.
+func EdgeLabel(label string) EdgeOption {
+    return func(e *edgeConfig) {
+        e.label = label
+    }
+}
+
+// EdgeWeight attaches a numeric weight to an edge, such as the expected
+// amount of data transferred. The weight appears in Edges and in the DOT and
+// Mermaid exports, for custom schedulers and documentation.
+func EdgeWeight(w float64) EdgeOption {
+    return func(e *edgeConfig) {
+        e.weight = w
+    }
+}
+
+var defaultEdge = &edgeConfig{}
+
+// edgeTo returns the settings of the edge from n to child. Edges added without
+// options share a default that must not be modified.
+func (n *Node) edgeTo(child *Node) *edgeConfig {
+    if ed, ok := n.edges[child]; ok {
+        return ed
+    }
+    return defaultEdge
+}
+
+// Edge identifies an edge between two nodes. Fallback is set for edges added
+// with OnFailure. Label and Weight are set by EdgeLabel and EdgeWeight and
+// are empty in the keys used by Diff.
+type Edge struct {
+    From     string
+    To       string
+    Fallback bool
+    Label    string
+    Weight   float64
+}
+
+func (e Edge) String() string {
+    if e.Fallback {
+        return e.From + " -> " + e.To + " (on failure)"
+    }
+    return e.From + " -> " + e.To
+}
+
+// Edges returns every edge in the graph, sorted by From, then To. Duplicate
+// edges are listed once per occurrence.
+func (g *Graph) Edges() []Edge {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    var edges []Edge
+    for _, node := range g.nodes {
+        for _, child := range node.children {
+            ed := node.edgeTo(child)
+            edges = append(edges, Edge{From: node.name, To: child.name, Label: ed.label, Weight: ed.weight})
+        }
+        for _, fallback := range node.fallbacks {
+            edges = append(edges, Edge{From: node.name, To: fallback.name, Fallback: true})
+        }
+    }
+    sortEdges(edges)
+    return edges
+}
+
+func sortEdges(edges []Edge) {
+    sort.Slice(edges, func(i, j int) bool {
+        a, b := edges[i], edges[j]
+        if a.From != b.From {
+            return a.From < b.From
+        }
+        if a.To != b.To {
+            return a.To < b.To
+        }
+        return !a.Fallback && b.Fallback
+    })
+}
+
+// metadata returns the edge's non-default settings as strings, keyed by
+// setting name.
+func (e *edgeConfig) metadata() map[string]string {
+    md := make(map[string]string)
+    if e.policy != EdgeSkip {
+        md["on_parent_failure"] = e.policy.String()
+    }
+    if e.stream {
+        md["stream"] = strconv.Itoa(e.buffer)
+    }
+    if e.label != "" {
+        md["label"] = e.label
+    }
+    if e.weight != 0 {
+        md["weight"] = strconv.FormatFloat(e.weight, 'g', -1, 64)
+    }
+    return md
+}
@@ -0,0 +1,114 @@
+package leo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEdgePolicies(t *testing.T) {
+    var mu sync.Mutex
+    executed := make(map[string]bool)
+    record := func(name string) TaskFunc {
+        return func() error {
+            mu.Lock()
+            defer mu.Unlock()
+            executed[name] = true
+            return nil
+        }
+    }
+
+    graph := TaskGraph()
+    graph.Add("deploy", func() error { return errors.New("deploy failed") })
+    graph.Add("smoke-test", record("smoke-test"))
+    graph.Add("notify", record("notify"))
+    graph.Precede("deploy", "smoke-test")
+    graph.Precede("deploy", "notify", OnParentFailure(EdgeRelease))
+
+    executor := NewExecutor(graph)
+    if err := executor.Execute(); err == nil {
+        t.Fatalf("expected the deploy failure to fail the run")
+    }
+
+    // The failing run returns as soon as deploy fails; wait for notify.
+    deadline := time.Now().Add(time.Second)
+    for time.Now().Before(deadline) {
+        mu.Lock()
+        done := executed["notify"]
+        mu.Unlock()
+        if done {
+            break
+        }
+        time.Sleep(time.Millisecond)
+    }
+
+    mu.Lock()
+    defer mu.Unlock()
+    if !executed["notify"] {
+        t.Errorf("notify should run despite the failure")
+    }
+    if executed["smoke-test"] {
+        t.Errorf("smoke-test should be skipped")
+    }
+}
+
+func TestEdgeBlockAbortsRun(t *testing.T) {
+    var mu sync.Mutex
+    executed := make(map[string]bool)
+
+    graph := TaskGraph()
+    graph.Add("check", func() error { return errors.New("precondition failed") })
+    graph.Add("restore", func() error { return nil })
+    graph.Add("apply", func() error { return nil })
+    graph.Add("slow", func() error {
+        time.Sleep(50 * time.Millisecond)
+        return nil
+    })
+    graph.Add("after-slow", func() error {
+        mu.Lock()
+        defer mu.Unlock()
+        executed["after-slow"] = true
+        return nil
+    })
+    graph.Precede("check", "apply", OnParentFailure(EdgeBlock))
+    graph.Precede("slow", "after-slow")
+    // The fallback makes check's failure handled, so only the block edge fails the run.
+    graph.OnFailure("check", "restore")
+
+    executor := NewExecutor(graph)
+    if err := executor.Execute(); err == nil {
+        t.Fatalf("expected the blocking edge to abort the run")
+    }
+
+    time.Sleep(100 * time.Millisecond)
+    mu.Lock()
+    defer mu.Unlock()
+    if executed["after-slow"] {
+        t.Errorf("no tasks should start after the run is aborted")
+    }
+}
+
+func TestEdgeLabelsAndWeights(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("build", func() error { return nil })
+    graph.Add("deploy", func() error { return nil })
+    hash := graph.Hash()
+    graph.Precede("build", "deploy", EdgeLabel("image"), EdgeWeight(2.5))
+
+    edges := graph.Edges()
+    if len(edges) != 1 || edges[0].Label != "image" || edges[0].Weight != 2.5 {
+        t.Errorf("unexpected edges %+v", edges)
+    }
+
+    other := TaskGraph()
+    other.Add("build", func() error { return nil })
+    other.Add("deploy", func() error { return nil })
+    other.Precede("build", "deploy")
+    if other.Hash() == graph.Hash() || other.Hash() == hash {
+        t.Errorf("expected the label and weight to be part of the hash")
+    }
+    if d := Diff(other, graph); len(d.Changed) != 2 {
+        t.Errorf("expected Diff to report the labelled edge as changed, got %+v", d)
+    }
+}
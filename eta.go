@@ -0,0 +1,92 @@
+package leo
+
+import "time"
+
+// ETA estimates the time remaining until the run completes. Each unfinished
+// node is expected to take the median of its successful durations in the
+// executor's history, or its WithExpectedDuration if it has no history, and
+// the estimate is the longest chain of unfinished nodes. Nodes with neither
+// count as instantaneous. ETA returns 0 once the run has finished.
+func (r *Run) ETA() time.Duration {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.eta()
+}
+
+// eta computes ETA. The caller must hold r.mu.
+func (r *Run) eta() time.Duration {
+    if r.report == nil {
+        return 0
+    }
+    now := r.executor.now()
+
+    done := make(map[*Node]bool)
+    r.report.mu.Lock()
+    for _, node := range r.graph.nodes {
+        if nr, ok := r.report.Nodes[node.name]; ok && nr.State != StatePending {
+            done[node] = true
+        }
+    }
+    r.report.mu.Unlock()
+
+    finish := make(map[*Node]time.Duration)
+    var visit func(n *Node) time.Duration
+    visit = func(n *Node) time.Duration {
+        if d, ok := finish[n]; ok {
+            return d
+        }
+        var after time.Duration
+        for _, p := range n.predecessors() {
+            if !done[p] {
+                if d := visit(p); d > after {
+                    after = d
+                }
+            }
+        }
+        remaining := r.estimate(n)
+        if start, ok := r.started[n]; ok {
+            remaining -= now.Sub(start)
+            if remaining < 0 {
+                remaining = 0
+            }
+        }
+        finish[n] = after + remaining
+        return finish[n]
+    }
+
+    var eta time.Duration
+    for _, node := range r.graph.nodes {
+        if !done[node] {
+            if d := visit(node); d > eta {
+                eta = d
+            }
+        }
+    }
+    return eta
+}
+
+// estimate returns the expected duration of n.
+func (r *Run) estimate(n *Node) time.Duration {
+    if d, ok := r.estimates[n.name]; ok {
+        return d
+    }
+    return n.estimate()
+}
+
+// estimate returns the expected duration of n without a history: its
+// WithCost, or else its WithExpectedDuration.
+func (n *Node) estimate() time.Duration {
+    if n.cost > 0 {
+        return n.cost
+    }
+    return n.expected
+}
+
+// progressETA returns the ETA to include in task events, or 0 if nobody is
+// subscribed to them. The caller must hold r.mu.
+func (r *Run) progressETA() time.Duration {
+    if !r.executor.events.active() {
+        return 0
+    }
+    return r.eta()
+}
@@ -0,0 +1,63 @@
+package leo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestETA(t *testing.T) {
+    history := NewMemoryHistory(0)
+    for _, d := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute} {
+        history.Save(RunRecord{Nodes: map[string]NodeRecord{
+            "build": {State: "succeeded", Duration: d},
+            "test":  {State: "succeeded", Duration: 10 * time.Minute},
+            "lint":  {State: "failed", Duration: time.Hour},
+        }})
+    }
+
+    graph := TaskGraph()
+    release := make(chan struct{})
+    var etaWhileBuilding time.Duration
+    var run *Run
+    graph.Add("build", func() error {
+        etaWhileBuilding = run.ETA()
+        return nil
+    })
+    graph.Add("test", func() error {
+        <-release
+        return nil
+    })
+    graph.Add("lint", func() error { return nil }, WithExpectedDuration(time.Minute))
+    graph.Precede("build", "test")
+
+    executor := NewExecutor(graph)
+    executor.SetHistory(history)
+    run = executor.NewRun()
+
+    sub := executor.SubscribeFilter(EventFilter{Types: []EventType{EventTaskFinished}, Nodes: []string{"build"}})
+    defer sub.Close()
+
+    done := make(chan error)
+    go func() { done <- run.Execute() }()
+
+    ev := <-sub.C
+    close(release)
+    if err := <-done; err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    // build's median is 2m and test's is 10m; lint has no successful history
+    // and falls back to its expected duration.
+    if etaWhileBuilding < 11*time.Minute || etaWhileBuilding > 12*time.Minute {
+        t.Errorf("expected an ETA of about 12m while building, got %s", etaWhileBuilding)
+    }
+    if ev.ETA < 9*time.Minute || ev.ETA > 10*time.Minute {
+        t.Errorf("expected an ETA of about 10m after building, got %s", ev.ETA)
+    }
+    if eta := run.ETA(); eta != 0 {
+        t.Errorf("expected no time remaining after the run, got %s", eta)
+    }
+    if runs, _ := history.Runs(0); len(runs) != 4 {
+        t.Errorf("expected the run to be saved, got %d runs", len(runs))
+    }
+}
@@ -0,0 +1,250 @@
+package leo
+
+import (
+    "fmt"
+    "sync"
+    "time"
+)
+
+// EventType identifies the kind of an execution event.
+type EventType int
+
+const (
+    // EventTaskQueued is published when a node's dependencies are met and it
+    // is queued for execution.
+    EventTaskQueued EventType = iota
+    // EventTaskStarted is published when a node's task is called.
+    EventTaskStarted
+    // EventTaskFinished is published when a node's task succeeds.
+    EventTaskFinished
+    // EventTaskFailed is published when a node's task fails.
+    EventTaskFailed
+    // EventTaskSkipped is published when a node is skipped.
+    EventTaskSkipped
+    // EventRunFinished is published when a run returns. If the run returns
+    // early because of an error, tasks that were already running may still
+    // publish events after it.
+    EventRunFinished
+    // EventTaskSlow is published when a running task exceeds its baseline
+    // from the history, see Executor.SetSlowTaskPercentile.
+    EventTaskSlow
+)
+
+func (t EventType) String() string {
+    switch t {
+    case EventTaskQueued:
+        return "TaskQueued"
+    case EventTaskStarted:
+        return "TaskStarted"
+    case EventTaskFinished:
+        return "TaskFinished"
+    case EventTaskFailed:
+        return "TaskFailed"
+    case EventTaskSkipped:
+        return "TaskSkipped"
+    case EventRunFinished:
+        return "RunFinished"
+    case EventTaskSlow:
+        return "TaskSlow"
+    }
+    return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event describes something that happened during a run.
+type Event struct {
+    Type EventType
+    Time time.Time
+    // Run is the run the event belongs to. Its Name and Labels identify it
+    // to subscribers.
+    Run *Run
+    // Node is the node's name; it is empty for EventRunFinished.
+    Node string
+    // Duration is the task's or run's duration, set for EventTaskFinished,
+    // EventTaskFailed and EventRunFinished. For EventTaskSlow, it is the
+    // baseline the task exceeded.
+    Duration time.Duration
+    // Err is set for EventTaskFailed, and for EventRunFinished if the run
+    // failed.
+    Err error
+    // Reason is set for EventTaskSkipped.
+    Reason string
+    // ETA is the run's estimated time remaining, see Run.ETA. It is set for
+    // EventTaskStarted, EventTaskFinished, EventTaskFailed and
+    // EventTaskSkipped.
+    ETA time.Duration
+}
+
+func (ev Event) String() string {
+    switch {
+    case ev.Err != nil && ev.Node != "":
+        return fmt.Sprintf("%s %s: %v", ev.Type, ev.Node, ev.Err)
+    case ev.Err != nil:
+        return fmt.Sprintf("%s: %v", ev.Type, ev.Err)
+    case ev.Reason != "":
+        return fmt.Sprintf("%s %s: %s", ev.Type, ev.Node, ev.Reason)
+    case ev.Node != "":
+        return fmt.Sprintf("%s %s", ev.Type, ev.Node)
+    }
+    return ev.Type.String()
+}
+
+// Subscription delivers an executor's events to a single consumer. Events are
+// queued without limit, so a slow consumer never blocks the run, and are
+// delivered in the order they were published.
+type Subscription struct {
+    // C receives the events. It is closed when the subscription is closed.
+    C <-chan Event
+
+    c      chan Event
+    mu     sync.Mutex
+    queue  []Event
+    signal chan struct{}
+    done   chan struct{}
+    closed bool
+    drain  bool
+    filter EventFilter
+    run    *Run
+    bus    *eventBus
+}
+
+// Subscribe returns a subscription to the events of every run of e. Close
+// the subscription when it is no longer needed.
+func (e *Executor) Subscribe() *Subscription {
+    c := make(chan Event)
+    s := &Subscription{
+        C:      c,
+        c:      c,
+        signal: make(chan struct{}, 1),
+        done:   make(chan struct{}),
+        bus:    &e.events,
+    }
+    e.events.add(s)
+    go s.deliver()
+    return s
+}
+
+// Close stops delivery and closes C. Queued events that have not been
+// received are discarded.
+func (s *Subscription) Close() {
+    s.bus.remove(s)
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if !s.closed {
+        s.closed = true
+        close(s.done)
+    }
+}
+
+// closeWhenDrained stops new events and closes C once the queued events have
+// been received.
+func (s *Subscription) closeWhenDrained() {
+    s.bus.remove(s)
+    s.mu.Lock()
+    s.drain = true
+    s.mu.Unlock()
+    select {
+    case s.signal <- struct{}{}:
+    default:
+    }
+}
+
+func (s *Subscription) push(ev Event, tags []string) {
+    s.mu.Lock()
+    if s.run != nil && ev.Run != s.run || !s.filter.match(ev, tags) {
+        s.mu.Unlock()
+        return
+    }
+    s.queue = append(s.queue, ev)
+    s.mu.Unlock()
+    select {
+    case s.signal <- struct{}{}:
+    default:
+    }
+}
+
+func (s *Subscription) deliver() {
+    defer close(s.c)
+    for {
+        s.mu.Lock()
+        if len(s.queue) == 0 {
+            drain := s.drain
+            s.mu.Unlock()
+            if drain {
+                return
+            }
+            select {
+            case <-s.signal:
+                continue
+            case <-s.done:
+                return
+            }
+        }
+        ev := s.queue[0]
+        s.queue[0] = Event{}
+        s.queue = s.queue[1:]
+        s.mu.Unlock()
+
+        select {
+        case s.c <- ev:
+        case <-s.done:
+            return
+        }
+        if ev.Type == EventRunFinished && s.run != nil {
+            s.Close()
+            return
+        }
+    }
+}
+
+// eventBus fans events out to an executor's subscriptions.
+type eventBus struct {
+    mu   sync.Mutex
+    subs map[*Subscription]struct{}
+}
+
+func (b *eventBus) add(s *Subscription) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.subs == nil {
+        b.subs = make(map[*Subscription]struct{})
+    }
+    b.subs[s] = struct{}{}
+}
+
+func (b *eventBus) remove(s *Subscription) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    delete(b.subs, s)
+}
+
+// active reports whether there are any subscriptions.
+func (b *eventBus) active() bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return len(b.subs) > 0
+}
+
+func (b *eventBus) publish(ev Event, tags []string) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    for s := range b.subs {
+        s.push(ev, tags)
+    }
+}
+
+// publish sends an event of the run to the executor's subscribers.
+func (r *Run) publish(ev Event) {
+    if r.executor.logger == nil && !r.executor.events.active() {
+        return
+    }
+    ev.Run = r
+    if ev.Time.IsZero() {
+        ev.Time = r.executor.now()
+    }
+    var tags []string
+    if n := r.graph.nodes[ev.Node]; n != nil {
+        tags = n.tags
+    }
+    r.executor.events.publish(ev, tags)
+    r.executor.log(ev)
+}
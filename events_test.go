@@ -0,0 +1,89 @@
+package leo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil })
+    graph.Add("B", func() error { return errors.New("boom") })
+    graph.Add("C", func() error { return nil })
+    graph.Add("fix", func() error { return nil })
+    graph.Precede("A", "B")
+    graph.Precede("B", "C")
+    graph.OnFailure("B", "fix")
+
+    executor := NewExecutor(graph)
+    subs := []*Subscription{executor.Subscribe(), executor.Subscribe()}
+
+    var wg sync.WaitGroup
+    seen := make([][]Event, len(subs))
+    for i, sub := range subs {
+        wg.Add(1)
+        go func(i int, sub *Subscription) {
+            defer wg.Done()
+            for ev := range sub.C {
+                seen[i] = append(seen[i], ev)
+                if ev.Type == EventRunFinished {
+                    return
+                }
+            }
+        }(i, sub)
+    }
+
+    run := executor.NewRun()
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    wg.Wait()
+
+    for i, events := range seen {
+        counts := map[EventType]int{}
+        for _, ev := range events {
+            counts[ev.Type]++
+            if ev.Run != run {
+                t.Errorf("event %v has the wrong run", ev)
+            }
+            if ev.Type == EventTaskFailed && (ev.Node != "B" || ev.Err == nil) {
+                t.Errorf("unexpected failure event %v", ev)
+            }
+            if ev.Type == EventTaskSkipped && (ev.Node != "C" || ev.Reason != "upstream B failed") {
+                t.Errorf("unexpected skip event %v", ev)
+            }
+        }
+        want := map[EventType]int{
+            EventTaskQueued:   3,
+            EventTaskStarted:  3,
+            EventTaskFinished: 2,
+            EventTaskFailed:   1,
+            EventTaskSkipped:  1,
+            EventRunFinished:  1,
+        }
+        for typ, n := range want {
+            if counts[typ] != n {
+                t.Errorf("subscriber %d: expected %d %s events, got %d", i, n, typ, counts[typ])
+            }
+        }
+        if last := events[len(events)-1]; last.Type != EventRunFinished {
+            t.Errorf("expected RunFinished last, got %v", last)
+        }
+    }
+}
+
+func TestSubscriptionClose(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil })
+    executor := NewExecutor(graph)
+
+    sub := executor.Subscribe()
+    sub.Close()
+    sub.Close()
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    for range sub.C {
+    }
+}
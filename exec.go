@@ -0,0 +1,239 @@
+package leo
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "os"
+    "os/exec"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// CommandError is returned by command tasks when the command fails. Output
+// holds the command's combined stdout and stderr.
+type CommandError struct {
+    Command string
+    Output  []byte
+    Err     error
+}
+
+func (e *CommandError) Error() string {
+    out := strings.TrimSpace(string(e.Output))
+    if out == "" {
+        return fmt.Sprintf("command %q: %v", e.Command, e.Err)
+    }
+    return fmt.Sprintf("command %q: %v: %s", e.Command, e.Err, out)
+}
+
+func (e *CommandError) Unwrap() error {
+    return e.Err
+}
+
+// Command returns a task that runs the named program with args. The process
+// is killed if the task's context is cancelled. Each line the process writes
+// is passed to the executor's OnOutput hook as it is written.
+func Command(name string, args ...string) TaskCtxFunc {
+    return func(ctx context.Context) error {
+        return runCommand(ctx, exec.CommandContext(ctx, name, args...))
+    }
+}
+
+// Shell returns a task that runs script with "sh -c".
+func Shell(script string) TaskCtxFunc {
+    return func(ctx context.Context) error {
+        return runCommand(ctx, exec.CommandContext(ctx, "sh", "-c", script))
+    }
+}
+
+// CommandSpec describes a command in full, for programs that need more than
+// Command's name and arguments:
+//
+//    leo.CommandSpec{
+//        Name:  "./migrate.sh",
+//        Args:  []string{"--apply"},
+//        Dir:   "/srv/app",
+//        Env:   map[string]string{"DATABASE_URL": url},
+//        Umask: 0o027,
+//    }.Task()
+type CommandSpec struct {
+    Name string
+    Args []string
+    // Env sets environment variables on top of the executor's environment,
+    // overriding inherited variables of the same name.
+    Env map[string]string
+    // ClearEnv starts the command with only the variables in Env instead
+    // of inheriting the executor's environment.
+    ClearEnv bool
+    // Dir is the working directory. Defaults to the executor's.
+    Dir string
+    // Stdin is passed to the command's standard input.
+    Stdin []byte
+    // StdinFile names a file passed to the command's standard input instead
+    // of Stdin. It is opened each time the task runs.
+    StdinFile string
+    // Umask is the command's file mode creation mask, applied with the
+    // shell's umask builtin, so sh must be available. Zero leaves the
+    // executor's mask. It is only supported on Unix.
+    Umask os.FileMode
+}
+
+// Task returns a task that runs the command. Like Command, the process is
+// killed if the task's context is cancelled and its output is passed to the
+// OnOutput hook.
+func (s CommandSpec) Task() TaskCtxFunc {
+    return func(ctx context.Context) error {
+        name, args := s.Name, s.Args
+        if s.Umask != 0 {
+            if err := checkUmask(); err != nil {
+                return err
+            }
+            mask := strconv.FormatUint(uint64(s.Umask.Perm()), 8)
+            args = append([]string{"-c", "umask " + mask + ` && exec "$@"`, "sh", name}, args...)
+            name = "sh"
+        }
+        cmd := exec.CommandContext(ctx, name, args...)
+        cmd.Dir = s.Dir
+        cmd.Env = s.environ()
+
+        switch {
+        case s.StdinFile != "":
+            f, err := os.Open(s.StdinFile)
+            if err != nil {
+                return fmt.Errorf("command %q: stdin: %w", s.Name, err)
+            }
+            defer f.Close()
+            cmd.Stdin = f
+        case s.Stdin != nil:
+            cmd.Stdin = bytes.NewReader(s.Stdin)
+        }
+        return runCommand(ctx, cmd)
+    }
+}
+
+// environ returns the command's environment, sorted by name after the
+// inherited variables, or nil to inherit the executor's unchanged.
+func (s CommandSpec) environ() []string {
+    if len(s.Env) == 0 && !s.ClearEnv {
+        return nil
+    }
+    var env []string
+    if !s.ClearEnv {
+        for _, kv := range os.Environ() {
+            name, _, _ := strings.Cut(kv, "=")
+            if _, overridden := s.Env[name]; !overridden {
+                env = append(env, kv)
+            }
+        }
+    }
+    names := make([]string, 0, len(s.Env))
+    for name := range s.Env {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        env = append(env, name+"="+s.Env[name])
+    }
+    if env == nil {
+        // A nil Env would inherit the executor's environment.
+        env = []string{}
+    }
+    return env
+}
+
+// AddShell adds a node that runs script with "sh -c". Unlike AddCtx with
+// Shell, the script is recorded on the node so that it is included in Diff,
+// Hash and exports.
+func (g *Graph) AddShell(name, script string, opts ...NodeOption) (AddResult, error) {
+    return g.AddCtx(name, Shell(script), append([]NodeOption{WithCommand(script)}, opts...)...)
+}
+
+// WithCommand records the shell command a node runs, so that exporters can
+// reproduce it.
+func WithCommand(script string) NodeOption {
+    return func(n *Node) {
+        n.command = script
+    }
+}
+
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+    var out syncBuffer
+    r, n := runNode(ctx)
+    if r != nil && n != nil && r.executor.getHooks().OnOutput != nil {
+        stdout := &lineWriter{emit: r.executor.outputFunc(r, n.name, "stdout")}
+        stderr := &lineWriter{emit: r.executor.outputFunc(r, n.name, "stderr")}
+        defer stdout.flush()
+        defer stderr.flush()
+        cmd.Stdout = io.MultiWriter(&out, stdout)
+        cmd.Stderr = io.MultiWriter(&out, stderr)
+    } else {
+        cmd.Stdout = &out
+        cmd.Stderr = &out
+    }
+    if err := cmd.Run(); err != nil {
+        cmdErr := &CommandError{Command: cmd.String(), Output: out.Bytes(), Err: err}
+        if r != nil {
+            cmdErr.Command = r.redact(cmdErr.Command)
+            cmdErr.Output = []byte(r.redact(string(cmdErr.Output)))
+        }
+        return cmdErr
+    }
+    return nil
+}
+
+// OutputLine is a line of output written by a command task.
+type OutputLine struct {
+    Node   string
+    Stream string // "stdout" or "stderr"
+    Text   string
+}
+
+// lineWriter passes each complete line written to it to emit, without the
+// trailing newline.
+type lineWriter struct {
+    emit func(line string)
+    buf  []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+    w.buf = append(w.buf, p...)
+    for {
+        i := bytes.IndexByte(w.buf, '\n')
+        if i < 0 {
+            break
+        }
+        w.emit(strings.TrimSuffix(string(w.buf[:i]), "\r"))
+        w.buf = w.buf[i+1:]
+    }
+    return len(p), nil
+}
+
+// flush emits a final line that was not terminated by a newline.
+func (w *lineWriter) flush() {
+    if len(w.buf) > 0 {
+        w.emit(string(w.buf))
+        w.buf = nil
+    }
+}
+
+// syncBuffer is a bytes.Buffer that stdout and stderr can be copied into
+// concurrently.
+type syncBuffer struct {
+    mu  sync.Mutex
+    buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.buf.Bytes()
+}
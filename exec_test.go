@@ -0,0 +1,109 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestShell(t *testing.T) {
+    if err := Shell("true")(context.Background()); err != nil {
+        t.Fatalf("Shell(true) failed: %v", err)
+    }
+
+    err := Shell("echo oops >&2; exit 3")(context.Background())
+    var cmdErr *CommandError
+    if !errors.As(err, &cmdErr) {
+        t.Fatalf("expected a CommandError, got %v", err)
+    }
+    if !strings.Contains(string(cmdErr.Output), "oops") {
+        t.Errorf("expected output to be captured, got %q", cmdErr.Output)
+    }
+}
+
+func TestCommandCancelled(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if err := Command("sleep", "5")(ctx); err == nil {
+        t.Fatalf("expected a cancelled command to fail")
+    }
+}
+
+func TestCommandOutputHook(t *testing.T) {
+    graph := TaskGraph()
+    graph.AddShell("talk", "echo one; echo two >&2; printf three")
+
+    var mu sync.Mutex
+    lines := map[string][]string{}
+    executor := NewExecutor(graph)
+    executor.SetHooks(Hooks{
+        OnOutput: func(line OutputLine) {
+            mu.Lock()
+            defer mu.Unlock()
+            if line.Node != "talk" {
+                t.Errorf("unexpected node %q", line.Node)
+            }
+            lines[line.Stream] = append(lines[line.Stream], line.Text)
+        },
+    })
+
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if got := strings.Join(lines["stdout"], ","); got != "one,three" {
+        t.Errorf("unexpected stdout lines %q", got)
+    }
+    if got := strings.Join(lines["stderr"], ","); got != "two" {
+        t.Errorf("unexpected stderr lines %q", got)
+    }
+}
+
+func TestCommandSpec(t *testing.T) {
+    dir := t.TempDir()
+    t.Setenv("LEO_INHERITED", "yes")
+    script := `{ printf '%s|%s|%s|' "$GREETING" "$LEO_INHERITED" "$(pwd)"; cat; printf '|'; umask; } > out`
+    read := func() string {
+        data, err := os.ReadFile(filepath.Join(dir, "out"))
+        if err != nil {
+            t.Fatalf("reading output: %v", err)
+        }
+        return strings.TrimSpace(string(data))
+    }
+    realDir, _ := filepath.EvalSymlinks(dir)
+
+    spec := CommandSpec{
+        Name:  "sh",
+        Args:  []string{"-c", script},
+        Env:   map[string]string{"GREETING": "hello"},
+        Dir:   dir,
+        Stdin: []byte("input"),
+        Umask: 0o027,
+    }
+    if err := spec.Task()(context.Background()); err != nil {
+        t.Fatalf("Task failed: %v", err)
+    }
+    if got, want := read(), "hello|yes|"+realDir+"|input|0027"; got != want {
+        t.Errorf("command saw %q, want %q", got, want)
+    }
+
+    os.WriteFile(filepath.Join(dir, "in.txt"), []byte("from file"), 0o644)
+    spec.ClearEnv = true
+    spec.Env["PATH"] = os.Getenv("PATH")
+    spec.Umask = 0
+    spec.StdinFile = filepath.Join(dir, "in.txt")
+    if err := spec.Task()(context.Background()); err != nil {
+        t.Fatalf("Task failed: %v", err)
+    }
+    if got := read(); !strings.HasPrefix(got, "hello||"+realDir+"|from file|") {
+        t.Errorf("with a cleared environment, command saw %q", got)
+    }
+
+    spec.StdinFile = filepath.Join(dir, "missing")
+    if err := spec.Task()(context.Background()); err == nil {
+        t.Error("a missing stdin file did not fail the task")
+    }
+}
@@ -0,0 +1,100 @@
+package leo
+
+import "errors"
+
+// FailureMode decides what a run does with its running tasks when a task
+// fails and nothing handles the failure.
+type FailureMode int
+
+const (
+    // FailAbort returns the failure as soon as it happens and cancels the
+    // context of the tasks that are still running. This is the default.
+    // Tasks added with Add cannot observe the cancellation, so they may
+    // still be running when Execute returns. The run's report is final by
+    // then and records them as pending.
+    FailAbort FailureMode = iota
+    // FailDrain lets the running tasks finish and returns the failure once
+    // they have, so that nothing the run started is still running when
+    // Execute returns.
+    FailDrain
+    // FailContinue keeps running every task that does not depend on the
+    // failed one, like make -k, and returns the failure once they have all
+    // finished. Use it for independent tasks such as test suites, whose
+    // outcomes are all wanted.
+    FailContinue
+)
+
+func (m FailureMode) String() string {
+    switch m {
+    case FailAbort:
+        return "abort"
+    case FailDrain:
+        return "drain"
+    case FailContinue:
+        return "continue"
+    }
+    return "unknown"
+}
+
+// SetFailureMode sets what runs do with their running tasks when a task
+// fails. Except with FailContinue, no further tasks start and the tasks
+// that have not started are skipped, except children released by the
+// failure through an EdgeRelease edge, such as cleanup and notification
+// tasks.
+func (e *Executor) SetFailureMode(m FailureMode) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.failureMode = m
+}
+
+func (e *Executor) getFailureMode() FailureMode {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.failureMode
+}
+
+// fail stops the run after it failed with err, according to the executor's
+// failure mode, and returns err. finished is closed once every task the run
+// started has finished.
+func (r *Run) fail(err error, finished <-chan struct{}) error {
+    reason := "a task failed"
+    var nodeErr NodeError
+    if errors.As(err, &nodeErr) {
+        reason = nodeErr.NodeName() + " failed"
+    }
+    mode := r.executor.getFailureMode()
+    if mode == FailContinue {
+        select {
+        case <-finished:
+        case <-r.ctx.Done():
+        }
+        return err
+    }
+    r.mu.Lock()
+    if r.aborted == "" {
+        r.stop(reason)
+        r.failedStop = true
+    }
+    if mode == FailAbort {
+        for _, c := range r.running {
+            c.cancel(err)
+        }
+    }
+    r.mu.Unlock()
+
+    if mode == FailDrain {
+        select {
+        case <-finished:
+        case <-r.ctx.Done():
+        }
+    }
+    return err
+}
+
+// stopped reports whether n must not start because the run was stopped or
+// aborted. Children released by a failed or skipped parent's EdgeRelease
+// edge, such as cleanup and notification tasks, still start when the run
+// was stopped because of a failure. The caller must hold r.mu.
+func (r *Run) stopped(n *Node) bool {
+    return r.aborted != "" && !(r.failedStop && r.mustRun[n])
+}
@@ -0,0 +1,125 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failureGraph returns a graph where migrate fails while load is running,
+// and index would start once load finishes.
+func failureGraph(load TaskCtxFunc) *Graph {
+    graph := TaskGraph()
+    graph.Add("migrate", func() error {
+        time.Sleep(10 * time.Millisecond)
+        return errors.New("schema locked")
+    })
+    graph.AddCtx("load", load)
+    graph.Add("index", func() error { return nil })
+    graph.Add("unlock", func() error { return nil })
+    graph.Precede("load", "index")
+    graph.Precede("migrate", "unlock", OnParentFailure(EdgeRelease))
+    return graph
+}
+
+func TestFailAbort(t *testing.T) {
+    cause := make(chan error, 1)
+    graph := failureGraph(func(ctx context.Context) error {
+        <-ctx.Done()
+        cause <- context.Cause(ctx)
+        return ctx.Err()
+    })
+
+    executor := NewExecutor(graph, WithConcurrency(-1))
+    err := executor.Execute()
+    if err == nil {
+        t.Fatalf("expected migrate to fail the run")
+    }
+    select {
+    case c := <-cause:
+        if c != err {
+            t.Errorf("expected load to be cancelled with the run's error, got %v", c)
+        }
+    case <-time.After(time.Second):
+        t.Fatalf("expected load to be cancelled")
+    }
+}
+
+func TestFailAbortReportIsFinal(t *testing.T) {
+    release, finished := make(chan struct{}), make(chan struct{})
+    graph := failureGraph(func(context.Context) error {
+        // Like a task added with Add, load ignores the cancellation.
+        <-release
+        defer close(finished)
+        return nil
+    })
+
+    executor := NewExecutor(graph, WithConcurrency(-1))
+    if err := executor.Execute(); err == nil {
+        t.Fatalf("expected migrate to fail the run")
+    }
+    report := executor.Report()
+    if nr := report.Nodes["load"]; nr.State != StatePending {
+        t.Errorf("expected the abandoned load to be reported as pending, got %s", nr.State)
+    }
+
+    // load finishing after Execute returned leaves the report unchanged.
+    close(release)
+    <-finished
+    time.Sleep(10 * time.Millisecond)
+    if nr := report.Nodes["load"]; nr.State != StatePending {
+        t.Errorf("expected the report not to change after Execute returned, got %s", nr.State)
+    }
+    if nr := report.Nodes["index"]; nr.State != StatePending {
+        t.Errorf("expected index not to be reported, got %s", nr.State)
+    }
+}
+
+func TestFailDrain(t *testing.T) {
+    var loaded atomic.Bool
+    graph := failureGraph(func(ctx context.Context) error {
+        time.Sleep(40 * time.Millisecond)
+        if ctx.Err() != nil {
+            t.Errorf("expected load not to be cancelled")
+        }
+        loaded.Store(true)
+        return nil
+    })
+
+    executor := NewExecutor(graph, WithConcurrency(-1), WithFailureMode(FailDrain))
+    if err := executor.Execute(); err == nil {
+        t.Fatalf("expected migrate to fail the run")
+    }
+    if !loaded.Load() {
+        t.Errorf("expected Execute to return after load finished")
+    }
+    report := executor.Report()
+    if got := report.Nodes["index"]; got.State != StateSkipped || got.SkipReason != "run stopped: migrate failed" {
+        t.Errorf("expected index to be skipped, got %s %q", got.State, got.SkipReason)
+    }
+    if got := report.Nodes["unlock"].State; got != StateSucceeded {
+        t.Errorf("expected unlock to run despite the failure, got %s", got)
+    }
+}
+
+func TestFailContinue(t *testing.T) {
+    graph := failureGraph(func(ctx context.Context) error {
+        time.Sleep(40 * time.Millisecond)
+        return ctx.Err()
+    })
+
+    executor := NewExecutor(graph, WithConcurrency(-1), WithFailureMode(FailContinue))
+    err := executor.Execute()
+    var nodeErr NodeError
+    if !errors.As(err, &nodeErr) || nodeErr.NodeName() != "migrate" {
+        t.Fatalf("expected migrate's failure, got %v", err)
+    }
+    report := executor.Report()
+    for _, name := range []string{"load", "index", "unlock"} {
+        if got := report.Nodes[name].State; got != StateSucceeded {
+            t.Errorf("expected %s to run despite the failure, got %s", name, got)
+        }
+    }
+}
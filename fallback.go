@@ -0,0 +1,44 @@
+package leo
+
+import "errors"
+
+// OnFailure adds a fallback edge: the fallback node runs only if node fails,
+// giving try/catch semantics within a graph. A failure that has a fallback is
+// considered handled and does not fail the run by itself; node's children are
+// still skipped. If node succeeds, the fallback and its descendants are
+// skipped.
+//
+// A fallback may protect several nodes, in which case it runs once all of
+// them have finished and at least one has failed.
+func (g *Graph) OnFailure(node, fallback string) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    n, nodeExists := g.lookup(node)
+    fb, fallbackExists := g.lookup(fallback)
+
+    if !nodeExists || !fallbackExists {
+        return errors.New("one or both nodes do not exist")
+    }
+
+    if cycle := g.cycleThrough(n, fb); cycle != nil {
+        return &CycleError{Path: cycle}
+    }
+
+    n.fallbacks = append(n.fallbacks, fb)
+    fb.fallbackFor = append(fb.fallbackFor, n)
+
+    return nil
+}
+
+// successors returns the nodes reachable from n by a single edge of any kind.
+func (n *Node) successors() []*Node {
+    if len(n.fallbacks) == 0 {
+        return n.children
+    }
+    out := make([]*Node, 0, len(n.children)+len(n.fallbacks))
+    out = append(out, n.children...)
+    return append(out, n.fallbacks...)
+}
@@ -0,0 +1,83 @@
+package leo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestOnFailure(t *testing.T) {
+    var mu sync.Mutex
+    executed := make(map[string]bool)
+    record := func(name string, err error) TaskFunc {
+        return func() error {
+            mu.Lock()
+            defer mu.Unlock()
+            executed[name] = true
+            return err
+        }
+    }
+
+    build := func(applyErr error) *Graph {
+        graph := TaskGraph()
+        graph.Add("apply-config", record("apply-config", applyErr))
+        graph.Add("verify", record("verify", nil))
+        graph.Add("restore-backup", record("restore-backup", nil))
+        graph.Precede("apply-config", "verify")
+        if err := graph.OnFailure("apply-config", "restore-backup"); err != nil {
+            t.Fatalf("OnFailure failed: %v", err)
+        }
+        return graph
+    }
+
+    // Success path: the fallback is skipped.
+    executor := NewExecutor(build(nil))
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if !executed["verify"] || executed["restore-backup"] {
+        t.Errorf("expected verify to run and restore-backup to be skipped, got %v", executed)
+    }
+    if nr := executor.Report().Nodes["restore-backup"]; nr.State != StateSkipped {
+        t.Errorf("restore-backup should be skipped, got %s", nr.State)
+    }
+
+    // Failure path: the fallback runs and the failure is handled.
+    executed = make(map[string]bool)
+    executor = NewExecutor(build(errors.New("bad config")))
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("a handled failure should not fail the run: %v", err)
+    }
+    if executed["verify"] || !executed["restore-backup"] {
+        t.Errorf("expected restore-backup to run and verify to be skipped, got %v", executed)
+    }
+    report := executor.Report()
+    if nr := report.Nodes["apply-config"]; nr.State != StateFailed || !nr.Handled {
+        t.Errorf("apply-config should be a handled failure, got %+v", nr)
+    }
+    if !report.Succeeded() {
+        t.Errorf("report should succeed when all failures are handled")
+    }
+}
+
+func TestOnFailureFallbackFails(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return errors.New("A failed") })
+    graph.Add("fallback", func() error { return errors.New("fallback failed") })
+    graph.OnFailure("A", "fallback")
+
+    if err := NewExecutor(graph).Execute(); err == nil {
+        t.Fatalf("expected the fallback's failure to fail the run")
+    }
+}
+
+func TestOnFailureCycle(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil })
+    graph.Add("B", func() error { return nil })
+    graph.Precede("A", "B")
+
+    if err := graph.OnFailure("B", "A"); err == nil {
+        t.Errorf("OnFailure should have detected a cycle")
+    }
+}
@@ -0,0 +1,94 @@
+package leo
+
+import (
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "time"
+)
+
+// WithInputs declares files that the node's task reads, for make-like
+// incremental runs, see WithOutputs. Paths may be glob patterns such as
+// "src/**/*.go", see Glob; a pattern that matches nothing is not an error.
+// Deleting an input does not make a node stale; use FilesKey for that.
+func WithInputs(paths ...string) NodeOption {
+    return func(n *Node) {
+        n.inputFiles = append(n.inputFiles, paths...)
+    }
+}
+
+// WithOutputs declares files that the node's task writes. A node with
+// outputs is skipped, as "up to date", when all of them exist and none is
+// older than its inputs (see WithInputs) or than the outputs of its
+// ancestors, which may have just been rebuilt; like a disabled node, its
+// children still run. Missing ancestor outputs are ignored, but a missing
+// input makes the node run.
+func WithOutputs(paths ...string) NodeOption {
+    return func(n *Node) {
+        n.outputFiles = append(n.outputFiles, paths...)
+    }
+}
+
+// upToDate reports whether n's outputs are newer than its inputs and its
+// ancestors' outputs. Nodes without outputs are never up to date.
+func (n *Node) upToDate() (bool, error) {
+    if len(n.outputFiles) == 0 {
+        return false, nil
+    }
+    var oldest time.Time
+    for _, path := range n.outputFiles {
+        mod, exists, err := modTime(path)
+        if err != nil || !exists {
+            return false, err
+        }
+        if oldest.IsZero() || mod.Before(oldest) {
+            oldest = mod
+        }
+    }
+    inputs, err := expandFiles(n.inputFiles)
+    if err != nil {
+        return false, err
+    }
+    for _, path := range inputs {
+        mod, exists, err := modTime(path)
+        if err != nil || !exists || mod.After(oldest) {
+            return false, err
+        }
+    }
+
+    seen := map[*Node]bool{n: true}
+    queue := append([]*Node(nil), n.parents...)
+    for len(queue) > 0 {
+        a := queue[0]
+        queue = queue[1:]
+        if seen[a] {
+            continue
+        }
+        seen[a] = true
+        queue = append(queue, a.parents...)
+        for _, path := range a.outputFiles {
+            mod, exists, err := modTime(path)
+            if err != nil {
+                return false, err
+            }
+            if exists && mod.After(oldest) {
+                return false, nil
+            }
+        }
+    }
+    return true, nil
+}
+
+// modTime returns the modification time of the file at path and whether it
+// exists.
+func modTime(path string) (time.Time, bool, error) {
+    info, err := os.Stat(path)
+    if errors.Is(err, fs.ErrNotExist) {
+        return time.Time{}, false, nil
+    }
+    if err != nil {
+        return time.Time{}, false, fmt.Errorf("checking whether outputs are up to date: %w", err)
+    }
+    return info.ModTime(), true, nil
+}
@@ -0,0 +1,86 @@
+package leo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileOutputs(t *testing.T) {
+    dir := t.TempDir()
+    src := filepath.Join(dir, "main.c")
+    obj := filepath.Join(dir, "main.o")
+    bin := filepath.Join(dir, "app")
+
+    // Each write gets a later time than the last, so the test does not
+    // depend on the file system's timestamp resolution.
+    var mu sync.Mutex
+    clock := time.Now().Add(-time.Hour)
+    touch := func(path string) {
+        mu.Lock()
+        defer mu.Unlock()
+        clock = clock.Add(time.Second)
+        os.WriteFile(path, []byte(path), 0o644)
+        os.Chtimes(path, clock, clock)
+    }
+
+    var ran []string
+    build := func(name, out string) TaskFunc {
+        return func() error {
+            touch(out)
+            mu.Lock()
+            ran = append(ran, name)
+            mu.Unlock()
+            return nil
+        }
+    }
+    graph := TaskGraph()
+    graph.Add("compile", build("compile", obj), WithInputs(src), WithOutputs(obj))
+    graph.Add("link", build("link", bin), WithOutputs(bin))
+    graph.Add("test", build("test", filepath.Join(dir, "log")))
+    graph.Precede("compile", "link")
+    graph.Precede("link", "test")
+    executor := NewExecutor(graph)
+
+    execute := func() []string {
+        ran = nil
+        if err := executor.Execute(); err != nil {
+            t.Fatalf("Execute failed: %v", err)
+        }
+        sort.Strings(ran)
+        return ran
+    }
+
+    touch(src)
+    if got := execute(); !reflect.DeepEqual(got, []string{"compile", "link", "test"}) {
+        t.Errorf("expected everything to run the first time, ran %v", got)
+    }
+    if got := execute(); !reflect.DeepEqual(got, []string{"test"}) {
+        t.Errorf("expected only the node without outputs to run again, ran %v", got)
+    }
+    if nr := executor.Report().Nodes["link"]; nr.State != StateSkipped || nr.SkipReason != "up to date" {
+        t.Errorf("expected link to be reported up to date, got %+v", nr)
+    }
+
+    // A newer ancestor output makes its descendants stale.
+    touch(obj)
+    if got := execute(); !reflect.DeepEqual(got, []string{"link", "test"}) {
+        t.Errorf("expected link to rebuild after main.o changed, ran %v", got)
+    }
+
+    // A newer input makes the node stale.
+    touch(src)
+    if got := execute(); !reflect.DeepEqual(got, []string{"compile", "link", "test"}) {
+        t.Errorf("expected everything to rebuild after main.c changed, ran %v", got)
+    }
+
+    // A missing output makes the node stale.
+    os.Remove(bin)
+    if got := execute(); !reflect.DeepEqual(got, []string{"link", "test"}) {
+        t.Errorf("expected link to rebuild its missing output, ran %v", got)
+    }
+}
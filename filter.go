@@ -0,0 +1,77 @@
+package leo
+
+import "path"
+
+// EventFilter selects the events delivered to a subscription. Each non-empty
+// field must match: an event passes if its type is one of Types, its node's
+// name matches one of the Nodes glob patterns (in path.Match syntax), and
+// its node has one of Tags, and its run has every label in Labels. Nodes and
+// Tags only apply to task events; EventRunFinished passes them. The zero
+// EventFilter passes every event.
+type EventFilter struct {
+    Types  []EventType
+    Nodes  []string
+    Tags   []string
+    Labels map[string]string
+}
+
+// SubscribeFilter is like Subscribe but delivers only the events that match
+// f. Events are filtered before they are queued, so a subscriber interested
+// in a few events is not burdened with the rest.
+func (e *Executor) SubscribeFilter(f EventFilter) *Subscription {
+    s := e.Subscribe()
+    s.mu.Lock()
+    s.filter = f
+    s.mu.Unlock()
+    return s
+}
+
+func (f EventFilter) match(ev Event, tags []string) bool {
+    if len(f.Types) > 0 && !containsType(f.Types, ev.Type) {
+        return false
+    }
+    for k, v := range f.Labels {
+        if ev.Run == nil || ev.Run.labels[k] != v {
+            return false
+        }
+    }
+    if ev.Node == "" {
+        return true
+    }
+    if len(f.Nodes) > 0 && !matchAny(f.Nodes, ev.Node) {
+        return false
+    }
+    if len(f.Tags) > 0 && !anyTag(f.Tags, tags) {
+        return false
+    }
+    return true
+}
+
+func containsType(types []EventType, t EventType) bool {
+    for _, typ := range types {
+        if typ == t {
+            return true
+        }
+    }
+    return false
+}
+
+func matchAny(patterns []string, name string) bool {
+    for _, p := range patterns {
+        if ok, _ := path.Match(p, name); ok {
+            return true
+        }
+    }
+    return false
+}
+
+func anyTag(want, tags []string) bool {
+    for _, w := range want {
+        for _, t := range tags {
+            if w == t {
+                return true
+            }
+        }
+    }
+    return false
+}
@@ -0,0 +1,74 @@
+package leo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubscribeFilter(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("build-api", func() error { return nil }, WithTags("backend"))
+    graph.Add("build-web", func() error { return errors.New("boom") }, WithTags("frontend"))
+    graph.Add("deploy-api", func() error { return nil }, WithTags("backend"))
+    graph.Precede("build-api", "deploy-api")
+    graph.OnFailure("build-web", "deploy-api")
+
+    executor := NewExecutor(graph)
+    failures := executor.SubscribeFilter(EventFilter{Types: []EventType{EventTaskFailed, EventRunFinished}})
+    builds := executor.SubscribeFilter(EventFilter{Nodes: []string{"build-*"}, Types: []EventType{EventTaskStarted}})
+    backend := executor.SubscribeFilter(EventFilter{Tags: []string{"backend"}, Types: []EventType{EventTaskFinished}})
+
+    executor.Execute()
+
+    collect := func(s *Subscription) []string {
+        var got []string
+        for ev := range s.C {
+            if ev.Type == EventRunFinished {
+                got = append(got, "run")
+                break
+            }
+            got = append(got, ev.Node)
+        }
+        s.Close()
+        return got
+    }
+    if got := collect(failures); len(got) != 2 || got[0] != "build-web" || got[1] != "run" {
+        t.Errorf("failures subscription got %v", got)
+    }
+
+    for _, tc := range []struct {
+        sub  *Subscription
+        want map[string]bool
+    }{
+        {builds, map[string]bool{"build-api": true, "build-web": true}},
+        {backend, map[string]bool{"build-api": true, "deploy-api": true}},
+    } {
+        got := map[string]bool{}
+        for i := 0; i < len(tc.want); i++ {
+            ev := <-tc.sub.C
+            got[ev.Node] = true
+        }
+        tc.sub.Close()
+        for name := range tc.want {
+            if !got[name] {
+                t.Errorf("expected an event for %s, got %v", name, got)
+            }
+        }
+    }
+}
+
+func TestEventFilterMatch(t *testing.T) {
+    f := EventFilter{Nodes: []string{"db-*"}, Tags: []string{"critical"}}
+    if !f.match(Event{Type: EventTaskStarted, Node: "db-migrate"}, []string{"critical"}) {
+        t.Errorf("expected a match")
+    }
+    if f.match(Event{Type: EventTaskStarted, Node: "db-migrate"}, nil) {
+        t.Errorf("expected an untagged node not to match")
+    }
+    if f.match(Event{Type: EventTaskStarted, Node: "web"}, []string{"critical"}) {
+        t.Errorf("expected a non-matching name not to match")
+    }
+    if !f.match(Event{Type: EventRunFinished}, nil) {
+        t.Errorf("expected RunFinished to pass node filters")
+    }
+}
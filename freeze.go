@@ -0,0 +1,192 @@
+package leo
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "sort"
+)
+
+// ErrFrozen is returned by methods that would modify a frozen graph, see
+// Graph.Freeze.
+var ErrFrozen = errors.New("graph is frozen")
+
+// Plan is the compiled form of a frozen graph: the checks and the
+// derivations that would otherwise run at the start of every execution are
+// done once, and the results are kept for the executor. A plan holds the
+// nodes in topological order, their levels and stages, the number of
+// dependencies each waits for, and, for scheduling policies, the rank of
+// each node under estimates that do not come from a history.
+type Plan struct {
+    graph    *Graph
+    order    []*Node
+    roots    []*Node
+    inDegree map[*Node]int
+    levels   map[*Node]int
+    stages   map[*Node]int
+    ranks    map[SchedulingPolicy]map[*Node]rank
+    hash     string
+}
+
+// Freeze validates the graph and compiles it into a Plan, which executors of
+// the graph then use. After Freeze succeeds, methods that would modify the
+// graph return ErrFrozen, so that mistakes in building a graph surface before
+// it first runs rather than during a run. Calling Freeze again returns the
+// same plan.
+//
+// Freeze checks that the stages of staged nodes exist and are consistent
+// with the edges, and that AutoWire has connected every function node's
+// arguments that another node produces, and that every placeholder created
+// by Precede in auto-create mode has been given a task.
+func (g *Graph) Freeze() (*Plan, error) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return g.plan, nil
+    }
+    if g.hasCycle() {
+        return nil, errors.New("graph has a cycle")
+    }
+    stages, err := g.stageIndex()
+    if err != nil {
+        return nil, err
+    }
+    if err := g.checkWiring(); err != nil {
+        return nil, err
+    }
+    if err := g.checkBound(); err != nil {
+        return nil, err
+    }
+    if err := g.checkGroups(); err != nil {
+        return nil, err
+    }
+
+    p := &Plan{
+        graph:  g,
+        levels: g.levels(),
+        stages: stages,
+        hash:   g.hash(),
+    }
+    p.order = make([]*Node, 0, len(g.nodes))
+    for _, node := range g.nodes {
+        p.order = append(p.order, node)
+    }
+    sort.Slice(p.order, func(i, j int) bool {
+        a, b := p.order[i], p.order[j]
+        if p.levels[a] != p.levels[b] {
+            return p.levels[a] < p.levels[b]
+        }
+        return a.name < b.name
+    })
+    p.inDegree = make(map[*Node]int, len(p.order))
+    for _, node := range p.order {
+        p.inDegree[node] = node.inDegree()
+        if p.inDegree[node] == 0 {
+            p.roots = append(p.roots, node)
+        }
+    }
+    p.ranks = map[SchedulingPolicy]map[*Node]rank{
+        ScheduleCriticalPath: g.ranks((*Node).estimate),
+        ScheduleLongestFirst: g.longestFirst((*Node).estimate),
+    }
+    g.plan = p
+    return p, nil
+}
+
+// Compile is Freeze, for callers that build a graph once and execute it many
+// times: the returned plan runs on any number of executors, see
+// NewPlanExecutor, without the graph being validated or analysed again.
+func (g *Graph) Compile() (*Plan, error) {
+    return g.Freeze()
+}
+
+// NewPlanExecutor returns an executor of the plan's graph, configured by
+// opts. Its runs start from the plan's roots and dependency counts, and
+// schedule by its ranks, rather than deriving them from the graph.
+func NewPlanExecutor(p *Plan, opts ...ExecutorOption) *Executor {
+    e := NewExecutor(p.graph, opts...)
+    e.plan = p
+    return e
+}
+
+// plan returns the plan the run starts from: its executor's, or its graph's
+// if the graph is frozen, or nil.
+func (r *Run) plan() *Plan {
+    if p := r.executor.plan; p != nil && p.graph == r.graph {
+        return p
+    }
+    return r.graph.plan
+}
+
+// Frozen reports whether Freeze has been called on the graph.
+func (g *Graph) Frozen() bool {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    return g.plan != nil
+}
+
+// ordered returns the graph's nodes, in the plan's order if it is frozen.
+func (g *Graph) ordered() []*Node {
+    if g.plan != nil {
+        return g.plan.order
+    }
+    nodes := make([]*Node, 0, len(g.nodes))
+    for _, node := range g.nodes {
+        nodes = append(nodes, node)
+    }
+    return nodes
+}
+
+// roots returns the nodes that may have no dependencies: those of the run's
+// plan if it has one, and otherwise every node.
+func (r *Run) roots() []*Node {
+    if p := r.plan(); p != nil {
+        return p.roots
+    }
+    return r.graph.ordered()
+}
+
+// inDegree returns the number of nodes n waits for: its parents and the
+// nodes it is a fallback for.
+func (n *Node) inDegree() int {
+    return len(n.parents) + len(n.fallbackFor)
+}
+
+// checkWiring returns an error if a function node consumes a type that
+// another node produces without an input wired to it.
+func (g *Graph) checkWiring() error {
+    produced := make(map[reflect.Type]bool)
+    for _, node := range g.nodes {
+        if node.produces != nil {
+            produced[node.produces] = true
+        }
+    }
+    for _, node := range g.nodes {
+        for _, t := range node.consumes {
+            if produced[t] && node.inputs[t] == nil {
+                return fmt.Errorf("node %s: argument of type %s is not wired, call AutoWire before Freeze", node.name, t)
+            }
+        }
+    }
+    return nil
+}
+
+// Graph returns the frozen graph.
+func (p *Plan) Graph() *Graph {
+    return p.graph
+}
+
+// Order returns the node names in a topological order: by level (see
+// Graph.Levels), then by name.
+func (p *Plan) Order() []string {
+    names := make([]string, len(p.order))
+    for i, node := range p.order {
+        names[i] = node.name
+    }
+    return names
+}
+
+// Hash returns the graph's hash, see Graph.Hash.
+func (p *Plan) Hash() string {
+    return p.hash
+}
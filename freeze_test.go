@@ -0,0 +1,171 @@
+package leo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFreeze(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("c", func() error { return nil })
+    graph.Add("b", func() error { return nil })
+    graph.Add("a", func() error { return nil })
+    graph.Precede("c", "a")
+    graph.Precede("b", "a")
+    hash := graph.Hash()
+
+    plan, err := graph.Freeze()
+    if err != nil {
+        t.Fatalf("Freeze failed: %v", err)
+    }
+    if again, _ := graph.Freeze(); again != plan {
+        t.Errorf("expected Freeze to return the same plan")
+    }
+    if got := plan.Order(); !reflect.DeepEqual(got, []string{"b", "c", "a"}) {
+        t.Errorf("unexpected order %v", got)
+    }
+    if plan.Hash() != hash || graph.Hash() != hash {
+        t.Errorf("expected freezing to keep the graph's hash")
+    }
+
+    _, addErr := graph.Add("d", func() error { return nil })
+    _, shellErr := graph.AddShell("e", "true")
+    removed, reduceErr := graph.TransitiveReduction()
+    for name, err := range map[string]error{
+        "Add":                 addErr,
+        "AddShell":            shellErr,
+        "Precede":             graph.Precede("b", "c"),
+        "OnFailure":           graph.OnFailure("a", "b"),
+        "Stage":               graph.Stage("deploy"),
+        "AutoWire":            graph.AutoWire(),
+        "Bind":                graph.Bind("a", nil),
+        "AddAll":              graph.AddAll(map[string]TaskFunc{"f": nil}),
+        "AddEdges":            graph.AddEdges([][2]string{{"b", "c"}}),
+        "Alias":               graph.Alias("z", "a"),
+        "Group":               graph.Group("grp", "a"),
+        "Transact":            graph.Transact("grp", Transaction{}),
+        "SetDuplicatePolicy":  graph.SetDuplicatePolicy(DuplicateError),
+        "SetAutoCreate":       graph.SetAutoCreate(true),
+        "TransitiveReduction": reduceErr,
+    } {
+        if !errors.Is(err, ErrFrozen) {
+            t.Errorf("%s: expected ErrFrozen, got %v", name, err)
+        }
+    }
+    if removed != 0 || len(graph.Edges()) != 2 {
+        t.Errorf("expected the frozen graph to be unchanged")
+    }
+
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+}
+
+func TestFreezeValidates(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("a", func() error { return nil }, InStage("missing"))
+    if _, err := graph.Freeze(); err == nil || !strings.Contains(err.Error(), "undeclared stage") {
+        t.Errorf("expected an undeclared stage error, got %v", err)
+    }
+    if graph.Frozen() {
+        t.Errorf("expected a graph that failed to freeze to stay mutable")
+    }
+
+    type config struct{}
+    graph = TaskGraph()
+    graph.AddFunc("load", func() config { return config{} })
+    graph.AddFunc("use", func(config) error { return nil })
+    if _, err := graph.Freeze(); err == nil || !strings.Contains(err.Error(), "AutoWire") {
+        t.Errorf("expected an unwired argument error, got %v", err)
+    }
+    graph.AutoWire()
+    if _, err := graph.Freeze(); err != nil {
+        t.Errorf("Freeze failed after AutoWire: %v", err)
+    }
+}
+
+func TestCompile(t *testing.T) {
+    graph := TaskGraph()
+    var mu sync.Mutex
+    counts := make(map[string]int)
+    for _, name := range []string{"fetch", "lint", "build", "test", "ship"} {
+        name := name
+        graph.Add(name, func() error {
+            mu.Lock()
+            counts[name]++
+            mu.Unlock()
+            return nil
+        }, WithCost(time.Duration(len(name))*time.Second))
+    }
+    graph.Precede("lint", "ship")
+    graph.Precede("fetch", "build")
+    graph.Precede("build", "test")
+    graph.Precede("test", "ship")
+
+    plan, err := graph.Compile()
+    if err != nil {
+        t.Fatalf("Compile failed: %v", err)
+    }
+    if again, _ := graph.Freeze(); again != plan || !graph.Frozen() {
+        t.Errorf("expected Compile to freeze the graph")
+    }
+    if got := fmt.Sprint(nodeNames(plan.roots)); got != "[fetch lint]" {
+        t.Errorf("expected the roots fetch and lint, got %s", got)
+    }
+    if plan.inDegree[graph.nodes["ship"]] != 2 {
+        t.Errorf("expected ship to wait for two nodes, got %d", plan.inDegree[graph.nodes["ship"]])
+    }
+
+    // Executors share the compiled graph without modifying it: ship's
+    // parents stay in the order their edges were added.
+    var wg sync.WaitGroup
+    for i := 0; i < 4; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            executor := NewPlanExecutor(plan, WithConcurrency(1), WithScheduling(ScheduleCriticalPath))
+            for j := 0; j < 5; j++ {
+                if err := executor.Execute(); err != nil {
+                    t.Errorf("Execute failed: %v", err)
+                }
+            }
+        }()
+    }
+    wg.Wait()
+    if counts["ship"] != 20 || counts["fetch"] != 20 {
+        t.Errorf("expected every node to run 20 times, got %v", counts)
+    }
+    if got := fmt.Sprint(nodeNames(graph.nodes["ship"].parents)); got != "[lint test]" {
+        t.Errorf("expected ship's parents in edge order, got %s", got)
+    }
+
+    // The plan's ranks match those a run derives without it.
+    run := NewPlanExecutor(plan).NewRun()
+    if run.plan() != plan {
+        t.Errorf("expected the run to start from the executor's plan")
+    }
+    for _, policy := range []SchedulingPolicy{ScheduleCriticalPath, ScheduleLongestFirst} {
+        var derived map[*Node]rank
+        if policy == ScheduleCriticalPath {
+            derived = graph.ranks(run.estimate)
+        } else {
+            derived = graph.longestFirst(run.estimate)
+        }
+        if !reflect.DeepEqual(run.policyRanks(policy), derived) {
+            t.Errorf("%s: expected the plan's ranks to match the derived ones", policy)
+        }
+    }
+}
+
+func nodeNames(nodes []*Node) []string {
+    names := make([]string, len(nodes))
+    for i, n := range nodes {
+        names[i] = n.name
+    }
+    return names
+}
@@ -0,0 +1,167 @@
+package leo
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "path"
+    "path/filepath"
+    "sort"
+    "strings"
+)
+
+// isGlob reports whether pattern has any glob metacharacters.
+func isGlob(pattern string) bool {
+    return strings.ContainsAny(pattern, "*?[")
+}
+
+// Glob returns the files matching pattern, sorted. Patterns use path.Match
+// syntax with forward slashes, plus "**", which as a whole path element
+// matches any number of directories: "src/**/*.go" matches src/main.go and
+// src/a/b/util.go. Only the directories below the pattern's leading literal
+// elements are walked, and only as deep as the pattern can match.
+// Directories are not returned.
+func Glob(pattern string) ([]string, error) {
+    pattern = filepath.ToSlash(pattern)
+    if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+        return nil, fmt.Errorf("glob %s: %w", pattern, err)
+    }
+    segs := strings.Split(pattern, "/")
+    i := 0
+    for i < len(segs)-1 && !isGlob(segs[i]) {
+        i++
+    }
+    root := strings.Join(segs[:i], "/")
+    if root == "" && strings.HasPrefix(pattern, "/") {
+        root = "/"
+    }
+    rest := segs[i:]
+    deep := false
+    for _, s := range rest {
+        deep = deep || s == "**"
+    }
+
+    if root == "" {
+        root = "."
+    }
+    root = filepath.FromSlash(root)
+    var matches []string
+    err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+        if err != nil {
+            if errors.Is(err, fs.ErrNotExist) && p == root {
+                return fs.SkipAll
+            }
+            return err
+        }
+        rel, err := filepath.Rel(root, p)
+        if err != nil || rel == "." {
+            return err
+        }
+        parts := strings.Split(filepath.ToSlash(rel), "/")
+        if d.IsDir() {
+            if !deep && len(parts) >= len(rest) {
+                return fs.SkipDir
+            }
+            return nil
+        }
+        if matchSegments(rest, parts) {
+            matches = append(matches, p)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, fmt.Errorf("glob %s: %w", pattern, err)
+    }
+    sort.Strings(matches)
+    return matches, nil
+}
+
+// matchSegments reports whether the path elements parts match the pattern
+// elements segs, in which "**" matches zero or more elements.
+func matchSegments(segs, parts []string) bool {
+    for len(segs) > 0 {
+        if segs[0] == "**" {
+            for skip := 0; skip <= len(parts); skip++ {
+                if matchSegments(segs[1:], parts[skip:]) {
+                    return true
+                }
+            }
+            return false
+        }
+        if len(parts) == 0 {
+            return false
+        }
+        if ok, _ := path.Match(segs[0], parts[0]); !ok {
+            return false
+        }
+        segs, parts = segs[1:], parts[1:]
+    }
+    return len(parts) == 0
+}
+
+// expandFiles returns the files named by paths, expanding glob patterns, see
+// Glob. Plain paths are returned whether or not they exist.
+func expandFiles(paths []string) ([]string, error) {
+    var files []string
+    for _, p := range paths {
+        if !isGlob(p) {
+            files = append(files, p)
+            continue
+        }
+        matches, err := Glob(p)
+        if err != nil {
+            return nil, err
+        }
+        files = append(files, matches...)
+    }
+    return files, nil
+}
+
+// HashFiles returns a SHA-256 digest of the names and contents of the files
+// named by paths, which may be glob patterns (see Glob). Files are hashed in
+// sorted order, so the digest changes when a file is added, removed, renamed
+// or edited, but not when it is merely touched.
+func HashFiles(paths ...string) (string, error) {
+    files, err := expandFiles(paths)
+    if err != nil {
+        return "", err
+    }
+    sort.Strings(files)
+    h := sha256.New()
+    for i, name := range files {
+        if i > 0 && files[i-1] == name {
+            continue
+        }
+        f, err := os.Open(name)
+        if err != nil {
+            return "", err
+        }
+        fmt.Fprintf(h, "%s\x00", filepath.ToSlash(name))
+        _, err = io.Copy(h, f)
+        f.Close()
+        if err != nil {
+            return "", err
+        }
+        h.Write([]byte{0})
+    }
+    return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FilesKey returns a cache key function for WithCacheKey that hashes the
+// files named by paths with HashFiles, so a node is served from the cache
+// until its input files change. A file that cannot be read disables caching
+// for that run.
+func FilesKey(paths ...string) func(ctx context.Context) string {
+    return func(context.Context) string {
+        key, err := HashFiles(paths...)
+        if err != nil {
+            return ""
+        }
+        return key
+    }
+}
@@ -0,0 +1,96 @@
+package leo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGlob(t *testing.T) {
+    dir := t.TempDir()
+    for _, name := range []string{"src/main.go", "src/a/util.go", "src/a/b/deep.go", "src/a/notes.txt", "docs/x.go"} {
+        path := filepath.Join(dir, name)
+        os.MkdirAll(filepath.Dir(path), 0o755)
+        os.WriteFile(path, []byte(name), 0o644)
+    }
+    rel := func(paths []string) []string {
+        var out []string
+        for _, p := range paths {
+            r, _ := filepath.Rel(dir, p)
+            out = append(out, filepath.ToSlash(r))
+        }
+        return out
+    }
+
+    for pattern, want := range map[string][]string{
+        "src/**/*.go":  {"src/a/b/deep.go", "src/a/util.go", "src/main.go"},
+        "src/*/*.go":   {"src/a/util.go"},
+        "src/**":       {"src/a/b/deep.go", "src/a/notes.txt", "src/a/util.go", "src/main.go"},
+        "**/x.go":      {"docs/x.go"},
+        "src/a/*.txt":  {"src/a/notes.txt"},
+        "missing/*.go": nil,
+    } {
+        got, err := Glob(filepath.ToSlash(dir) + "/" + pattern)
+        if err != nil {
+            t.Fatalf("Glob(%s) failed: %v", pattern, err)
+        }
+        if !reflect.DeepEqual(rel(got), want) {
+            t.Errorf("Glob(%s) = %v, want %v", pattern, rel(got), want)
+        }
+    }
+    if _, err := Glob(dir + "/[.go"); err == nil {
+        t.Errorf("expected a malformed pattern to fail")
+    }
+
+    before, err := HashFiles(dir + "/src/**/*.go")
+    if err != nil {
+        t.Fatalf("HashFiles failed: %v", err)
+    }
+    key := FilesKey(dir + "/src/**/*.go")
+    if key(context.Background()) != before {
+        t.Errorf("expected FilesKey to match HashFiles")
+    }
+    os.Chtimes(filepath.Join(dir, "src/main.go"), time.Now(), time.Now())
+    if after, _ := HashFiles(dir + "/src/**/*.go"); after != before {
+        t.Errorf("expected touching a file to keep the hash")
+    }
+    os.Remove(filepath.Join(dir, "src/a/b/deep.go"))
+    if after, _ := HashFiles(dir + "/src/**/*.go"); after == before {
+        t.Errorf("expected removing a file to change the hash")
+    }
+}
+
+func TestGlobInputs(t *testing.T) {
+    dir := t.TempDir()
+    os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0o755)
+    src := filepath.Join(dir, "src", "pkg", "lib.go")
+    out := filepath.Join(dir, "app")
+    old := time.Now().Add(-time.Hour)
+    os.WriteFile(src, nil, 0o644)
+    os.Chtimes(src, old, old)
+
+    runs := 0
+    graph := TaskGraph()
+    graph.Add("build", func() error {
+        runs++
+        return os.WriteFile(out, nil, 0o644)
+    }, WithInputs(filepath.Join(dir, "src", "**", "*.go")), WithOutputs(out))
+    executor := NewExecutor(graph)
+    for i := 0; i < 2; i++ {
+        if err := executor.Execute(); err != nil {
+            t.Fatalf("Execute failed: %v", err)
+        }
+    }
+    if runs != 1 {
+        t.Errorf("expected build to run once, ran %d times", runs)
+    }
+    future := time.Now().Add(time.Hour)
+    os.Chtimes(src, future, future)
+    executor.Execute()
+    if runs != 2 {
+        t.Errorf("expected a newer file matching the pattern to rebuild, ran %d times", runs)
+    }
+}
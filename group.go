@@ -0,0 +1,376 @@
+package leo
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+)
+
+// Group places the named nodes in an atomic group, which succeeds or fails
+// as a whole. Members run as their edges allow, in parallel with each other,
+// but the group's failure is shared:
+//
+//   - once a member fails, members that have not started are skipped, while
+//     those already running finish;
+//   - edges from members to nodes outside the group are held until every
+//     member has finished or been skipped, and if the group failed, the
+//     nodes outside are handled as if their parent had failed, according to
+//     the edge's policy.
+//
+// A failed member fails the run as usual unless the group has a
+// compensation, see OnGroupFailure. Report.Group returns the group's outcome.
+//
+// A node belongs to at most one group. Paths that leave a group must not
+// lead back into it, even through other groups, since the group would then
+// wait on itself; Group, Freeze and every run return an error for such a
+// group.
+func (g *Graph) Group(name string, members ...string) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    if name == "" {
+        return errors.New("group needs a name")
+    }
+    if len(members) == 0 {
+        return fmt.Errorf("group %s has no members", name)
+    }
+    for _, n := range g.nodes {
+        if n.group == name {
+            return fmt.Errorf("group %s already exists", name)
+        }
+    }
+    nodes := make([]*Node, 0, len(members))
+    for _, member := range members {
+        n, exists := g.lookup(member)
+        if !exists {
+            return fmt.Errorf("node %s does not exist", member)
+        }
+        if n.group != "" {
+            return fmt.Errorf("node %s is already in group %s", n.name, n.group)
+        }
+        nodes = append(nodes, n)
+    }
+    for _, n := range nodes {
+        n.group = name
+    }
+    if err := g.checkGroups(); err != nil {
+        for _, n := range nodes {
+            n.group = ""
+        }
+        return err
+    }
+    return nil
+}
+
+// OnGroupFailure makes compensation the fallback of every member of group,
+// see OnFailure: it runs once every member has finished or been skipped if
+// the group failed, for example to undo what the members that succeeded
+// did, and is skipped otherwise. The group's failure is then handled and
+// does not fail the run by itself, though the nodes that depend on its
+// members are still skipped.
+func (g *Graph) OnGroupFailure(group, compensation string) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    fb, exists := g.lookup(compensation)
+    if !exists {
+        return fmt.Errorf("node %s does not exist", compensation)
+    }
+    if fb.group == group {
+        return fmt.Errorf("compensation %s is a member of group %s", fb.name, group)
+    }
+    members := g.groupMembers(group)
+    if len(members) == 0 {
+        return fmt.Errorf("group %s does not exist", group)
+    }
+    for _, n := range members {
+        if cycle := g.cycleThrough(n, fb); cycle != nil {
+            return &CycleError{Path: cycle}
+        }
+    }
+    for _, n := range members {
+        n.fallbacks = append(n.fallbacks, fb)
+        fb.fallbackFor = append(fb.fallbackFor, n)
+    }
+    return nil
+}
+
+// Groups returns the names of the graph's groups, sorted.
+func (g *Graph) Groups() []string {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    seen := make(map[string]bool)
+    var names []string
+    for _, n := range g.nodes {
+        if n.group != "" && !seen[n.group] {
+            seen[n.group] = true
+            names = append(names, n.group)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+// groupMembers returns the members of group sorted by name. The caller must
+// hold g.mu, unless the graph is no longer modified.
+func (g *Graph) groupMembers(group string) []*Node {
+    var members []*Node
+    for _, n := range g.nodes {
+        if n.group == group {
+            members = append(members, n)
+        }
+    }
+    sort.Slice(members, func(i, j int) bool { return members[i].name < members[j].name })
+    return members
+}
+
+// checkGroups returns an error if a path leaves a group and leads back into
+// it, directly or through other groups. Since the edges leaving a group are
+// held until the whole group has finished, the group would wait on itself:
+// this is a cycle in the graph with each group contracted to one vertex.
+func (g *Graph) checkGroups() error {
+    vertex := func(n *Node) any {
+        if n.group != "" {
+            return n.group
+        }
+        return n
+    }
+    out := make(map[any][]*Node)
+    var groups []string
+    for _, n := range g.nodes {
+        if n.group != "" {
+            if _, seen := out[n.group]; !seen {
+                groups = append(groups, n.group)
+            }
+        }
+        v := vertex(n)
+        out[v] = append(out[v], n.successors()...)
+    }
+    if len(groups) == 0 {
+        return nil
+    }
+    sort.Strings(groups)
+
+    visited := make(map[any]bool)
+    onStack := make(map[any]bool)
+    var cyclic func(v any) bool
+    cyclic = func(v any) bool {
+        if onStack[v] {
+            return true
+        }
+        if visited[v] {
+            return false
+        }
+        visited[v] = true
+        onStack[v] = true
+        defer delete(onStack, v)
+        for _, s := range out[v] {
+            if w := vertex(s); w != v && cyclic(w) {
+                return true
+            }
+        }
+        return false
+    }
+    for _, name := range groups {
+        if cyclic(name) {
+            return fmt.Errorf("group %s reaches a path that leaves a group and leads back into it", name)
+        }
+    }
+    return nil
+}
+
+// GroupReport is the outcome of an atomic group in a run, see Graph.Group.
+type GroupReport struct {
+    Name      string
+    Members   []string
+    // State is StateFailed if a member failed, StateSkipped if every member
+    // was skipped, StateSucceeded once every member has otherwise finished,
+    // and StatePending until then.
+    State     NodeState
+    // Err is the error of the first member that failed, or of the group's
+    // transaction, see Graph.Transact.
+    Err       error
+    // Committed and Aborted report whether the group's transaction was
+    // committed or aborted.
+    Committed bool
+    Aborted   bool
+}
+
+// groups tracks the progress of a run's atomic groups.
+type groups struct {
+    size     map[string]int
+    pending  map[string]int
+    skipped  map[string]int
+    err      map[string]error
+    held     map[string][]heldEdge
+    prepared map[string]*preparation
+}
+
+// heldEdge is an edge from a member of a group to a node outside it, held
+// until the group has finished. reason and err describe the parent's
+// failure or skip; ok is set if the parent succeeded.
+type heldEdge struct {
+    parent, child *Node
+    ok            bool
+    reason        string
+    err           error
+}
+
+// initGroups prepares the run's groups. It must be called before any node is
+// dispatched.
+func (r *Run) initGroups() error {
+    r.groups = nil
+    g := r.graph
+    if g.plan == nil {
+        if err := g.checkGroups(); err != nil {
+            return err
+        }
+    }
+    grouped := false
+    for _, n := range g.nodes {
+        grouped = grouped || n.group != ""
+    }
+    if !grouped {
+        return nil
+    }
+    s := &groups{
+        size:     make(map[string]int),
+        pending:  make(map[string]int),
+        skipped:  make(map[string]int),
+        err:      make(map[string]error),
+        held:     make(map[string][]heldEdge),
+        prepared: make(map[string]*preparation),
+    }
+    for _, n := range g.nodes {
+        if n.group != "" {
+            s.size[n.group]++
+            s.pending[n.group]++
+        }
+    }
+    for name := range s.pending {
+        rep := &GroupReport{Name: name, State: StatePending}
+        for _, n := range g.groupMembers(name) {
+            rep.Members = append(rep.Members, n.name)
+        }
+        r.report.setGroup(rep)
+    }
+    r.groups = s
+    return nil
+}
+
+// groupFailure returns the reason n must be skipped because its group
+// failed, or "" if it may run. The caller must hold r.mu.
+func (r *Run) groupFailure(n *Node) string {
+    if r.groups == nil || n.group == "" || r.groups.err[n.group] == nil {
+        return ""
+    }
+    return fmt.Sprintf("group %s failed", n.group)
+}
+
+// failGroup records that n, a member of a group if it has one, failed with
+// err. The caller must hold r.mu.
+func (r *Run) failGroup(n *Node, err error) {
+    if r.groups == nil || n.group == "" || r.groups.err[n.group] != nil {
+        return
+    }
+    r.groups.err[n.group] = r.newTaskError(n, err)
+}
+
+// holdEdge holds the edge from parent to child if it leaves parent's group,
+// returning whether it did. ok, reason and err describe how parent finished.
+// The caller must hold r.mu.
+func (r *Run) holdEdge(parent, child *Node, ok bool, reason string, err error) bool {
+    if r.groups == nil || parent.group == "" || child.group == parent.group {
+        return false
+    }
+    r.groups.held[parent.group] = append(r.groups.held[parent.group], heldEdge{parent, child, ok, reason, err})
+    return true
+}
+
+// groupResolved records that n has finished or, if skipped is set, been
+// skipped, ending its group once every member has, after committing or
+// aborting its transaction if it has one. The caller must hold r.mu.
+func (r *Run) groupResolved(n *Node, skipped bool) {
+    s := r.groups
+    if s == nil || n.group == "" {
+        return
+    }
+    name := n.group
+    if skipped {
+        s.skipped[name]++
+    }
+    s.pending[name]--
+    if s.pending[name] > 0 {
+        return
+    }
+    if p := s.prepared[name]; p != nil {
+        r.wg.Add(1)
+        go r.completeTransaction(name, r.graph.transactions[name], p, s.err[name])
+        return
+    }
+    r.endGroup(name, s.err[name])
+}
+
+// endGroup records the outcome of the group name, which finished with gerr,
+// and releases the edges it held. The caller must hold r.mu.
+func (r *Run) endGroup(name string, gerr error) {
+    s := r.groups
+    state := StateSucceeded
+    switch {
+    case gerr != nil:
+        state = StateFailed
+    case s.skipped[name] == s.size[name]:
+        state = StateSkipped
+    }
+    r.report.resolveGroup(name, state, gerr)
+
+    held := s.held[name]
+    s.held[name] = nil
+    for _, h := range held {
+        switch {
+        case gerr != nil:
+            r.unsatisfied(h.parent, h.child, fmt.Sprintf("group %s failed", name), gerr)
+        case h.ok:
+            r.satisfy(h.child, h.parent)
+        default:
+            r.unsatisfied(h.parent, h.child, h.reason, h.err)
+        }
+    }
+}
+
+// Group returns the outcome of the named group in the run, see Graph.Group.
+func (r *Report) Group(name string) (GroupReport, bool) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    g, ok := r.Groups[name]
+    if !ok {
+        return GroupReport{}, false
+    }
+    return *g, true
+}
+
+func (r *Report) setGroup(g *GroupReport) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.final {
+        return
+    }
+    if r.Groups == nil {
+        r.Groups = make(map[string]*GroupReport)
+    }
+    r.Groups[g.Name] = g
+}
+
+func (r *Report) resolveGroup(name string, state NodeState, err error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.final {
+        return
+    }
+    r.Groups[name].State = state
+    r.Groups[name].Err = err
+}
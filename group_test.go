@@ -0,0 +1,151 @@
+package leo
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroup(t *testing.T) {
+    var mu sync.Mutex
+    var ran []string
+    task := func(name string, err error) TaskFunc {
+        return func() error {
+            mu.Lock()
+            ran = append(ran, name)
+            mu.Unlock()
+            return err
+        }
+    }
+    graph := TaskGraph()
+    graph.Add("reserve", task("reserve", nil))
+    graph.Add("charge", task("charge", errors.New("card declined")))
+    graph.Add("confirm", task("confirm", nil))
+    graph.Add("ship", task("ship", nil))
+    graph.Add("refund", task("refund", nil))
+    graph.Precede("reserve", "charge")
+    graph.Precede("reserve", "confirm")
+    graph.Precede("reserve", "ship")
+    if err := graph.Group("order", "reserve", "charge", "confirm"); err != nil {
+        t.Fatalf("Group failed: %v", err)
+    }
+    if err := graph.OnGroupFailure("order", "refund"); err != nil {
+        t.Fatalf("OnGroupFailure failed: %v", err)
+    }
+
+    // With one slot, confirm is queued behind charge and has not started
+    // when charge fails.
+    executor := NewExecutor(graph, WithConcurrency(1))
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("expected the compensation to handle the failure, got %v", err)
+    }
+    if strings.Join(ran, " ") != "reserve charge refund" {
+        t.Errorf("unexpected tasks %v", ran)
+    }
+    report := executor.Report()
+    for name, reason := range map[string]string{"confirm": "group order failed", "ship": "group order failed"} {
+        if nr := report.Nodes[name]; nr.State != StateSkipped || nr.SkipReason != reason {
+            t.Errorf("%s: expected to be skipped with %q, got %+v", name, reason, nr)
+        }
+    }
+    group, ok := report.Group("order")
+    if !ok || group.State != StateFailed || group.Err == nil || !strings.Contains(group.Err.Error(), "card declined") {
+        t.Errorf("unexpected group report %+v", group)
+    }
+    if strings.Join(group.Members, " ") != "charge confirm reserve" {
+        t.Errorf("unexpected members %v", group.Members)
+    }
+}
+
+func TestGroupHoldsEdgesLeavingIt(t *testing.T) {
+    var mu sync.Mutex
+    var order []string
+    record := func(name string, d time.Duration) TaskFunc {
+        return func() error {
+            time.Sleep(d)
+            mu.Lock()
+            order = append(order, name)
+            mu.Unlock()
+            return nil
+        }
+    }
+    graph := TaskGraph()
+    graph.Add("fast", record("fast", 0))
+    graph.Add("slow", record("slow", 20*time.Millisecond))
+    graph.Add("after", record("after", 0))
+    graph.Precede("fast", "after")
+    graph.Group("g", "fast", "slow")
+
+    executor := NewExecutor(graph, WithConcurrency(-1))
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if strings.Join(order, " ") != "fast slow after" {
+        t.Errorf("expected after to wait for the whole group, got %v", order)
+    }
+    if group, _ := executor.Report().Group("g"); group.State != StateSucceeded {
+        t.Errorf("unexpected group report %+v", group)
+    }
+}
+
+func TestGroupWithoutCompensation(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("a", func() error { return errors.New("boom") })
+    graph.Add("b", func() error { return nil })
+    graph.Add("c", func() error { return nil })
+    graph.Precede("b", "c")
+    graph.Group("g", "a", "b")
+
+    executor := NewExecutor(graph, WithFailureMode(FailContinue))
+    if err := executor.Execute(); err == nil || !strings.Contains(err.Error(), "boom") {
+        t.Fatalf("expected the member's failure to fail the run, got %v", err)
+    }
+    if nr := executor.Report().Nodes["c"]; nr.State != StateSkipped {
+        t.Errorf("expected c to be skipped, got %+v", nr)
+    }
+}
+
+func TestGroupErrors(t *testing.T) {
+    graph := TaskGraph()
+    for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+        graph.Add(name, nil)
+    }
+    graph.Precede("a", "b")
+    graph.Precede("b", "c")
+    graph.Precede("d", "e")
+
+    if err := graph.Group("g", "a", "c"); err == nil {
+        t.Errorf("expected a group that a path leaves and re-enters to fail")
+    }
+    if err := graph.Group("g", "a", "missing"); err == nil {
+        t.Errorf("expected a missing member to fail")
+    }
+    if err := graph.Group("g", "a", "b", "c"); err != nil {
+        t.Fatalf("Group failed: %v", err)
+    }
+    if err := graph.Group("h", "c", "d"); err == nil {
+        t.Errorf("expected a node in two groups to fail")
+    }
+    if err := graph.Group("g", "d"); err == nil {
+        t.Errorf("expected a repeated group to fail")
+    }
+    if err := graph.OnGroupFailure("g", "b"); err == nil {
+        t.Errorf("expected a member as compensation to fail")
+    }
+    if err := graph.OnGroupFailure("nope", "f"); err == nil {
+        t.Errorf("expected an unknown group to fail")
+    }
+
+    // Groups that wait on each other deadlock.
+    graph.Group("h", "d", "e")
+    graph.Precede("a", "e")
+    graph.Precede("d", "c")
+    if err := NewExecutor(graph).Execute(); err == nil || !strings.Contains(err.Error(), "leads back into it") {
+        t.Errorf("expected the groups to be rejected, got %v", err)
+    }
+    if _, err := graph.Freeze(); err == nil {
+        t.Errorf("expected Freeze to reject the groups")
+    }
+}
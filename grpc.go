@@ -0,0 +1,317 @@
+package leo
+
+import (
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+)
+
+// EventsService is the full name of the gRPC service served by
+// Executor.EventsHandler.
+const EventsService = "leo.v1.Events"
+
+// maxWatchRequest bounds the size of a Watch request message.
+const maxWatchRequest = 1 << 20
+
+// gRPC status codes used by EventsHandler.
+const (
+    grpcOK            = 0
+    grpcInvalidArg    = 3
+    grpcUnimplemented = 12
+)
+
+// EventsHandler returns an HTTP handler that serves the executor's events
+// over gRPC, for UIs and controllers that follow runs in real time. It
+// implements a single server-streaming RPC:
+//
+//    syntax = "proto3";
+//    package leo.v1;
+//
+//    service Events {
+//      rpc Watch(WatchRequest) returns (stream Event);
+//    }
+//
+//    // WatchRequest selects events like leo.EventFilter; empty fields
+//    // match everything.
+//    message WatchRequest {
+//      repeated EventType types = 1;
+//      repeated string nodes = 2;
+//      repeated string tags = 3;
+//      map<string, string> labels = 4;
+//    }
+//
+//    enum EventType {
+//      EVENT_TYPE_UNSPECIFIED = 0;
+//      EVENT_TYPE_TASK_QUEUED = 1;
+//      EVENT_TYPE_TASK_STARTED = 2;
+//      EVENT_TYPE_TASK_FINISHED = 3;
+//      EVENT_TYPE_TASK_FAILED = 4;
+//      EVENT_TYPE_TASK_SKIPPED = 5;
+//      EVENT_TYPE_RUN_FINISHED = 6;
+//    }
+//
+//    message Event {
+//      uint64 sequence = 1;
+//      EventType type = 2;
+//      int64 time_unix_nano = 3;
+//      string run_id = 4;
+//      string run_name = 5;
+//      map<string, string> run_labels = 6;
+//      string node = 7;
+//      int64 duration_nanos = 8;
+//      string error = 9;
+//      string reason = 10;
+//      int64 eta_nanos = 11;
+//    }
+//
+// Events are streamed in the order they were published, numbered from 1 by
+// sequence, until the client cancels the call. Errors are redacted of the
+// run's secrets. gRPC needs HTTP/2, which net/http serves over TLS;
+// compressed requests are not supported.
+func (e *Executor) EventsHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+            http.Error(w, "expected a gRPC request", http.StatusUnsupportedMediaType)
+            return
+        }
+        w.Header().Set("Content-Type", "application/grpc+proto")
+        w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+        if r.URL.Path != "/"+EventsService+"/Watch" {
+            grpcStatus(w, grpcUnimplemented, "unknown method "+r.URL.Path)
+            return
+        }
+        msg, err := readGRPCMessage(r.Body)
+        if err != nil {
+            grpcStatus(w, grpcInvalidArg, err.Error())
+            return
+        }
+        filter, err := decodeWatchRequest(msg)
+        if err != nil {
+            grpcStatus(w, grpcInvalidArg, fmt.Sprintf("decoding WatchRequest: %v", err))
+            return
+        }
+
+        sub := e.SubscribeFilter(filter)
+        defer sub.Close()
+        w.WriteHeader(http.StatusOK)
+        flusher, _ := w.(http.Flusher)
+        if flusher != nil {
+            flusher.Flush()
+        }
+        var seq uint64
+        for {
+            select {
+            case ev := <-sub.C:
+                seq++
+                if _, err := w.Write(grpcFrame(encodeEvent(seq, ev))); err != nil {
+                    return
+                }
+                if flusher != nil {
+                    flusher.Flush()
+                }
+            case <-r.Context().Done():
+                grpcStatus(w, grpcOK, "")
+                return
+            }
+        }
+    })
+}
+
+// grpcStatus ends a gRPC response with code and message in its trailers.
+func grpcStatus(w http.ResponseWriter, code int, message string) {
+    w.Header().Set("Grpc-Status", fmt.Sprint(code))
+    if message != "" {
+        w.Header().Set("Grpc-Message", url.PathEscape(message))
+    }
+}
+
+// readGRPCMessage reads a single length-prefixed gRPC message from body.
+func readGRPCMessage(body io.Reader) ([]byte, error) {
+    var prefix [5]byte
+    if _, err := io.ReadFull(body, prefix[:]); err != nil {
+        return nil, fmt.Errorf("reading request: %w", err)
+    }
+    if prefix[0] != 0 {
+        return nil, errors.New("compressed requests are not supported")
+    }
+    size := binary.BigEndian.Uint32(prefix[1:])
+    if size > maxWatchRequest {
+        return nil, fmt.Errorf("request of %d bytes is too large", size)
+    }
+    msg := make([]byte, size)
+    if _, err := io.ReadFull(body, msg); err != nil {
+        return nil, fmt.Errorf("reading request: %w", err)
+    }
+    return msg, nil
+}
+
+// grpcFrame prefixes msg with the gRPC message header.
+func grpcFrame(msg []byte) []byte {
+    frame := make([]byte, 5, 5+len(msg))
+    binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+    return append(frame, msg...)
+}
+
+func decodeWatchRequest(msg []byte) (EventFilter, error) {
+    var f EventFilter
+    err := protoFields(msg, func(num int, wire int, v uint64, data []byte) error {
+        switch {
+        case num == 1 && wire == protoVarint:
+            f.Types = append(f.Types, EventType(v-1))
+        case num == 1 && wire == protoBytes:
+            // Packed repeated enum.
+            for len(data) > 0 {
+                t, n := binary.Uvarint(data)
+                if n <= 0 {
+                    return errors.New("malformed packed types")
+                }
+                f.Types = append(f.Types, EventType(t-1))
+                data = data[n:]
+            }
+        case num == 2 && wire == protoBytes:
+            f.Nodes = append(f.Nodes, string(data))
+        case num == 3 && wire == protoBytes:
+            f.Tags = append(f.Tags, string(data))
+        case num == 4 && wire == protoBytes:
+            var key, value string
+            err := protoFields(data, func(num int, wire int, v uint64, data []byte) error {
+                switch {
+                case num == 1 && wire == protoBytes:
+                    key = string(data)
+                case num == 2 && wire == protoBytes:
+                    value = string(data)
+                }
+                return nil
+            })
+            if err != nil {
+                return err
+            }
+            if f.Labels == nil {
+                f.Labels = make(map[string]string)
+            }
+            f.Labels[key] = value
+        }
+        return nil
+    })
+    return f, err
+}
+
+func encodeEvent(seq uint64, ev Event) []byte {
+    var b []byte
+    b = protoAppendVarint(b, 1, seq)
+    b = protoAppendVarint(b, 2, uint64(ev.Type)+1)
+    if !ev.Time.IsZero() {
+        b = protoAppendVarint(b, 3, uint64(ev.Time.UnixNano()))
+    }
+    if r := ev.Run; r != nil {
+        b = protoAppendString(b, 4, r.id)
+        b = protoAppendString(b, 5, r.name)
+        keys := make([]string, 0, len(r.labels))
+        for k := range r.labels {
+            keys = append(keys, k)
+        }
+        sort.Strings(keys)
+        for _, k := range keys {
+            var entry []byte
+            entry = protoAppendString(entry, 1, k)
+            entry = protoAppendString(entry, 2, r.labels[k])
+            b = protoAppendBytes(b, 6, entry)
+        }
+    }
+    b = protoAppendString(b, 7, ev.Node)
+    b = protoAppendVarint(b, 8, uint64(ev.Duration))
+    if ev.Err != nil {
+        msg := ev.Err.Error()
+        if ev.Run != nil {
+            msg = ev.Run.redact(msg)
+        }
+        b = protoAppendString(b, 9, msg)
+    }
+    b = protoAppendString(b, 10, ev.Reason)
+    b = protoAppendVarint(b, 11, uint64(ev.ETA))
+    return b
+}
+
+// Protocol buffer wire types.
+const (
+    protoVarint = 0
+    protoBytes  = 2
+)
+
+func protoAppendTag(b []byte, num, wire int) []byte {
+    return binary.AppendUvarint(b, uint64(num)<<3|uint64(wire))
+}
+
+// protoAppendVarint appends a varint field, omitting it if v is 0 as proto3
+// does.
+func protoAppendVarint(b []byte, num int, v uint64) []byte {
+    if v == 0 {
+        return b
+    }
+    return binary.AppendUvarint(protoAppendTag(b, num, protoVarint), v)
+}
+
+// protoAppendString appends a string field, omitting it if s is empty.
+func protoAppendString(b []byte, num int, s string) []byte {
+    if s == "" {
+        return b
+    }
+    return protoAppendBytes(b, num, []byte(s))
+}
+
+func protoAppendBytes(b []byte, num int, data []byte) []byte {
+    b = binary.AppendUvarint(protoAppendTag(b, num, protoBytes), uint64(len(data)))
+    return append(b, data...)
+}
+
+// protoFields calls fn for each field of the encoded message msg, with the
+// value of varint fields or the contents of length-delimited ones. Fixed-size
+// fields are skipped.
+func protoFields(msg []byte, fn func(num int, wire int, v uint64, data []byte) error) error {
+    for len(msg) > 0 {
+        tag, n := binary.Uvarint(msg)
+        if n <= 0 {
+            return errors.New("malformed field tag")
+        }
+        msg = msg[n:]
+        num, wire := int(tag>>3), int(tag&7)
+        var v uint64
+        var data []byte
+        switch wire {
+        case protoVarint:
+            v, n = binary.Uvarint(msg)
+            if n <= 0 {
+                return fmt.Errorf("malformed field %d", num)
+            }
+            msg = msg[n:]
+        case protoBytes:
+            size, n := binary.Uvarint(msg)
+            if n <= 0 || size > uint64(len(msg)-n) {
+                return fmt.Errorf("malformed field %d", num)
+            }
+            data = msg[n : n+int(size)]
+            msg = msg[n+int(size):]
+        case 1, 5:
+            size := 8
+            if wire == 5 {
+                size = 4
+            }
+            if len(msg) < size {
+                return fmt.Errorf("malformed field %d", num)
+            }
+            msg = msg[size:]
+            continue
+        default:
+            return fmt.Errorf("unsupported wire type %d in field %d", wire, num)
+        }
+        if err := fn(num, wire, v, data); err != nil {
+            return err
+        }
+    }
+    return nil
+}
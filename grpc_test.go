@@ -0,0 +1,131 @@
+package leo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventsHandler(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("build", func() error { return nil })
+    graph.Add("test", func() error { return errors.New("flaky") })
+    graph.Add("lint", func() error { return nil })
+    graph.Precede("build", "test")
+    executor := NewExecutor(graph)
+
+    srv := httptest.NewUnstartedServer(executor.EventsHandler())
+    srv.EnableHTTP2 = true
+    srv.StartTLS()
+    defer srv.Close()
+
+    call := func(ctx context.Context, method string, req []byte) *http.Response {
+        httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/leo.v1.Events/"+method, bytes.NewReader(grpcFrame(req)))
+        if err != nil {
+            t.Fatal(err)
+        }
+        httpReq.Header.Set("Content-Type", "application/grpc")
+        resp, err := srv.Client().Do(httpReq)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if resp.ProtoMajor != 2 {
+            t.Fatalf("expected HTTP/2, got %s", resp.Proto)
+        }
+        return resp
+    }
+
+    // Watch only the task events of build and test, and the run's end.
+    var req []byte
+    req = protoAppendBytes(req, 1, []byte{3, 4, 6})
+    req = protoAppendString(req, 2, "build")
+    req = protoAppendString(req, 2, "test")
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    resp := call(ctx, "Watch", req)
+    defer resp.Body.Close()
+
+    if err := executor.Execute(); err == nil {
+        t.Fatalf("expected the run to fail")
+    }
+
+    type received struct {
+        seq             uint64
+        typ             EventType
+        runID, node, msg string
+    }
+    var got []received
+    for len(got) == 0 || got[len(got)-1].typ != EventRunFinished {
+        msg, err := readGRPCMessage(resp.Body)
+        if err != nil {
+            t.Fatalf("reading event: %v", err)
+        }
+        var ev received
+        protoFields(msg, func(num int, wire int, v uint64, data []byte) error {
+            switch num {
+            case 1:
+                ev.seq = v
+            case 2:
+                ev.typ = EventType(v - 1)
+            case 4:
+                ev.runID = string(data)
+            case 7:
+                ev.node = string(data)
+            case 9:
+                ev.msg = string(data)
+            }
+            return nil
+        })
+        got = append(got, ev)
+    }
+
+    runID := executor.Report().ID
+    want := []received{
+        {1, EventTaskFinished, runID, "build", ""},
+        {2, EventTaskFailed, runID, "test", "flaky"},
+        {3, EventRunFinished, runID, "", ""},
+    }
+    if len(got) != len(want) {
+        t.Fatalf("expected %d events, got %+v", len(want), got)
+    }
+    for i := range want {
+        if got[i].seq != want[i].seq || got[i].typ != want[i].typ || got[i].runID != want[i].runID || got[i].node != want[i].node {
+            t.Errorf("event %d: expected %+v, got %+v", i, want[i], got[i])
+        }
+    }
+    if got[1].msg == "" {
+        t.Errorf("expected the failure's error, got %+v", got[1])
+    }
+    cancel()
+
+    unknown := call(context.Background(), "Follow", nil)
+    io.Copy(io.Discard, unknown.Body)
+    unknown.Body.Close()
+    if status := unknown.Trailer.Get("Grpc-Status"); status != "12" {
+        t.Errorf("expected UNIMPLEMENTED for an unknown method, got status %q", status)
+    }
+}
+
+func TestDecodeWatchRequest(t *testing.T) {
+    var entry, req []byte
+    entry = protoAppendString(entry, 1, "env")
+    entry = protoAppendString(entry, 2, "prod")
+    req = protoAppendVarint(req, 1, 2)
+    req = protoAppendString(req, 3, "slow")
+    req = protoAppendBytes(req, 4, entry)
+
+    f, err := decodeWatchRequest(req)
+    if err != nil {
+        t.Fatalf("decodeWatchRequest failed: %v", err)
+    }
+    if len(f.Types) != 1 || f.Types[0] != EventTaskStarted || len(f.Tags) != 1 || f.Tags[0] != "slow" || f.Labels["env"] != "prod" {
+        t.Errorf("unexpected filter %+v", f)
+    }
+    if _, err := decodeWatchRequest([]byte{0x0a, 0x05, 'x'}); err == nil {
+        t.Errorf("expected a truncated request to fail")
+    }
+}
@@ -0,0 +1,63 @@
+package leo
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "sort"
+)
+
+// Hash returns a hex-encoded SHA-256 digest of the graph's structure and node
+// and edge settings. It is independent of the order in which nodes and edges
+// were added, so two graphs with the same topology and settings hash equally.
+// Tasks themselves are not part of the hash.
+func (g *Graph) Hash() string {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    if g.plan != nil {
+        return g.plan.hash
+    }
+    return g.hash()
+}
+
+// hash computes the graph's hash. The caller must hold g.mu.
+func (g *Graph) hash() string {
+    h := sha256.New()
+
+    names := make([]string, 0, len(g.nodes))
+    for name := range g.nodes {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    for _, name := range names {
+        fmt.Fprintf(h, "node %q\n", name)
+        writeMetadata(h, g.nodes[name].metadata())
+    }
+
+    edges := edgeSet(g)
+    sorted := make([]Edge, 0, len(edges))
+    for e := range edges {
+        sorted = append(sorted, e)
+    }
+    sortEdges(sorted)
+
+    for _, e := range sorted {
+        fmt.Fprintf(h, "edge %q %q %t\n", e.From, e.To, e.Fallback)
+        writeMetadata(h, edges[e])
+    }
+
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeMetadata(w io.Writer, md map[string]string) {
+    keys := make([]string, 0, len(md))
+    for key := range md {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        fmt.Fprintf(w, "\t%q=%q\n", key, md[key])
+    }
+}
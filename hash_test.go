@@ -0,0 +1,39 @@
+package leo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHash(t *testing.T) {
+    noop := func() error { return nil }
+
+    build := func(names []string, expected time.Duration) *Graph {
+        graph := TaskGraph()
+        for _, name := range names {
+            graph.Add(name, noop, WithExpectedDuration(expected))
+        }
+        graph.Precede("A", "B")
+        graph.Precede("A", "C")
+        return graph
+    }
+
+    a := build([]string{"A", "B", "C"}, time.Second)
+    b := build([]string{"C", "B", "A"}, time.Second)
+    if a.Hash() != b.Hash() {
+        t.Errorf("hash should not depend on insertion order")
+    }
+    if a.Hash() != a.Hash() {
+        t.Errorf("hash should be stable")
+    }
+
+    if c := build([]string{"A", "B", "C"}, 2*time.Second); a.Hash() == c.Hash() {
+        t.Errorf("hash should change when node metadata changes")
+    }
+
+    d := build([]string{"A", "B", "C"}, time.Second)
+    d.Precede("B", "C")
+    if a.Hash() == d.Hash() {
+        t.Errorf("hash should change when edges change")
+    }
+}
@@ -0,0 +1,77 @@
+package leo
+
+import (
+    "encoding/json"
+    "net/http"
+    "time"
+)
+
+// Health is a snapshot of a scheduler's state, for health and readiness
+// checks.
+type Health struct {
+    // Alive is set while the scheduler's Run is in progress.
+    Alive     bool                      `json:"alive"`
+    Started   time.Time                 `json:"started,omitempty"`
+    Pipelines map[string]PipelineHealth `json:"pipelines"`
+}
+
+// PipelineHealth describes a scheduled pipeline.
+type PipelineHealth struct {
+    Running             bool          `json:"running"`
+    Runs                int           `json:"runs"`
+    LastRun             time.Time     `json:"last_run,omitempty"`
+    LastDuration        time.Duration `json:"last_duration,omitempty"`
+    LastError           string        `json:"last_error,omitempty"`
+    ConsecutiveFailures int           `json:"consecutive_failures"`
+    NextRun             time.Time     `json:"next_run,omitempty"`
+}
+
+// Health returns the scheduler's current state.
+func (s *Scheduler) Health() Health {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    h := Health{
+        Alive:     s.running,
+        Pipelines: make(map[string]PipelineHealth, len(s.pipelines)),
+    }
+    if s.running {
+        h.Started = s.started
+    }
+    for name, p := range s.pipelines {
+        ph := p.health
+        ph.Running = p.busy
+        h.Pipelines[name] = ph
+    }
+    return h
+}
+
+// Ready reports whether the scheduler is alive and no pipeline has failed
+// maxFailures or more times in a row. A maxFailures of 0 or less ignores
+// failures.
+func (h Health) Ready(maxFailures int) bool {
+    if !h.Alive {
+        return false
+    }
+    if maxFailures > 0 {
+        for _, p := range h.Pipelines {
+            if p.ConsecutiveFailures >= maxFailures {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+// HealthHandler returns an HTTP handler that serves the scheduler's Health
+// as JSON, with status 200 if it is ready according to Health.Ready and 503
+// otherwise.
+func (s *Scheduler) HealthHandler(maxFailures int) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        h := s.Health()
+        w.Header().Set("Content-Type", "application/json")
+        if !h.Ready(maxFailures) {
+            w.WriteHeader(http.StatusServiceUnavailable)
+        }
+        json.NewEncoder(w).Encode(h)
+    })
+}
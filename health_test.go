@@ -0,0 +1,56 @@
+package leo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthHandler(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("fail", func() error { return errors.New("down") })
+
+    s := NewScheduler()
+    s.Every("flaky", NewExecutor(graph), time.Hour)
+    handler := s.HealthHandler(1)
+
+    get := func() (int, Health) {
+        rec := httptest.NewRecorder()
+        handler.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+        var h Health
+        if err := json.Unmarshal(rec.Body.Bytes(), &h); err != nil {
+            t.Fatalf("invalid JSON: %v", err)
+        }
+        return rec.Code, h
+    }
+
+    if code, h := get(); code != http.StatusServiceUnavailable || h.Alive {
+        t.Errorf("expected a stopped scheduler to be unavailable, got %d %+v", code, h)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    go s.Run(ctx)
+    for !s.Health().Alive {
+        time.Sleep(time.Millisecond)
+    }
+    if code, _ := get(); code != http.StatusOK {
+        t.Errorf("expected a running scheduler to be ready, got %d", code)
+    }
+
+    s.Trigger("flaky")
+    for s.Health().Pipelines["flaky"].Runs == 0 {
+        time.Sleep(time.Millisecond)
+    }
+    code, h := get()
+    if code != http.StatusServiceUnavailable || h.Pipelines["flaky"].ConsecutiveFailures != 1 {
+        t.Errorf("expected a failing pipeline to make the scheduler unready, got %d %+v", code, h)
+    }
+    if !h.Ready(0) {
+        t.Errorf("expected failures to be ignored with maxFailures 0")
+    }
+}
@@ -0,0 +1,63 @@
+package leo
+
+import (
+    "context"
+    "time"
+)
+
+// WithHedge enables speculative execution for a task. If the first attempt has
+// not finished after delay, a second attempt is started and the first attempt
+// to succeed wins; the other attempt's context is cancelled. If both attempts
+// fail, the first error is returned.
+//
+// Only use this for idempotent tasks; for tasks marked Idempotent(false),
+// the second attempt needs the executor's confirmation. Tasks added with Add
+// cannot observe the cancellation, so a losing attempt keeps running until it
+// returns on its own.
+func WithHedge(delay time.Duration) NodeOption {
+    return func(n *Node) {
+        n.hedge = delay
+    }
+}
+
+// runHedged runs task, starting a second attempt after delay if allow
+// returns true.
+func runHedged(ctx context.Context, task TaskCtxFunc, delay time.Duration, allow func() bool) error {
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    results := make(chan error, 2)
+    attempt := func() {
+        results <- task(ctx)
+    }
+
+    go attempt()
+    launched, finished := 1, 0
+
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+
+    var firstErr error
+    for {
+        select {
+        case <-timer.C:
+            if allow() {
+                go attempt()
+                launched++
+            }
+        case err := <-results:
+            finished++
+            if err == nil {
+                return nil
+            }
+            if firstErr == nil {
+                firstErr = err
+            }
+            if finished == launched {
+                return firstErr
+            }
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+}
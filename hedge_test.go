@@ -0,0 +1,72 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgeSecondAttemptWins(t *testing.T) {
+    graph := TaskGraph()
+
+    var attempts int32
+    var cancelled int32
+    graph.AddCtx("flaky", func(ctx context.Context) error {
+        if atomic.AddInt32(&attempts, 1) == 1 {
+            // The first attempt hangs until it is cancelled.
+            <-ctx.Done()
+            atomic.AddInt32(&cancelled, 1)
+            return ctx.Err()
+        }
+        return nil
+    }, WithHedge(10*time.Millisecond))
+
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    if got := atomic.LoadInt32(&attempts); got != 2 {
+        t.Errorf("expected 2 attempts, got %d", got)
+    }
+
+    // The losing attempt observes cancellation shortly after the winner returns.
+    deadline := time.Now().Add(time.Second)
+    for atomic.LoadInt32(&cancelled) == 0 && time.Now().Before(deadline) {
+        time.Sleep(time.Millisecond)
+    }
+    if atomic.LoadInt32(&cancelled) != 1 {
+        t.Errorf("losing attempt was not cancelled")
+    }
+}
+
+func TestHedgeFastTaskRunsOnce(t *testing.T) {
+    graph := TaskGraph()
+
+    var attempts int32
+    graph.Add("fast", func() error {
+        atomic.AddInt32(&attempts, 1)
+        return nil
+    }, WithHedge(time.Second))
+
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if got := atomic.LoadInt32(&attempts); got != 1 {
+        t.Errorf("expected 1 attempt, got %d", got)
+    }
+}
+
+func TestHedgeBothAttemptsFail(t *testing.T) {
+    graph := TaskGraph()
+
+    graph.Add("broken", func() error {
+        time.Sleep(20 * time.Millisecond)
+        return errors.New("boom")
+    }, WithHedge(time.Millisecond))
+
+    if err := NewExecutor(graph).Execute(); err == nil {
+        t.Fatalf("expected an error when both attempts fail")
+    }
+}
@@ -0,0 +1,220 @@
+package leo
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "sort"
+    "sync"
+    "time"
+)
+
+// HistoryStore keeps the outcomes of past runs, which the executor uses to
+// estimate durations. Set one with Executor.SetHistory; every run is saved
+// to it when it returns.
+type HistoryStore interface {
+    // Save appends a run.
+    Save(rec RunRecord) error
+    // Runs returns up to limit of the most recent runs, newest first. A
+    // limit of 0 or less returns every run.
+    Runs(limit int) ([]RunRecord, error)
+}
+
+// RunRecord is the stored outcome of a run.
+type RunRecord struct {
+    ID        string                `json:"id,omitempty"`
+    Name      string                `json:"name,omitempty"`
+    Labels    map[string]string     `json:"labels,omitempty"`
+    Start     time.Time             `json:"start"`
+    Duration  time.Duration         `json:"duration"`
+    Succeeded bool                  `json:"succeeded"`
+    Nodes     map[string]NodeRecord `json:"nodes"`
+    Artifacts []Artifact            `json:"artifacts,omitempty"`
+}
+
+// NodeRecord is the stored outcome of a node within a run.
+type NodeRecord struct {
+    State    string        `json:"state"`
+    Duration time.Duration `json:"duration,omitempty"`
+    Err      string        `json:"error,omitempty"`
+}
+
+// Record returns the report in the form kept by a HistoryStore.
+func (r *Report) Record() RunRecord {
+    succeeded := r.Succeeded()
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    rec := RunRecord{
+        ID:        r.ID,
+        Name:      r.Name,
+        Labels:    r.Labels,
+        Start:     r.Start,
+        Duration:  r.Duration,
+        Succeeded: succeeded,
+        Nodes:     make(map[string]NodeRecord, len(r.Nodes)),
+        Artifacts: r.Artifacts,
+    }
+    for name, nr := range r.Nodes {
+        node := NodeRecord{State: nr.State.String(), Duration: nr.Duration}
+        if nr.Err != nil {
+            node.Err = nr.Err.Error()
+        }
+        rec.Nodes[name] = node
+    }
+    return rec
+}
+
+// SetHistory sets the store runs are saved to and durations are estimated
+// from.
+func (e *Executor) SetHistory(h HistoryStore) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.history = h
+}
+
+func (e *Executor) getHistory() HistoryStore {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.history
+}
+
+// MemoryHistory is a HistoryStore that keeps the most recent runs in memory.
+type MemoryHistory struct {
+    max  int
+    mu   sync.Mutex
+    runs []RunRecord
+}
+
+// NewMemoryHistory returns a store that keeps up to max runs, or every run
+// if max is 0.
+func NewMemoryHistory(max int) *MemoryHistory {
+    return &MemoryHistory{max: max}
+}
+
+func (h *MemoryHistory) Save(rec RunRecord) error {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    h.runs = append(h.runs, rec)
+    if h.max > 0 && len(h.runs) > h.max {
+        h.runs = append([]RunRecord(nil), h.runs[len(h.runs)-h.max:]...)
+    }
+    return nil
+}
+
+func (h *MemoryHistory) Runs(limit int) ([]RunRecord, error) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    return newestFirst(h.runs, limit), nil
+}
+
+// FileHistory is a HistoryStore that appends runs to a file as JSON lines.
+type FileHistory struct {
+    Path string
+
+    mu sync.Mutex
+}
+
+func (h *FileHistory) Save(rec RunRecord) error {
+    data, err := json.Marshal(rec)
+    if err != nil {
+        return err
+    }
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    f, err := os.OpenFile(h.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+    if err != nil {
+        return err
+    }
+    if _, err := f.Write(append(data, '\n')); err != nil {
+        f.Close()
+        return err
+    }
+    return f.Close()
+}
+
+func (h *FileHistory) Runs(limit int) ([]RunRecord, error) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    f, err := os.Open(h.Path)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    var runs []RunRecord
+    scanner := bufio.NewScanner(f)
+    scanner.Buffer(nil, 16<<20)
+    for line := 1; scanner.Scan(); line++ {
+        if len(scanner.Bytes()) == 0 {
+            continue
+        }
+        var rec RunRecord
+        if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+            return nil, fmt.Errorf("%s:%d: %w", h.Path, line, err)
+        }
+        runs = append(runs, rec)
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return newestFirst(runs, limit), nil
+}
+
+// newestFirst returns up to limit of runs, which are oldest first, in
+// reverse order.
+func newestFirst(runs []RunRecord, limit int) []RunRecord {
+    if limit <= 0 || limit > len(runs) {
+        limit = len(runs)
+    }
+    out := make([]RunRecord, limit)
+    for i := range out {
+        out[i] = runs[len(runs)-1-i]
+    }
+    return out
+}
+
+// historyWindow is the number of past runs used to estimate durations.
+const historyWindow = 20
+
+// estimateDurations returns the median duration of each node's successful
+// executions in the most recent runs in h.
+func estimateDurations(h HistoryStore) (map[string]time.Duration, error) {
+    samples, err := successfulDurations(h)
+    if err != nil {
+        return nil, err
+    }
+    estimates := make(map[string]time.Duration, len(samples))
+    for name, ds := range samples {
+        estimates[name] = ds[len(ds)/2]
+    }
+    return estimates, nil
+}
+
+// successfulDurations returns the durations of each node's successful
+// executions in the most recent runs in h, sorted.
+func successfulDurations(h HistoryStore) (map[string][]time.Duration, error) {
+    runs, err := h.Runs(historyWindow)
+    if err != nil {
+        return nil, err
+    }
+    samples := make(map[string][]time.Duration)
+    for _, rec := range runs {
+        for name, node := range rec.Nodes {
+            if node.State == StateSucceeded.String() {
+                samples[name] = append(samples[name], node.Duration)
+            }
+        }
+    }
+    for _, ds := range samples {
+        sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+    }
+    return samples, nil
+}
@@ -0,0 +1,58 @@
+package leo
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistorySaved(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("ok", func() error { return nil })
+    graph.Add("bad", func() error { return errors.New("boom") })
+
+    for _, h := range []HistoryStore{NewMemoryHistory(0), &FileHistory{Path: filepath.Join(t.TempDir(), "history.jsonl")}} {
+        executor := NewExecutor(graph)
+        executor.SetHistory(h)
+        executor.Execute()
+        executor.Execute()
+
+        runs, err := h.Runs(0)
+        if err != nil {
+            t.Fatalf("%T: Runs failed: %v", h, err)
+        }
+        if len(runs) != 2 {
+            t.Fatalf("%T: expected 2 runs, got %d", h, len(runs))
+        }
+        rec := runs[0]
+        if rec.Succeeded || rec.Nodes["bad"].State != "failed" || rec.Nodes["bad"].Err != "boom" {
+            t.Errorf("%T: unexpected record %+v", h, rec)
+        }
+        if !runs[0].Start.After(runs[1].Start) {
+            t.Errorf("%T: expected the newest run first", h)
+        }
+    }
+}
+
+func TestMemoryHistoryLimit(t *testing.T) {
+    h := NewMemoryHistory(2)
+    for i := 1; i <= 3; i++ {
+        h.Save(RunRecord{Duration: time.Duration(i)})
+    }
+    runs, _ := h.Runs(0)
+    if len(runs) != 2 || runs[0].Duration != 3 || runs[1].Duration != 2 {
+        t.Errorf("unexpected runs %+v", runs)
+    }
+    if runs, _ := h.Runs(1); len(runs) != 1 || runs[0].Duration != 3 {
+        t.Errorf("unexpected limited runs %+v", runs)
+    }
+}
+
+func TestFileHistoryMissing(t *testing.T) {
+    h := &FileHistory{Path: filepath.Join(t.TempDir(), "none.jsonl")}
+    runs, err := h.Runs(10)
+    if err != nil || len(runs) != 0 {
+        t.Errorf("expected no runs from a missing file, got %v, %v", runs, err)
+    }
+}
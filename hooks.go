@@ -0,0 +1,122 @@
+package leo
+
+import "time"
+
+// Hooks are optional callbacks the executor invokes while running a graph.
+// Nil hooks are ignored. Hooks may be called from multiple goroutines at once.
+type Hooks struct {
+    // OnSLAViolation is called when a task takes longer than the expected
+    // duration declared with WithExpectedDuration.
+    OnSLAViolation func(v SLAViolation)
+
+    // OnTaskSkipped is called when a node's task is not run, with the reason
+    // it was skipped, e.g. "upstream Task A failed".
+    OnTaskSkipped func(name, reason string)
+
+    // OnOutput is called for each line a command task created with Command,
+    // Shell or AddShell writes to stdout or stderr, while the command runs.
+    OnOutput func(line OutputLine)
+
+    // OnStageStarted is called when the barrier in front of a stage opens,
+    // see Graph.Stage. Stages skipped because an earlier stage failed are
+    // not started.
+    OnStageStarted func(name string)
+
+    // OnStageFinished is called once every node of a started stage has
+    // finished or been skipped. err is the stage's first unhandled failure,
+    // or its timeout, or nil.
+    OnStageFinished func(name string, d time.Duration, err error)
+
+    // OnIterationStarted and OnIterationFinished are called around each run
+    // of ExecuteN and ExecuteEvery, with the iteration's index counting
+    // from 0.
+    OnIterationStarted  func(index int)
+    OnIterationFinished func(it Iteration)
+
+    // OnRunStart is called when a run starts dispatching tasks. Runs that
+    // fail to start, for example because of an invalid graph, return their
+    // error without calling any run hook.
+    OnRunStart func(run *Run)
+
+    // OnRunComplete is called when a run that started succeeds, and
+    // OnRunError when it fails, with the run's final report and error.
+    // They are called before Execute returns, for notifications that need
+    // the whole pipeline's outcome.
+    OnRunComplete func(report *Report)
+    OnRunError    func(report *Report, err error)
+}
+
+// SetHooks replaces the executor's hooks.
+func (e *Executor) SetHooks(h Hooks) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.hooks = h
+}
+
+func (e *Executor) getHooks() Hooks {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.hooks
+}
+
+func (e *Executor) violation(v SLAViolation) {
+    if h := e.getHooks(); h.OnSLAViolation != nil {
+        h.OnSLAViolation(v)
+    }
+}
+
+func (e *Executor) skipped(name, reason string) {
+    if h := e.getHooks(); h.OnTaskSkipped != nil {
+        h.OnTaskSkipped(name, reason)
+    }
+}
+
+func (e *Executor) stageStarted(name string) {
+    if h := e.getHooks(); h.OnStageStarted != nil {
+        h.OnStageStarted(name)
+    }
+}
+
+func (e *Executor) iterationStarted(index int) {
+    if h := e.getHooks(); h.OnIterationStarted != nil {
+        h.OnIterationStarted(index)
+    }
+}
+
+func (e *Executor) iterationFinished(it Iteration) {
+    if h := e.getHooks(); h.OnIterationFinished != nil {
+        h.OnIterationFinished(it)
+    }
+}
+
+func (e *Executor) runStarted(r *Run) {
+    if h := e.getHooks(); h.OnRunStart != nil {
+        h.OnRunStart(r)
+    }
+}
+
+func (e *Executor) runFinished(report *Report, err error) {
+    h := e.getHooks()
+    switch {
+    case err == nil && h.OnRunComplete != nil:
+        h.OnRunComplete(report)
+    case err != nil && h.OnRunError != nil:
+        h.OnRunError(report, err)
+    }
+}
+
+func (e *Executor) stageFinished(name string, d time.Duration, err error) {
+    if h := e.getHooks(); h.OnStageFinished != nil {
+        h.OnStageFinished(name, d, err)
+    }
+}
+
+// outputFunc returns a function that passes lines written by node's command
+// to the OnOutput hook, with the run's secrets redacted.
+func (e *Executor) outputFunc(r *Run, node, stream string) func(string) {
+    return func(text string) {
+        if h := e.getHooks(); h.OnOutput != nil {
+            h.OnOutput(OutputLine{Node: node, Stream: stream, Text: r.redact(text)})
+        }
+    }
+}
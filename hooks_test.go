@@ -0,0 +1,60 @@
+package leo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRunHooks(t *testing.T) {
+    var calls []string
+    var completed, failed *Report
+    var runErr error
+    hooks := Hooks{
+        OnRunStart: func(run *Run) { calls = append(calls, "start "+run.ID()) },
+        OnRunComplete: func(report *Report) {
+            calls = append(calls, "complete")
+            completed = report
+        },
+        OnRunError: func(report *Report, err error) {
+            calls = append(calls, "error")
+            failed, runErr = report, err
+        },
+    }
+
+    fail := false
+    graph := TaskGraph()
+    graph.Add("deploy", func() error {
+        if fail {
+            return errors.New("rollout stalled")
+        }
+        return nil
+    })
+    executor := NewExecutor(graph, WithHooks(hooks))
+
+    run := executor.NewRun()
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if len(calls) != 2 || calls[0] != "start "+run.ID() || calls[1] != "complete" {
+        t.Errorf("unexpected hook calls %v", calls)
+    }
+    if completed == nil || completed.ID != run.ID() || !completed.Succeeded() {
+        t.Errorf("expected the completed run's report, got %+v", completed)
+    }
+
+    calls, fail = nil, true
+    err := executor.Execute()
+    if len(calls) != 2 || calls[1] != "error" {
+        t.Errorf("unexpected hook calls %v", calls)
+    }
+    if runErr != err || failed == nil || len(failed.Failed()) != 1 {
+        t.Errorf("expected the failed run's report and error, got %v, %v", failed, runErr)
+    }
+
+    // A run that cannot start calls no run hooks.
+    calls = nil
+    graph.Add("gpu", func() error { return nil }, RequireWorker("has-gpu"))
+    if err := executor.Execute(); err == nil || len(calls) != 0 {
+        t.Errorf("expected an error and no hook calls, got %v, %v", err, calls)
+    }
+}
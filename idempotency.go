@@ -0,0 +1,91 @@
+package leo
+
+import "context"
+
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey gives a node's task an idempotency key, computed from
+// the run's context when the node is ready, for tasks with external side
+// effects such as charging a card or flashing firmware. When the executor has
+// a journal (see SetJournal), a node whose key is already recorded as
+// committed by any run, including a run being recovered or retried, is
+// reported as succeeded without calling its task again. An empty key
+// disables the check for that run.
+//
+// The key is committed after the task returns, so a crash in between still
+// repeats the task; tasks should pass IdempotencyKey(ctx) on to the external
+// system where it supports deduplication.
+func WithIdempotencyKey(key func(ctx context.Context) string) NodeOption {
+    return func(n *Node) {
+        n.idempotencyKey = key
+    }
+}
+
+// IdempotencyKey returns the idempotency key of the task that received ctx,
+// or "" if it has none.
+func IdempotencyKey(ctx context.Context) string {
+    key, _ := ctx.Value(idempotencyKeyCtx{}).(string)
+    return key
+}
+
+// loadCommitted reads the committed idempotency keys from the journal, if
+// any node of the graph uses them.
+func (r *Run) loadCommitted(j Journal) error {
+    uses := false
+    for _, node := range r.graph.nodes {
+        if node.idempotencyKey != nil {
+            uses = true
+        }
+    }
+    if !uses {
+        return nil
+    }
+
+    entries, err := j.Entries()
+    if err != nil {
+        return err
+    }
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    r.committed = make(map[string]bool)
+    for _, entry := range entries {
+        if entry.Key != "" && entry.State == JournalSucceeded {
+            r.committed[entry.Key] = true
+        }
+    }
+    return nil
+}
+
+// setKey records the idempotency key of n for this run.
+func (r *Run) setKey(n *Node, key string) {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    if r.keys == nil {
+        r.keys = make(map[string]string)
+    }
+    r.keys[n.name] = key
+}
+
+// key returns the idempotency key recorded for the named node.
+func (r *Run) key(node string) string {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    return r.keys[node]
+}
+
+// isCommitted reports whether key has been committed.
+func (r *Run) isCommitted(key string) bool {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    return r.committed[key]
+}
+
+// commit records that key has been committed.
+func (r *Run) commit(key string) {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    if r.committed == nil {
+        r.committed = make(map[string]bool)
+    }
+    r.committed[key] = true
+}
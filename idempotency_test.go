@@ -0,0 +1,55 @@
+package leo
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+    var charges atomic.Int32
+    var gotKey string
+    graph := TaskGraph()
+    graph.AddCtx("charge", func(ctx context.Context) error {
+        charges.Add(1)
+        gotKey = IdempotencyKey(ctx)
+        return nil
+    }, WithIdempotencyKey(func(ctx context.Context) string {
+        return "charge-" + Params(ctx)["order"]
+    }))
+
+    journal := &FileJournal{Path: filepath.Join(t.TempDir(), "journal.jsonl")}
+    defer journal.Close()
+    executor := NewExecutor(graph)
+    executor.SetJournal(journal)
+
+    for _, order := range []string{"1", "1", "2"} {
+        run := executor.NewRun()
+        run.SetParams(map[string]string{"order": order})
+        if err := run.Execute(); err != nil {
+            t.Fatalf("Execute failed: %v", err)
+        }
+        if run.Report().Nodes["charge"].State != StateSucceeded {
+            t.Errorf("expected charge to be reported as succeeded")
+        }
+    }
+
+    if charges.Load() != 2 {
+        t.Errorf("expected one charge per order, got %d", charges.Load())
+    }
+    if gotKey != "charge-2" {
+        t.Errorf("expected the task to see its key, got %q", gotKey)
+    }
+
+    // A new executor, as after a restart, reads the committed keys from the
+    // journal.
+    restarted := NewExecutor(graph)
+    restarted.SetJournal(journal)
+    run := restarted.NewRun()
+    run.SetParams(map[string]string{"order": "2"})
+    run.Execute()
+    if charges.Load() != 2 {
+        t.Errorf("expected the committed order not to be charged again")
+    }
+}
@@ -0,0 +1,98 @@
+package leo
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// Instantiate merges a copy of template into g for each of ids, namespaced
+// by the id as with Merge and Namespace, so that per-device or per-site
+// copies of the same pipeline coexist in one graph: instantiating a template
+// with a "flash" node for "dev1" and "dev2" adds "dev1/flash" and
+// "dev2/flash". The copies share the template's tasks, which tell the
+// instances apart with Instance. Use InstanceNode to connect the copies to
+// the rest of g.
+//
+// Either every instance is added or none is: a repeated or empty id, or a
+// name that collides with a node of g, is an error, wrapping ErrNodeExists
+// for a collision.
+func (g *Graph) Instantiate(template *Graph, ids ...string) error {
+    if template == g {
+        return errors.New("cannot instantiate a graph into itself")
+    }
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    template.mu.RLock()
+    defer template.mu.RUnlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    seen := make(map[string]bool, len(ids))
+    for _, id := range ids {
+        if id == "" {
+            return errors.New("instance id is empty")
+        }
+        if seen[id] {
+            return fmt.Errorf("instance %s is repeated", id)
+        }
+        seen[id] = true
+        if name := g.mergeConflict(template, id); name != "" {
+            return fmt.Errorf("instantiating %s: %w", name, ErrNodeExists)
+        }
+    }
+    for _, id := range ids {
+        g.merge(template, id)
+    }
+    return nil
+}
+
+// InstanceNode returns the name of the copy of the template node name in
+// the instance id, see Instantiate.
+func InstanceNode(id, name string) string {
+    return id + "/" + name
+}
+
+// Instance returns the instance of the node whose task received ctx, or ""
+// if the node was not added by Instantiate or merged under a Namespace.
+// Instances nest: a node of instance "dev1" merged under "siteA" belongs to
+// "siteA/dev1".
+func Instance(ctx context.Context) string {
+    if _, n := runNode(ctx); n != nil {
+        return n.instance
+    }
+    return ""
+}
+
+// Instances returns the sorted ids of the instances in g.
+func (g *Graph) Instances() []string {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    seen := make(map[string]bool)
+    var ids []string
+    for _, n := range g.nodes {
+        if n.instance != "" && !seen[n.instance] {
+            seen[n.instance] = true
+            ids = append(ids, n.instance)
+        }
+    }
+    sort.Strings(ids)
+    return ids
+}
+
+// InstanceNodes returns the sorted names of the nodes of the instance id,
+// including those of instances nested in it.
+func (g *Graph) InstanceNodes(id string) []string {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    var names []string
+    for name, n := range g.nodes {
+        if n.instance == id || strings.HasPrefix(n.instance, id+"/") {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
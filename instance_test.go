@@ -0,0 +1,71 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestInstantiate(t *testing.T) {
+    var mu sync.Mutex
+    var flashed []string
+    template := TaskGraph()
+    template.Add("build", nil)
+    template.AddCtx("flash", func(ctx context.Context) error {
+        mu.Lock()
+        flashed = append(flashed, Instance(ctx))
+        mu.Unlock()
+        return nil
+    })
+    template.Precede("build", "flash")
+
+    graph := TaskGraph()
+    graph.Add("report", nil)
+    if err := graph.Instantiate(template, "dev1", "dev2"); err != nil {
+        t.Fatalf("Instantiate failed: %v", err)
+    }
+    for _, id := range graph.Instances() {
+        graph.Precede(InstanceNode(id, "flash"), "report")
+    }
+    if got := graph.InstanceNodes("dev2"); fmt.Sprint(got) != "[dev2/build dev2/flash]" {
+        t.Errorf("unexpected nodes of dev2: %v", got)
+    }
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    sort.Strings(flashed)
+    if fmt.Sprint(flashed) != "[dev1 dev2]" {
+        t.Errorf("expected each instance to see its id, got %v", flashed)
+    }
+
+    site := TaskGraph()
+    if err := site.Merge(graph, Namespace("siteA")); err != nil {
+        t.Fatalf("Merge failed: %v", err)
+    }
+    if got := site.Instances(); fmt.Sprint(got) != "[siteA siteA/dev1 siteA/dev2]" {
+        t.Errorf("expected nested instances, got %v", got)
+    }
+    if got := site.InstanceNodes("siteA/dev1"); fmt.Sprint(got) != "[siteA/dev1/build siteA/dev1/flash]" {
+        t.Errorf("unexpected nodes of siteA/dev1: %v", got)
+    }
+}
+
+func TestInstantiateIsAllOrNothing(t *testing.T) {
+    template := TaskGraph()
+    template.Add("flash", nil)
+    graph := TaskGraph()
+    graph.Add("dev2/flash", nil)
+
+    if err := graph.Instantiate(template, "dev1", "dev2"); !errors.Is(err, ErrNodeExists) {
+        t.Errorf("expected ErrNodeExists, got %v", err)
+    }
+    if err := graph.Instantiate(template, "dev1", "dev1"); err == nil {
+        t.Errorf("expected a repeated id to fail")
+    }
+    if len(graph.nodes) != 1 {
+        t.Errorf("expected the failed calls to add nothing, got %d nodes", len(graph.nodes))
+    }
+}
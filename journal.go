@@ -0,0 +1,236 @@
+package leo
+
+import (
+    "bufio"
+    "bytes"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "sync"
+    "time"
+)
+
+// Journal is a write-ahead log of run and node state transitions. With a
+// journal set, the executor appends each transition before acting on it, so
+// that a run interrupted by a crash can be resumed with Recover.
+type Journal interface {
+    // Append durably records an entry.
+    Append(entry JournalEntry) error
+    // Entries returns every entry, oldest first.
+    Entries() ([]JournalEntry, error)
+}
+
+// Journal entry states.
+const (
+    JournalRunStarted  = "run_started"
+    JournalRunFinished = "run_finished"
+    JournalStarted     = "started"
+    JournalSucceeded   = "succeeded"
+    JournalFailed      = "failed"
+    JournalSkipped     = "skipped"
+)
+
+// JournalEntry is a single state transition. Node is empty for run
+// transitions.
+type JournalEntry struct {
+    Run   string    `json:"run"`
+    Time  time.Time `json:"time"`
+    Node  string    `json:"node,omitempty"`
+    State string    `json:"state"`
+    Err   string    `json:"error,omitempty"`
+    // Key is the node's idempotency key, see WithIdempotencyKey.
+    Key string `json:"key,omitempty"`
+}
+
+// SetJournal sets the journal runs record their transitions in.
+func (e *Executor) SetJournal(j Journal) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.journal = j
+}
+
+func (e *Executor) getJournal() Journal {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.journal
+}
+
+// ID returns the run's identifier, which is unique to the run and kept when
+// it is resumed with Recover.
+func (r *Run) ID() string {
+    return r.id
+}
+
+func newRunID() string {
+    b := make([]byte, 8)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
+
+// journal appends an entry for the run, if the executor has a journal.
+// Reverse runs are not journaled, since Recover resumes forward runs only.
+func (r *Run) journal(node, state string, err error) error {
+    j := r.executor.getJournal()
+    if j == nil || r.reverse {
+        return nil
+    }
+    entry := JournalEntry{Run: r.id, Time: r.executor.now(), Node: node, State: state}
+    if node != "" {
+        entry.Key = r.key(node)
+    }
+    if err != nil {
+        entry.Err = r.redact(err.Error())
+    }
+    if err := j.Append(entry); err != nil {
+        return fmt.Errorf("journal: %w", err)
+    }
+    return nil
+}
+
+// Recover returns a run that resumes the most recent run in j that did not
+// finish, or nil if there is none. Nodes the journal records as succeeded
+// are not run again; nodes that had started but not finished are, so tasks
+// must tolerate being repeated. Tasks marked Idempotent(false) are only
+// rerun if the executor confirms it, see SetConfirm. The results of
+// functions added with AddFunc are not journaled, so consumers of recovered
+// nodes should not rely on them. Execute the returned run with e's journal
+// set to keep journaling it.
+func Recover(j Journal, e *Executor) (*Run, error) {
+    entries, err := j.Entries()
+    if err != nil {
+        return nil, err
+    }
+
+    var last string
+    finished := make(map[string]bool)
+    for _, entry := range entries {
+        switch entry.State {
+        case JournalRunStarted:
+            last = entry.Run
+        case JournalRunFinished:
+            finished[entry.Run] = true
+        }
+    }
+    if last == "" || finished[last] {
+        return nil, nil
+    }
+
+    r := e.NewRun()
+    r.id = last
+    r.completed = make(map[*Node]bool)
+    r.inDoubt = make(map[*Node]bool)
+    for _, entry := range entries {
+        if entry.Run != last || entry.Node == "" {
+            continue
+        }
+        node, ok := e.graph.nodes[entry.Node]
+        if !ok {
+            return nil, fmt.Errorf("journal: run %s refers to unknown node %s", last, entry.Node)
+        }
+        switch entry.State {
+        case JournalSucceeded:
+            r.completed[node] = true
+            delete(r.inDoubt, node)
+        case JournalStarted, JournalFailed:
+            delete(r.completed, node)
+            r.inDoubt[node] = true
+        }
+    }
+    return r, nil
+}
+
+// FileJournal is a Journal stored in a file as JSON lines. Each entry is
+// synced to disk before Append returns.
+type FileJournal struct {
+    Path string
+
+    mu sync.Mutex
+    f  *os.File
+}
+
+func (j *FileJournal) Append(entry JournalEntry) error {
+    data, err := json.Marshal(entry)
+    if err != nil {
+        return err
+    }
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    if j.f == nil {
+        if err := truncatePartial(j.Path); err != nil {
+            return err
+        }
+        f, err := os.OpenFile(j.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+        if err != nil {
+            return err
+        }
+        j.f = f
+    }
+    if _, err := j.f.Write(append(data, '\n')); err != nil {
+        return err
+    }
+    return j.f.Sync()
+}
+
+func (j *FileJournal) Entries() ([]JournalEntry, error) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    f, err := os.Open(j.Path)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    var entries []JournalEntry
+    var partial error
+    scanner := bufio.NewScanner(f)
+    for line := 1; scanner.Scan(); line++ {
+        if partial != nil {
+            return nil, partial
+        }
+        var entry JournalEntry
+        if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+            // A crash can leave a partially written last line, which was
+            // never acted on and is ignored. Anywhere else it is an error.
+            partial = fmt.Errorf("%s:%d: %w", j.Path, line, err)
+            continue
+        }
+        entries = append(entries, entry)
+    }
+    return entries, scanner.Err()
+}
+
+// truncatePartial removes a partially written last line left by a crash, so
+// that new entries are not appended to it.
+func truncatePartial(path string) error {
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    if len(data) == 0 || data[len(data)-1] == '\n' {
+        return nil
+    }
+    return os.Truncate(path, int64(bytes.LastIndexByte(data, '\n')+1))
+}
+
+// Close closes the journal file.
+func (j *FileJournal) Close() error {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    if j.f == nil {
+        return nil
+    }
+    err := j.f.Close()
+    j.f = nil
+    return err
+}
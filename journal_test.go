@@ -0,0 +1,110 @@
+package leo
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil })
+    graph.Add("B", func() error { return nil })
+    graph.Precede("A", "B")
+
+    journal := &FileJournal{Path: filepath.Join(t.TempDir(), "journal.jsonl")}
+    defer journal.Close()
+    executor := NewExecutor(graph)
+    executor.SetJournal(journal)
+
+    run := executor.NewRun()
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    entries, err := journal.Entries()
+    if err != nil {
+        t.Fatalf("Entries failed: %v", err)
+    }
+    var got []string
+    for _, e := range entries {
+        if e.Run != run.ID() {
+            t.Errorf("entry %+v has the wrong run", e)
+        }
+        got = append(got, e.Node+":"+e.State)
+    }
+    want := []string{":run_started", "A:started", "A:succeeded", "B:started", "B:succeeded", ":run_finished"}
+    if len(got) != len(want) {
+        t.Fatalf("expected entries %v, got %v", want, got)
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Errorf("entry %d: expected %s, got %s", i, want[i], got[i])
+        }
+    }
+
+    if r, err := Recover(journal, executor); r != nil || err != nil {
+        t.Errorf("expected nothing to recover after a finished run, got %v, %v", r, err)
+    }
+}
+
+func TestRecover(t *testing.T) {
+    var a, b, c atomic.Int32
+    graph := TaskGraph()
+    graph.Add("A", func() error { a.Add(1); return nil })
+    graph.Add("B", func() error { b.Add(1); return nil })
+    graph.Add("C", func() error { c.Add(1); return nil })
+    graph.Precede("A", "B")
+    graph.Precede("B", "C")
+
+    // A journal left by a process that crashed while B was running, ending
+    // in a partially written entry.
+    path := filepath.Join(t.TempDir(), "journal.jsonl")
+    journal := &FileJournal{Path: path}
+    now := time.Now()
+    for _, e := range []JournalEntry{
+        {Run: "old", Time: now, State: JournalRunStarted},
+        {Run: "old", Time: now, State: JournalRunFinished},
+        {Run: "crashed", Time: now, State: JournalRunStarted},
+        {Run: "crashed", Time: now, Node: "A", State: JournalStarted},
+        {Run: "crashed", Time: now, Node: "A", State: JournalSucceeded},
+        {Run: "crashed", Time: now, Node: "B", State: JournalStarted},
+    } {
+        journal.Append(e)
+    }
+    journal.Close()
+    f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+    f.WriteString(`{"run": "crashed", "node": "B", "sta`)
+    f.Close()
+
+    // The restarted process opens the journal afresh.
+    journal = &FileJournal{Path: path}
+    defer journal.Close()
+
+    executor := NewExecutor(graph)
+    executor.SetJournal(journal)
+    run, err := Recover(journal, executor)
+    if err != nil || run == nil {
+        t.Fatalf("Recover failed: %v, %v", run, err)
+    }
+    if run.ID() != "crashed" {
+        t.Errorf("expected the crashed run to be resumed, got %s", run.ID())
+    }
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if a.Load() != 0 || b.Load() != 1 || c.Load() != 1 {
+        t.Errorf("expected only B and C to run, got A=%d B=%d C=%d", a.Load(), b.Load(), c.Load())
+    }
+    if run.Report().Nodes["A"].State != StateSucceeded {
+        t.Errorf("expected A to be reported as succeeded")
+    }
+
+    // The partial entry was dropped before the resumed run appended to the
+    // journal, so the journal is still readable and the run is finished.
+    if r, err := Recover(journal, executor); r != nil || err != nil {
+        t.Errorf("expected nothing left to recover, got %v, %v", r, err)
+    }
+}
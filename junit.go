@@ -0,0 +1,150 @@
+package leo
+
+import (
+    "encoding/xml"
+    "fmt"
+    "io"
+    "sort"
+)
+
+// TestSuiteGraph returns a graph for an integration-test run: setup runs
+// first, then every suite in parallel, then teardown, which runs even if
+// setup or a suite fails. The nodes are named "setup", "teardown" and after
+// the suites; setup and teardown may be nil. Run the graph with the
+// FailContinue failure mode so that one failing suite does not stop the
+// others, and write the outcome with WriteJUnit:
+//
+//    graph, err := leo.TestSuiteGraph(startDB, suites, stopDB)
+//    executor := leo.NewExecutor(graph, leo.WithFailureMode(leo.FailContinue))
+//    err = executor.Execute()
+//    leo.WriteJUnit(f, executor.Report())
+func TestSuiteGraph(setup TaskCtxFunc, suites map[string]TaskCtxFunc, teardown TaskCtxFunc) (*Graph, error) {
+    graph := TaskGraph()
+    for name, suite := range suites {
+        if name == "setup" || name == "teardown" {
+            return nil, fmt.Errorf("suite name %s is reserved", name)
+        }
+        graph.AddCtx(name, suite)
+    }
+    if setup != nil {
+        graph.AddCtx("setup", setup)
+    }
+    if teardown != nil {
+        graph.AddCtx("teardown", teardown)
+    }
+    for name := range suites {
+        if setup != nil {
+            graph.Precede("setup", name)
+        }
+        if teardown != nil {
+            graph.Precede(name, "teardown", OnParentFailure(EdgeRelease))
+        }
+    }
+    if setup != nil && teardown != nil {
+        graph.Precede("setup", "teardown", OnParentFailure(EdgeRelease))
+    }
+    return graph, nil
+}
+
+type junitSuites struct {
+    XMLName  xml.Name     `xml:"testsuites"`
+    Name     string       `xml:"name,attr,omitempty"`
+    Tests    int          `xml:"tests,attr"`
+    Failures int          `xml:"failures,attr"`
+    Skipped  int          `xml:"skipped,attr"`
+    Time     string       `xml:"time,attr"`
+    Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+    Name      string      `xml:"name,attr"`
+    ID        string      `xml:"id,attr,omitempty"`
+    Tests     int         `xml:"tests,attr"`
+    Failures  int         `xml:"failures,attr"`
+    Skipped   int         `xml:"skipped,attr"`
+    Time      string      `xml:"time,attr"`
+    Timestamp string      `xml:"timestamp,attr"`
+    Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+    Name      string        `xml:"name,attr"`
+    ClassName string        `xml:"classname,attr"`
+    Time      string        `xml:"time,attr"`
+    Failure   *junitMessage `xml:"failure"`
+    Skipped   *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+    Message string `xml:"message,attr,omitempty"`
+    Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes rep as a JUnit XML report, as read by CI systems, with a
+// test case per node: failed nodes are failures, and skipped nodes and
+// nodes that never ran are skipped. Test cases are sorted by name and
+// belong to a single test suite named after the run, or "leo" if it has no
+// name.
+func WriteJUnit(w io.Writer, rep *Report) error {
+    rep.mu.Lock()
+    name := rep.Name
+    if name == "" {
+        name = "leo"
+    }
+    suite := junitSuite{
+        Name:      name,
+        ID:        rep.ID,
+        Time:      junitSeconds(rep.Duration.Seconds()),
+        Timestamp: rep.Start.UTC().Format("2006-01-02T15:04:05"),
+    }
+    names := make([]string, 0, len(rep.Nodes))
+    for n := range rep.Nodes {
+        names = append(names, n)
+    }
+    sort.Strings(names)
+    for _, n := range names {
+        nr := rep.Nodes[n]
+        tc := junitCase{Name: n, ClassName: name, Time: junitSeconds(nr.Duration.Seconds())}
+        switch nr.State {
+        case StateFailed:
+            msg := ""
+            if nr.Err != nil {
+                msg = nr.Err.Error()
+            }
+            tc.Failure = &junitMessage{Message: msg, Text: msg}
+            suite.Failures++
+        case StateSkipped:
+            tc.Skipped = &junitMessage{Message: nr.SkipReason}
+            suite.Skipped++
+        case StatePending:
+            tc.Skipped = &junitMessage{Message: "did not run"}
+            suite.Skipped++
+        }
+        suite.Cases = append(suite.Cases, tc)
+    }
+    suite.Tests = len(suite.Cases)
+    rep.mu.Unlock()
+
+    doc := junitSuites{
+        Name:     name,
+        Tests:    suite.Tests,
+        Failures: suite.Failures,
+        Skipped:  suite.Skipped,
+        Time:     suite.Time,
+        Suites:   []junitSuite{suite},
+    }
+    if _, err := io.WriteString(w, xml.Header); err != nil {
+        return err
+    }
+    enc := xml.NewEncoder(w)
+    enc.Indent("", "  ")
+    if err := enc.Encode(doc); err != nil {
+        return err
+    }
+    _, err := io.WriteString(w, "\n")
+    return err
+}
+
+func junitSeconds(s float64) string {
+    return fmt.Sprintf("%.3f", s)
+}
@@ -0,0 +1,75 @@
+package leo
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTestSuiteGraph(t *testing.T) {
+    var tornDown atomic.Bool
+    ok := func(context.Context) error { return nil }
+    graph, err := TestSuiteGraph(ok, map[string]TaskCtxFunc{
+        "api":     ok,
+        "billing": func(context.Context) error { return errors.New("expected 200, got 500") },
+        "search":  ok,
+    }, func(context.Context) error {
+        tornDown.Store(true)
+        return nil
+    })
+    if err != nil {
+        t.Fatalf("TestSuiteGraph failed: %v", err)
+    }
+
+    // With one suite at a time, suites still queued when billing fails
+    // must run too.
+    executor := NewExecutor(graph, WithConcurrency(1), WithFailureMode(FailContinue))
+    run := executor.NewRun()
+    run.SetName("integration")
+    if err := run.Execute(); err == nil {
+        t.Fatalf("expected the failing suite to fail the run")
+    }
+    if !tornDown.Load() {
+        t.Errorf("expected teardown to run after a suite failed")
+    }
+
+    var buf bytes.Buffer
+    if err := WriteJUnit(&buf, run.Report()); err != nil {
+        t.Fatalf("WriteJUnit failed: %v", err)
+    }
+    var doc struct {
+        Tests    int `xml:"tests,attr"`
+        Failures int `xml:"failures,attr"`
+        Suites   []struct {
+            Name  string `xml:"name,attr"`
+            Cases []struct {
+                Name    string `xml:"name,attr"`
+                Failure *struct {
+                    Message string `xml:"message,attr"`
+                } `xml:"failure"`
+            } `xml:"testcase"`
+        } `xml:"testsuite"`
+    }
+    if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+        t.Fatalf("parsing report: %v\n%s", err, buf.String())
+    }
+    if doc.Tests != 5 || doc.Failures != 1 || len(doc.Suites) != 1 || doc.Suites[0].Name != "integration" {
+        t.Fatalf("unexpected report:\n%s", buf.String())
+    }
+    for _, c := range doc.Suites[0].Cases {
+        if (c.Failure != nil) != (c.Name == "billing") {
+            t.Errorf("unexpected outcome for %s:\n%s", c.Name, buf.String())
+        }
+    }
+    if !strings.Contains(buf.String(), "expected 200, got 500") {
+        t.Errorf("expected the failure message in the report:\n%s", buf.String())
+    }
+
+    if _, err := TestSuiteGraph(nil, map[string]TaskCtxFunc{"setup": ok}, nil); err == nil {
+        t.Errorf("expected a suite named setup to be rejected")
+    }
+}
@@ -0,0 +1,29 @@
+package leo
+
+// SetName names the run, for example after the pipeline or the trigger that
+// started it. The name is included in the run's report and history record.
+func (r *Run) SetName(name string) {
+    r.name = name
+}
+
+// Name returns the name set with SetName.
+func (r *Run) Name() string {
+    return r.name
+}
+
+// SetLabels attaches metadata to the run, such as who triggered it, the git
+// commit or the target environment. Labels are included in the run's report
+// and history record, and subscribers can read them from Event.Run. Call it
+// before the run starts.
+func (r *Run) SetLabels(labels map[string]string) {
+    r.labels = make(map[string]string, len(labels))
+    for k, v := range labels {
+        r.labels[k] = v
+    }
+}
+
+// Labels returns the labels set with SetLabels. The returned map must not be
+// modified.
+func (r *Run) Labels() map[string]string {
+    return r.labels
+}
@@ -0,0 +1,38 @@
+package leo
+
+import "testing"
+
+func TestRunLabels(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil })
+
+    executor := NewExecutor(graph)
+    history := NewMemoryHistory(0)
+    executor.SetHistory(history)
+    prod := executor.SubscribeFilter(EventFilter{Labels: map[string]string{"env": "prod"}, Types: []EventType{EventRunFinished}})
+    defer prod.Close()
+
+    staging := executor.NewRun()
+    staging.SetLabels(map[string]string{"env": "staging"})
+    staging.Execute()
+
+    labels := map[string]string{"env": "prod", "git_sha": "abc123"}
+    run := executor.NewRun()
+    run.SetName("nightly")
+    run.SetLabels(labels)
+    labels["env"] = "changed"
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    if ev := <-prod.C; ev.Run != run || ev.Run.Name() != "nightly" {
+        t.Errorf("expected only the prod run's event, got %v from %q", ev, ev.Run.Name())
+    }
+    if r := run.Report(); r.Name != "nightly" || r.Labels["env"] != "prod" {
+        t.Errorf("unexpected report name %q and labels %v", r.Name, r.Labels)
+    }
+    runs, _ := history.Runs(1)
+    if runs[0].Name != "nightly" || runs[0].Labels["git_sha"] != "abc123" {
+        t.Errorf("unexpected history record %+v", runs[0])
+    }
+}
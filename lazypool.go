@@ -0,0 +1,92 @@
+package leo
+
+import (
+    "fmt"
+    "time"
+)
+
+// NewLazyWorkerPool returns a pool that starts with no workers and adds
+// workers like template when a task is ready and no idle worker can run it,
+// up to size, so that a long-lived service whose pipelines run sporadically
+// holds no workers between runs. Workers are named after template, or
+// "worker", and a number, starting from 1. An idle worker keeps its goroutine
+// to run the next task it is given, and after idleTimeout without one
+// retires, and its goroutine exits; 0 retires it as soon as it is idle. A size below 1
+// allows one worker.
+//
+// Tasks whose requirements the template does not satisfy cannot run on the
+// pool, see RequireWorker.
+func NewLazyWorkerPool(size int, idleTimeout time.Duration, template Worker) *WorkerPool {
+    p := NewWorkerPool()
+    p.max = max(size, 1)
+    p.idleTimeout = idleTimeout
+    p.template = &template
+    p.waiting = make(map[*Worker]chan *job)
+    return p
+}
+
+// spawn adds a worker for j if the pool is lazy, has room and its template
+// can run j, and returns it, or nil. The caller must hold p.mu.
+func (p *WorkerPool) spawn(j *job) *Worker {
+    if p.template == nil || len(p.workers) >= p.max || !p.template.matches(j.node.requires) {
+        return nil
+    }
+    p.spawned++
+    prefix := p.template.Name
+    if prefix == "" {
+        prefix = "worker"
+    }
+    w := &Worker{Name: fmt.Sprintf("%s-%d", prefix, p.spawned), Labels: p.template.Labels}
+    p.add(w)
+    return w
+}
+
+// idled returns the channel on which w, which has just become idle, waits
+// for its next job if the pool is lazy, or nil if its goroutine exits. The
+// caller must hold p.mu.
+func (p *WorkerPool) idled(w *Worker) chan *job {
+    if p.template == nil {
+        return nil
+    }
+    if p.idleTimeout <= 0 {
+        p.retire(w)
+        return nil
+    }
+    ch := make(chan *job, 1)
+    p.waiting[w] = ch
+    return ch
+}
+
+// await waits for w's next job on ch, the channel idled returned for it. If
+// none comes within the pool's idle timeout, w retires and await returns nil.
+func (p *WorkerPool) await(w *Worker, ch chan *job) *job {
+    t := time.NewTimer(p.idleTimeout)
+    defer t.Stop()
+    select {
+    case j := <-ch:
+        return j
+    case <-t.C:
+    }
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if p.waiting[w] != ch {
+        // The worker was given a job as the timer fired.
+        return <-ch
+    }
+    p.retire(w)
+    return nil
+}
+
+// retire removes w, which is idle, from the pool. The caller must hold p.mu.
+func (p *WorkerPool) retire(w *Worker) {
+    for i, other := range p.workers {
+        if other == w {
+            p.workers = append(p.workers[:i], p.workers[i+1:]...)
+            break
+        }
+    }
+    delete(p.idle, w)
+    delete(p.busy, w)
+    delete(p.joined, w)
+    delete(p.waiting, w)
+}
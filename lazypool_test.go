@@ -0,0 +1,130 @@
+package leo
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForWorkers waits until p has n workers.
+func waitForWorkers(t *testing.T, p *WorkerPool, n int) []Worker {
+    t.Helper()
+    deadline := time.Now().Add(2 * time.Second)
+    for {
+        ws := p.Workers()
+        if len(ws) == n {
+            return ws
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("expected %d workers, got %v", n, ws)
+        }
+        time.Sleep(time.Millisecond)
+    }
+}
+
+func TestLazyWorkerPool(t *testing.T) {
+    pool := NewLazyWorkerPool(2, 50*time.Millisecond, Worker{Name: "builder", Labels: map[string]string{"site": "syd"}})
+    if ws := pool.Workers(); len(ws) != 0 {
+        t.Fatalf("expected no workers before the first run, got %v", ws)
+    }
+
+    var mu sync.Mutex
+    running, peak := 0, 0
+    graph := TaskGraph()
+    for i := 0; i < 4; i++ {
+        graph.Add(fmt.Sprintf("task%d", i), func() error {
+            mu.Lock()
+            running++
+            peak = max(peak, running)
+            mu.Unlock()
+            time.Sleep(5 * time.Millisecond)
+            mu.Lock()
+            running--
+            mu.Unlock()
+            return nil
+        }, RequireWorker("site=syd"))
+    }
+    executor := NewExecutor(graph, WithWorkerPool(pool))
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if peak != 2 {
+        t.Errorf("expected two tasks at once, got %d", peak)
+    }
+    ws := waitForWorkers(t, pool, 2)
+    if ws[0].Name != "builder-1" || ws[1].Name != "builder-2" || ws[0].Labels["site"] != "syd" {
+        t.Errorf("expected workers like the template, got %v", ws)
+    }
+
+    // A second run within the idle timeout reuses the workers.
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    for _, w := range waitForWorkers(t, pool, 2) {
+        if w.Name != "builder-1" && w.Name != "builder-2" {
+            t.Errorf("expected the workers to be reused, got %s", w.Name)
+        }
+    }
+    waitForWorkers(t, pool, 0)
+}
+
+func TestLazyWorkerPoolRetiresImmediately(t *testing.T) {
+    pool := NewLazyWorkerPool(0, 0, Worker{})
+    graph := TaskGraph()
+    graph.Add("a", func() error { return nil })
+    graph.Add("b", func() error { return nil })
+    executor := NewExecutor(graph, WithWorkerPool(pool))
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    waitForWorkers(t, pool, 0)
+    if stats := pool.Stats(); stats.Queued != 0 || len(stats.Workers) != 0 {
+        t.Errorf("expected an empty pool, got %+v", stats)
+    }
+
+    graph.Add("gpu", func() error { return nil }, RequireWorker("has-gpu"))
+    if err := executor.Execute(); err == nil || !strings.Contains(err.Error(), "no worker matches has-gpu") {
+        t.Errorf("expected the template to be checked, got %v", err)
+    }
+}
+
+func TestLazyWorkerPoolGoroutines(t *testing.T) {
+    // waitForIdle waits until n goroutines of idle workers are waiting for
+    // a job.
+    waitForIdle := func(n int) {
+        t.Helper()
+        buf := make([]byte, 1<<20)
+        deadline := time.Now().Add(2 * time.Second)
+        for {
+            stacks := string(buf[:runtime.Stack(buf, true)])
+            got := strings.Count(stacks, "leo.(*WorkerPool).await(")
+            if got == n {
+                return
+            }
+            if time.Now().After(deadline) {
+                t.Fatalf("expected %d idle worker goroutines, got %d", n, got)
+            }
+            time.Sleep(time.Millisecond)
+        }
+    }
+
+    pool := NewLazyWorkerPool(1, 200*time.Millisecond, Worker{})
+    graph := TaskGraph()
+    graph.Add("a", func() error { return nil })
+    graph.Add("b", func() error { return nil })
+    graph.Precede("a", "b")
+    executor := NewExecutor(graph, WithWorkerPool(pool))
+    for i := 0; i < 2; i++ {
+        if err := executor.Execute(); err != nil {
+            t.Fatalf("Execute failed: %v", err)
+        }
+        // The idle worker keeps its goroutine for the next task.
+        waitForIdle(1)
+    }
+    // The goroutine exits when the worker retires.
+    waitForWorkers(t, pool, 0)
+    waitForIdle(0)
+}
@@ -1,23 +1,82 @@
 package leo
 
 import (
+    "context"
     "errors"
     "fmt"
+    "log/slog"
+    "reflect"
     "sync"
+    "time"
 )
 
 type TaskFunc func() error
 
+// TaskCtxFunc is a task that receives a context which is cancelled when the
+// execution is cancelled or the task's result is no longer needed.
+type TaskCtxFunc func(ctx context.Context) error
+
 type Node struct {
-    task     TaskFunc
+    task     TaskCtxFunc
     children []*Node
     parents  []*Node
     name     string
+    expected time.Duration
+    hedge    time.Duration
+    command  string
+    tags     []string
+    stage    string
+    instance string
+    group    string
+    disabled bool
+    unbound  bool
+    teardown TaskCtxFunc
+    cost     time.Duration
+    requires []string
+
+    inputFiles  []string
+    outputFiles []string
+
+    resources Resources
+
+    notIdempotent bool
+    retries       int
+    retryDelay    time.Duration
+    retryIf       RetryFunc
+
+    condition      func(ctx context.Context) (bool, error)
+    idempotencyKey func(ctx context.Context) string
+    cacheKey       func(ctx context.Context) string
+
+    fallbacks   []*Node
+    fallbackFor []*Node
+    edges       map[*Node]*edgeConfig
+
+    produces reflect.Type
+    consumes []reflect.Type
+    inputs   map[reflect.Type]*Node
 }
 
+// NodeOption configures a node when it is added to a graph.
+type NodeOption func(*Node)
+
+// Graph is a set of tasks and the dependencies between them. Its methods are
+// safe to call from multiple goroutines, so a graph can be built
+// concurrently, for example by discovery workers each adding the nodes they
+// find. Executors and functions such as Diff and the exporters read the graph
+// without locking: finish building it before running or exporting it, or
+// call Freeze to make sure it is no longer modified.
 type Graph struct {
+    mu         sync.RWMutex
     nodes      map[string]*Node
     startNodes []*Node
+    stages     []*stage
+    plan       *Plan
+    duplicates DuplicatePolicy
+    autoCreate bool
+    aliases    map[string]string
+
+    transactions map[string]*Transaction
 }
 
 func TaskGraph() *Graph {
@@ -26,7 +85,67 @@ func TaskGraph() *Graph {
     }
 }
 
-func (g *Graph) Add(name string, task TaskFunc) {
+// Add adds a task to the graph and reports what it did. Adding a node whose
+// name already exists is handled according to the graph's DuplicatePolicy.
+// It returns ErrFrozen if the graph is frozen. A nil task adds a placeholder
+// node that succeeds without doing anything; Lint reports such nodes.
+func (g *Graph) Add(name string, task TaskFunc, opts ...NodeOption) (AddResult, error) {
+    if task == nil {
+        return g.AddCtx(name, nil, opts...)
+    }
+    return g.AddCtx(name, func(context.Context) error { return task() }, opts...)
+}
+
+// AddCtx adds a context-aware task to the graph, like Add.
+func (g *Graph) AddCtx(name string, task TaskCtxFunc, opts ...NodeOption) (AddResult, error) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return NodeRejected, ErrFrozen
+    }
+    if n, exists := g.lookup(name); exists {
+        if n.unbound {
+            n.bind(task, opts)
+            return NodeAdded, nil
+        }
+        switch g.duplicates {
+        case DuplicateError:
+            return NodeRejected, fmt.Errorf("node %s: %w", name, ErrNodeExists)
+        case DuplicateReplace:
+            n.task = task
+            n.command = ""
+            for _, opt := range opts {
+                opt(n)
+            }
+            return NodeReplaced, nil
+        }
+        return NodeKept, nil
+    }
+    g.addNode(name, task, opts)
+    return NodeAdded, nil
+}
+
+// Bind sets the task of an existing node, such as a placeholder added by
+// ImportDOT or by Precede in auto-create mode, keeping its edges and
+// settings. It returns an error if the node
+// does not exist, and ErrFrozen if the graph is frozen.
+func (g *Graph) Bind(name string, task TaskCtxFunc) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    n, exists := g.lookup(name)
+    if !exists {
+        return fmt.Errorf("node %s does not exist", name)
+    }
+    n.bind(task, nil)
+    return nil
+}
+
+// addNode adds a node unless one with the same name exists. The caller must
+// hold g.mu.
+func (g *Graph) addNode(name string, task TaskCtxFunc, opts []NodeOption) {
     if _, exists := g.nodes[name]; !exists {
         g.nodes[name] = &Node{
             task:     task,
@@ -34,123 +153,160 @@ func (g *Graph) Add(name string, task TaskFunc) {
             parents:  make([]*Node, 0),
             name:     name,
         }
+        for _, opt := range opts {
+            opt(g.nodes[name])
+        }
         g.startNodes = append(g.startNodes, g.nodes[name])
     }
 }
 
-// Precede adds a directed edge from node `from` to node `to`
-func (g *Graph) Precede(from, to string) error {
-    fromNode, fromExists := g.nodes[from]
-    toNode, toExists := g.nodes[to]
+// Precede adds a directed edge from node `from` to node `to`. If the edge
+// would create a cycle it is not added and a *CycleError is returned. Nodes
+// that do not exist are created as placeholders if the graph is in
+// auto-create mode, see SetAutoCreate.
+func (g *Graph) Precede(from, to string, opts ...EdgeOption) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    return g.precede(from, to, opts)
+}
+
+// precede adds an edge. The caller must hold g.mu.
+func (g *Graph) precede(from, to string, opts []EdgeOption) error {
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    fromNode, fromExists := g.lookup(from)
+    toNode, toExists := g.lookup(to)
 
+    if g.autoCreate && (!fromExists || !toExists) {
+        // An edge to or from a new node can only close a cycle onto itself.
+        if from == to {
+            return &CycleError{Path: []string{from, from}}
+        }
+        fromNode, toNode = g.placeholder(from), g.placeholder(to)
+        fromExists, toExists = true, true
+    }
     if !fromExists || !toExists {
         return errors.New("one or both nodes do not exist")
     }
 
+    if cycle := g.cycleThrough(fromNode, toNode); cycle != nil {
+        return &CycleError{Path: cycle}
+    }
+
     fromNode.children = append(fromNode.children, toNode)
     toNode.parents = append(toNode.parents, fromNode)
 
-    if g.hasCycle() {
-        fromNode.children = fromNode.children[:len(fromNode.children)-1]
-        toNode.parents = toNode.parents[:len(toNode.parents)-1]
-        return errors.New("adding this edge would create a cycle")
+    if len(opts) > 0 {
+        if fromNode.edges == nil {
+            fromNode.edges = make(map[*Node]*edgeConfig)
+        }
+        ed := &edgeConfig{}
+        for _, opt := range opts {
+            opt(ed)
+        }
+        fromNode.edges[toNode] = ed
     }
 
     return nil
 }
 
 // Succeed sets up a "succeeds" relationship, indicating that `to` should succeed `from`.
-func (g *Graph) Succeed(from, to string) error {
-    return g.Precede(to, from)
+func (g *Graph) Succeed(from, to string, opts ...EdgeOption) error {
+    return g.Precede(to, from, opts...)
 }
 
 type Executor struct {
-    graph *Graph
+    graph        *Graph
+    plan         *Plan
+    hooks        Hooks
+    middleware   []Middleware
+    services     map[reflect.Type]any
+    events       eventBus
+    history      HistoryStore
+    journal      Journal
+    cache        Cache
+    mode         ExecutionMode
+    repeatPolicy RepeatPolicy
+    concurrency  int
+    ioBound      bool
+    scheduling   SchedulingPolicy
+    failureMode  FailureMode
+    pool         *WorkerPool
+    snapshotDir  string
+    stepper      StepFunc
+    breakpoints  []Breakpoint
+    seed         *int64
+    slowPct      float64
+    optionErr    error
+    confirm      ConfirmFunc
+    retryIf      RetryFunc
+    capacity     *capacity
+    adaptive     *aimd
+    clock        Clock
+    logger       *slog.Logger
+    mu           sync.Mutex
+    report       *Report
 }
 
-func NewExecutor(graph *Graph) *Executor {
-    parentCounts := make(map[*Node]int)
-    for _, node := range graph.nodes {
-        for _, child := range node.children {
-            parentCounts[child]++
-        }
-    }
-
-    for node, count := range parentCounts {
-        node.parents = make([]*Node, 0, count)
-    }
-
-    for _, node := range graph.nodes {
-        for _, child := range node.children {
-            child.parents = append(child.parents, node)
-        }
+// NewExecutor returns an executor for graph, configured by opts.
+func NewExecutor(graph *Graph, opts ...ExecutorOption) *Executor {
+    e := newExecutor(graph)
+    for _, opt := range opts {
+        opt(e)
     }
+    return e
+}
 
-    return &Executor{
-        graph: graph,
-    }
+// newExecutor returns an executor of graph. Edges keep every node's parents
+// and children in step, so the graph is used as it is and may be shared by
+// executors.
+func newExecutor(graph *Graph) *Executor {
+    return &Executor{graph: graph}
 }
 
 func (e *Executor) Execute() error {
-    var wg sync.WaitGroup
-    inDegree := make(map[*Node]int)
-    ready := make(chan *Node, len(e.graph.nodes))
-    errors := make(chan error, 1)
-    finished := make(chan struct{})
-
-    for _, node := range e.graph.nodes {
-        inDegree[node] = len(node.parents)
-        if inDegree[node] == 0 {
-            wg.Add(1)
-            go func(n *Node) {
-                ready <- n
-            }(node)
-        }
-    }
+    return e.ExecuteContext(context.Background())
+}
 
-    go func() {
-        wg.Wait()
-        close(finished)
-    }()
-
-    go func() {
-        for node := range ready {
-            go func(n *Node) {
-                defer wg.Done()
-                if err := n.task(); err != nil {
-                    select {
-                    case errors <- fmt.Errorf("error executing node %s: %w", n.name, err):
-                    default:
-                        // If an error is already recorded, we ignore subsequent errors
-                    }
-                    return
-                }
-
-                for _, child := range n.children {
-                    inDegree[child]--
-                    if inDegree[child] == 0 {
-                        wg.Add(1)
-                        ready <- child
-                    }
-                }
-            }(node)
-        }
-    }()
+// ExecuteContext executes the graph, passing ctx to context-aware tasks. It
+// returns ctx.Err() if ctx is cancelled before the graph completes.
+func (e *Executor) ExecuteContext(ctx context.Context) error {
+    return e.NewRun().ExecuteContext(ctx)
+}
 
-    select {
-    case <-finished:
+func (n *Node) run(ctx context.Context) error {
+    if n.task == nil {
         return nil
-    case err := <-errors:
-        return err
     }
+    return n.runRetried(ctx)
 }
 
-func (g Graph) Print() {
+// Report returns the report of the most recent call to Execute, or nil if the
+// executor has not run yet.
+func (e *Executor) Report() *Report {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.report
+}
+
+func (e *Executor) setReport(r *Report) {
+    e.mu.Lock()
+    e.report = r
+    e.mu.Unlock()
+}
+
+func (g *Graph) Print() {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
     for _, node := range g.nodes {
         fmt.Printf("%s -> ", node.name)
         for _, child := range node.children {
             fmt.Printf("%s, ", child.name)
         }
+        for _, fallback := range node.fallbacks {
+            fmt.Printf("%s (on failure), ", fallback.name)
+        }
         fmt.Println()
     }
 }
@@ -161,7 +317,7 @@ func (g *Graph) dfsCheckCycle(node *Node, visited, recStack map[*Node]bool) bool
         visited[node] = true
         recStack[node] = true
 
-        for _, child := range node.children {
+        for _, child := range node.successors() {
             if !visited[child] && g.dfsCheckCycle(child, visited, recStack) {
                 return true
             } else if recStack[child] {
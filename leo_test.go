@@ -18,6 +18,38 @@ func TestAddNode(t *testing.T) {
     }
 }
 
+func TestConcurrentConstruction(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("root", func() error { return nil })
+
+    var wg sync.WaitGroup
+    for w := 0; w < 8; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            for i := 0; i < 20; i++ {
+                name := fmt.Sprintf("worker%d-%d", w, i)
+                graph.Add(name, func() error { return nil }, WithTags(fmt.Sprintf("worker%d", w)))
+                if err := graph.Precede("root", name); err != nil {
+                    t.Errorf("Precede failed: %v", err)
+                }
+                graph.Edges()
+            }
+        }(w)
+    }
+    wg.Wait()
+
+    if n := len(graph.Edges()); n != 160 {
+        t.Errorf("expected 160 edges, got %d", n)
+    }
+    if n := len(graph.Tagged("worker3")); n != 20 {
+        t.Errorf("expected 20 nodes from worker3, got %d", n)
+    }
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+}
+
 func TestPrecede(t *testing.T) {
     graph := TaskGraph()
 
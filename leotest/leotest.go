@@ -0,0 +1,177 @@
+// Package leotest generates random task graphs for property-based tests of
+// leo executors and custom schedulers, and checks that a run respected the
+// graph's dependencies:
+//
+//    for seed := int64(0); seed < 100; seed++ {
+//        dag := leotest.Generate(rand.New(rand.NewSource(seed)), leotest.Shape{Nodes: 50})
+//        executor := leo.NewExecutor(dag.Graph)
+//        executor.SetConcurrency(4)
+//        executor.Execute()
+//        if err := dag.Check(); err != nil {
+//            t.Fatalf("seed %d: %v", seed, err)
+//        }
+//    }
+package leotest
+
+import (
+    "errors"
+    "fmt"
+    "math/rand"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/mips171/leo"
+)
+
+// ErrPlanned is returned by the tasks of a DAG that were generated to fail.
+var ErrPlanned = errors.New("planned failure")
+
+// Shape configures Generate. Zero fields take their defaults.
+type Shape struct {
+    // Nodes is the number of nodes, 20 by default.
+    Nodes int
+    // EdgeProbability is the probability of an edge between any two nodes,
+    // in the direction that keeps the graph acyclic. The default is 0.2.
+    EdgeProbability float64
+    // MaxParents caps the number of dependencies of a node; 0 means no cap.
+    MaxParents int
+    // FailureProbability is the probability that a node's task fails with
+    // ErrPlanned.
+    FailureProbability float64
+    // MaxDuration bounds how long each task sleeps, chosen at random for
+    // each node. The default of 0 makes tasks return immediately.
+    MaxDuration time.Duration
+}
+
+// DAG is a generated graph whose tasks record when they run.
+type DAG struct {
+    Graph *leo.Graph
+    // Names lists the nodes in the topological order they were generated
+    // in: every edge goes from an earlier node to a later one.
+    Names []string
+    // Parents lists each node's dependencies.
+    Parents map[string][]string
+    // Failing lists the nodes whose tasks fail.
+    Failing map[string]bool
+
+    mu         sync.Mutex
+    started    map[string]int
+    finished   map[string]bool
+    violations []string
+}
+
+// Generate returns a random DAG of the given shape, using rng for every
+// choice so that a seed reproduces it.
+func Generate(rng *rand.Rand, s Shape) *DAG {
+    if s.Nodes <= 0 {
+        s.Nodes = 20
+    }
+    if s.EdgeProbability == 0 {
+        s.EdgeProbability = 0.2
+    }
+
+    d := &DAG{
+        Graph:   leo.TaskGraph(),
+        Parents: make(map[string][]string),
+        Failing: make(map[string]bool),
+    }
+    d.Reset()
+    for i := 0; i < s.Nodes; i++ {
+        name := fmt.Sprintf("n%03d", i)
+        d.Names = append(d.Names, name)
+        fail := rng.Float64() < s.FailureProbability
+        var sleep time.Duration
+        if s.MaxDuration > 0 {
+            sleep = time.Duration(rng.Int63n(int64(s.MaxDuration)))
+        }
+        if fail {
+            d.Failing[name] = true
+        }
+        d.Graph.Add(name, d.task(name, sleep, fail))
+
+        for _, j := range rng.Perm(i) {
+            if s.MaxParents > 0 && len(d.Parents[name]) >= s.MaxParents {
+                break
+            }
+            if rng.Float64() < s.EdgeProbability {
+                parent := d.Names[j]
+                d.Graph.Precede(parent, name)
+                d.Parents[name] = append(d.Parents[name], parent)
+            }
+        }
+        sort.Strings(d.Parents[name])
+    }
+    return d
+}
+
+// task returns the task of the named node, which records that it ran and
+// whether its parents had finished.
+func (d *DAG) task(name string, sleep time.Duration, fail bool) leo.TaskFunc {
+    return func() error {
+        d.mu.Lock()
+        d.started[name]++
+        if d.started[name] > 1 {
+            d.violations = append(d.violations, fmt.Sprintf("%s ran %d times", name, d.started[name]))
+        }
+        for _, p := range d.Parents[name] {
+            if !d.finished[p] {
+                d.violations = append(d.violations, fmt.Sprintf("%s started before its parent %s succeeded", name, p))
+            }
+        }
+        d.mu.Unlock()
+
+        time.Sleep(sleep)
+
+        d.mu.Lock()
+        defer d.mu.Unlock()
+        if fail {
+            return ErrPlanned
+        }
+        d.finished[name] = true
+        return nil
+    }
+}
+
+// Reset clears what the DAG's tasks have recorded, to check another run.
+func (d *DAG) Reset() {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    d.started = make(map[string]int)
+    d.finished = make(map[string]bool)
+    d.violations = nil
+}
+
+// Ran reports whether the named node's task has run since the DAG was
+// generated or reset.
+func (d *DAG) Ran(name string) bool {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    return d.started[name] > 0
+}
+
+// Check returns an error describing every way the runs since the DAG was
+// generated or reset broke its dependencies: a task that started before one
+// of its parents succeeded, or that ran more than once. If no node is
+// planned to fail, it also reports tasks that did not run, so Check should
+// follow a run that returned.
+func (d *DAG) Check() error {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    violations := append([]string(nil), d.violations...)
+    if len(d.Failing) == 0 {
+        for _, name := range d.Names {
+            if d.started[name] == 0 {
+                violations = append(violations, fmt.Sprintf("%s did not run", name))
+            }
+        }
+    }
+    if len(violations) == 0 {
+        return nil
+    }
+    errs := make([]error, len(violations))
+    for i, v := range violations {
+        errs[i] = errors.New(v)
+    }
+    return errors.Join(errs...)
+}
@@ -0,0 +1,87 @@
+package leotest
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mips171/leo"
+)
+
+func TestGenerateIsReproducible(t *testing.T) {
+    shape := Shape{Nodes: 30, MaxParents: 3, FailureProbability: 0.2}
+    a := Generate(rand.New(rand.NewSource(9)), shape)
+    b := Generate(rand.New(rand.NewSource(9)), shape)
+    if !reflect.DeepEqual(a.Parents, b.Parents) || !reflect.DeepEqual(a.Failing, b.Failing) {
+        t.Error("the same seed generated different DAGs")
+    }
+    if a.Graph.Hash() != b.Graph.Hash() {
+        t.Error("the same seed generated graphs with different hashes")
+    }
+    for name, parents := range a.Parents {
+        if len(parents) > 3 {
+            t.Errorf("%s has %d parents, want at most 3", name, len(parents))
+        }
+    }
+}
+
+func TestExecutorsRespectDependencies(t *testing.T) {
+    configs := map[string]func(e *leo.Executor){
+        "default":     func(*leo.Executor) {},
+        "limited":     func(e *leo.Executor) { e.SetConcurrency(3); e.SetScheduling(leo.ScheduleCriticalPath) },
+        "wave":        func(e *leo.Executor) { e.SetMode(leo.ModeWave) },
+        "seeded":      func(e *leo.Executor) { e.SetSeed(5) },
+        "worker pool": func(e *leo.Executor) { e.SetWorkerPool(leo.NewWorkerPool(leo.Worker{}, leo.Worker{})) },
+    }
+    for name, configure := range configs {
+        for seed := int64(0); seed < 20; seed++ {
+            dag := Generate(rand.New(rand.NewSource(seed)), Shape{Nodes: 25, MaxDuration: time.Millisecond})
+            executor := leo.NewExecutor(dag.Graph)
+            configure(executor)
+            if err := executor.Execute(); err != nil {
+                t.Fatalf("%s, seed %d: Execute failed: %v", name, seed, err)
+            }
+            if err := dag.Check(); err != nil {
+                t.Fatalf("%s, seed %d: %v", name, seed, err)
+            }
+        }
+    }
+}
+
+func TestCheckReportsViolations(t *testing.T) {
+    dag := Generate(rand.New(rand.NewSource(1)), Shape{Nodes: 10, EdgeProbability: 1})
+    // Running a task directly ignores its dependencies.
+    sub, _ := dag.Graph.Subgraph(dag.Names[9])
+    leo.NewExecutor(sub).Execute()
+    err := dag.Check()
+    if err == nil {
+        t.Fatal("Check passed after a task ran without its dependencies")
+    }
+    for _, want := range []string{"n009 started before its parent n000 succeeded", "n000 did not run"} {
+        if !strings.Contains(err.Error(), want) {
+            t.Errorf("Check = %v, want it to mention %q", err, want)
+        }
+    }
+
+    dag.Reset()
+    if err := leo.NewExecutor(dag.Graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if err := dag.Check(); err != nil {
+        t.Errorf("Check after a full run = %v", err)
+    }
+}
+
+func TestPlannedFailuresStopDescendants(t *testing.T) {
+    for seed := int64(0); seed < 20; seed++ {
+        dag := Generate(rand.New(rand.NewSource(seed)), Shape{Nodes: 20, FailureProbability: 0.15})
+        executor := leo.NewExecutor(dag.Graph)
+        executor.SetConcurrency(1)
+        executor.Execute()
+        if err := dag.Check(); err != nil {
+            t.Fatalf("seed %d: %v", seed, err)
+        }
+    }
+}
@@ -0,0 +1,66 @@
+package leotest
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/mips171/leo"
+)
+
+type testKey struct{}
+
+// T returns the subtest running the task that received ctx, see Run, or nil
+// if the task is not running under Run.
+func T(ctx context.Context) *testing.T {
+    t, _ := ctx.Value(testKey{}).(*testing.T)
+    return t
+}
+
+// Run executes g as a graph of subtests of t: each node runs as a subtest
+// named after it, once its dependencies have passed, and independent nodes
+// run in parallel as the executor's concurrency allows. A task fails its
+// subtest by returning an error or through T(ctx), with the usual methods
+// of testing.T. Nodes that do not run because a dependency failed are
+// reported as skipped subtests.
+//
+// Run uses the FailContinue failure mode, so that a failure only stops the
+// nodes that depend on it; opts may override it and configure the executor
+// further. It returns the run's report.
+func Run(t *testing.T, g *leo.Graph, opts ...leo.ExecutorOption) *leo.Report {
+    t.Helper()
+    opts = append([]leo.ExecutorOption{leo.WithFailureMode(leo.FailContinue)}, opts...)
+    executor := leo.NewExecutor(g, opts...)
+    executor.Use(func(next leo.TaskCtxFunc, node leo.NodeInfo) leo.TaskCtxFunc {
+        return func(ctx context.Context) error {
+            var err error
+            passed := t.Run(node.Name, func(st *testing.T) {
+                if err = next(context.WithValue(ctx, testKey{}, st)); err != nil {
+                    st.Error(err)
+                }
+            })
+            if !passed && err == nil {
+                err = errors.New("subtest failed")
+            }
+            return err
+        }
+    })
+    err := executor.Execute()
+
+    report := executor.Report()
+    if report == nil {
+        t.Fatalf("executing the graph: %v", err)
+        return nil
+    }
+    for _, name := range report.Skipped() {
+        reason := report.Nodes[name].SkipReason
+        t.Run(name, func(st *testing.T) { st.Skip(reason) })
+    }
+    for _, name := range report.Pending() {
+        t.Run(name, func(st *testing.T) { st.Skip("did not run") })
+    }
+    if err != nil && len(report.Failed()) == 0 {
+        t.Errorf("executing the graph: %v", err)
+    }
+    return report
+}
@@ -0,0 +1,50 @@
+package leotest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/mips171/leo"
+)
+
+func TestRun(t *testing.T) {
+    var mu sync.Mutex
+    var order []string
+    task := func(ctx context.Context) error {
+        st := T(ctx)
+        if st == nil {
+            return nil
+        }
+        mu.Lock()
+        order = append(order, st.Name())
+        mu.Unlock()
+        return nil
+    }
+    graph := leo.TaskGraph()
+    graph.AddCtx("setup", task)
+    graph.AddCtx("unit", task)
+    graph.AddCtx("integration", task)
+    graph.AddCtx("report", task)
+    graph.Precede("setup", "unit")
+    graph.Precede("setup", "integration")
+    graph.Precede("unit", "report")
+    graph.Precede("integration", "report")
+
+    report := Run(t, graph, leo.WithConcurrency(-1))
+    if report == nil || len(report.Failed()) != 0 {
+        t.Fatalf("unexpected report %+v", report)
+    }
+    if len(order) != 4 {
+        t.Fatalf("expected every task to see its subtest, got %v", order)
+    }
+    if order[0] != t.Name()+"/setup" || order[3] != t.Name()+"/report" {
+        t.Errorf("subtests ran out of dependency order: %v", order)
+    }
+}
+
+func TestTOutsideRun(t *testing.T) {
+    if T(context.Background()) != nil {
+        t.Error("expected no subtest outside Run")
+    }
+}
@@ -0,0 +1,190 @@
+package leo
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// LintCheck identifies the anti-pattern a LintWarning reports.
+type LintCheck string
+
+const (
+    // LintBottleneck reports a node with a very large fan-in or fan-out,
+    // which serializes the graph around it.
+    LintBottleneck LintCheck = "bottleneck"
+    // LintSerialChain reports a long chain of nodes each depending only on
+    // the previous one, which may not all need to run in order.
+    LintSerialChain LintCheck = "serial-chain"
+    // LintDisconnected reports a graph made of components that share no
+    // edges.
+    LintDisconnected LintCheck = "disconnected"
+    // LintNoTask reports a node added without a task.
+    LintNoTask LintCheck = "no-task"
+)
+
+// LintWarning is a possible problem found by Lint.
+type LintWarning struct {
+    Check   LintCheck
+    Nodes   []string
+    Message string
+}
+
+func (w LintWarning) String() string {
+    return fmt.Sprintf("%s: %s", w.Check, w.Message)
+}
+
+type lintConfig struct {
+    maxFan   int
+    minChain int
+}
+
+// LintOption configures Lint.
+type LintOption func(*lintConfig)
+
+// LintMaxFan sets the fan-in or fan-out at which a node is reported as a
+// bottleneck. The default is 10.
+func LintMaxFan(n int) LintOption {
+    return func(c *lintConfig) {
+        c.maxFan = n
+    }
+}
+
+// LintMinChain sets the number of nodes at which a serial chain is reported.
+// The default is 4.
+func LintMinChain(n int) LintOption {
+    return func(c *lintConfig) {
+        c.minChain = n
+    }
+}
+
+// Lint reports anti-patterns in g that make pipelines slower or harder to
+// maintain than they need to be: bottleneck nodes with a huge fan-in or
+// fan-out, serial chains that might run in parallel, disconnected
+// components, and nodes without a task. The warnings are advice, not
+// errors: a graph with warnings still runs. They are ordered by check, then
+// by node name.
+//
+// A chain link between function nodes (see AddFunc) that passes a value is
+// a real dependency and does not count towards a serial chain, and neither
+// does a streaming edge, whose ends already run concurrently.
+func Lint(g *Graph, opts ...LintOption) []LintWarning {
+    cfg := lintConfig{maxFan: 10, minChain: 4}
+    for _, opt := range opts {
+        opt(&cfg)
+    }
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+
+    var warnings []LintWarning
+    names := make([]string, 0, len(g.nodes))
+    for name := range g.nodes {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    for _, name := range names {
+        n := g.nodes[name]
+        if in := len(n.predecessors()); in >= cfg.maxFan {
+            warnings = append(warnings, LintWarning{
+                Check:   LintBottleneck,
+                Nodes:   []string{name},
+                Message: fmt.Sprintf("%s waits for %d dependencies; consider splitting it or grouping them", name, in),
+            })
+        }
+        if out := len(n.successors()); out >= cfg.maxFan {
+            warnings = append(warnings, LintWarning{
+                Check:   LintBottleneck,
+                Nodes:   []string{name},
+                Message: fmt.Sprintf("%s holds up %d dependents; consider splitting it so they can start sooner", name, out),
+            })
+        }
+    }
+
+    for _, name := range names {
+        n := g.nodes[name]
+        if len(n.parents) == 1 && serialLink(n.parents[0], n) {
+            continue
+        }
+        chain := []string{name}
+        for len(n.children) == 1 && serialLink(n, n.children[0]) {
+            n = n.children[0]
+            chain = append(chain, n.name)
+        }
+        if len(chain) >= cfg.minChain {
+            warnings = append(warnings, LintWarning{
+                Check:   LintSerialChain,
+                Nodes:   chain,
+                Message: fmt.Sprintf("%s run one after another; check that each needs the one before it", strings.Join(chain, " → ")),
+            })
+        }
+    }
+
+    if roots := g.components(names); len(roots) > 1 {
+        warnings = append(warnings, LintWarning{
+            Check:   LintDisconnected,
+            Nodes:   roots,
+            Message: fmt.Sprintf("graph has %d components that share no edges, containing %s; they may belong in separate graphs", len(roots), strings.Join(roots, ", ")),
+        })
+    }
+
+    for _, name := range names {
+        if g.nodes[name].task == nil {
+            warnings = append(warnings, LintWarning{
+                Check:   LintNoTask,
+                Nodes:   []string{name},
+                Message: fmt.Sprintf("%s has no task", name),
+            })
+        }
+    }
+    return warnings
+}
+
+// serialLink reports whether the edge from parent to child is the only way
+// into child and out of parent, without passing a value or streaming.
+func serialLink(parent, child *Node) bool {
+    if len(parent.children) != 1 || len(parent.fallbacks) != 0 {
+        return false
+    }
+    if len(child.parents) != 1 || len(child.fallbackFor) != 0 {
+        return false
+    }
+    if parent.edgeTo(child).stream {
+        return false
+    }
+    for _, producer := range child.inputs {
+        if producer == parent {
+            return false
+        }
+    }
+    return true
+}
+
+// components returns the first name, in the order of names, of each weakly
+// connected component of the graph.
+func (g *Graph) components(names []string) []string {
+    seen := make(map[*Node]bool, len(g.nodes))
+    var roots []string
+    for _, name := range names {
+        start := g.nodes[name]
+        if seen[start] {
+            continue
+        }
+        roots = append(roots, name)
+        seen[start] = true
+        stack := []*Node{start}
+        for len(stack) > 0 {
+            n := stack[len(stack)-1]
+            stack = stack[:len(stack)-1]
+            for _, list := range [][]*Node{n.successors(), n.predecessors()} {
+                for _, m := range list {
+                    if !seen[m] {
+                        seen[m] = true
+                        stack = append(stack, m)
+                    }
+                }
+            }
+        }
+    }
+    return roots
+}
@@ -0,0 +1,63 @@
+package leo
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+    graph := TaskGraph()
+    noop := func() error { return nil }
+    graph.Add("checkout", noop)
+    for i := 0; i < 3; i++ {
+        name := fmt.Sprintf("test-%d", i)
+        graph.Add(name, noop)
+        graph.Precede("checkout", name)
+    }
+    for _, name := range []string{"lint", "vet", "build", "package"} {
+        graph.Add(name, noop)
+    }
+    graph.Precede("lint", "vet")
+    graph.Precede("vet", "build")
+    graph.Precede("build", "package")
+    graph.Add("todo", nil)
+
+    warnings := Lint(graph, LintMaxFan(3))
+    var got []string
+    for _, w := range warnings {
+        got = append(got, w.String())
+    }
+    want := []string{
+        "bottleneck: checkout holds up 3 dependents; consider splitting it so they can start sooner",
+        "serial-chain: lint → vet → build → package run one after another; check that each needs the one before it",
+        "disconnected: graph has 3 components that share no edges, containing build, checkout, todo; they may belong in separate graphs",
+        "no-task: todo has no task",
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("Lint =\n%q\nwant\n%q", got, want)
+    }
+    if w := warnings[1]; w.Check != LintSerialChain || len(w.Nodes) != 4 {
+        t.Errorf("chain warning = %+v", w)
+    }
+
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Errorf("a graph with a node without a task failed: %v", err)
+    }
+}
+
+func TestLintSkipsDataDependencies(t *testing.T) {
+    type a struct{}
+    type b struct{}
+    type c struct{}
+    graph := TaskGraph()
+    graph.AddFunc("a", func() a { return a{} })
+    graph.AddFunc("b", func(a) b { return b{} })
+    graph.AddFunc("c", func(b) c { return c{} })
+    graph.AddFunc("d", func(c) error { return nil })
+    graph.AutoWire()
+
+    if warnings := Lint(graph); len(warnings) != 0 {
+        t.Errorf("Lint = %v, want no warnings for a chain that passes values", warnings)
+    }
+}
@@ -0,0 +1,52 @@
+package leo
+
+import (
+    "context"
+    "log/slog"
+)
+
+// WithLogger logs the executor's events (see Event) to l: tasks being
+// queued and started at debug level, finishing and being skipped at info
+// level, failing at error level, and runs finishing at info level, or error
+// level if they failed. Each record has the run's ID and the node's name.
+// The logger is only set when the executor is created.
+func WithLogger(l *slog.Logger) ExecutorOption {
+    return func(e *Executor) {
+        e.logger = l
+    }
+}
+
+// log logs ev to the executor's logger, if it has one.
+func (e *Executor) log(ev Event) {
+    if e.logger == nil {
+        return
+    }
+    level := slog.LevelInfo
+    switch {
+    case ev.Err != nil:
+        level = slog.LevelError
+    case ev.Type == EventTaskQueued, ev.Type == EventTaskStarted:
+        level = slog.LevelDebug
+    case ev.Type == EventTaskSlow:
+        level = slog.LevelWarn
+    }
+    ctx := context.Background()
+    if !e.logger.Enabled(ctx, level) {
+        return
+    }
+
+    attrs := []slog.Attr{slog.String("run", ev.Run.ID())}
+    if ev.Node != "" {
+        attrs = append(attrs, slog.String("node", ev.Node))
+    }
+    if ev.Duration > 0 {
+        attrs = append(attrs, slog.Duration("duration", ev.Duration))
+    }
+    if ev.Reason != "" {
+        attrs = append(attrs, slog.String("reason", ev.Reason))
+    }
+    if ev.Err != nil {
+        attrs = append(attrs, slog.Any("error", ev.Err))
+    }
+    e.logger.LogAttrs(ctx, level, ev.Type.String(), attrs...)
+}
@@ -0,0 +1,40 @@
+package leo
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("fetch", func() error { return nil })
+    graph.Add("build", func() error { return errors.New("compiler crashed") })
+    graph.Add("ship", func() error { return nil })
+    graph.Precede("fetch", "build")
+    graph.Precede("build", "ship")
+
+    var buf bytes.Buffer
+    logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+    executor := NewExecutor(graph, WithLogger(logger))
+    run := executor.NewRun()
+    run.Execute()
+
+    out := buf.String()
+    for _, want := range []string{
+        "level=INFO msg=TaskFinished run=" + run.ID() + " node=fetch duration=",
+        `level=ERROR msg=TaskFailed run=` + run.ID() + ` node=build duration=`,
+        `error="compiler crashed"`,
+        `level=INFO msg=TaskSkipped run=` + run.ID() + ` node=ship reason="upstream build failed"`,
+        "level=ERROR msg=RunFinished",
+    } {
+        if !strings.Contains(out, want) {
+            t.Errorf("expected log to contain %q, got\n%s", want, out)
+        }
+    }
+    if strings.Contains(out, "TaskStarted") {
+        t.Errorf("expected debug records to be filtered out, got\n%s", out)
+    }
+}
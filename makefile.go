@@ -0,0 +1,250 @@
+package leo
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+)
+
+// ParseMakefile builds a graph from a simple Makefile. Each target becomes a
+// node whose task runs the target's recipe lines in order with "sh -c", and
+// each prerequisite that is itself a target becomes an edge. Independent
+// targets therefore run in parallel.
+//
+// The supported subset is: comments, "VAR = value" and "VAR := value"
+// assignments expanded with $(VAR) or ${VAR}, rules with one or more targets,
+// tab-indented recipes, backslash line continuations, the automatic variable
+// $@, and the recipe prefixes "@" and "-" (ignore errors). Special targets such
+// as .PHONY are ignored, as are prerequisites that are not targets, which make
+// would treat as plain files. Pattern rules, conditionals and includes are not
+// supported.
+func ParseMakefile(r io.Reader) (*Graph, error) {
+    type rule struct {
+        targets []string
+        prereqs []string
+        recipe  []string
+    }
+
+    vars := make(map[string]string)
+    var rules []*rule
+    var current *rule
+
+    lines, err := makefileLines(r)
+    if err != nil {
+        return nil, err
+    }
+
+    for i, line := range lines {
+        lineNo := i + 1
+        if strings.HasPrefix(line, "\t") {
+            if current == nil {
+                return nil, fmt.Errorf("makefile line %d: recipe without a target", lineNo)
+            }
+            if cmd := strings.TrimSpace(line); cmd != "" {
+                current.recipe = append(current.recipe, cmd)
+            }
+            continue
+        }
+
+        if idx := strings.Index(line, "#"); idx >= 0 {
+            line = line[:idx]
+        }
+        if strings.TrimSpace(line) == "" {
+            continue
+        }
+
+        if name, value, ok := makefileAssignment(line); ok {
+            vars[name] = expandMakeVars(value, vars)
+            current = nil
+            continue
+        }
+
+        colon := strings.Index(line, ":")
+        if colon < 0 {
+            return nil, fmt.Errorf("makefile line %d: expected a rule or assignment", lineNo)
+        }
+        targets := strings.Fields(expandMakeVars(line[:colon], vars))
+        if len(targets) == 0 {
+            return nil, fmt.Errorf("makefile line %d: rule without a target", lineNo)
+        }
+        rest := line[colon+1:]
+        var inline string
+        if semi := strings.Index(rest, ";"); semi >= 0 {
+            inline = strings.TrimSpace(rest[semi+1:])
+            rest = rest[:semi]
+        }
+
+        current = &rule{targets: targets, prereqs: strings.Fields(expandMakeVars(rest, vars))}
+        if inline != "" {
+            current.recipe = append(current.recipe, inline)
+        }
+        if strings.HasPrefix(targets[0], ".") {
+            // Special targets such as .PHONY are accepted but ignored.
+            continue
+        }
+        rules = append(rules, current)
+    }
+
+    graph := TaskGraph()
+    for _, rl := range rules {
+        for _, target := range rl.targets {
+            if _, exists := graph.nodes[target]; exists {
+                return nil, fmt.Errorf("makefile: target %s defined more than once", target)
+            }
+            recipe := make([]string, len(rl.recipe))
+            for i, cmd := range rl.recipe {
+                cmd = expandMakeVars(strings.ReplaceAll(cmd, "$@", target), vars)
+                recipe[i] = strings.ReplaceAll(cmd, "$$", "$")
+            }
+            graph.AddCtx(target, makeRecipe(recipe), WithCommand(recipeScript(recipe)))
+        }
+    }
+
+    for _, rl := range rules {
+        for _, target := range rl.targets {
+            for _, prereq := range rl.prereqs {
+                if _, exists := graph.nodes[prereq]; !exists {
+                    continue
+                }
+                if err := graph.Precede(prereq, target); err != nil {
+                    return nil, fmt.Errorf("makefile: %s -> %s: %w", prereq, target, err)
+                }
+            }
+        }
+    }
+
+    return graph, nil
+}
+
+// ParseMakefileFile is ParseMakefile for a file on disk.
+func ParseMakefileFile(path string) (*Graph, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    return ParseMakefile(f)
+}
+
+func makeRecipe(recipe []string) TaskCtxFunc {
+    return func(ctx context.Context) error {
+        for _, cmd := range recipe {
+            ignoreErrors := false
+            for len(cmd) > 0 && (cmd[0] == '@' || cmd[0] == '-') {
+                if cmd[0] == '-' {
+                    ignoreErrors = true
+                }
+                cmd = cmd[1:]
+            }
+            if err := Shell(cmd)(ctx); err != nil && !ignoreErrors {
+                return err
+            }
+        }
+        return nil
+    }
+}
+
+// recipeScript returns a single shell command equivalent to recipe.
+func recipeScript(recipe []string) string {
+    parts := make([]string, 0, len(recipe))
+    for _, cmd := range recipe {
+        ignoreErrors := false
+        for len(cmd) > 0 && (cmd[0] == '@' || cmd[0] == '-') {
+            if cmd[0] == '-' {
+                ignoreErrors = true
+            }
+            cmd = cmd[1:]
+        }
+        if ignoreErrors {
+            cmd = "(" + cmd + ") || true"
+        }
+        parts = append(parts, cmd)
+    }
+    return strings.Join(parts, " && ")
+}
+
+// makefileLines reads r, joining backslash-continued lines.
+func makefileLines(r io.Reader) ([]string, error) {
+    var lines []string
+    var pending strings.Builder
+    continued := false
+
+    scanner := bufio.NewScanner(r)
+    for scanner.Scan() {
+        line := scanner.Text()
+        if continued {
+            line = strings.TrimLeft(line, " \t")
+        }
+        if strings.HasSuffix(line, "\\") {
+            pending.WriteString(strings.TrimSuffix(line, "\\"))
+            pending.WriteString(" ")
+            continued = true
+            continue
+        }
+        pending.WriteString(line)
+        lines = append(lines, pending.String())
+        pending.Reset()
+        continued = false
+    }
+    if continued {
+        lines = append(lines, pending.String())
+    }
+    return lines, scanner.Err()
+}
+
+func makefileAssignment(line string) (name, value string, ok bool) {
+    eq := strings.Index(line, "=")
+    if eq < 0 {
+        return "", "", false
+    }
+    lhs := line[:eq]
+    lhs = strings.TrimSuffix(lhs, ":")
+    lhs = strings.TrimSuffix(lhs, "?")
+    if strings.ContainsAny(lhs, ":") {
+        // "target: VAR=value" is a target-specific variable, not supported.
+        return "", "", false
+    }
+    name = strings.TrimSpace(lhs)
+    if name == "" || strings.ContainsAny(name, " \t") {
+        return "", "", false
+    }
+    return name, strings.TrimSpace(line[eq+1:]), true
+}
+
+// expandMakeVars replaces $(NAME) and ${NAME} with their values. Unknown
+// variables expand to the environment variable of the same name, as in make.
+func expandMakeVars(s string, vars map[string]string) string {
+    var b strings.Builder
+    for i := 0; i < len(s); i++ {
+        if s[i] != '$' || i+1 >= len(s) {
+            b.WriteByte(s[i])
+            continue
+        }
+        var closing byte
+        switch s[i+1] {
+        case '(':
+            closing = ')'
+        case '{':
+            closing = '}'
+        default:
+            b.WriteByte(s[i])
+            continue
+        }
+        end := strings.IndexByte(s[i+2:], closing)
+        if end < 0 {
+            b.WriteByte(s[i])
+            continue
+        }
+        name := s[i+2 : i+2+end]
+        if value, ok := vars[name]; ok {
+            b.WriteString(value)
+        } else {
+            b.WriteString(os.Getenv(name))
+        }
+        i += end + 2
+    }
+    return b.String()
+}
@@ -0,0 +1,82 @@
+package leo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMakefile(t *testing.T) {
+    dir := t.TempDir()
+    out := filepath.Join(dir, "out.txt")
+
+    makefile := `
+# Build everything.
+OUT := ` + out + `
+GREETING = hello
+
+.PHONY: all lint test
+
+all: lint test
+	@echo done >> $(OUT)
+
+lint test: deps missing-file.txt
+	echo $@ >> ${OUT}
+
+deps:
+	-false
+	echo $(GREETING) \
+	  world >> $(OUT)
+`
+
+    graph, err := ParseMakefile(strings.NewReader(makefile))
+    if err != nil {
+        t.Fatalf("ParseMakefile failed: %v", err)
+    }
+
+    if len(graph.nodes) != 4 {
+        t.Fatalf("expected 4 targets, got %d", len(graph.nodes))
+    }
+    for target, want := range map[string][]string{
+        "all":  {"lint", "test"},
+        "lint": {"deps"},
+        "test": {"deps"},
+        "deps": {},
+    } {
+        got := []string{}
+        for _, p := range graph.nodes[target].parents {
+            got = append(got, p.name)
+        }
+        if !reflect.DeepEqual(got, want) {
+            t.Errorf("%s: parents %v, want %v", target, got, want)
+        }
+    }
+
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    data, err := os.ReadFile(out)
+    if err != nil {
+        t.Fatalf("reading output: %v", err)
+    }
+    lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+    if len(lines) != 4 || lines[0] != "hello world" || lines[3] != "done" {
+        t.Errorf("unexpected output order: %q", lines)
+    }
+}
+
+func TestParseMakefileErrors(t *testing.T) {
+    for _, makefile := range []string{
+        "\techo orphan recipe\n",
+        "not a rule\n",
+        "a: b\nb: a\n",
+        "a:\na:\n",
+    } {
+        if _, err := ParseMakefile(strings.NewReader(makefile)); err == nil {
+            t.Errorf("expected an error for %q", makefile)
+        }
+    }
+}
@@ -0,0 +1,156 @@
+package leo
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "sort"
+)
+
+type mergeConfig struct {
+    namespace string
+}
+
+// MergeOption configures Graph.Merge.
+type MergeOption func(*mergeConfig)
+
+// Namespace prefixes the names of merged nodes with ns and a slash, so that
+// "Task B" merged under Namespace("siteA") becomes "siteA/Task B". Edges are
+// rewritten to match, which lets the same template graph be merged several
+// times without its names colliding. Tasks of the merged nodes find ns with
+// Instance.
+func Namespace(ns string) MergeOption {
+    return func(c *mergeConfig) {
+        c.namespace = ns
+    }
+}
+
+// Merge copies the nodes and edges of other into g. The copies share their
+// tasks and settings with other's nodes, and other is not modified. Stages of
+// other that g lacks are appended to g's stages; nodes keep their stage
+// names, so a template's "deploy" nodes join g's "deploy" stage.
+//
+// It is an error, wrapping ErrNodeExists, for a merged name to collide with a
+// node or alias of g; nothing is merged in that case. Aliases of other are
+// merged with its nodes. Connect the merged nodes to the
+// rest of g with Precede, using their namespaced names.
+func (g *Graph) Merge(other *Graph, opts ...MergeOption) error {
+    if other == g {
+        return errors.New("cannot merge a graph into itself")
+    }
+    var cfg mergeConfig
+    for _, opt := range opts {
+        opt(&cfg)
+    }
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    other.mu.RLock()
+    defer other.mu.RUnlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    if name := g.mergeConflict(other, cfg.namespace); name != "" {
+        return fmt.Errorf("merging %s: %w", name, ErrNodeExists)
+    }
+    g.merge(other, cfg.namespace)
+    return nil
+}
+
+// mergeConflict returns the first name of other, a node's or an alias, that
+// would collide with a node or alias of g if other were merged under
+// namespace, or "" if none would. The caller must hold g.mu and other.mu.
+func (g *Graph) mergeConflict(other *Graph, namespace string) string {
+    names := make([]string, 0, len(other.nodes)+len(other.aliases))
+    for name := range other.nodes {
+        names = append(names, name)
+    }
+    for alias := range other.aliases {
+        names = append(names, alias)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        if namespace != "" {
+            name = namespace + "/" + name
+        }
+        if _, exists := g.lookup(name); exists {
+            return name
+        }
+    }
+    return ""
+}
+
+// merge copies the nodes and edges of other into g, under namespace if it
+// is not empty. The caller must hold g.mu and other.mu, and have checked
+// that the names do not collide.
+func (g *Graph) merge(other *Graph, namespace string) {
+    rename := func(name string) string {
+        if namespace == "" {
+            return name
+        }
+        return namespace + "/" + name
+    }
+    copies := make(map[*Node]*Node, len(other.nodes))
+    for _, node := range other.startNodes {
+        c := node.clone()
+        c.name = rename(node.name)
+        if namespace != "" {
+            if node.group != "" {
+                c.group = rename(node.group)
+            }
+            c.instance = rename(node.instance)
+            if node.instance == "" {
+                c.instance = namespace
+            }
+        }
+        copies[node] = c
+        g.nodes[c.name] = c
+        g.startNodes = append(g.startNodes, c)
+    }
+    for node, c := range copies {
+        for _, child := range node.children {
+            to := copies[child]
+            c.children = append(c.children, to)
+            to.parents = append(to.parents, c)
+            if ed, ok := node.edges[child]; ok {
+                if c.edges == nil {
+                    c.edges = make(map[*Node]*edgeConfig)
+                }
+                cp := *ed
+                c.edges[to] = &cp
+            }
+        }
+        for _, fallback := range node.fallbacks {
+            fb := copies[fallback]
+            c.fallbacks = append(c.fallbacks, fb)
+            fb.fallbackFor = append(fb.fallbackFor, c)
+        }
+        if node.inputs != nil {
+            c.inputs = make(map[reflect.Type]*Node, len(node.inputs))
+            for t, producer := range node.inputs {
+                c.inputs[t] = copies[producer]
+            }
+        }
+    }
+
+    for _, s := range other.stages {
+        exists := false
+        for _, own := range g.stages {
+            exists = exists || own.name == s.name
+        }
+        if !exists {
+            g.stages = append(g.stages, s)
+        }
+    }
+    for group, tx := range other.transactions {
+        if g.transactions == nil {
+            g.transactions = make(map[string]*Transaction)
+        }
+        g.transactions[rename(group)] = tx
+    }
+    for alias, name := range other.aliases {
+        if g.aliases == nil {
+            g.aliases = make(map[string]string)
+        }
+        g.aliases[rename(alias)] = rename(name)
+    }
+}
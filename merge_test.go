@@ -0,0 +1,105 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type siteConfig struct{ site string }
+
+func siteTemplate(ran *sync.Map) *Graph {
+    graph := TaskGraph()
+    graph.AddCtx("Task A", func(ctx context.Context) error {
+        _, n := runNode(ctx)
+        ran.Store(n.name, true)
+        return nil
+    })
+    graph.AddFunc("Load", func(ctx context.Context) siteConfig {
+        _, n := runNode(ctx)
+        return siteConfig{site: n.name}
+    })
+    graph.AddFunc("Task B", func(cfg siteConfig) error {
+        ran.Store(cfg.site, true)
+        return nil
+    })
+    graph.AutoWire()
+    graph.Precede("Task A", "Task B", EdgeLabel("then"))
+    return graph
+}
+
+func TestMergeNamespaced(t *testing.T) {
+    var ran sync.Map
+    template := siteTemplate(&ran)
+
+    graph := TaskGraph()
+    graph.Add("setup", func() error { return nil })
+    for _, site := range []string{"siteA", "siteB"} {
+        if err := graph.Merge(template, Namespace(site)); err != nil {
+            t.Fatalf("Merge(%s) failed: %v", site, err)
+        }
+        if err := graph.Precede("setup", site+"/Task A"); err != nil {
+            t.Fatalf("Precede failed: %v", err)
+        }
+    }
+
+    var edges []string
+    for _, e := range graph.Edges() {
+        edges = append(edges, e.From+" -> "+e.To+" "+e.Label)
+    }
+    want := []string{
+        "setup -> siteA/Task A ",
+        "setup -> siteB/Task A ",
+        "siteA/Load -> siteA/Task B ",
+        "siteA/Task A -> siteA/Task B then",
+        "siteB/Load -> siteB/Task B ",
+        "siteB/Task A -> siteB/Task B then",
+    }
+    if !reflect.DeepEqual(edges, want) {
+        t.Errorf("edges = %q, want %q", edges, want)
+    }
+    if len(template.Edges()) != 2 {
+        t.Errorf("template has %d edges after merging, want 2", len(template.Edges()))
+    }
+
+    run := NewExecutor(graph).NewRun()
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    for _, name := range []string{"siteA/Task A", "siteB/Task A", "siteA/Load", "siteB/Load"} {
+        if _, ok := ran.Load(name); !ok {
+            t.Errorf("%s did not run under its namespaced name", name)
+        }
+    }
+    if v, _ := run.Result("siteB/Load"); v != (siteConfig{site: "siteB/Load"}) {
+        t.Errorf("Result(siteB/Load) = %v", v)
+    }
+}
+
+func TestMergeCollision(t *testing.T) {
+    template := TaskGraph()
+    template.Add("build", func() error { return nil })
+    template.Add("test", func() error { return nil })
+
+    graph := TaskGraph()
+    graph.Add("test", func() error { return nil })
+    if err := graph.Merge(template); !errors.Is(err, ErrNodeExists) {
+        t.Fatalf("Merge = %v, want ErrNodeExists", err)
+    }
+    if _, err := graph.Ancestors("build"); err == nil {
+        t.Error("a failed merge added nodes")
+    }
+    if err := graph.Merge(template, Namespace("ci")); err != nil {
+        t.Fatalf("Merge with a namespace failed: %v", err)
+    }
+    if err := graph.Merge(graph); err == nil {
+        t.Error("merging a graph into itself did not fail")
+    }
+
+    graph.Freeze()
+    if err := graph.Merge(template, Namespace("late")); !errors.Is(err, ErrFrozen) {
+        t.Errorf("Merge into a frozen graph = %v, want ErrFrozen", err)
+    }
+}
@@ -0,0 +1,53 @@
+package leo
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+)
+
+// ExportMermaid writes g as a Mermaid flowchart, for embedding in Markdown
+// documentation. Nodes are given generated IDs and labelled with their
+// names. Edge labels and weights (see EdgeLabel and EdgeWeight) are shown on
+// the edge, and fallback edges are drawn dotted and labelled "on failure".
+func ExportMermaid(w io.Writer, g *Graph) error {
+    names := sortedNodeNames(g)
+    ids := make(map[string]string, len(names))
+
+    bw := bufio.NewWriter(w)
+    fmt.Fprintf(bw, "flowchart TD\n")
+    for i, name := range names {
+        ids[name] = "n" + strconv.Itoa(i)
+        fmt.Fprintf(bw, "    %s[%s]\n", ids[name], mermaidQuote(name))
+    }
+    for _, e := range exportEdges(g) {
+        arrow := "-->"
+        var text []string
+        if e.Fallback {
+            arrow = "-.->"
+            text = append(text, "on failure")
+        }
+        if e.Label != "" {
+            text = append(text, e.Label)
+        }
+        if e.Weight != 0 {
+            text = append(text, "weight "+strconv.FormatFloat(e.Weight, 'g', -1, 64))
+        }
+        if len(text) > 0 {
+            fmt.Fprintf(bw, "    %s %s|%s| %s\n", ids[e.From], arrow, mermaidQuote(strings.Join(text, ", ")), ids[e.To])
+        } else {
+            fmt.Fprintf(bw, "    %s %s %s\n", ids[e.From], arrow, ids[e.To])
+        }
+    }
+    return bw.Flush()
+}
+
+// mermaidQuote returns s as a quoted Mermaid label, using Mermaid's entity
+// codes for characters that would end the label.
+func mermaidQuote(s string) string {
+    s = strings.ReplaceAll(s, `"`, "#quot;")
+    s = strings.ReplaceAll(s, "\n", "<br>")
+    return `"` + s + `"`
+}
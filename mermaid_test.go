@@ -0,0 +1,30 @@
+package leo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExportMermaid(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("build", func() error { return nil })
+    graph.Add("deploy", func() error { return nil })
+    graph.Add("rollback", func() error { return nil })
+    graph.Precede("build", "deploy", EdgeLabel("image \"v2\""), EdgeWeight(0.5))
+    graph.OnFailure("deploy", "rollback")
+
+    var buf bytes.Buffer
+    if err := ExportMermaid(&buf, graph); err != nil {
+        t.Fatalf("ExportMermaid failed: %v", err)
+    }
+    want := `flowchart TD
+    n0["build"]
+    n1["deploy"]
+    n2["rollback"]
+    n0 -->|"image #quot;v2#quot;, weight 0.5"| n1
+    n1 -.->|"on failure"| n2
+`
+    if buf.String() != want {
+        t.Errorf("unexpected Mermaid output:\n%s", buf.String())
+    }
+}
@@ -0,0 +1,230 @@
+package leo
+
+import (
+    "encoding/json"
+    "expvar"
+    "sync"
+    "time"
+)
+
+// Metrics aggregates counters and duration summaries from executor events,
+// along with the utilization of the observed executors: how many tasks are
+// queued and running, how long tasks wait between being queued and
+// starting, and how busy their worker pools are, see WorkerPool.Stats.
+// It implements expvar.Var, so once published it appears under /debug/vars
+// of any service that imports net/http/pprof or serves expvar.Handler:
+//
+//    m := leo.NewMetrics()
+//    expvar.Publish("leo", m)
+//    defer m.Observe(executor)()
+type Metrics struct {
+    mu      sync.Mutex
+    runs    counts
+    tasks   counts
+    runTime summary
+    perTask map[string]*summary
+
+    queued    map[taskKey]time.Time
+    started   map[taskKey]bool
+    latency   summary
+    executors []*Executor
+}
+
+// taskKey identifies a node in a run.
+type taskKey struct {
+    run  *Run
+    node string
+}
+
+type counts struct {
+    Succeeded int64 `json:"succeeded"`
+    Failed    int64 `json:"failed"`
+    Skipped   int64 `json:"skipped,omitempty"`
+}
+
+// summary is a running summary of durations, reported in seconds.
+type summary struct {
+    count         int64
+    sum, min, max time.Duration
+}
+
+func (s *summary) add(d time.Duration) {
+    if s.count == 0 || d < s.min {
+        s.min = d
+    }
+    if d > s.max {
+        s.max = d
+    }
+    s.count++
+    s.sum += d
+}
+
+func (s *summary) MarshalJSON() ([]byte, error) {
+    out := struct {
+        Count int64   `json:"count"`
+        Total float64 `json:"total_seconds"`
+        Mean  float64 `json:"mean_seconds"`
+        Min   float64 `json:"min_seconds"`
+        Max   float64 `json:"max_seconds"`
+    }{Count: s.count, Total: s.sum.Seconds(), Min: s.min.Seconds(), Max: s.max.Seconds()}
+    if s.count > 0 {
+        out.Mean = (s.sum / time.Duration(s.count)).Seconds()
+    }
+    return json.Marshal(out)
+}
+
+// NewMetrics returns empty metrics.
+func NewMetrics() *Metrics {
+    return &Metrics{
+        perTask: make(map[string]*summary),
+        queued:  make(map[taskKey]time.Time),
+        started: make(map[taskKey]bool),
+    }
+}
+
+// PublishMetrics publishes metrics for e under name with expvar and returns
+// them. Like expvar.Publish, it panics if name is already published.
+func PublishMetrics(name string, e *Executor) *Metrics {
+    m := NewMetrics()
+    expvar.Publish(name, m)
+    m.Observe(e)
+    return m
+}
+
+// Observe records the events of e's runs until the returned function is
+// called, and reports the utilization of e's worker pool until then. A
+// Metrics may observe several executors.
+func (m *Metrics) Observe(e *Executor) (stop func()) {
+    m.mu.Lock()
+    m.executors = append(m.executors, e)
+    m.mu.Unlock()
+    sub := e.SubscribeFilter(EventFilter{Types: []EventType{
+        EventTaskQueued, EventTaskStarted,
+        EventTaskFinished, EventTaskFailed, EventTaskSkipped, EventRunFinished,
+    }})
+    finished := make(chan struct{})
+    go func() {
+        defer close(finished)
+        for ev := range sub.C {
+            m.Handle(ev)
+        }
+    }()
+    return func() {
+        sub.closeWhenDrained()
+        <-finished
+        m.mu.Lock()
+        defer m.mu.Unlock()
+        for i, o := range m.executors {
+            if o == e {
+                m.executors = append(m.executors[:i], m.executors[i+1:]...)
+                break
+            }
+        }
+    }
+}
+
+// Handle records ev.
+func (m *Metrics) Handle(ev Event) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    key := taskKey{ev.Run, ev.Node}
+    switch ev.Type {
+    case EventTaskQueued:
+        m.queued[key] = ev.Time
+    case EventTaskStarted:
+        if t, ok := m.queued[key]; ok {
+            delete(m.queued, key)
+            m.latency.add(ev.Time.Sub(t))
+        }
+        m.started[key] = true
+    case EventTaskFinished:
+        m.tasks.Succeeded++
+        m.task(ev.Node).add(ev.Duration)
+        m.resolved(key)
+    case EventTaskFailed:
+        m.tasks.Failed++
+        m.task(ev.Node).add(ev.Duration)
+        m.resolved(key)
+    case EventTaskSkipped:
+        m.tasks.Skipped++
+        m.resolved(key)
+    case EventRunFinished:
+        // Nodes still queued will not start; tasks still running publish
+        // their events after the run has returned.
+        for k := range m.queued {
+            if k.run == ev.Run {
+                delete(m.queued, k)
+            }
+        }
+        if ev.Err != nil {
+            m.runs.Failed++
+        } else {
+            m.runs.Succeeded++
+        }
+        m.runTime.add(ev.Duration)
+    }
+}
+
+// resolved records that the node key has finished, failed or been skipped,
+// which may happen without it starting, such as when it is cached.
+func (m *Metrics) resolved(key taskKey) {
+    delete(m.queued, key)
+    delete(m.started, key)
+}
+
+func (m *Metrics) task(name string) *summary {
+    s, ok := m.perTask[name]
+    if !ok {
+        s = &summary{}
+        m.perTask[name] = s
+    }
+    return s
+}
+
+// String returns the metrics as JSON, implementing expvar.Var.
+func (m *Metrics) String() string {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    var pools []poolMetrics
+    seen := make(map[*WorkerPool]bool)
+    for _, e := range m.executors {
+        if p := e.getWorkerPool(); p != nil && !seen[p] {
+            seen[p] = true
+            pools = append(pools, newPoolMetrics(p.Stats()))
+        }
+    }
+    data, _ := json.Marshal(struct {
+        Runs            counts              `json:"runs"`
+        Tasks           counts              `json:"tasks"`
+        RunDuration     *summary            `json:"run_duration"`
+        TaskDuration    map[string]*summary `json:"task_duration"`
+        QueueDepth      int                 `json:"queue_depth"`
+        Running         int                 `json:"running"`
+        DispatchLatency *summary            `json:"dispatch_latency"`
+        Pools           []poolMetrics       `json:"pools,omitempty"`
+    }{m.runs, m.tasks, &m.runTime, m.perTask, len(m.queued), len(m.started), &m.latency, pools})
+    return string(data)
+}
+
+// poolMetrics is the JSON form of PoolStats, in seconds.
+type poolMetrics struct {
+    QueueDepth int             `json:"queue_depth"`
+    Workers    []workerMetrics `json:"workers"`
+}
+
+type workerMetrics struct {
+    Name     string  `json:"name"`
+    Busy     bool    `json:"busy"`
+    BusyTime float64 `json:"busy_seconds"`
+    IdleTime float64 `json:"idle_seconds"`
+}
+
+func newPoolMetrics(s PoolStats) poolMetrics {
+    out := poolMetrics{QueueDepth: s.Queued, Workers: make([]workerMetrics, len(s.Workers))}
+    for i, w := range s.Workers {
+        out.Workers[i] = workerMetrics{w.Name, w.Busy, w.BusyTime.Seconds(), w.IdleTime.Seconds()}
+    }
+    return out
+}
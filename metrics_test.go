@@ -0,0 +1,109 @@
+package leo
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("ok", func() error { return nil })
+    graph.Add("bad", func() error { return errors.New("boom") })
+    graph.Add("after", func() error { return nil })
+    graph.Precede("bad", "after")
+    // ok runs before bad aborts the run.
+    graph.Precede("ok", "bad")
+
+    executor := NewExecutor(graph)
+    m := NewMetrics()
+    stop := m.Observe(executor)
+    executor.Execute()
+    executor.Execute()
+    stop()
+
+    var out struct {
+        Runs struct {
+            Succeeded, Failed int64
+        }
+        Tasks struct {
+            Succeeded, Failed, Skipped int64
+        }
+        TaskDuration map[string]struct {
+            Count int64
+        } `json:"task_duration"`
+    }
+    if err := json.Unmarshal([]byte(m.String()), &out); err != nil {
+        t.Fatalf("invalid JSON: %v", err)
+    }
+    if out.Runs.Failed != 2 || out.Runs.Succeeded != 0 {
+        t.Errorf("unexpected run counts %+v", out.Runs)
+    }
+    if out.Tasks.Failed != 2 || out.Tasks.Skipped != 2 {
+        t.Errorf("unexpected task counts %+v", out.Tasks)
+    }
+    if out.TaskDuration["bad"].Count != 2 {
+        t.Errorf("unexpected task durations %+v", out.TaskDuration)
+    }
+}
+
+func TestPublishMetrics(t *testing.T) {
+    if expvar.Get("leo_test") == nil {
+        PublishMetrics("leo_test", NewExecutor(TaskGraph()))
+    }
+    if v := expvar.Get("leo_test"); v == nil || !json.Valid([]byte(v.String())) {
+        t.Errorf("expected published metrics, got %v", v)
+    }
+}
+
+func TestMetricsUtilization(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("a", func() error {
+        time.Sleep(5 * time.Millisecond)
+        return nil
+    })
+    graph.Add("b", func() error { return nil })
+    graph.Add("disabled", func() error { return nil }, Disabled())
+
+    executor := NewExecutor(graph, WithConcurrency(1))
+    executor.SetWorkerPool(NewWorkerPool(Worker{Name: "only"}))
+    m := NewMetrics()
+    stop := m.Observe(executor)
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    // Pools are reported while their executor is observed.
+    observed := m.String()
+    stop()
+
+    var out struct {
+        QueueDepth      int `json:"queue_depth"`
+        Running         int
+        DispatchLatency struct {
+            Count int64
+        } `json:"dispatch_latency"`
+        Pools []struct {
+            Workers []struct {
+                Name     string
+                BusyTime float64 `json:"busy_seconds"`
+            }
+        }
+    }
+    if err := json.Unmarshal([]byte(m.String()), &out); err != nil {
+        t.Fatalf("invalid JSON: %v", err)
+    }
+    if out.QueueDepth != 0 || out.Running != 0 {
+        t.Errorf("expected nothing queued or running after the run, got %+v", out)
+    }
+    if out.DispatchLatency.Count != 2 {
+        t.Errorf("expected the latency of the two tasks that started, got %+v", out.DispatchLatency)
+    }
+    if err := json.Unmarshal([]byte(observed), &out); err != nil {
+        t.Fatalf("invalid JSON: %v", err)
+    }
+    if len(out.Pools) != 1 || len(out.Pools[0].Workers) != 1 || out.Pools[0].Workers[0].BusyTime <= 0 {
+        t.Errorf("expected the pool's busy worker, got %+v", out.Pools)
+    }
+}
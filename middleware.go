@@ -0,0 +1,97 @@
+package leo
+
+import (
+    "context"
+    "fmt"
+    "runtime/debug"
+    "time"
+)
+
+// NodeInfo describes the node a middleware is wrapping.
+type NodeInfo struct {
+    Name             string
+    Parents          []string
+    Children         []string
+    ExpectedDuration time.Duration
+    Tags             []string
+    Stage            string
+}
+
+// Middleware wraps every task run by an executor, for cross-cutting concerns
+// such as logging, metrics or panic recovery. It is called each time a node
+// runs and must return a task that calls next to run the wrapped task.
+type Middleware func(next TaskCtxFunc, node NodeInfo) TaskCtxFunc
+
+// Use appends middleware to the executor. The first middleware added is the
+// outermost, so it sees the task's full duration including the others.
+func (e *Executor) Use(mw ...Middleware) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.middleware = append(e.middleware, mw...)
+}
+
+// wrap returns n's task wrapped in the executor's middleware.
+func (e *Executor) wrap(n *Node) TaskCtxFunc {
+    e.mu.Lock()
+    mw := e.middleware
+    e.mu.Unlock()
+
+    task := TaskCtxFunc(n.run)
+    if len(mw) == 0 {
+        return task
+    }
+    info := n.info()
+    for i := len(mw) - 1; i >= 0; i-- {
+        task = mw[i](task, info)
+    }
+    return task
+}
+
+// call runs n's task with ctx, wrapped in the executor's middleware. Without
+// middleware, it calls the task directly rather than through wrap, which
+// allocates.
+func (e *Executor) call(ctx context.Context, n *Node) error {
+    e.mu.Lock()
+    wrapped := len(e.middleware) > 0
+    e.mu.Unlock()
+    if !wrapped {
+        return n.run(ctx)
+    }
+    return e.wrap(n)(ctx)
+}
+
+func (n *Node) info() NodeInfo {
+    info := NodeInfo{Name: n.name, ExpectedDuration: n.expected, Tags: n.tags, Stage: n.stage}
+    for _, p := range n.parents {
+        info.Parents = append(info.Parents, p.name)
+    }
+    for _, c := range n.children {
+        info.Children = append(info.Children, c.name)
+    }
+    return info
+}
+
+// PanicError is returned by tasks wrapped with RecoverPanics when they panic.
+type PanicError struct {
+    Value any
+    Stack []byte
+}
+
+func (e *PanicError) Error() string {
+    return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// RecoverPanics returns middleware that turns a panicking task into a failed
+// task returning a *PanicError, instead of crashing the process.
+func RecoverPanics() Middleware {
+    return func(next TaskCtxFunc, node NodeInfo) TaskCtxFunc {
+        return func(ctx context.Context) (err error) {
+            defer func() {
+                if v := recover(); v != nil {
+                    err = &PanicError{Value: v, Stack: debug.Stack()}
+                }
+            }()
+            return next(ctx)
+        }
+    }
+}
@@ -0,0 +1,94 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+    graph := TaskGraph()
+
+    var mu sync.Mutex
+    var calls []string
+    log := func(s string) {
+        mu.Lock()
+        defer mu.Unlock()
+        calls = append(calls, s)
+    }
+
+    graph.Add("A", func() error {
+        log("task")
+        return nil
+    })
+
+    tag := func(name string) Middleware {
+        return func(next TaskCtxFunc, node NodeInfo) TaskCtxFunc {
+            return func(ctx context.Context) error {
+                log(name + " before " + node.Name)
+                err := next(ctx)
+                log(name + " after " + node.Name)
+                return err
+            }
+        }
+    }
+
+    executor := NewExecutor(graph)
+    executor.Use(tag("outer"), tag("inner"))
+
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    want := []string{"outer before A", "inner before A", "task", "inner after A", "outer after A"}
+    if !reflect.DeepEqual(calls, want) {
+        t.Errorf("calls = %v, want %v", calls, want)
+    }
+}
+
+func TestMiddlewareNodeInfo(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil })
+    graph.Add("B", func() error { return nil })
+    graph.Precede("A", "B")
+
+    var mu sync.Mutex
+    infos := make(map[string]NodeInfo)
+
+    executor := NewExecutor(graph)
+    executor.Use(func(next TaskCtxFunc, node NodeInfo) TaskCtxFunc {
+        mu.Lock()
+        infos[node.Name] = node
+        mu.Unlock()
+        return next
+    })
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    if got := infos["A"].Children; !reflect.DeepEqual(got, []string{"B"}) {
+        t.Errorf("A children = %v", got)
+    }
+    if got := infos["B"].Parents; !reflect.DeepEqual(got, []string{"A"}) {
+        t.Errorf("B parents = %v", got)
+    }
+}
+
+func TestRecoverPanics(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { panic("boom") })
+
+    executor := NewExecutor(graph)
+    executor.Use(RecoverPanics())
+
+    err := executor.Execute()
+    var panicErr *PanicError
+    if !errors.As(err, &panicErr) {
+        t.Fatalf("expected a PanicError, got %v", err)
+    }
+    if panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+        t.Errorf("unexpected panic error %+v", panicErr)
+    }
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package leo
+
+const raceEnabled = false
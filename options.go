@@ -0,0 +1,117 @@
+package leo
+
+// ExecutorOption configures an executor when it is created, see NewExecutor.
+// Most options have a setter of the same name that changes the setting
+// afterwards, so that
+//
+//    executor := leo.NewExecutor(graph, leo.WithConcurrency(4), leo.WithHooks(hooks))
+//
+// is the same as calling SetConcurrency and SetHooks on the new executor.
+type ExecutorOption func(*Executor)
+
+// WithConcurrency limits the number of tasks of a run that execute at once,
+// see SetConcurrency.
+func WithConcurrency(n int) ExecutorOption {
+    return func(e *Executor) {
+        e.SetConcurrency(n)
+    }
+}
+
+// WithWorkerPool runs the executor's tasks on the workers of p, see
+// SetWorkerPool.
+func WithWorkerPool(p *WorkerPool) ExecutorOption {
+    return func(e *Executor) {
+        e.SetWorkerPool(p)
+    }
+}
+
+// WithScheduling sets the policy for starting ready tasks, see
+// SetScheduling.
+func WithScheduling(p SchedulingPolicy) ExecutorOption {
+    return func(e *Executor) {
+        e.SetScheduling(p)
+    }
+}
+
+// WithRepeatPolicy sets the policy of ExecuteN and ExecuteEvery, see
+// SetRepeatPolicy.
+func WithRepeatPolicy(p RepeatPolicy) ExecutorOption {
+    return func(e *Executor) {
+        e.SetRepeatPolicy(p)
+    }
+}
+
+// WithMode sets the execution mode, see SetMode.
+func WithMode(m ExecutionMode) ExecutorOption {
+    return func(e *Executor) {
+        e.SetMode(m)
+    }
+}
+
+// WithFailureMode sets what runs do with their running tasks when a task
+// fails, see SetFailureMode.
+func WithFailureMode(m FailureMode) ExecutorOption {
+    return func(e *Executor) {
+        e.SetFailureMode(m)
+    }
+}
+
+// WithHooks sets the executor's hooks, see SetHooks.
+func WithHooks(h Hooks) ExecutorOption {
+    return func(e *Executor) {
+        e.SetHooks(h)
+    }
+}
+
+// WithRetryIf sets which task errors are retried, see SetRetryIf.
+func WithRetryIf(retryable RetryFunc) ExecutorOption {
+    return func(e *Executor) {
+        e.SetRetryIf(retryable)
+    }
+}
+
+// WithSlowTaskPercentile flags tasks slower than the percentile of their
+// history, see SetSlowTaskPercentile. If p is not between 0 and 100, the
+// executor's runs return the error instead of starting.
+func WithSlowTaskPercentile(p float64) ExecutorOption {
+    return func(e *Executor) {
+        e.optionFailed(e.SetSlowTaskPercentile(p))
+    }
+}
+
+// WithAdaptiveConcurrency adjusts the concurrency limit to the tasks'
+// latency and failures, see SetAdaptiveConcurrency. If c is invalid, the
+// executor's runs return the error instead of starting.
+func WithAdaptiveConcurrency(c AdaptiveConcurrency) ExecutorOption {
+    return func(e *Executor) {
+        e.optionFailed(e.SetAdaptiveConcurrency(c))
+    }
+}
+
+// WithMiddleware appends middleware to the executor, see Use.
+func WithMiddleware(mw ...Middleware) ExecutorOption {
+    return func(e *Executor) {
+        e.Use(mw...)
+    }
+}
+
+// optionFailed records err, unless it is nil or an earlier option failed, for
+// the executor's runs to return, as an ExecutorOption cannot.
+func (e *Executor) optionFailed(err error) {
+    if err == nil {
+        return
+    }
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    if e.optionErr == nil {
+        e.optionErr = err
+    }
+}
+
+// checkOptions returns the error of the first of the executor's options that
+// failed, if any.
+func (e *Executor) checkOptions() error {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.optionErr
+}
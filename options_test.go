@@ -0,0 +1,43 @@
+package leo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExecutorOptions(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return errors.New("broken") })
+
+    var skipped []string
+    var wrapped bool
+    pool := NewWorkerPool(Worker{})
+    executor := NewExecutor(graph,
+        WithConcurrency(2),
+        WithScheduling(ScheduleLongestFirst),
+        WithWorkerPool(pool),
+        WithRepeatPolicy(UntilSuccess),
+        WithMode(ModeWave),
+        WithHooks(Hooks{OnTaskSkipped: func(name, reason string) { skipped = append(skipped, name) }}),
+        WithMiddleware(func(next TaskCtxFunc, node NodeInfo) TaskCtxFunc {
+            wrapped = true
+            return next
+        }),
+    )
+    if limit, policy := executor.getConcurrency(); limit != 2 || policy != ScheduleLongestFirst {
+        t.Errorf("concurrency = %d, %s", limit, policy)
+    }
+    if executor.getWorkerPool() != pool || executor.getRepeatPolicy() != UntilSuccess || executor.getMode() != ModeWave {
+        t.Errorf("options were not applied")
+    }
+
+    graph.Add("B", func() error { return nil })
+    graph.Precede("A", "B")
+    executor.Execute()
+    if !wrapped {
+        t.Errorf("expected the middleware to be used")
+    }
+    if len(skipped) != 1 || skipped[0] != "B" {
+        t.Errorf("expected the hooks to be called for B, got %v", skipped)
+    }
+}
@@ -0,0 +1,39 @@
+package leo
+
+import "context"
+
+type paramsKey struct{}
+
+// SetParams sets the run's parameters. Tasks read them from their context
+// with Params, and conditions added with WithCondition can use them to decide
+// whether a node runs.
+func (r *Run) SetParams(params map[string]string) {
+    r.params = make(map[string]string, len(params))
+    for k, v := range params {
+        r.params[k] = v
+    }
+}
+
+// Params returns the parameters of the run executing the task that received
+// ctx. The returned map must not be modified.
+func Params(ctx context.Context) map[string]string {
+    params, _ := ctx.Value(paramsKey{}).(map[string]string)
+    return params
+}
+
+func withParams(ctx context.Context, params map[string]string) context.Context {
+    if params == nil {
+        return ctx
+    }
+    return context.WithValue(ctx, paramsKey{}, params)
+}
+
+// WithCondition makes a node conditional. The condition is evaluated when the
+// node becomes ready; if it returns false the node is skipped, with the skip
+// propagated to its children according to their edge policies. An error from
+// the condition fails the node.
+func WithCondition(cond func(ctx context.Context) (bool, error)) NodeOption {
+    return func(n *Node) {
+        n.condition = cond
+    }
+}
@@ -0,0 +1,66 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestConditionsAndParams(t *testing.T) {
+    var mu sync.Mutex
+    executed := make(map[string]string)
+    record := func(name string) TaskCtxFunc {
+        return func(ctx context.Context) error {
+            mu.Lock()
+            defer mu.Unlock()
+            executed[name] = Params(ctx)["env"]
+            return nil
+        }
+    }
+    onlyIn := func(env string) func(context.Context) (bool, error) {
+        return func(ctx context.Context) (bool, error) {
+            return Params(ctx)["env"] == env, nil
+        }
+    }
+
+    graph := TaskGraph()
+    graph.AddCtx("build", record("build"))
+    graph.AddCtx("canary", record("canary"), WithCondition(onlyIn("prod")))
+    graph.AddCtx("after-canary", record("after-canary"))
+    graph.AddCtx("seed-data", record("seed-data"), WithCondition(onlyIn("dev")))
+    graph.Precede("build", "canary")
+    graph.Precede("canary", "after-canary")
+    graph.Precede("build", "seed-data")
+
+    run := NewExecutor(graph).NewRun()
+    run.SetParams(map[string]string{"env": "dev"})
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    if executed["build"] != "dev" || executed["seed-data"] != "dev" {
+        t.Errorf("expected build and seed-data to run with env=dev, got %v", executed)
+    }
+    if _, ran := executed["canary"]; ran {
+        t.Errorf("canary should be skipped outside prod")
+    }
+    report := run.Report()
+    if nr := report.Nodes["canary"]; nr.State != StateSkipped || nr.SkipReason != "condition not met" {
+        t.Errorf("canary: got %s (%q)", nr.State, nr.SkipReason)
+    }
+    if nr := report.Nodes["after-canary"]; nr.State != StateSkipped {
+        t.Errorf("after-canary should be skipped with canary, got %s", nr.State)
+    }
+}
+
+func TestConditionError(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil }, WithCondition(func(context.Context) (bool, error) {
+        return false, errors.New("bad expression")
+    }))
+
+    if err := NewExecutor(graph).Execute(); err == nil {
+        t.Fatalf("expected a condition error to fail the run")
+    }
+}
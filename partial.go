@@ -0,0 +1,56 @@
+package leo
+
+// Results returns the values produced so far by the run's function nodes
+// (see AddFunc), by node name. Like Report, it is available after a failed
+// run, to salvage the work that succeeded.
+func (r *Run) Results() map[string]any {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    out := make(map[string]any, len(r.results))
+    for k, v := range r.results {
+        out[k] = v
+    }
+    return out
+}
+
+// Rerun returns a new run of r's graph that only runs the nodes that did not
+// succeed in r: nodes that failed, were skipped or never started. Nodes that
+// succeeded are reported as succeeded without running again, and their
+// results (see Result), artifacts (see RecordArtifact) and the run's state
+// (see Set) are carried over. The new run has r's parameters, name, labels
+// and disabled nodes, and an ID of its own. Call Rerun once r has returned
+// and its tasks have stopped, since tasks still running are rerun.
+func (r *Run) Rerun() *Run {
+    next := r.executor.NewRun()
+    next.graph = r.graph
+    next.reverse = r.reverse
+    next.params = r.params
+    next.name = r.name
+    next.labels = r.labels
+    for n := range r.disabled {
+        next.disabled[n] = true
+    }
+
+    r.stateMu.Lock()
+    next.state = make(map[string]any, len(r.state))
+    for k, v := range r.state {
+        next.state[k] = v
+    }
+    next.results = make(map[string]any, len(r.results))
+    for k, v := range r.results {
+        next.results[k] = v
+    }
+    next.artifacts = append([]Artifact(nil), r.artifacts...)
+    next.secrets = append([]string(nil), r.secrets...)
+    r.stateMu.Unlock()
+
+    next.completed = make(map[*Node]bool)
+    if r.report != nil {
+        for _, name := range r.report.Completed() {
+            if n, ok := r.graph.nodes[name]; ok {
+                next.completed[n] = true
+            }
+        }
+    }
+    return next
+}
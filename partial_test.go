@@ -0,0 +1,65 @@
+package leo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type artifact string
+
+func TestPartialResultsAndRerun(t *testing.T) {
+    runs := make(map[string]int)
+    flaky := true
+    graph := TaskGraph()
+    graph.AddFunc("build", func() artifact {
+        runs["build"]++
+        return "app.tar"
+    })
+    graph.AddFunc("publish", func(out artifact) error {
+        runs["publish"]++
+        if flaky {
+            return errors.New("registry unavailable")
+        }
+        if out != "app.tar" {
+            t.Errorf("publish got %q, want the first run's build output", out)
+        }
+        return nil
+    })
+    graph.Add("announce", func() error { runs["announce"]++; return nil })
+    if err := graph.AutoWire(); err != nil {
+        t.Fatal(err)
+    }
+    graph.Precede("publish", "announce")
+
+    run := NewExecutor(graph).NewRun()
+    run.SetParams(map[string]string{"env": "prod"})
+    if err := run.Execute(); err == nil {
+        t.Fatalf("expected publish to fail")
+    }
+    report := run.Report()
+    if got := report.Completed(); !reflect.DeepEqual(got, []string{"build"}) {
+        t.Errorf("Completed() = %v", got)
+    }
+    if got := report.Failed(); !reflect.DeepEqual(got, []string{"publish"}) {
+        t.Errorf("Failed() = %v", got)
+    }
+    if got := run.Results(); !reflect.DeepEqual(got, map[string]any{"build": artifact("app.tar")}) {
+        t.Errorf("Results() = %v", got)
+    }
+
+    flaky = false
+    rerun := run.Rerun()
+    if rerun.ID() == run.ID() || rerun.params["env"] != "prod" {
+        t.Errorf("expected a new run with the same parameters")
+    }
+    if err := rerun.Execute(); err != nil {
+        t.Fatalf("Rerun failed: %v", err)
+    }
+    if want := map[string]int{"build": 1, "publish": 2, "announce": 1}; !reflect.DeepEqual(runs, want) {
+        t.Errorf("runs = %v, want %v", runs, want)
+    }
+    if got := rerun.Report().Completed(); len(got) != 3 {
+        t.Errorf("expected every node to be reported as succeeded, got %v", got)
+    }
+}
@@ -0,0 +1,442 @@
+package pipeline
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// Expr is a compiled condition or interpolation expression. Expressions are
+// evaluated against run parameters, which are referenced by name:
+//
+//    env == "prod" && (replicas > 2 || force)
+//
+// The language supports string literals in single or double quotes, numbers,
+// true and false, parameter names, parentheses, the comparison operators
+// ==, !=, <, <=, > and >=, and the logical operators &&, || and !. Parameters
+// are strings; comparisons are numeric when both sides are numbers and
+// lexical otherwise. Referencing a parameter that is not set is an error.
+type Expr struct {
+    src  string
+    root exprNode
+}
+
+// CompileExpr parses src.
+func CompileExpr(src string) (*Expr, error) {
+    p := &exprParser{src: src}
+    if err := p.next(); err != nil {
+        return nil, err
+    }
+    root, err := p.or()
+    if err != nil {
+        return nil, err
+    }
+    if p.tok.kind != tokEOF {
+        return nil, p.errorf("unexpected %q", p.tok.text)
+    }
+    return &Expr{src: src, root: root}, nil
+}
+
+// String returns the expression's source.
+func (e *Expr) String() string {
+    return e.src
+}
+
+// Eval evaluates the expression, returning a string, float64 or bool.
+func (e *Expr) Eval(params map[string]string) (any, error) {
+    v, err := e.root.eval(params)
+    if err != nil {
+        return nil, fmt.Errorf("expression %q: %w", e.src, err)
+    }
+    return v, nil
+}
+
+// EvalBool evaluates the expression as a condition.
+func (e *Expr) EvalBool(params map[string]string) (bool, error) {
+    v, err := e.Eval(params)
+    if err != nil {
+        return false, err
+    }
+    b, err := truth(v)
+    if err != nil {
+        return false, fmt.Errorf("expression %q: %w", e.src, err)
+    }
+    return b, nil
+}
+
+// Template is a string with {{ expression }} placeholders, such as
+// "deploy --env={{ env }}".
+type Template struct {
+    text  []string
+    exprs []*Expr
+}
+
+// CompileTemplate parses s.
+func CompileTemplate(s string) (*Template, error) {
+    t := &Template{}
+    for {
+        start := strings.Index(s, "{{")
+        if start < 0 {
+            t.text = append(t.text, s)
+            return t, nil
+        }
+        end := strings.Index(s[start:], "}}")
+        if end < 0 {
+            return nil, fmt.Errorf("template %q: unterminated {{", s)
+        }
+        e, err := CompileExpr(s[start+2 : start+end])
+        if err != nil {
+            return nil, err
+        }
+        t.text = append(t.text, s[:start])
+        t.exprs = append(t.exprs, e)
+        s = s[start+end+2:]
+    }
+}
+
+// Static reports whether the template has no placeholders.
+func (t *Template) Static() bool {
+    return len(t.exprs) == 0
+}
+
+// Render evaluates the template's placeholders against params.
+func (t *Template) Render(params map[string]string) (string, error) {
+    return t.expand(params, nil, nil)
+}
+
+// expand renders the template like Render, passing each piece of the
+// template's own text through text and each placeholder's value through
+// value, if they are not nil.
+func (t *Template) expand(params map[string]string, text func(string) (string, error), value func(string) string) (string, error) {
+    var b strings.Builder
+    for i, s := range t.text {
+        if text != nil {
+            var err error
+            if s, err = text(s); err != nil {
+                return "", err
+            }
+        }
+        b.WriteString(s)
+        if i < len(t.exprs) {
+            v, err := t.exprs[i].Eval(params)
+            if err != nil {
+                return "", err
+            }
+            s := format(v)
+            if value != nil {
+                s = value(s)
+            }
+            b.WriteString(s)
+        }
+    }
+    return b.String(), nil
+}
+
+type exprNode interface {
+    eval(params map[string]string) (any, error)
+}
+
+type literal struct{ v any }
+
+func (l literal) eval(map[string]string) (any, error) { return l.v, nil }
+
+type param struct{ name string }
+
+func (p param) eval(params map[string]string) (any, error) {
+    v, ok := params[p.name]
+    if !ok {
+        return nil, fmt.Errorf("parameter %s is not set", p.name)
+    }
+    return v, nil
+}
+
+type not struct{ x exprNode }
+
+func (n not) eval(params map[string]string) (any, error) {
+    v, err := n.x.eval(params)
+    if err != nil {
+        return nil, err
+    }
+    b, err := truth(v)
+    return !b, err
+}
+
+type binary struct {
+    op   string
+    x, y exprNode
+}
+
+func (b binary) eval(params map[string]string) (any, error) {
+    x, err := b.x.eval(params)
+    if err != nil {
+        return nil, err
+    }
+
+    switch b.op {
+    case "&&", "||":
+        xb, err := truth(x)
+        if err != nil {
+            return nil, err
+        }
+        if (b.op == "&&" && !xb) || (b.op == "||" && xb) {
+            return xb, nil
+        }
+        y, err := b.y.eval(params)
+        if err != nil {
+            return nil, err
+        }
+        return truth(y)
+    }
+
+    y, err := b.y.eval(params)
+    if err != nil {
+        return nil, err
+    }
+
+    var cmp int
+    xn, xok := number(x)
+    yn, yok := number(y)
+    switch {
+    case xok && yok:
+        cmp = compareFloats(xn, yn)
+    default:
+        cmp = strings.Compare(format(x), format(y))
+    }
+
+    switch b.op {
+    case "==":
+        return cmp == 0, nil
+    case "!=":
+        return cmp != 0, nil
+    case "<":
+        return cmp < 0, nil
+    case "<=":
+        return cmp <= 0, nil
+    case ">":
+        return cmp > 0, nil
+    case ">=":
+        return cmp >= 0, nil
+    }
+    return nil, fmt.Errorf("unknown operator %s", b.op)
+}
+
+func truth(v any) (bool, error) {
+    switch v := v.(type) {
+    case bool:
+        return v, nil
+    case string:
+        if b, err := strconv.ParseBool(v); err == nil {
+            return b, nil
+        }
+    }
+    return false, fmt.Errorf("%q is not a boolean", format(v))
+}
+
+func number(v any) (float64, bool) {
+    switch v := v.(type) {
+    case float64:
+        return v, true
+    case string:
+        f, err := strconv.ParseFloat(v, 64)
+        return f, err == nil
+    }
+    return 0, false
+}
+
+func compareFloats(a, b float64) int {
+    switch {
+    case a < b:
+        return -1
+    case a > b:
+        return 1
+    }
+    return 0
+}
+
+func format(v any) string {
+    switch v := v.(type) {
+    case string:
+        return v
+    case float64:
+        return strconv.FormatFloat(v, 'f', -1, 64)
+    case bool:
+        return strconv.FormatBool(v)
+    }
+    return fmt.Sprint(v)
+}
+
+type tokenKind int
+
+const (
+    tokEOF tokenKind = iota
+    tokIdent
+    tokString
+    tokNumber
+    tokOp
+)
+
+type token struct {
+    kind tokenKind
+    text string
+}
+
+type exprParser struct {
+    src string
+    pos int
+    tok token
+}
+
+func (p *exprParser) or() (exprNode, error) {
+    x, err := p.and()
+    for err == nil && p.tok.kind == tokOp && p.tok.text == "||" {
+        if err = p.next(); err != nil {
+            break
+        }
+        var y exprNode
+        if y, err = p.and(); err == nil {
+            x = binary{op: "||", x: x, y: y}
+        }
+    }
+    return x, err
+}
+
+func (p *exprParser) and() (exprNode, error) {
+    x, err := p.unary()
+    for err == nil && p.tok.kind == tokOp && p.tok.text == "&&" {
+        if err = p.next(); err != nil {
+            break
+        }
+        var y exprNode
+        if y, err = p.unary(); err == nil {
+            x = binary{op: "&&", x: x, y: y}
+        }
+    }
+    return x, err
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+    if p.tok.kind == tokOp && p.tok.text == "!" {
+        if err := p.next(); err != nil {
+            return nil, err
+        }
+        x, err := p.unary()
+        return not{x}, err
+    }
+    return p.comparison()
+}
+
+func (p *exprParser) comparison() (exprNode, error) {
+    x, err := p.primary()
+    if err != nil {
+        return nil, err
+    }
+    if p.tok.kind == tokOp {
+        switch op := p.tok.text; op {
+        case "==", "!=", "<", "<=", ">", ">=":
+            if err := p.next(); err != nil {
+                return nil, err
+            }
+            y, err := p.primary()
+            if err != nil {
+                return nil, err
+            }
+            return binary{op: op, x: x, y: y}, nil
+        }
+    }
+    return x, nil
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+    tok := p.tok
+    switch tok.kind {
+    case tokString:
+        return literal{tok.text}, p.next()
+    case tokNumber:
+        f, err := strconv.ParseFloat(tok.text, 64)
+        if err != nil {
+            return nil, p.errorf("invalid number %q", tok.text)
+        }
+        return literal{f}, p.next()
+    case tokIdent:
+        switch tok.text {
+        case "true":
+            return literal{true}, p.next()
+        case "false":
+            return literal{false}, p.next()
+        }
+        return param{tok.text}, p.next()
+    case tokOp:
+        if tok.text == "(" {
+            if err := p.next(); err != nil {
+                return nil, err
+            }
+            x, err := p.or()
+            if err != nil {
+                return nil, err
+            }
+            if p.tok.kind != tokOp || p.tok.text != ")" {
+                return nil, p.errorf("expected )")
+            }
+            return x, p.next()
+        }
+    case tokEOF:
+        return nil, p.errorf("unexpected end of expression")
+    }
+    return nil, p.errorf("unexpected %q", tok.text)
+}
+
+// next advances to the next token.
+func (p *exprParser) next() error {
+    for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+        p.pos++
+    }
+    if p.pos >= len(p.src) {
+        p.tok = token{kind: tokEOF}
+        return nil
+    }
+
+    start := p.pos
+    c := p.src[p.pos]
+    switch {
+    case c == '"' || c == '\'':
+        p.pos++
+        var b strings.Builder
+        for p.pos < len(p.src) && p.src[p.pos] != c {
+            if p.src[p.pos] == '\\' && p.pos+1 < len(p.src) {
+                p.pos++
+            }
+            b.WriteByte(p.src[p.pos])
+            p.pos++
+        }
+        if p.pos >= len(p.src) {
+            return p.errorf("unterminated string")
+        }
+        p.pos++
+        p.tok = token{kind: tokString, text: b.String()}
+    case isDigit(c) || (c == '-' && p.pos+1 < len(p.src) && isDigit(p.src[p.pos+1])):
+        p.pos++
+        for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+            p.pos++
+        }
+        p.tok = token{kind: tokNumber, text: p.src[start:p.pos]}
+    case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+        for p.pos < len(p.src) && (isBareKeyChar(p.src[p.pos]) || p.src[p.pos] == '.') {
+            p.pos++
+        }
+        p.tok = token{kind: tokIdent, text: p.src[start:p.pos]}
+    default:
+        for _, op := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"} {
+            if strings.HasPrefix(p.src[p.pos:], op) {
+                p.pos += len(op)
+                p.tok = token{kind: tokOp, text: op}
+                return nil
+            }
+        }
+        return p.errorf("unexpected %q", c)
+    }
+    return nil
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+    return fmt.Errorf("expression %q: %s", p.src, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,76 @@
+package pipeline
+
+import "testing"
+
+func TestExprEvalBool(t *testing.T) {
+    params := map[string]string{
+        "env":      "prod",
+        "replicas": "3",
+        "force":    "false",
+        "version":  "1.10",
+    }
+
+    for src, want := range map[string]bool{
+        `env == "prod"`:                                true,
+        `env != 'prod'`:                                false,
+        `replicas > 2`:                                 true,
+        `replicas >= 3 && replicas < 4`:                true,
+        `force`:                                        false,
+        `!force`:                                       true,
+        `force || env == "prod"`:                       true,
+        `(env == "dev" || env == "staging") && !force`: false,
+        `version < 1.9`:                                true,
+        `"b" > "a"`:                                    true,
+        `true`:                                         true,
+    } {
+        e, err := CompileExpr(src)
+        if err != nil {
+            t.Errorf("CompileExpr(%q) failed: %v", src, err)
+            continue
+        }
+        got, err := e.EvalBool(params)
+        if err != nil {
+            t.Errorf("EvalBool(%q) failed: %v", src, err)
+            continue
+        }
+        if got != want {
+            t.Errorf("EvalBool(%q) = %t, want %t", src, got, want)
+        }
+    }
+}
+
+func TestExprErrors(t *testing.T) {
+    for _, src := range []string{"", "env ==", "(env", `"open`, "env $ 1", "a b"} {
+        if _, err := CompileExpr(src); err == nil {
+            t.Errorf("CompileExpr(%q) should fail", src)
+        }
+    }
+
+    e, _ := CompileExpr("missing == 1")
+    if _, err := e.EvalBool(map[string]string{}); err == nil {
+        t.Errorf("expected an error for an unset parameter")
+    }
+
+    e, _ = CompileExpr("env")
+    if _, err := e.EvalBool(map[string]string{"env": "prod"}); err == nil {
+        t.Errorf("expected an error for a non-boolean condition")
+    }
+}
+
+func TestTemplate(t *testing.T) {
+    tmpl, err := CompileTemplate("deploy --env={{ env }} --big={{ replicas > 2 }}")
+    if err != nil {
+        t.Fatalf("CompileTemplate failed: %v", err)
+    }
+    got, err := tmpl.Render(map[string]string{"env": "prod", "replicas": "3"})
+    if err != nil {
+        t.Fatalf("Render failed: %v", err)
+    }
+    if want := "deploy --env=prod --big=true"; got != want {
+        t.Errorf("Render = %q, want %q", got, want)
+    }
+
+    if _, err := CompileTemplate("{{ env "); err == nil {
+        t.Errorf("expected an error for an unterminated placeholder")
+    }
+}
@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+
+    "github.com/mips171/leo"
+)
+
+// httpTask sends an HTTP request and fails unless the response status
+// matches. Parameters:
+//
+//    url     request URL (required)
+//    method  request method, default GET
+//    body    request body
+//    headers object of header names to values
+//    status  expected status code, default any 2xx
+//
+// The url, body and header values may contain {{ expression }} placeholders
+// and secret:// URIs, which are resolved when the task runs.
+func httpTask(spec TaskSpec) (leo.TaskCtxFunc, error) {
+    url, err := spec.Template("url")
+    if err != nil {
+        return nil, err
+    }
+    if url == nil {
+        return nil, fmt.Errorf("http: url is required")
+    }
+    method, err := spec.String("method")
+    if err != nil {
+        return nil, err
+    }
+    if method == "" {
+        method = http.MethodGet
+    }
+    body, err := spec.Template("body")
+    if err != nil {
+        return nil, err
+    }
+
+    headers := make(map[string]*Template)
+    if raw, ok := spec.With["headers"]; ok {
+        m, ok := raw.(map[string]any)
+        if !ok {
+            return nil, fmt.Errorf("headers: expected an object")
+        }
+        for name, v := range m {
+            s, ok := v.(string)
+            if !ok {
+                return nil, fmt.Errorf("headers: %s: expected a string", name)
+            }
+            if headers[name], err = CompileTemplate(s); err != nil {
+                return nil, err
+            }
+        }
+    }
+
+    status := 0
+    if raw, ok := spec.With["status"]; ok {
+        switch v := raw.(type) {
+        case float64:
+            status = int(v)
+        case int64:
+            status = int(v)
+        default:
+            return nil, fmt.Errorf("status: expected a number")
+        }
+    }
+
+    return func(ctx context.Context) error {
+        params := spec.Params(ctx)
+        u, err := render(ctx, url, params)
+        if err != nil {
+            return err
+        }
+        var reqBody io.Reader
+        if body != nil {
+            b, err := render(ctx, body, params)
+            if err != nil {
+                return err
+            }
+            reqBody = strings.NewReader(b)
+        }
+
+        req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+        if err != nil {
+            return err
+        }
+        for name, tmpl := range headers {
+            v, err := render(ctx, tmpl, params)
+            if err != nil {
+                return err
+            }
+            req.Header.Set(name, v)
+        }
+
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            return err
+        }
+        defer resp.Body.Close()
+        io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+        if (status != 0 && resp.StatusCode != status) || (status == 0 && resp.StatusCode/100 != 2) {
+            return fmt.Errorf("%s %s: unexpected status %s", method, u, resp.Status)
+        }
+        return nil
+    }, nil
+}
@@ -0,0 +1,178 @@
+package pipeline
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "os"
+    "strings"
+)
+
+// ParseOption configures how a pipeline file is parsed.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+    interpolate bool
+    lookup      func(string) (string, bool)
+}
+
+func newParseConfig(opts []ParseOption) *parseConfig {
+    c := &parseConfig{interpolate: true, lookup: os.LookupEnv}
+    for _, opt := range opts {
+        opt(c)
+    }
+    return c
+}
+
+// NoInterpolation leaves ${...} references in string values as they are, so
+// that a pipeline loads the same way in every environment.
+func NoInterpolation() ParseOption {
+    return func(c *parseConfig) {
+        c.interpolate = false
+    }
+}
+
+// WithEnv resolves ${...} references with lookup instead of the process
+// environment.
+func WithEnv(lookup func(name string) (string, bool)) ParseOption {
+    return func(c *parseConfig) {
+        c.lookup = lookup
+    }
+}
+
+// scriptFields are the fields of a task that interpolation leaves alone:
+// those holding the text of a script or a command, and with, of which only
+// the parameters other than scriptParams, those of the shell and exec types,
+// are interpolated.
+var (
+    scriptFields = map[string]bool{"shell": true, "command": true, "with": true}
+    scriptParams = map[string]bool{"script": true, "command": true}
+)
+
+// interpolateJSON replaces ${NAME} and ${NAME:-default} references in the
+// string values of the JSON document data, except scripts and commands, see
+// Parse. Object keys are left alone.
+func interpolateJSON(data []byte, lookup func(string) (string, bool)) ([]byte, error) {
+    if !bytes.Contains(data, []byte("${")) {
+        return data, nil
+    }
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber()
+    var doc any
+    if err := dec.Decode(&doc); err != nil {
+        // Leave syntax errors for the strict decoder to report.
+        return data, nil
+    }
+    root, ok := doc.(map[string]any)
+    if !ok {
+        return data, nil
+    }
+    tasks, _ := root["tasks"].([]any)
+    for _, item := range tasks {
+        task, ok := item.(map[string]any)
+        if !ok {
+            continue
+        }
+        if with, ok := task["with"].(map[string]any); ok {
+            if err := interpolateFields(with, lookup, scriptParams); err != nil {
+                return nil, err
+            }
+        }
+        if err := interpolateFields(task, lookup, scriptFields); err != nil {
+            return nil, err
+        }
+    }
+    if err := interpolateFields(root, lookup, map[string]bool{"tasks": true}); err != nil {
+        return nil, err
+    }
+    return json.Marshal(root)
+}
+
+// interpolateFields interpolates the values of m, except those of the keys
+// in skip.
+func interpolateFields(m map[string]any, lookup func(string) (string, bool), skip map[string]bool) error {
+    for k, item := range m {
+        if skip[k] {
+            continue
+        }
+        out, err := interpolateValue(item, lookup)
+        if err != nil {
+            return err
+        }
+        m[k] = out
+    }
+    return nil
+}
+
+func interpolateValue(v any, lookup func(string) (string, bool)) (any, error) {
+    switch v := v.(type) {
+    case string:
+        return interpolate(v, lookup)
+    case []any:
+        for i, item := range v {
+            out, err := interpolateValue(item, lookup)
+            if err != nil {
+                return nil, err
+            }
+            v[i] = out
+        }
+    case map[string]any:
+        for k, item := range v {
+            out, err := interpolateValue(item, lookup)
+            if err != nil {
+                return nil, err
+            }
+            v[k] = out
+        }
+    }
+    return v, nil
+}
+
+// interpolate expands the references in s. $${ stands for a literal ${.
+func interpolate(s string, lookup func(string) (string, bool)) (string, error) {
+    var b strings.Builder
+    for {
+        i := strings.Index(s, "${")
+        if i < 0 {
+            b.WriteString(s)
+            return b.String(), nil
+        }
+        if i > 0 && s[i-1] == '$' {
+            b.WriteString(s[:i])
+            b.WriteString("{")
+            s = s[i+2:]
+            continue
+        }
+        b.WriteString(s[:i])
+        end := strings.IndexByte(s[i:], '}')
+        if end < 0 {
+            return "", fmt.Errorf("unterminated ${ in %q", s)
+        }
+        ref := s[i+2 : i+end]
+        name, def, hasDefault := strings.Cut(ref, ":-")
+        if !validEnvName(name) {
+            return "", fmt.Errorf("invalid reference ${%s}", ref)
+        }
+        value, ok := lookup(name)
+        switch {
+        case (!ok || value == "") && hasDefault:
+            value = def
+        case !ok:
+            return "", fmt.Errorf("environment variable %s is not set", name)
+        }
+        b.WriteString(value)
+        s = s[i+end+1:]
+    }
+}
+
+func validEnvName(name string) bool {
+    if name == "" || name[0] >= '0' && name[0] <= '9' {
+        return false
+    }
+    for _, r := range name {
+        if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+            return false
+        }
+    }
+    return true
+}
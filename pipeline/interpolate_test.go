@@ -0,0 +1,69 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInterpolation(t *testing.T) {
+    env := map[string]string{"REGISTRY": "ghcr.io/acme", "EMPTY": ""}
+    lookup := func(name string) (string, bool) {
+        v, ok := env[name]
+        return v, ok
+    }
+    data := []byte(`{
+        "params": {"registry": "${REGISTRY}", "tag": "${TAG:-latest}", "fallback": "${EMPTY:-none}"},
+        "tasks": [
+            {"name": "push", "type": "http", "with": {"url": "https://${REGISTRY}/v2/app:${TAG:-dev}"}},
+            {"name": "echo", "shell": "for f in a b; do echo ${f} $HOME {{ tag }}; done"},
+            {"name": "run", "command": ["sh", "-c", "echo ${f:-none}"]},
+            {"name": "script", "type": "shell", "with": {"script": "echo ${f}"}}
+        ]
+    }`)
+
+    f, err := Parse(data, WithEnv(lookup))
+    if err != nil {
+        t.Fatalf("Parse failed: %v", err)
+    }
+    if f.Params["registry"] != "ghcr.io/acme" || f.Params["tag"] != "latest" || f.Params["fallback"] != "none" {
+        t.Errorf("unexpected params %v", f.Params)
+    }
+    if got := f.Tasks[0].With["url"]; got != "https://ghcr.io/acme/v2/app:dev" {
+        t.Errorf("unexpected with parameter %q", got)
+    }
+    // Scripts and commands keep their shell variables for the shell.
+    if got := f.Tasks[1].Shell; got != "for f in a b; do echo ${f} $HOME {{ tag }}; done" {
+        t.Errorf("unexpected script %q", got)
+    }
+    if got := f.Tasks[2].Command[2]; got != "echo ${f:-none}" {
+        t.Errorf("unexpected command argument %q", got)
+    }
+    if got := f.Tasks[3].With["script"]; got != "echo ${f}" {
+        t.Errorf("unexpected script parameter %q", got)
+    }
+    script := []byte(`{"tasks": [{"name": "echo", "shell": "for f in a b; do echo ${f}; done"}]}`)
+    if err := Validate(script); err != nil {
+        t.Errorf("expected shell variables not to affect validation, got %v", err)
+    }
+
+    raw, err := Parse(data, NoInterpolation())
+    if err != nil {
+        t.Fatalf("Parse failed: %v", err)
+    }
+    if raw.Params["registry"] != "${REGISTRY}" {
+        t.Errorf("expected NoInterpolation to keep references, got %v", raw.Params)
+    }
+
+    for _, src := range []string{
+        `{"tasks": [{"name": "${MISSING}"}]}`,
+        `{"tasks": [{"name": "${REGISTRY"}]}`,
+        `{"tasks": [{"name": "${1X}"}]}`,
+    } {
+        if _, err := Parse([]byte(src), WithEnv(lookup)); err == nil {
+            t.Errorf("expected %s to fail", src)
+        }
+    }
+    if _, err := Parse([]byte(`{"tasks": [{"name": "${REGISTRY}", "bogus": 1}]}`), WithEnv(lookup)); err == nil || !strings.Contains(err.Error(), "bogus") {
+        t.Errorf("expected unknown fields to still be rejected, got %v", err)
+    }
+}
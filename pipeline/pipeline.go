@@ -0,0 +1,335 @@
+// Package pipeline loads leo task graphs from declarative pipeline files.
+//
+// A pipeline file is a JSON or TOML document listing tasks and their dependencies:
+//
+//    {
+//        "name": "deploy",
+//        "tasks": [
+//            {"name": "build", "shell": "make build"},
+//            {"name": "push", "command": ["docker", "push", "app"], "depends_on": ["build"]},
+//            {"name": "rollback", "shell": "./rollback.sh", "fallback_for": ["push"]}
+//        ]
+//    }
+//
+// Each task's type names a factory in a Registry; the shell and command fields
+// are shorthands for the built-in shell and exec types. See LoadTOML for the
+// TOML form, and Profile for per-environment overlays. The format is
+// described by the JSON Schema returned by Schema.
+package pipeline
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/mips171/leo"
+)
+
+// File is the top-level structure of a pipeline file.
+type File struct {
+    Name        string             `json:"name,omitempty" desc:"Human-readable pipeline name."`
+    Params      map[string]string  `json:"params,omitempty" desc:"Default values for run parameters, overridden by the parameters a run is started with."`
+    Concurrency int                `json:"concurrency,omitempty" desc:"Maximum number of tasks run at once. 0 uses the executor's default and a negative value removes the limit."`
+    Stages      []Stage            `json:"stages,omitempty" desc:"Phases of the pipeline, run in order with a barrier between them. Tasks join a phase with stage."`
+    Tasks       []Task             `json:"tasks" desc:"Tasks in the pipeline. Order does not affect execution."`
+    Profiles    map[string]Profile `json:"profiles,omitempty" desc:"Named overlays, such as dev, staging or prod, of which one may be selected when the pipeline is loaded."`
+}
+
+// Stage describes a phase of the pipeline, see leo.Graph.Stage.
+type Stage struct {
+    Name        string `json:"name" desc:"Unique stage name, used to place tasks in the stage."`
+    Timeout     string `json:"timeout,omitempty" desc:"Time the stage may run, such as 10m, from when its first tasks may start until its last finishes. Its running tasks are then cancelled and later stages skipped."`
+    Concurrency int    `json:"concurrency,omitempty" desc:"Maximum number of the stage's tasks run at once, such as 5 for a rollout to 5 devices at a time. 0 leaves only the pipeline's limit."`
+}
+
+// Task describes a single node of the pipeline.
+type Task struct {
+    Name             string         `json:"name" desc:"Unique task name, used to reference the task from other tasks."`
+    Aliases          []string       `json:"aliases,omitempty" desc:"Former names of the task, by which other tasks, profiles and targets may still reference it."`
+    Type             string         `json:"type,omitempty" desc:"Task type, naming a factory in the task registry: noop, shell, exec, http or an application-defined type. Defaults to noop."`
+    With             map[string]any `json:"with,omitempty" desc:"Parameters for the task type. String values may contain {{ expression }} placeholders."`
+    Shell            string         `json:"shell,omitempty" desc:"Script run with sh -c. May contain {{ expression }} placeholders evaluated against run parameters, passed to sh as positional parameters so that they cannot inject commands, and secret:// references. Shorthand for type shell."`
+    Command          []string       `json:"command,omitempty" desc:"Program and arguments to run. Arguments may contain {{ expression }} placeholders and secret:// references. Shorthand for type exec."`
+    When             string         `json:"when,omitempty" desc:"Condition evaluated against run parameters when the task becomes ready, such as env == 'prod'. The task is skipped if it is false."`
+    DependsOn        []string       `json:"depends_on,omitempty" desc:"Tasks that must succeed before this task runs."`
+    FallbackFor      []string       `json:"fallback_for,omitempty" desc:"Tasks whose failure triggers this task. The task is skipped if they all succeed."`
+    ExpectedDuration string         `json:"expected_duration,omitempty" desc:"Expected duration, such as 30s; longer runs are reported as SLA violations."`
+    Hedge            string         `json:"hedge,omitempty" desc:"Start a second attempt after this duration, such as 5s, and keep whichever succeeds first."`
+    Retries          int            `json:"retries,omitempty" desc:"Number of times to retry the task if it fails."`
+    RetryDelay       string         `json:"retry_delay,omitempty" desc:"Wait before the first retry, such as 1s, doubled before each following one."`
+    Idempotent       *bool          `json:"idempotent,omitempty" desc:"Whether the task may safely run more than once. Tasks marked false are not retried, hedged or rerun on recovery without confirmation. Defaults to true."`
+    Tags             []string       `json:"tags,omitempty" desc:"Labels for selecting the task's events, such as a team or resource name."`
+    Requires         []string       `json:"requires,omitempty" desc:"Worker labels the task needs, such as has-gpu or site=syd. The executor must have a worker pool."`
+    Disabled         bool           `json:"disabled,omitempty" desc:"Skip the task in every run. Tasks that depend on it are skipped too."`
+    Stage            string         `json:"stage,omitempty" desc:"Stage the task belongs to, which must be declared in stages."`
+}
+
+// Parse decodes a pipeline file without building a graph. Unknown fields are
+// an error.
+//
+// String values may refer to environment variables as ${NAME}, or
+// ${NAME:-default} to use default when NAME is unset or empty; $${ stands
+// for a literal ${. A reference to an unset variable without a default is
+// an error. References are resolved once, when the file is parsed, unlike
+// {{ expression }} placeholders; see NoInterpolation and WithEnv. The shell
+// and command of tasks, and the script and command with parameters, are left
+// as they are, so that a script's own ${...} references are expanded by the
+// shell when the task runs.
+func Parse(data []byte, opts ...ParseOption) (*File, error) {
+    if c := newParseConfig(opts); c.interpolate {
+        var err error
+        if data, err = interpolateJSON(data, c.lookup); err != nil {
+            return nil, fmt.Errorf("pipeline: %w", err)
+        }
+    }
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.DisallowUnknownFields()
+
+    var f File
+    if err := dec.Decode(&f); err != nil {
+        return nil, fmt.Errorf("pipeline: %w", err)
+    }
+    return &f, nil
+}
+
+// Load reads a pipeline file from r and builds its graph.
+func Load(r io.Reader, opts ...ParseOption) (*leo.Graph, error) {
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return nil, err
+    }
+    f, err := Parse(data, opts...)
+    if err != nil {
+        return nil, err
+    }
+    return f.Graph()
+}
+
+// LoadFile is Load for a file on disk. Files with a .toml extension are read
+// as TOML documents containing only a pipeline; use LoadTOML to read a
+// pipeline from a table within a larger TOML file.
+func LoadFile(path string, opts ...ParseOption) (*leo.Graph, error) {
+    data, err := ReadFile(path, "")
+    if err != nil {
+        return nil, err
+    }
+    f, err := Parse(data, opts...)
+    if err != nil {
+        return nil, err
+    }
+    return f.Graph()
+}
+
+// task returns the task function for t, built by the factory registered
+// for its type. The shell and command shorthands select the shell and exec
+// types. A task with neither a type nor a shorthand has no body, and task
+// returns nil for it.
+func (f *File) task(t Task, reg *Registry) (leo.TaskCtxFunc, error) {
+    spec := TaskSpec{Name: t.Name, Type: t.Type, With: t.With, file: f}
+
+    shorthands := 0
+    if t.Shell != "" {
+        shorthands++
+        spec.Type, spec.With = "shell", map[string]any{"script": t.Shell}
+    }
+    if len(t.Command) > 0 {
+        shorthands++
+        command := make([]any, len(t.Command))
+        for i, arg := range t.Command {
+            command[i] = arg
+        }
+        spec.Type, spec.With = "exec", map[string]any{"command": command}
+    }
+    switch {
+    case shorthands > 1:
+        return nil, fmt.Errorf("shell and command are mutually exclusive")
+    case shorthands == 1 && (t.Type != "" || t.With != nil):
+        return nil, fmt.Errorf("type and with cannot be combined with shell or command")
+    case spec.Type == "":
+        return nil, nil
+    }
+
+    factory, ok := reg.Lookup(spec.Type)
+    if !ok {
+        return nil, fmt.Errorf("unknown task type %q", spec.Type)
+    }
+    return factory(spec)
+}
+
+// params returns the file's default parameters overridden by the run's.
+func (f *File) params(ctx context.Context) map[string]string {
+    run := leo.Params(ctx)
+    if len(f.Params) == 0 {
+        return run
+    }
+    params := make(map[string]string, len(f.Params)+len(run))
+    for k, v := range f.Params {
+        params[k] = v
+    }
+    for k, v := range run {
+        params[k] = v
+    }
+    return params
+}
+
+// ReadFile reads a pipeline file and returns it as JSON, converting TOML files
+// (by their .toml extension) using the table at tomlKey.
+func ReadFile(path, tomlKey string) ([]byte, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    if strings.EqualFold(filepath.Ext(path), ".toml") {
+        return TOMLToJSON(data, tomlKey)
+    }
+    return data, nil
+}
+
+// Graph builds the task graph described by f, using the task types in
+// DefaultRegistry.
+func (f *File) Graph() (*leo.Graph, error) {
+    return f.GraphWithRegistry(DefaultRegistry)
+}
+
+// GraphWithRegistry builds the task graph described by f, using the task
+// types in reg.
+func (f *File) GraphWithRegistry(reg *Registry) (*leo.Graph, error) {
+    graph := leo.TaskGraph()
+    seen := make(map[string]bool, len(f.Tasks))
+
+    stages := make(map[string]bool, len(f.Stages))
+    for i, s := range f.Stages {
+        if s.Name == "" {
+            return nil, fmt.Errorf("pipeline: stage %d has no name", i)
+        }
+        var opts []leo.StageOption
+        if s.Timeout != "" {
+            d, err := time.ParseDuration(s.Timeout)
+            if err != nil {
+                return nil, fmt.Errorf("pipeline: stage %s: timeout: %w", s.Name, err)
+            }
+            opts = append(opts, leo.StageTimeout(d))
+        }
+        if s.Concurrency > 0 {
+            opts = append(opts, leo.StageConcurrency(s.Concurrency))
+        }
+        if err := graph.Stage(s.Name, opts...); err != nil {
+            return nil, fmt.Errorf("pipeline: %w", err)
+        }
+        stages[s.Name] = true
+    }
+
+    for i, t := range f.Tasks {
+        if t.Name == "" {
+            return nil, fmt.Errorf("pipeline: task %d has no name", i)
+        }
+        if seen[t.Name] {
+            return nil, fmt.Errorf("pipeline: task %s defined more than once", t.Name)
+        }
+        seen[t.Name] = true
+
+        var opts []leo.NodeOption
+        if t.ExpectedDuration != "" {
+            d, err := time.ParseDuration(t.ExpectedDuration)
+            if err != nil {
+                return nil, fmt.Errorf("pipeline: task %s: expected_duration: %w", t.Name, err)
+            }
+            opts = append(opts, leo.WithExpectedDuration(d))
+        }
+        if t.Hedge != "" {
+            d, err := time.ParseDuration(t.Hedge)
+            if err != nil {
+                return nil, fmt.Errorf("pipeline: task %s: hedge: %w", t.Name, err)
+            }
+            opts = append(opts, leo.WithHedge(d))
+        }
+        if t.Retries > 0 {
+            var delay time.Duration
+            if t.RetryDelay != "" {
+                d, err := time.ParseDuration(t.RetryDelay)
+                if err != nil {
+                    return nil, fmt.Errorf("pipeline: task %s: retry_delay: %w", t.Name, err)
+                }
+                delay = d
+            }
+            opts = append(opts, leo.WithRetry(t.Retries, delay))
+        }
+        if t.Idempotent != nil {
+            opts = append(opts, leo.Idempotent(*t.Idempotent))
+        }
+
+        if len(t.Tags) > 0 {
+            opts = append(opts, leo.WithTags(t.Tags...))
+        }
+        if len(t.Requires) > 0 {
+            opts = append(opts, leo.RequireWorker(t.Requires...))
+        }
+        if t.Disabled {
+            opts = append(opts, leo.Disabled())
+        }
+        if t.Stage != "" {
+            if !stages[t.Stage] {
+                return nil, fmt.Errorf("pipeline: task %s is in unknown stage %s", t.Name, t.Stage)
+            }
+            opts = append(opts, leo.InStage(t.Stage))
+        }
+
+        if t.When != "" {
+            cond, err := CompileExpr(t.When)
+            if err != nil {
+                return nil, fmt.Errorf("pipeline: task %s: when: %w", t.Name, err)
+            }
+            opts = append(opts, leo.WithCondition(func(ctx context.Context) (bool, error) {
+                return cond.EvalBool(f.params(ctx))
+            }))
+        }
+
+        task, err := f.task(t, reg)
+        if err != nil {
+            return nil, fmt.Errorf("pipeline: task %s: %w", t.Name, err)
+        }
+        if t.Shell != "" {
+            opts = append(opts, leo.WithCommand(t.Shell))
+        }
+        graph.AddCtx(t.Name, task, opts...)
+    }
+
+    for _, t := range f.Tasks {
+        for _, alias := range t.Aliases {
+            if seen[alias] {
+                return nil, fmt.Errorf("pipeline: task %s: alias %s is already used", t.Name, alias)
+            }
+            seen[alias] = true
+            if err := graph.Alias(alias, t.Name); err != nil {
+                return nil, fmt.Errorf("pipeline: task %s: %w", t.Name, err)
+            }
+        }
+    }
+
+    for _, t := range f.Tasks {
+        for _, dep := range t.DependsOn {
+            if !seen[dep] {
+                return nil, fmt.Errorf("pipeline: task %s depends on unknown task %s", t.Name, dep)
+            }
+            if err := graph.Precede(dep, t.Name); err != nil {
+                return nil, fmt.Errorf("pipeline: %s -> %s: %w", dep, t.Name, err)
+            }
+        }
+        for _, protected := range t.FallbackFor {
+            if !seen[protected] {
+                return nil, fmt.Errorf("pipeline: task %s is a fallback for unknown task %s", t.Name, protected)
+            }
+            if err := graph.OnFailure(protected, t.Name); err != nil {
+                return nil, fmt.Errorf("pipeline: %s -> %s (on failure): %w", protected, t.Name, err)
+            }
+        }
+    }
+
+    return graph, nil
+}
@@ -0,0 +1,214 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mips171/leo"
+)
+
+func TestLoad(t *testing.T) {
+    dir := t.TempDir()
+    out := filepath.Join(dir, "out.txt")
+
+    file := `{
+        "name": "deploy",
+        "tasks": [
+            {"name": "build", "shell": "echo build >> ` + out + `", "expected_duration": "10s"},
+            {"name": "push", "command": ["sh", "-c", "echo push >> ` + out + `"], "depends_on": ["build"]},
+            {"name": "rollback", "shell": "echo rollback >> ` + out + `", "fallback_for": ["push"]},
+            {"name": "done", "depends_on": ["push"]}
+        ]
+    }`
+
+    graph, err := Load(strings.NewReader(file))
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+
+    if want := []leo.Edge{
+        {From: "build", To: "push"},
+        {From: "push", To: "done"},
+        {From: "push", To: "rollback", Fallback: true},
+    }; !reflect.DeepEqual(graph.Edges(), want) {
+        t.Errorf("edges = %v, want %v", graph.Edges(), want)
+    }
+
+    if err := leo.NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    data, err := os.ReadFile(out)
+    if err != nil {
+        t.Fatalf("reading output: %v", err)
+    }
+    if got := string(data); got != "build\npush\n" {
+        t.Errorf("unexpected output %q", got)
+    }
+}
+
+func TestLoadErrors(t *testing.T) {
+    for _, file := range []string{
+        `{"tasks": [{"name": "a", "depends_on": ["missing"]}]}`,
+        `{"tasks": [{"name": "a"}, {"name": "a"}]}`,
+        `{"tasks": [{"name": "a", "depends_on": ["b"]}, {"name": "b", "depends_on": ["a"]}]}`,
+        `{"tasks": [{"name": "a", "shell": "true", "command": ["true"]}]}`,
+        `{"tasks": [{"name": "a", "expected_duration": "soon"}]}`,
+        `{"tasks": [{"name": "a", "unknown": true}]}`,
+        `{"tasks": [{"name": "a", "aliases": ["b"]}, {"name": "b"}]}`,
+        `{"tasks": [{"name": "a", "stage": "missing"}]}`,
+        `{"stages": [{"name": "s", "timeout": "soon"}], "tasks": []}`,
+        `{"stages": [{"name": "s"}, {"name": "s"}], "tasks": []}`,
+        `{"tasks": [{"name": "a", "aliases": ["c"]}, {"name": "b", "aliases": ["c"]}]}`,
+    } {
+        if _, err := Load(strings.NewReader(file)); err == nil {
+            t.Errorf("expected an error for %s", file)
+        }
+    }
+}
+
+func TestLoadDisabled(t *testing.T) {
+    graph, err := Load(strings.NewReader(`{
+        "tasks": [
+            {"name": "build", "disabled": true},
+            {"name": "push", "depends_on": ["build"]},
+            {"name": "lint"}
+        ]
+    }`))
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+    executor := leo.NewExecutor(graph)
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    report := executor.Report()
+    if got := report.Skipped(); !reflect.DeepEqual(got, []string{"build", "push"}) {
+        t.Errorf("expected build and the task depending on it to be skipped, got %v", got)
+    }
+    if nr := report.Nodes["push"]; nr.SkipReason != "upstream build skipped" {
+        t.Errorf("unexpected skip reason %q", nr.SkipReason)
+    }
+    if nr := report.Nodes["lint"]; nr.State != leo.StateSucceeded {
+        t.Errorf("expected lint to run, got %s", nr.State)
+    }
+}
+
+func TestLoadAliases(t *testing.T) {
+    data := []byte(`{
+        "tasks": [
+            {"name": "compile", "aliases": ["build"]},
+            {"name": "test", "depends_on": ["build"]},
+            {"name": "publish", "depends_on": ["compile"], "fallback_for": ["build"]}
+        ],
+        "profiles": {"quick": {"disable": ["build"]}}
+    }`)
+    f, err := ParseProfile(data, "quick")
+    if err != nil {
+        t.Fatalf("ParseProfile failed: %v", err)
+    }
+    if !f.Tasks[0].Disabled {
+        t.Errorf("expected the profile to disable compile through its alias")
+    }
+    graph, err := f.Graph()
+    if err != nil {
+        t.Fatalf("Graph failed: %v", err)
+    }
+    if name, ok := graph.Resolve("build"); !ok || name != "compile" {
+        t.Errorf("expected build to resolve to compile, got %q", name)
+    }
+    if want := []leo.Edge{
+        {From: "compile", To: "publish"},
+        {From: "compile", To: "publish", Fallback: true},
+        {From: "compile", To: "test"},
+    }; !reflect.DeepEqual(graph.Edges(), want) {
+        t.Errorf("edges = %v, want %v", graph.Edges(), want)
+    }
+}
+
+func TestLoadStages(t *testing.T) {
+    graph, err := Load(strings.NewReader(`{
+        "stages": [
+            {"name": "download", "timeout": "1h", "concurrency": 2},
+            {"name": "verify", "timeout": "20ms"}
+        ],
+        "tasks": [
+            {"name": "fetch", "stage": "download"},
+            {"name": "check", "command": ["sleep", "5"], "stage": "verify"}
+        ]
+    }`))
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+    if got := graph.Stages(); !reflect.DeepEqual(got, []string{"download", "verify"}) {
+        t.Errorf("stages = %v", got)
+    }
+
+    start := time.Now()
+    executor := leo.NewExecutor(graph)
+    if err := executor.Execute(); err == nil {
+        t.Fatalf("expected the verify stage to time out")
+    }
+    if elapsed := time.Since(start); elapsed > 2*time.Second {
+        t.Errorf("expected the stuck stage to fail fast, took %v", elapsed)
+    }
+    if state := executor.Report().Nodes["fetch"].State; state != leo.StateSucceeded {
+        t.Errorf("expected fetch to succeed within its budget, got %v", state)
+    }
+}
+
+func TestLoadWhenAndParams(t *testing.T) {
+    dir := t.TempDir()
+    out := filepath.Join(dir, "out.txt")
+
+    file := `{
+        "params": {"env": "dev", "region": "syd"},
+        "tasks": [
+            {"name": "deploy", "shell": "echo deploy {{ env }} {{ region }} >> ` + out + `"},
+            {"name": "canary", "shell": "echo canary >> ` + out + `", "when": "env == 'prod'", "depends_on": ["deploy"]}
+        ]
+    }`
+
+    graph, err := Load(strings.NewReader(file))
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+
+    for _, env := range []string{"dev", "prod"} {
+        run := leo.NewExecutor(graph).NewRun()
+        run.SetParams(map[string]string{"env": env})
+        if err := run.Execute(); err != nil {
+            t.Fatalf("Execute failed: %v", err)
+        }
+    }
+
+    data, err := os.ReadFile(out)
+    if err != nil {
+        t.Fatalf("reading output: %v", err)
+    }
+    if got, want := string(data), "deploy dev syd\ndeploy prod syd\ncanary\n"; got != want {
+        t.Errorf("output %q, want %q", got, want)
+    }
+
+    if _, err := Load(strings.NewReader(`{"tasks": [{"name": "a", "when": "env =="}]}`)); err == nil {
+        t.Errorf("expected an error for an invalid condition")
+    }
+}
+
+func TestLoadRequires(t *testing.T) {
+    graph, err := Load(strings.NewReader(`{"tasks": [{"name": "train", "requires": ["has-gpu"]}]}`))
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+    executor := leo.NewExecutor(graph)
+    if err := executor.Execute(); err == nil {
+        t.Errorf("expected the requirement to need a worker pool")
+    }
+    executor.SetWorkerPool(leo.NewWorkerPool(leo.Worker{Labels: map[string]string{"has-gpu": ""}}))
+    if err := executor.Execute(); err != nil {
+        t.Errorf("Execute failed: %v", err)
+    }
+}
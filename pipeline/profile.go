@@ -0,0 +1,109 @@
+package pipeline
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/mips171/leo"
+)
+
+// Profile overlays a pipeline file for one environment, such as dev or
+// prod, so that a single file can describe them all:
+//
+//    {
+//        "params": {"env": "dev"},
+//        "concurrency": 2,
+//        "tasks": [
+//            {"name": "build", "shell": "make build"},
+//            {"name": "notify", "shell": "./notify.sh", "disabled": true}
+//        ],
+//        "profiles": {
+//            "prod": {"params": {"env": "prod"}, "concurrency": 8, "enable": ["notify"]}
+//        }
+//    }
+type Profile struct {
+    Params      map[string]string `json:"params,omitempty" desc:"Parameter defaults that override the file's."`
+    Concurrency *int              `json:"concurrency,omitempty" desc:"Overrides the file's concurrency."`
+    Enable      []string          `json:"enable,omitempty" desc:"Tasks to run even though the file disables them."`
+    Disable     []string          `json:"disable,omitempty" desc:"Tasks to skip in every run, along with the tasks that depend on them."`
+}
+
+// WithProfile returns a copy of f with the named profile applied and no
+// profiles of its own. An empty name returns f unchanged. It is an error for
+// the profile not to exist, or to enable or disable unknown tasks.
+func (f *File) WithProfile(name string) (*File, error) {
+    if name == "" {
+        return f, nil
+    }
+    p, ok := f.Profiles[name]
+    if !ok {
+        return nil, fmt.Errorf("pipeline: unknown profile %q (have %s)", name, strings.Join(f.profileNames(), ", "))
+    }
+
+    out := *f
+    out.Profiles = nil
+    if len(p.Params) > 0 {
+        out.Params = make(map[string]string, len(f.Params)+len(p.Params))
+        for k, v := range f.Params {
+            out.Params[k] = v
+        }
+        for k, v := range p.Params {
+            out.Params[k] = v
+        }
+    }
+    if p.Concurrency != nil {
+        out.Concurrency = *p.Concurrency
+    }
+
+    out.Tasks = make([]Task, len(f.Tasks))
+    copy(out.Tasks, f.Tasks)
+    index := make(map[string]int, len(out.Tasks))
+    for i, t := range out.Tasks {
+        index[t.Name] = i
+        for _, alias := range t.Aliases {
+            index[alias] = i
+        }
+    }
+    for _, list := range []struct {
+        names    []string
+        disabled bool
+    }{{p.Enable, false}, {p.Disable, true}} {
+        for _, task := range list.names {
+            i, ok := index[task]
+            if !ok {
+                return nil, fmt.Errorf("pipeline: profile %s: unknown task %s", name, task)
+            }
+            out.Tasks[i].Disabled = list.disabled
+        }
+    }
+    return &out, nil
+}
+
+func (f *File) profileNames() []string {
+    names := make([]string, 0, len(f.Profiles))
+    for name := range f.Profiles {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// ParseProfile is Parse followed by WithProfile.
+func ParseProfile(data []byte, profile string, opts ...ParseOption) (*File, error) {
+    f, err := Parse(data, opts...)
+    if err != nil {
+        return nil, err
+    }
+    return f.WithProfile(profile)
+}
+
+// ExecutorOptions returns the executor settings described by f, for
+// leo.NewExecutor: its concurrency limit, if it has one.
+func (f *File) ExecutorOptions() []leo.ExecutorOption {
+    var opts []leo.ExecutorOption
+    if f.Concurrency != 0 {
+        opts = append(opts, leo.WithConcurrency(f.Concurrency))
+    }
+    return opts
+}
@@ -0,0 +1,76 @@
+package pipeline
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mips171/leo"
+)
+
+func TestProfiles(t *testing.T) {
+    data := []byte(`{
+        "params": {"env": "dev", "region": "syd"},
+        "concurrency": 2,
+        "tasks": [
+            {"name": "build"},
+            {"name": "notify", "disabled": true, "depends_on": ["build"]},
+            {"name": "seed", "depends_on": ["build"]}
+        ],
+        "profiles": {
+            "prod": {"params": {"env": "prod"}, "concurrency": -1, "enable": ["notify"], "disable": ["seed"]},
+            "ci": {}
+        }
+    }`)
+    if err := Validate(data); err != nil {
+        t.Fatalf("Validate failed: %v", err)
+    }
+
+    dev, err := ParseProfile(data, "")
+    if err != nil {
+        t.Fatalf("ParseProfile failed: %v", err)
+    }
+    prod, err := ParseProfile(data, "prod")
+    if err != nil {
+        t.Fatalf("ParseProfile failed: %v", err)
+    }
+    if want := map[string]string{"env": "prod", "region": "syd"}; !reflect.DeepEqual(prod.Params, want) {
+        t.Errorf("prod params = %v, want %v", prod.Params, want)
+    }
+    if dev.Params["env"] != "dev" || !dev.Tasks[1].Disabled || dev.Tasks[2].Disabled {
+        t.Errorf("expected applying prod to leave the file unchanged, got %+v", dev)
+    }
+    if prod.Concurrency != -1 || prod.Profiles != nil {
+        t.Errorf("unexpected prod file %+v", prod)
+    }
+    if len(prod.ExecutorOptions()) != 1 {
+        t.Errorf("expected prod's concurrency to become an executor option")
+    }
+
+    skipped := func(f *File) []string {
+        graph, err := f.Graph()
+        if err != nil {
+            t.Fatalf("Graph failed: %v", err)
+        }
+        executor := leo.NewExecutor(graph, f.ExecutorOptions()...)
+        if err := executor.ExecuteContext(context.Background()); err != nil {
+            t.Fatalf("Execute failed: %v", err)
+        }
+        return executor.Report().Skipped()
+    }
+    if got := skipped(dev); !reflect.DeepEqual(got, []string{"notify"}) {
+        t.Errorf("dev skipped %v", got)
+    }
+    if got := skipped(prod); !reflect.DeepEqual(got, []string{"seed"}) {
+        t.Errorf("prod skipped %v", got)
+    }
+
+    if _, err := ParseProfile(data, "staging"); err == nil {
+        t.Errorf("expected an unknown profile to fail")
+    }
+    f, _ := Parse(data)
+    f.Profiles["broken"] = Profile{Enable: []string{"missing"}}
+    if _, err := f.WithProfile("broken"); err == nil {
+        t.Errorf("expected a profile enabling an unknown task to fail")
+    }
+}
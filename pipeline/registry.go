@@ -0,0 +1,302 @@
+package pipeline
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/mips171/leo"
+)
+
+// Factory builds the task for a pipeline node of a registered type. It is
+// called once when the graph is built; the returned task runs on every
+// execution.
+type Factory func(spec TaskSpec) (leo.TaskCtxFunc, error)
+
+// TaskSpec is the configuration a Factory receives: the task's name, its type
+// and the parameters from its "with" object.
+type TaskSpec struct {
+    Name string
+    Type string
+    With map[string]any
+
+    file *File
+}
+
+// String returns the string parameter key, or "" if it is not set.
+func (s TaskSpec) String(key string) (string, error) {
+    v, ok := s.With[key]
+    if !ok {
+        return "", nil
+    }
+    str, ok := v.(string)
+    if !ok {
+        return "", fmt.Errorf("%s: expected a string", key)
+    }
+    return str, nil
+}
+
+// Strings returns the string array parameter key, or nil if it is not set.
+func (s TaskSpec) Strings(key string) ([]string, error) {
+    v, ok := s.With[key]
+    if !ok {
+        return nil, nil
+    }
+    arr, ok := v.([]any)
+    if !ok {
+        return nil, fmt.Errorf("%s: expected an array of strings", key)
+    }
+    out := make([]string, len(arr))
+    for i, item := range arr {
+        str, ok := item.(string)
+        if !ok {
+            return nil, fmt.Errorf("%s: expected an array of strings", key)
+        }
+        out[i] = str
+    }
+    return out, nil
+}
+
+// Duration returns the duration parameter key, such as "30s", or 0 if it is
+// not set.
+func (s TaskSpec) Duration(key string) (time.Duration, error) {
+    str, err := s.String(key)
+    if err != nil || str == "" {
+        return 0, err
+    }
+    d, err := time.ParseDuration(str)
+    if err != nil {
+        return 0, fmt.Errorf("%s: %w", key, err)
+    }
+    return d, nil
+}
+
+// Template compiles the string parameter key as a template, or returns nil if
+// it is not set.
+func (s TaskSpec) Template(key string) (*Template, error) {
+    str, err := s.String(key)
+    if err != nil || str == "" {
+        return nil, err
+    }
+    t, err := CompileTemplate(str)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", key, err)
+    }
+    return t, nil
+}
+
+// Params returns the parameters of the run executing the task, with the
+// pipeline file's defaults filled in, for rendering templates.
+func (s TaskSpec) Params(ctx context.Context) map[string]string {
+    if s.file == nil {
+        return leo.Params(ctx)
+    }
+    return s.file.params(ctx)
+}
+
+// Registry maps task type names to factories. It is safe for concurrent use.
+type Registry struct {
+    mu        sync.RWMutex
+    factories map[string]Factory
+}
+
+// NewRegistry returns a registry containing the built-in task types: noop,
+// shell, exec and http.
+func NewRegistry() *Registry {
+    r := &Registry{factories: make(map[string]Factory)}
+    r.factories["noop"] = noopTask
+    r.factories["shell"] = shellTask
+    r.factories["exec"] = execTask
+    r.factories["http"] = httpTask
+    return r
+}
+
+// DefaultRegistry is used by Load, LoadFile, LoadTOML and File.Graph.
+var DefaultRegistry = NewRegistry()
+
+// Register adds a task type to DefaultRegistry.
+func Register(typ string, f Factory) error {
+    return DefaultRegistry.Register(typ, f)
+}
+
+// Register adds a task type. Registering a type that already exists is an
+// error.
+func (r *Registry) Register(typ string, f Factory) error {
+    if typ == "" || f == nil {
+        return fmt.Errorf("pipeline: task type needs a name and a factory")
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if _, exists := r.factories[typ]; exists {
+        return fmt.Errorf("pipeline: task type %q already registered", typ)
+    }
+    r.factories[typ] = f
+    return nil
+}
+
+// Lookup returns the factory for typ.
+func (r *Registry) Lookup(typ string) (Factory, bool) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    f, ok := r.factories[typ]
+    return f, ok
+}
+
+// Types returns the registered type names, sorted.
+func (r *Registry) Types() []string {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    types := make([]string, 0, len(r.factories))
+    for typ := range r.factories {
+        types = append(types, typ)
+    }
+    sort.Strings(types)
+    return types
+}
+
+func noopTask(TaskSpec) (leo.TaskCtxFunc, error) {
+    return func(context.Context) error { return nil }, nil
+}
+
+// shellTask runs with.script using sh -c. The script may contain
+// {{ expression }} placeholders and secret:// URIs. The value of each
+// placeholder is passed to sh as a positional parameter, which the script
+// refers to in its place, so that run parameters cannot inject commands
+// whether the placeholder is quoted or not. See sandboxKeys for the resource
+// limit parameters.
+func shellTask(spec TaskSpec) (leo.TaskCtxFunc, error) {
+    script, err := spec.Template("script")
+    if err != nil {
+        return nil, err
+    }
+    if script == nil {
+        return nil, fmt.Errorf("shell: script is required")
+    }
+    sandbox, err := sandboxFrom(spec)
+    if err != nil {
+        return nil, err
+    }
+    return func(ctx context.Context) error {
+        params := spec.Params(ctx)
+        s, values, err := shellScript(ctx, script, params)
+        if err != nil {
+            return err
+        }
+        args := append([]string{"-c", s, "sh"}, values...)
+        if sandbox == nil {
+            return leo.Command("sh", args...)(ctx)
+        }
+        sb, err := sandbox.build(ctx, params)
+        if err != nil {
+            return err
+        }
+        return sb.Command("sh", args...)(ctx)
+    }, nil
+}
+
+// execTask runs with.command, an array of program and arguments.
+// Arguments may contain {{ expression }} placeholders and secret:// URIs. See
+// sandboxKeys for the resource limit parameters.
+func execTask(spec TaskSpec) (leo.TaskCtxFunc, error) {
+    command, err := spec.Strings("command")
+    if err != nil {
+        return nil, err
+    }
+    if len(command) == 0 {
+        return nil, fmt.Errorf("exec: command is required")
+    }
+    args := make([]*Template, len(command))
+    for i, arg := range command {
+        if args[i], err = CompileTemplate(arg); err != nil {
+            return nil, err
+        }
+    }
+    sandbox, err := sandboxFrom(spec)
+    if err != nil {
+        return nil, err
+    }
+    return func(ctx context.Context) error {
+        params := spec.Params(ctx)
+        rendered := make([]string, len(args))
+        for i, arg := range args {
+            s, err := render(ctx, arg, params)
+            if err != nil {
+                return err
+            }
+            rendered[i] = s
+        }
+        if sandbox == nil {
+            return leo.Command(rendered[0], rendered[1:]...)(ctx)
+        }
+        sb, err := sandbox.build(ctx, params)
+        if err != nil {
+            return err
+        }
+        return sb.Command(rendered[0], rendered[1:]...)(ctx)
+    }, nil
+}
+
+// render renders t for a run. The secret:// URIs in the template's own text
+// are resolved, but not those in the parameter values it inserts, so that
+// whoever sets a run's parameters cannot read secrets through them.
+func render(ctx context.Context, t *Template, params map[string]string) (string, error) {
+    return t.expand(params, func(s string) (string, error) {
+        return leo.ResolveSecrets(ctx, s)
+    }, nil)
+}
+
+// shellScript renders t for a run like render, as a script for sh -c that
+// refers to the value of each placeholder as a positional parameter. It
+// returns the script and the values of its positional parameters.
+func shellScript(ctx context.Context, t *Template, params map[string]string) (string, []string, error) {
+    var values []string
+    var q shellQuotes
+    script, err := t.expand(params, func(s string) (string, error) {
+        s, err := leo.ResolveSecrets(ctx, s)
+        q.scan(s)
+        return s, err
+    }, func(v string) string {
+        values = append(values, v)
+        return q.ref(len(values))
+    })
+    return script, values, err
+}
+
+// shellQuotes tracks the quotes open at the end of the script text scanned
+// so far.
+type shellQuotes struct {
+    single, double, escaped bool
+}
+
+func (q *shellQuotes) scan(s string) {
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        switch {
+        case q.escaped:
+            q.escaped = false
+        case q.single:
+            q.single = c != '\''
+        case c == '\\':
+            q.escaped = true
+        case c == '\'' && !q.double:
+            q.single = true
+        case c == '"':
+            q.double = !q.double
+        }
+    }
+}
+
+// ref returns a reference to positional parameter n that expands to its
+// value as it is where the scanned text ends: inside double quotes, inside
+// single quotes, which it closes and reopens around it, or outside quotes.
+func (q *shellQuotes) ref(n int) string {
+    switch {
+    case q.single:
+        return fmt.Sprintf(`'"${%d}"'`, n)
+    case q.double:
+        return fmt.Sprintf("${%d}", n)
+    }
+    return fmt.Sprintf(`"${%d}"`, n)
+}
@@ -0,0 +1,205 @@
+package pipeline
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mips171/leo"
+)
+
+func TestRegistryCustomType(t *testing.T) {
+    reg := NewRegistry()
+
+    var mu sync.Mutex
+    var flashed []string
+    err := reg.Register("flash", func(spec TaskSpec) (leo.TaskCtxFunc, error) {
+        image, err := spec.Template("image")
+        if err != nil {
+            return nil, err
+        }
+        return func(ctx context.Context) error {
+            s, err := image.Render(spec.Params(ctx))
+            if err != nil {
+                return err
+            }
+            mu.Lock()
+            defer mu.Unlock()
+            flashed = append(flashed, spec.Name+":"+s)
+            return nil
+        }, nil
+    })
+    if err != nil {
+        t.Fatalf("Register failed: %v", err)
+    }
+    if err := reg.Register("flash", nil); err == nil {
+        t.Errorf("expected duplicate registration to fail")
+    }
+
+    f, err := Parse([]byte(`{
+        "params": {"version": "1.2"},
+        "tasks": [{"name": "router", "type": "flash", "with": {"image": "fw-{{ version }}.bin"}}]
+    }`))
+    if err != nil {
+        t.Fatalf("Parse failed: %v", err)
+    }
+    graph, err := f.GraphWithRegistry(reg)
+    if err != nil {
+        t.Fatalf("GraphWithRegistry failed: %v", err)
+    }
+    if err := leo.NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if len(flashed) != 1 || flashed[0] != "router:fw-1.2.bin" {
+        t.Errorf("unexpected flashes %v", flashed)
+    }
+
+    if _, err := f.Graph(); err == nil || !strings.Contains(err.Error(), `unknown task type "flash"`) {
+        t.Errorf("expected the default registry to reject the custom type, got %v", err)
+    }
+}
+
+func TestHTTPTask(t *testing.T) {
+    var gotAuth, gotMethod string
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        gotAuth = r.Header.Get("Authorization")
+        gotMethod = r.Method
+        if r.URL.Path == "/missing" {
+            http.NotFound(w, r)
+        }
+    }))
+    defer server.Close()
+
+    graph, err := Load(strings.NewReader(`{
+        "params": {"token": "abc"},
+        "tasks": [{
+            "name": "ping",
+            "type": "http",
+            "with": {"url": "` + server.URL + `/ok", "method": "POST", "headers": {"Authorization": "Bearer {{ token }}"}}
+        }]
+    }`))
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+    if err := leo.NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if gotMethod != "POST" || gotAuth != "Bearer abc" {
+        t.Errorf("unexpected request: %s with Authorization %q", gotMethod, gotAuth)
+    }
+
+    graph, err = Load(strings.NewReader(`{"tasks": [{"name": "ping", "type": "http", "with": {"url": "` + server.URL + `/missing"}}]}`))
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+    if err := leo.NewExecutor(graph).Execute(); err == nil {
+        t.Errorf("expected a 404 to fail the task")
+    }
+}
+
+func TestTaskTypeErrors(t *testing.T) {
+    for _, file := range []string{
+        `{"tasks": [{"name": "a", "type": "nope"}]}`,
+        `{"tasks": [{"name": "a", "type": "shell"}]}`,
+        `{"tasks": [{"name": "a", "type": "exec", "with": {"command": "ls"}}]}`,
+        `{"tasks": [{"name": "a", "type": "http", "with": {}}]}`,
+        `{"tasks": [{"name": "a", "shell": "true", "type": "exec"}]}`,
+    } {
+        if _, err := Load(strings.NewReader(file)); err == nil {
+            t.Errorf("expected an error for %s", file)
+        }
+    }
+}
+
+func TestSecretReferences(t *testing.T) {
+    graph, err := Load(strings.NewReader(`{"tasks": [
+        {"name": "check", "command": ["sh", "-c", "test \"$0\" = hunter2 && echo \"$0\" && exit 1", "secret://env/LEO_TEST_SECRET"]}
+    ]}`))
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+    t.Setenv("LEO_TEST_SECRET", "hunter2")
+
+    executor := leo.NewExecutor(graph)
+    leo.Provide[leo.SecretsProvider](executor, leo.Secrets{"env": leo.EnvSecrets{}})
+
+    err = executor.Execute()
+    if err == nil {
+        t.Fatalf("expected the command to fail after seeing the secret")
+    }
+    if strings.Contains(err.Error(), "hunter2") {
+        t.Errorf("secret leaked into the error: %v", err)
+    }
+    if !strings.Contains(err.Error(), "[REDACTED]") {
+        t.Errorf("expected the secret to be redacted, got %v", err)
+    }
+    if strings.Contains(executor.Report().Nodes["check"].Err.Error(), "hunter2") {
+        t.Errorf("secret leaked into the report")
+    }
+}
+
+func TestUntrustedParams(t *testing.T) {
+    out := filepath.Join(t.TempDir(), "out.txt")
+    graph, err := Load(strings.NewReader(`{"params": {"name": "leo"}, "tasks": [
+        {"name": "script", "shell": "echo {{ name }} >> ` + out + `"},
+        {"name": "single", "shell": "echo '{{ name }}' >> ` + out + `", "depends_on": ["script"]},
+        {"name": "double", "shell": "echo \"{{ name }}\" >> ` + out + `", "depends_on": ["single"]},
+        {"name": "args", "command": ["sh", "-c", "echo \"$0\" >> ` + out + `", "{{ name }}"], "depends_on": ["double"]}
+    ]}`))
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+    t.Setenv("LEO_TEST_SECRET", "hunter2")
+
+    executor := leo.NewExecutor(graph)
+    leo.Provide[leo.SecretsProvider](executor, leo.Secrets{"env": leo.EnvSecrets{}})
+    run := executor.NewRun()
+    // Parameters are inserted as they are: neither resolved as secrets nor
+    // run as shell code, whichever quotes surround them.
+    name := `secret://env/LEO_TEST_SECRET'; echo injected '" $(echo injected) "`
+    run.SetParams(map[string]string{"name": name})
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    data, err := os.ReadFile(out)
+    if err != nil {
+        t.Fatalf("reading output: %v", err)
+    }
+    if got, want := string(data), strings.Repeat(name+"\n", 4); got != want {
+        t.Errorf("output %q, want %q", got, want)
+    }
+}
+
+func TestSandboxedTasks(t *testing.T) {
+    graph, err := Load(strings.NewReader(`{"params": {"who": "leo"}, "tasks": [
+        {"name": "env", "type": "shell", "with": {"script": "test \"$GREETING\" = 'hi leo' && test -z \"$HOME\"", "env": {"GREETING": "hi {{ who }}"}}},
+        {"name": "slow", "type": "exec", "with": {"command": ["sleep", "5"], "timeout": "100ms", "memory": "512M"}, "depends_on": ["env"]}
+    ]}`))
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+
+    // slow runs after env, so that env finishes before slow fails the run
+    // under any concurrency limit, the default GOMAXPROCS one included.
+    executor := leo.NewExecutor(graph)
+    executor.Execute()
+    report := executor.Report()
+    if err := report.Nodes["env"].Err; err != nil {
+        t.Errorf("env task failed: %v", err)
+    }
+    if err := report.Nodes["slow"].Err; err == nil || !strings.Contains(err.Error(), "time limit") {
+        t.Errorf("expected the slow task to hit its time limit, got %v", err)
+    }
+
+    for _, with := range []string{`"timeout": "soon"`, `"memory": "lots"`, `"env": ["A=1"]`} {
+        _, err := Load(strings.NewReader(`{"tasks": [{"name": "t", "type": "shell", "with": {"script": "true", ` + with + `}}]}`))
+        if err == nil {
+            t.Errorf("expected an error for %s", with)
+        }
+    }
+}
@@ -0,0 +1,133 @@
+package pipeline
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/mips171/leo"
+)
+
+// sandboxKeys are the shell and exec parameters that run the command in a
+// leo.Sandbox:
+//
+//    timeout   kill the command after this duration, such as 5m
+//    cpu_time  CPU time limit, such as 30s
+//    memory    virtual memory limit in bytes, or with a K, M or G suffix
+//    env       object of environment variables replacing the inherited ones;
+//              values may contain {{ expression }} placeholders and
+//              secret:// URIs
+//    dir       working directory
+//    chroot    root directory (requires privileges)
+var sandboxKeys = []string{"timeout", "cpu_time", "memory", "env", "dir", "chroot"}
+
+// sandboxConfig is the sandbox configured by a task's parameters.
+type sandboxConfig struct {
+    sandbox leo.Sandbox
+    env     map[string]*Template
+}
+
+// sandboxFrom returns the sandbox configured by spec, or nil if spec sets
+// none of the sandbox parameters.
+func sandboxFrom(spec TaskSpec) (*sandboxConfig, error) {
+    set := false
+    for _, key := range sandboxKeys {
+        if _, ok := spec.With[key]; ok {
+            set = true
+        }
+    }
+    if !set {
+        return nil, nil
+    }
+
+    c := &sandboxConfig{}
+    var err error
+    if c.sandbox.Timeout, err = spec.Duration("timeout"); err != nil {
+        return nil, err
+    }
+    if c.sandbox.CPUTime, err = spec.Duration("cpu_time"); err != nil {
+        return nil, err
+    }
+    if c.sandbox.Memory, err = spec.size("memory"); err != nil {
+        return nil, err
+    }
+    if c.sandbox.Dir, err = spec.String("dir"); err != nil {
+        return nil, err
+    }
+    if c.sandbox.Chroot, err = spec.String("chroot"); err != nil {
+        return nil, err
+    }
+
+    if raw, ok := spec.With["env"]; ok {
+        m, ok := raw.(map[string]any)
+        if !ok {
+            return nil, fmt.Errorf("env: expected an object")
+        }
+        c.env = make(map[string]*Template, len(m))
+        for name, v := range m {
+            s, ok := v.(string)
+            if !ok {
+                return nil, fmt.Errorf("env: %s: expected a string", name)
+            }
+            if c.env[name], err = CompileTemplate(s); err != nil {
+                return nil, fmt.Errorf("env: %s: %w", name, err)
+            }
+        }
+    }
+    return c, nil
+}
+
+// build returns the sandbox with its environment rendered for a run.
+func (c *sandboxConfig) build(ctx context.Context, params map[string]string) (leo.Sandbox, error) {
+    sb := c.sandbox
+    if c.env == nil {
+        return sb, nil
+    }
+    sb.Env = make([]string, 0, len(c.env))
+    for name, tmpl := range c.env {
+        v, err := render(ctx, tmpl, params)
+        if err != nil {
+            return sb, fmt.Errorf("env: %s: %w", name, err)
+        }
+        sb.Env = append(sb.Env, name+"="+v)
+    }
+    sort.Strings(sb.Env)
+    return sb, nil
+}
+
+// size returns the byte size parameter key, given as a number of bytes or a
+// string with an optional K, M or G suffix, or 0 if it is not set.
+func (s TaskSpec) size(key string) (int64, error) {
+    v, ok := s.With[key]
+    if !ok {
+        return 0, nil
+    }
+    switch v := v.(type) {
+    case float64:
+        return int64(v), nil
+    case int64:
+        return v, nil
+    case string:
+        str := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(v)), "B")
+        mult := int64(1)
+        switch {
+        case strings.HasSuffix(str, "K"):
+            mult = 1 << 10
+        case strings.HasSuffix(str, "M"):
+            mult = 1 << 20
+        case strings.HasSuffix(str, "G"):
+            mult = 1 << 30
+        }
+        if mult > 1 {
+            str = str[:len(str)-1]
+        }
+        n, err := strconv.ParseInt(str, 10, 64)
+        if err != nil || n < 0 {
+            return 0, fmt.Errorf("%s: invalid size %q", key, v)
+        }
+        return n * mult, nil
+    }
+    return 0, fmt.Errorf("%s: expected a size", key)
+}
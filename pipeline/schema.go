@@ -0,0 +1,220 @@
+package pipeline
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "sort"
+    "strings"
+)
+
+// SchemaID is the $id of the pipeline file schema.
+const SchemaID = "https://github.com/mips171/leo/pipeline/schema.json"
+
+// Schema returns the JSON Schema for pipeline files, generated from File.
+// Point an editor at it to get validation and completion while writing
+// pipelines; "leo schema" prints it.
+func Schema() []byte {
+    s := schemaFor(reflect.TypeOf(File{}))
+    s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+    s["$id"] = SchemaID
+    s["title"] = "leo pipeline"
+
+    data, err := json.MarshalIndent(s, "", "  ")
+    if err != nil {
+        panic(err)
+    }
+    return append(data, '\n')
+}
+
+// schemaFor returns the schema for t, derived from its json and desc tags.
+func schemaFor(t reflect.Type) map[string]any {
+    switch t.Kind() {
+    case reflect.Ptr:
+        return schemaFor(t.Elem())
+    case reflect.String:
+        return map[string]any{"type": "string"}
+    case reflect.Bool:
+        return map[string]any{"type": "boolean"}
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return map[string]any{"type": "integer"}
+    case reflect.Float32, reflect.Float64:
+        return map[string]any{"type": "number"}
+    case reflect.Slice, reflect.Array:
+        return map[string]any{"type": "array", "items": schemaFor(t.Elem())}
+    case reflect.Map:
+        return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+    case reflect.Interface:
+        return map[string]any{}
+    case reflect.Struct:
+        props := make(map[string]any)
+        var required []string
+        for i := 0; i < t.NumField(); i++ {
+            field := t.Field(i)
+            name, omitempty, ok := jsonField(field)
+            if !ok {
+                continue
+            }
+            prop := schemaFor(field.Type)
+            if desc := field.Tag.Get("desc"); desc != "" {
+                prop["description"] = desc
+            }
+            if enum := field.Tag.Get("enum"); enum != "" {
+                values := []any{}
+                for _, v := range strings.Split(enum, ",") {
+                    values = append(values, v)
+                }
+                prop["enum"] = values
+            }
+            props[name] = prop
+            if !omitempty {
+                required = append(required, name)
+            }
+        }
+        s := map[string]any{
+            "type":                 "object",
+            "properties":           props,
+            "additionalProperties": false,
+        }
+        if len(required) > 0 {
+            s["required"] = required
+        }
+        return s
+    }
+    panic(fmt.Sprintf("pipeline: no schema for %s", t))
+}
+
+func jsonField(f reflect.StructField) (name string, omitempty, ok bool) {
+    if !f.IsExported() {
+        return "", false, false
+    }
+    tag := f.Tag.Get("json")
+    if tag == "-" {
+        return "", false, false
+    }
+    parts := strings.Split(tag, ",")
+    name = parts[0]
+    if name == "" {
+        name = f.Name
+    }
+    for _, opt := range parts[1:] {
+        if opt == "omitempty" {
+            omitempty = true
+        }
+    }
+    return name, omitempty, true
+}
+
+// Validate checks a pipeline file against the schema and then builds its
+// graph to catch unknown dependencies, cycles and invalid durations. All
+// schema violations are reported together.
+func Validate(data []byte) error {
+    var doc any
+    if err := json.Unmarshal(data, &doc); err != nil {
+        return fmt.Errorf("pipeline: %w", err)
+    }
+
+    var schema map[string]any
+    if err := json.Unmarshal(Schema(), &schema); err != nil {
+        return err
+    }
+
+    var errs []error
+    validate(schema, doc, "", &errs)
+    if len(errs) > 0 {
+        return errors.Join(errs...)
+    }
+
+    f, err := Parse(data)
+    if err != nil {
+        return err
+    }
+    _, err = f.Graph()
+    return err
+}
+
+// validate checks v against the subset of JSON Schema produced by schemaFor.
+func validate(schema map[string]any, v any, path string, errs *[]error) {
+    where := path
+    if where == "" {
+        where = "/"
+    }
+    fail := func(format string, args ...any) {
+        *errs = append(*errs, fmt.Errorf("pipeline: %s: %s", where, fmt.Sprintf(format, args...)))
+    }
+
+    typ, _ := schema["type"].(string)
+    switch typ {
+    case "object":
+        obj, ok := v.(map[string]any)
+        if !ok {
+            fail("expected an object")
+            return
+        }
+        props, _ := schema["properties"].(map[string]any)
+        if required, ok := schema["required"].([]any); ok {
+            for _, r := range required {
+                if _, exists := obj[r.(string)]; !exists {
+                    fail("missing required property %q", r)
+                }
+            }
+        }
+        keys := make([]string, 0, len(obj))
+        for key := range obj {
+            keys = append(keys, key)
+        }
+        sort.Strings(keys)
+        for _, key := range keys {
+            if prop, ok := props[key].(map[string]any); ok {
+                validate(prop, obj[key], path+"/"+key, errs)
+                continue
+            }
+            switch extra := schema["additionalProperties"].(type) {
+            case bool:
+                if !extra {
+                    fail("unknown property %q", key)
+                }
+            case map[string]any:
+                validate(extra, obj[key], path+"/"+key, errs)
+            }
+        }
+    case "array":
+        arr, ok := v.([]any)
+        if !ok {
+            fail("expected an array")
+            return
+        }
+        items, _ := schema["items"].(map[string]any)
+        for i, item := range arr {
+            validate(items, item, fmt.Sprintf("%s/%d", path, i), errs)
+        }
+    case "string":
+        s, ok := v.(string)
+        if !ok {
+            fail("expected a string")
+            return
+        }
+        if enum, ok := schema["enum"].([]any); ok {
+            for _, e := range enum {
+                if e == s {
+                    return
+                }
+            }
+            fail("%q is not one of %v", s, enum)
+        }
+    case "boolean":
+        if _, ok := v.(bool); !ok {
+            fail("expected a boolean")
+        }
+    case "integer":
+        if n, ok := v.(float64); !ok || n != float64(int64(n)) {
+            fail("expected an integer")
+        }
+    case "number":
+        if _, ok := v.(float64); !ok {
+            fail("expected a number")
+        }
+    }
+}
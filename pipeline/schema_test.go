@@ -0,0 +1,58 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+    var schema map[string]any
+    if err := json.Unmarshal(Schema(), &schema); err != nil {
+        t.Fatalf("schema is not valid JSON: %v", err)
+    }
+
+    props := schema["properties"].(map[string]any)
+    tasks := props["tasks"].(map[string]any)
+    task := tasks["items"].(map[string]any)
+    taskProps := task["properties"].(map[string]any)
+
+    for _, name := range []string{"name", "shell", "command", "depends_on", "fallback_for", "expected_duration", "hedge"} {
+        prop, ok := taskProps[name].(map[string]any)
+        if !ok {
+            t.Errorf("schema is missing task property %q", name)
+            continue
+        }
+        if prop["description"] == "" {
+            t.Errorf("task property %q has no description", name)
+        }
+    }
+    if required := task["required"].([]any); len(required) != 1 || required[0] != "name" {
+        t.Errorf("expected only name to be required, got %v", required)
+    }
+}
+
+func TestValidate(t *testing.T) {
+    if err := Validate([]byte(`{"tasks": [{"name": "a"}, {"name": "b", "depends_on": ["a"]}]}`)); err != nil {
+        t.Errorf("valid pipeline rejected: %v", err)
+    }
+
+    err := Validate([]byte(`{"tasks": [{"name": 1, "depends_on": "a", "extra": true}, {}]}`))
+    if err == nil {
+        t.Fatalf("invalid pipeline accepted")
+    }
+    for _, want := range []string{
+        "/tasks/0/name: expected a string",
+        "/tasks/0/depends_on: expected an array",
+        `/tasks/0: unknown property "extra"`,
+        `/tasks/1: missing required property "name"`,
+    } {
+        if !strings.Contains(err.Error(), want) {
+            t.Errorf("error missing %q:\n%v", want, err)
+        }
+    }
+
+    if err := Validate([]byte(`{"tasks": [{"name": "a", "depends_on": ["a"]}]}`)); err == nil {
+        t.Errorf("expected a cycle to be rejected")
+    }
+}
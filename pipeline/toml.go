@@ -0,0 +1,632 @@
+package pipeline
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "math"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+
+    "github.com/mips171/leo"
+)
+
+// LoadTOML reads a pipeline from a TOML document. The pipeline is taken from
+// the table at key, a dotted path such as "pipeline" or "deploy.pipeline", so
+// that pipelines can live alongside other configuration in the same file; an
+// empty key uses the whole document. The table has the same structure as a
+// JSON pipeline file:
+//
+//    [pipeline]
+//    name = "deploy"
+//
+//    [[pipeline.tasks]]
+//    name = "build"
+//    shell = "make build"
+//
+//    [[pipeline.tasks]]
+//    name = "push"
+//    command = ["docker", "push", "app"]
+//    depends_on = ["build"]
+//
+// Dates and times are accepted anywhere in the document and decoded as
+// strings.
+func LoadTOML(r io.Reader, key string, opts ...ParseOption) (*leo.Graph, error) {
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return nil, err
+    }
+    js, err := TOMLToJSON(data, key)
+    if err != nil {
+        return nil, err
+    }
+    f, err := Parse(js, opts...)
+    if err != nil {
+        return nil, err
+    }
+    return f.Graph()
+}
+
+// TOMLToJSON extracts the table at key from a TOML document and encodes it as
+// JSON, for use with Parse or Validate.
+func TOMLToJSON(data []byte, key string) ([]byte, error) {
+    doc, err := parseTOML(string(data))
+    if err != nil {
+        return nil, err
+    }
+
+    var table any = doc
+    if key != "" {
+        for _, part := range strings.Split(key, ".") {
+            m, ok := table.(map[string]any)
+            if !ok {
+                return nil, fmt.Errorf("pipeline: toml: %s is not a table", key)
+            }
+            if table, ok = m[part]; !ok {
+                return nil, fmt.Errorf("pipeline: toml: no table %s", key)
+            }
+        }
+    }
+    if _, ok := table.(map[string]any); !ok {
+        return nil, fmt.Errorf("pipeline: toml: %s is not a table", key)
+    }
+
+    return json.Marshal(table)
+}
+
+// tomlParser parses the subset of TOML 1.0 needed for configuration files:
+// tables, arrays of tables, dotted and quoted keys, all string forms,
+// integers, floats, booleans, arrays and inline tables. Dates and times are
+// returned as strings.
+type tomlParser struct {
+    src  string
+    pos  int
+    line int
+}
+
+func parseTOML(src string) (map[string]any, error) {
+    p := &tomlParser{src: src, line: 1}
+    root := make(map[string]any)
+    current := root
+    // Tables defined by headers or inline, which may not be redefined.
+    defined := make(map[string]bool)
+
+    for {
+        p.skipBlank()
+        if p.eof() {
+            return root, nil
+        }
+
+        if p.peek() == '[' {
+            array := strings.HasPrefix(p.src[p.pos:], "[[")
+            if array {
+                p.pos += 2
+            } else {
+                p.pos++
+            }
+            p.skipSpace()
+            path, err := p.key()
+            if err != nil {
+                return nil, err
+            }
+            p.skipSpace()
+            closing := "]"
+            if array {
+                closing = "]]"
+            }
+            if !strings.HasPrefix(p.src[p.pos:], closing) {
+                return nil, p.errorf("expected %s", closing)
+            }
+            p.pos += len(closing)
+            if err := p.endOfLine(); err != nil {
+                return nil, err
+            }
+
+            parent, err := p.descend(root, path[:len(path)-1])
+            if err != nil {
+                return nil, err
+            }
+            last := path[len(path)-1]
+            id := strings.Join(path, "\x00")
+            if array {
+                existing, exists := parent[last]
+                arr, ok := existing.([]any)
+                if exists && (!ok || defined[id]) {
+                    return nil, p.errorf("%s is not an array of tables", strings.Join(path, "."))
+                }
+                table := make(map[string]any)
+                parent[last] = append(arr, table)
+                current = table
+            } else {
+                if defined[id] {
+                    return nil, p.errorf("table %s defined more than once", strings.Join(path, "."))
+                }
+                defined[id] = true
+                existing, exists := parent[last]
+                table, ok := existing.(map[string]any)
+                if exists && !ok {
+                    return nil, p.errorf("%s is not a table", strings.Join(path, "."))
+                }
+                if !exists {
+                    table = make(map[string]any)
+                    parent[last] = table
+                }
+                current = table
+            }
+            continue
+        }
+
+        if err := p.keyValue(current); err != nil {
+            return nil, err
+        }
+        if err := p.endOfLine(); err != nil {
+            return nil, err
+        }
+    }
+}
+
+// descend walks path from table, creating tables as needed and entering the
+// last element of arrays of tables.
+func (p *tomlParser) descend(table map[string]any, path []string) (map[string]any, error) {
+    for _, part := range path {
+        switch next := table[part].(type) {
+        case nil:
+            t := make(map[string]any)
+            table[part] = t
+            table = t
+        case map[string]any:
+            table = next
+        case []any:
+            if len(next) == 0 {
+                return nil, p.errorf("%s is not a table", part)
+            }
+            t, ok := next[len(next)-1].(map[string]any)
+            if !ok {
+                return nil, p.errorf("%s is not a table", part)
+            }
+            table = t
+        default:
+            return nil, p.errorf("%s is not a table", part)
+        }
+    }
+    return table, nil
+}
+
+func (p *tomlParser) keyValue(table map[string]any) error {
+    path, err := p.key()
+    if err != nil {
+        return err
+    }
+    p.skipSpace()
+    if p.eof() || p.peek() != '=' {
+        return p.errorf("expected = after key %s", strings.Join(path, "."))
+    }
+    p.pos++
+    p.skipSpace()
+
+    value, err := p.value()
+    if err != nil {
+        return err
+    }
+
+    parent, err := p.descend(table, path[:len(path)-1])
+    if err != nil {
+        return err
+    }
+    last := path[len(path)-1]
+    if _, exists := parent[last]; exists {
+        return p.errorf("key %s defined more than once", strings.Join(path, "."))
+    }
+    parent[last] = value
+    return nil
+}
+
+// key parses a possibly dotted key.
+func (p *tomlParser) key() ([]string, error) {
+    var path []string
+    for {
+        p.skipSpace()
+        if p.eof() {
+            return nil, p.errorf("expected a key")
+        }
+        var part string
+        var err error
+        switch p.peek() {
+        case '"':
+            part, err = p.basicString()
+        case '\'':
+            part, err = p.literalString()
+        default:
+            start := p.pos
+            for !p.eof() && isBareKeyChar(p.peek()) {
+                p.pos++
+            }
+            if start == p.pos {
+                return nil, p.errorf("expected a key")
+            }
+            part = p.src[start:p.pos]
+        }
+        if err != nil {
+            return nil, err
+        }
+        path = append(path, part)
+
+        p.skipSpace()
+        if p.eof() || p.peek() != '.' {
+            return path, nil
+        }
+        p.pos++
+    }
+}
+
+func (p *tomlParser) value() (any, error) {
+    if p.eof() {
+        return nil, p.errorf("expected a value")
+    }
+    rest := p.src[p.pos:]
+    switch {
+    case strings.HasPrefix(rest, `"""`):
+        return p.multilineBasicString()
+    case strings.HasPrefix(rest, "'''"):
+        return p.multilineLiteralString()
+    case rest[0] == '"':
+        return p.basicString()
+    case rest[0] == '\'':
+        return p.literalString()
+    case rest[0] == '[':
+        return p.array()
+    case rest[0] == '{':
+        return p.inlineTable()
+    case strings.HasPrefix(rest, "true") && !continuesToken(rest, 4):
+        p.pos += 4
+        return true, nil
+    case strings.HasPrefix(rest, "false") && !continuesToken(rest, 5):
+        p.pos += 5
+        return false, nil
+    }
+    return p.scalar()
+}
+
+func (p *tomlParser) array() (any, error) {
+    p.pos++ // [
+    arr := []any{}
+    for {
+        p.skipBlank()
+        if p.eof() {
+            return nil, p.errorf("unterminated array")
+        }
+        if p.peek() == ']' {
+            p.pos++
+            return arr, nil
+        }
+        v, err := p.value()
+        if err != nil {
+            return nil, err
+        }
+        arr = append(arr, v)
+        p.skipBlank()
+        if p.eof() {
+            return nil, p.errorf("unterminated array")
+        }
+        switch p.peek() {
+        case ',':
+            p.pos++
+        case ']':
+        default:
+            return nil, p.errorf("expected , or ] in array")
+        }
+    }
+}
+
+func (p *tomlParser) inlineTable() (any, error) {
+    p.pos++ // {
+    table := make(map[string]any)
+    p.skipSpace()
+    if !p.eof() && p.peek() == '}' {
+        p.pos++
+        return table, nil
+    }
+    for {
+        if err := p.keyValue(table); err != nil {
+            return nil, err
+        }
+        p.skipSpace()
+        if p.eof() {
+            return nil, p.errorf("unterminated inline table")
+        }
+        switch p.peek() {
+        case ',':
+            p.pos++
+        case '}':
+            p.pos++
+            return table, nil
+        default:
+            return nil, p.errorf("expected , or } in inline table")
+        }
+    }
+}
+
+// scalar parses a number, or a date or time which is returned as a string.
+func (p *tomlParser) scalar() (any, error) {
+    start := p.pos
+    for !p.eof() && isScalarChar(p.peek()) {
+        p.pos++
+    }
+    // Local date-times may use a space instead of T between date and time.
+    if p.pos-start == 10 && strings.Count(p.src[start:p.pos], "-") == 2 &&
+        p.pos+1 < len(p.src) && p.src[p.pos] == ' ' && isDigit(p.src[p.pos+1]) {
+        p.pos++
+        for !p.eof() && isScalarChar(p.peek()) {
+            p.pos++
+        }
+    }
+    tok := p.src[start:p.pos]
+    if tok == "" {
+        return nil, p.errorf("unexpected %q", p.peek())
+    }
+
+    if strings.Contains(tok, ":") || (len(tok) >= 10 && tok[4] == '-' && isDigit(tok[0])) {
+        return tok, nil
+    }
+
+    clean := strings.ReplaceAll(tok, "_", "")
+    switch strings.TrimLeft(clean, "+-") {
+    case "inf":
+        if strings.HasPrefix(clean, "-") {
+            return math.Inf(-1), nil
+        }
+        return math.Inf(1), nil
+    case "nan":
+        return math.NaN(), nil
+    }
+    if hasLeadingZero(clean) {
+        return nil, p.errorf("invalid value %q: leading zeros are not allowed", tok)
+    }
+    if n, err := strconv.ParseInt(clean, 0, 64); err == nil {
+        return n, nil
+    }
+    if !strings.HasPrefix(clean, "0x") && !strings.HasPrefix(clean, "0o") && !strings.HasPrefix(clean, "0b") {
+        if f, err := strconv.ParseFloat(clean, 64); err == nil {
+            return f, nil
+        }
+    }
+    return nil, p.errorf("invalid value %q", tok)
+}
+
+func (p *tomlParser) basicString() (string, error) {
+    p.pos++ // "
+    var b strings.Builder
+    for {
+        if p.eof() || p.peek() == '\n' {
+            return "", p.errorf("unterminated string")
+        }
+        c := p.peek()
+        switch c {
+        case '"':
+            p.pos++
+            return b.String(), nil
+        case '\\':
+            if err := p.escape(&b); err != nil {
+                return "", err
+            }
+        default:
+            b.WriteByte(c)
+            p.pos++
+        }
+    }
+}
+
+func (p *tomlParser) multilineBasicString() (string, error) {
+    p.pos += 3
+    p.skipNewline()
+    var b strings.Builder
+    for {
+        if p.eof() {
+            return "", p.errorf("unterminated string")
+        }
+        if strings.HasPrefix(p.src[p.pos:], `"""`) {
+            p.pos += 3
+            // Up to two quotes may directly precede the closing delimiter.
+            for i := 0; i < 2 && !p.eof() && p.peek() == '"'; i++ {
+                b.WriteByte('"')
+                p.pos++
+            }
+            return b.String(), nil
+        }
+        c := p.peek()
+        if c == '\\' {
+            // A backslash at the end of a line trims all following whitespace.
+            j := p.pos + 1
+            for j < len(p.src) && (p.src[j] == ' ' || p.src[j] == '\t') {
+                j++
+            }
+            if j < len(p.src) && (p.src[j] == '\n' || p.src[j] == '\r') {
+                p.pos = j
+                for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.peek())) {
+                    if p.peek() == '\n' {
+                        p.line++
+                    }
+                    p.pos++
+                }
+                continue
+            }
+            if err := p.escape(&b); err != nil {
+                return "", err
+            }
+            continue
+        }
+        if c == '\n' {
+            p.line++
+        }
+        b.WriteByte(c)
+        p.pos++
+    }
+}
+
+func (p *tomlParser) literalString() (string, error) {
+    p.pos++ // '
+    start := p.pos
+    for {
+        if p.eof() || p.peek() == '\n' {
+            return "", p.errorf("unterminated string")
+        }
+        if p.peek() == '\'' {
+            s := p.src[start:p.pos]
+            p.pos++
+            return s, nil
+        }
+        p.pos++
+    }
+}
+
+func (p *tomlParser) multilineLiteralString() (string, error) {
+    p.pos += 3
+    p.skipNewline()
+    end := strings.Index(p.src[p.pos:], "'''")
+    if end < 0 {
+        return "", p.errorf("unterminated string")
+    }
+    end += p.pos
+    // Up to two quotes may directly precede the closing delimiter.
+    for i := 0; i < 2 && end+3 < len(p.src) && p.src[end+3] == '\''; i++ {
+        end++
+    }
+    s := p.src[p.pos:end]
+    p.line += strings.Count(s, "\n")
+    p.pos = end + 3
+    return s, nil
+}
+
+func (p *tomlParser) escape(b *strings.Builder) error {
+    p.pos++ // backslash
+    if p.eof() {
+        return p.errorf("unterminated escape")
+    }
+    c := p.peek()
+    p.pos++
+    switch c {
+    case 'b':
+        b.WriteByte('\b')
+    case 't':
+        b.WriteByte('\t')
+    case 'n':
+        b.WriteByte('\n')
+    case 'f':
+        b.WriteByte('\f')
+    case 'r':
+        b.WriteByte('\r')
+    case '"':
+        b.WriteByte('"')
+    case '\\':
+        b.WriteByte('\\')
+    case 'u', 'U':
+        n := 4
+        if c == 'U' {
+            n = 8
+        }
+        if p.pos+n > len(p.src) {
+            return p.errorf("invalid unicode escape")
+        }
+        code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+        if err != nil || !utf8.ValidRune(rune(code)) {
+            return p.errorf("invalid unicode escape")
+        }
+        b.WriteRune(rune(code))
+        p.pos += n
+    default:
+        return p.errorf("invalid escape \\%c", c)
+    }
+    return nil
+}
+
+// endOfLine consumes trailing whitespace and an optional comment, and
+// requires a newline or the end of input.
+func (p *tomlParser) endOfLine() error {
+    p.skipSpace()
+    if !p.eof() && p.peek() == '#' {
+        p.skipComment()
+    }
+    if p.eof() {
+        return nil
+    }
+    if p.peek() == '\r' {
+        p.pos++
+    }
+    if p.eof() || p.peek() != '\n' {
+        return p.errorf("expected end of line")
+    }
+    return nil
+}
+
+// skipBlank skips whitespace, newlines and comments.
+func (p *tomlParser) skipBlank() {
+    for !p.eof() {
+        switch p.peek() {
+        case ' ', '\t', '\r':
+            p.pos++
+        case '\n':
+            p.line++
+            p.pos++
+        case '#':
+            p.skipComment()
+        default:
+            return
+        }
+    }
+}
+
+func (p *tomlParser) skipSpace() {
+    for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+        p.pos++
+    }
+}
+
+func (p *tomlParser) skipComment() {
+    for !p.eof() && p.peek() != '\n' {
+        p.pos++
+    }
+}
+
+func (p *tomlParser) skipNewline() {
+    if strings.HasPrefix(p.src[p.pos:], "\r\n") {
+        p.pos += 2
+        p.line++
+    } else if !p.eof() && p.peek() == '\n' {
+        p.pos++
+        p.line++
+    }
+}
+
+func (p *tomlParser) eof() bool {
+    return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+    return p.src[p.pos]
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+    return fmt.Errorf("pipeline: toml line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func isBareKeyChar(c byte) bool {
+    return c == '_' || c == '-' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isScalarChar(c byte) bool {
+    return isBareKeyChar(c) || c == '+' || c == '.' || c == ':'
+}
+
+func isDigit(c byte) bool {
+    return c >= '0' && c <= '9'
+}
+
+func continuesToken(s string, i int) bool {
+    return i < len(s) && isBareKeyChar(s[i])
+}
+
+func hasLeadingZero(n string) bool {
+    n = strings.TrimLeft(n, "+-")
+    return len(n) > 1 && n[0] == '0' && isDigit(n[1])
+}
@@ -0,0 +1,123 @@
+package pipeline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mips171/leo"
+)
+
+func TestLoadTOML(t *testing.T) {
+    doc := `
+# Infrastructure settings live in the same file.
+[database]
+host = "db.internal"
+port = 5432
+maintenance = 2024-01-01T03:00:00Z
+
+[deploy.pipeline]
+name = "deploy"
+
+[[deploy.pipeline.tasks]]
+name = "build"
+shell = '''
+make build'''
+expected_duration = "10s"
+
+[[deploy.pipeline.tasks]]
+name = "push"
+command = [
+    "true",  # arguments may span lines
+]
+depends_on = ["build"]
+
+[[deploy.pipeline.tasks]]
+name = "rollback"
+shell = "echo \"rolling back\""
+fallback_for = ["push"]
+`
+
+    graph, err := LoadTOML(strings.NewReader(doc), "deploy.pipeline")
+    if err != nil {
+        t.Fatalf("LoadTOML failed: %v", err)
+    }
+
+    want := []leo.Edge{
+        {From: "build", To: "push"},
+        {From: "push", To: "rollback", Fallback: true},
+    }
+    if got := graph.Edges(); !reflect.DeepEqual(got, want) {
+        t.Errorf("edges = %v, want %v", got, want)
+    }
+
+    if _, err := LoadTOML(strings.NewReader(doc), "database.host"); err == nil {
+        t.Errorf("expected an error for a key that is not a table")
+    }
+    if _, err := LoadTOML(strings.NewReader(doc), "missing"); err == nil {
+        t.Errorf("expected an error for a missing table")
+    }
+}
+
+func TestParseTOMLValues(t *testing.T) {
+    doc, err := parseTOML(`
+str = "tab\there \u00e9"
+lit = 'C:\path'
+multi = """
+one \
+  two"""
+ints = [1_000, 0x1f, -3]
+floats = [1.5, 2e3]
+bools = [true, false]
+inline = { a = 1, "b.c" = "x", d.e = 2 }
+date = 1979-05-27 07:32:00
+"quoted key" = 1
+a.b.c = "dotted"
+
+[[items]]
+id = 1
+[items.sub]
+x = 1
+[[items]]
+id = 2
+`)
+    if err != nil {
+        t.Fatalf("parseTOML failed: %v", err)
+    }
+
+    want := map[string]any{
+        "str":    "tab\there é",
+        "lit":    `C:\path`,
+        "multi":  "one two",
+        "ints":   []any{int64(1000), int64(31), int64(-3)},
+        "floats": []any{1.5, 2000.0},
+        "bools":  []any{true, false},
+        "inline": map[string]any{"a": int64(1), "b.c": "x", "d": map[string]any{"e": int64(2)}},
+        "date":   "1979-05-27 07:32:00",
+        "quoted key": int64(1),
+        "a":      map[string]any{"b": map[string]any{"c": "dotted"}},
+        "items": []any{
+            map[string]any{"id": int64(1), "sub": map[string]any{"x": int64(1)}},
+            map[string]any{"id": int64(2)},
+        },
+    }
+    if !reflect.DeepEqual(doc, want) {
+        t.Errorf("parseTOML =\n%#v\nwant\n%#v", doc, want)
+    }
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+    for _, doc := range []string{
+        "a = 1\na = 2\n",
+        "[t]\n[t]\n",
+        "a = \"unterminated\n",
+        "a = [1, 2\n",
+        "a = 1 b = 2\n",
+        "a = 01\n",
+        "= 1\n",
+    } {
+        if _, err := parseTOML(doc); err == nil {
+            t.Errorf("expected an error for %q", doc)
+        }
+    }
+}
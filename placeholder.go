@@ -0,0 +1,86 @@
+package leo
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// ErrUnbound is returned by Execute and Freeze when a placeholder created by
+// Precede in auto-create mode has not been given a task, see SetAutoCreate.
+var ErrUnbound = errors.New("no task bound")
+
+// SetAutoCreate sets whether Precede and Succeed create the nodes they name
+// that do not exist yet, instead of returning an error. This allows the
+// topology of a graph to be built before the tasks, which are bound to the
+// created placeholders later with Add, AddCtx, AddShell, AddAll or Bind,
+// whatever the graph's DuplicatePolicy. Executing or freezing the graph
+// while a placeholder has no task returns an error wrapping ErrUnbound.
+// SetAutoCreate returns ErrFrozen if the graph is frozen.
+func (g *Graph) SetAutoCreate(on bool) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    g.autoCreate = on
+    return nil
+}
+
+// placeholder returns the node called name, creating an unbound one if it
+// does not exist. The caller must hold g.mu.
+func (g *Graph) placeholder(name string) *Node {
+    if n, exists := g.lookup(name); exists {
+        return n
+    }
+    g.addNode(name, nil, nil)
+    g.nodes[name].unbound = true
+    return g.nodes[name]
+}
+
+// bind sets the task of n, replacing its earlier task or binding it if it is
+// a placeholder, and applies opts to it. The caller must hold the graph's
+// lock.
+func (n *Node) bind(task TaskCtxFunc, opts []NodeOption) {
+    n.task = task
+    n.command = ""
+    n.unbound = false
+    for _, opt := range opts {
+        opt(n)
+    }
+}
+
+// checkBound returns an error wrapping ErrUnbound naming the placeholders
+// of g that have no task.
+func (g *Graph) checkBound() error {
+    var names []string
+    for name, n := range g.nodes {
+        if n.unbound {
+            names = append(names, name)
+        }
+    }
+    if len(names) == 0 {
+        return nil
+    }
+    sort.Strings(names)
+    return fmt.Errorf("placeholders %s: %w", strings.Join(names, ", "), ErrUnbound)
+}
+
+// Unbound returns the sorted names of the nodes that have no task: those
+// added with a nil task, such as the nodes of ImportDOT, and placeholders
+// created by Precede in auto-create mode. Declarative loaders can build the
+// topology and leave the tasks to code, which binds them with Bind; Unbound
+// then lists what is left to bind.
+func (g *Graph) Unbound() []string {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    var names []string
+    for name, n := range g.nodes {
+        if n.task == nil || n.unbound {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
@@ -0,0 +1,88 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAutoCreate(t *testing.T) {
+    graph := TaskGraph()
+    if err := graph.Precede("fetch", "build"); err == nil {
+        t.Fatalf("expected Precede to fail without auto-create")
+    }
+    graph.SetAutoCreate(true)
+    graph.SetDuplicatePolicy(DuplicateError)
+    if err := graph.Precede("fetch", "build"); err != nil {
+        t.Fatalf("Precede failed: %v", err)
+    }
+    if err := graph.Succeed("test", "build"); err != nil {
+        t.Fatalf("Succeed failed: %v", err)
+    }
+    var cycle *CycleError
+    if err := graph.Precede("deploy", "deploy"); !errors.As(err, &cycle) {
+        t.Errorf("expected a self-edge to be a cycle, got %v", err)
+    }
+    if _, exists := graph.nodes["deploy"]; exists {
+        t.Errorf("expected the rejected edge not to create a node")
+    }
+
+    err := NewExecutor(graph).Execute()
+    if !errors.Is(err, ErrUnbound) || err.Error() != "placeholders build, fetch, test: no task bound" {
+        t.Fatalf("expected the unbound placeholders to be reported, got %v", err)
+    }
+
+    var ran []string
+    record := func(name string) TaskFunc {
+        return func() error { ran = append(ran, name); return nil }
+    }
+    if res, err := graph.Add("fetch", record("fetch")); err != nil || res != NodeAdded {
+        t.Fatalf("expected Add to bind the placeholder, got %v, %v", res, err)
+    }
+    if err := graph.AddAll(map[string]TaskFunc{"build": record("build")}); err != nil {
+        t.Fatalf("AddAll failed: %v", err)
+    }
+    if _, err := graph.Freeze(); !errors.Is(err, ErrUnbound) {
+        t.Errorf("expected Freeze to report the unbound placeholder, got %v", err)
+    }
+    if err := graph.Bind("test", func(ctx context.Context) error { return record("test")() }); err != nil {
+        t.Fatalf("Bind failed: %v", err)
+    }
+    if _, err := graph.Add("test", nil); !errors.Is(err, ErrNodeExists) {
+        t.Errorf("expected a bound node to follow the duplicate policy, got %v", err)
+    }
+
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if fmt.Sprint(ran) != "[fetch build test]" {
+        t.Errorf("unexpected order %v", ran)
+    }
+}
+
+func TestUnbound(t *testing.T) {
+    graph, err := ImportDOT(strings.NewReader(`digraph { lint -> build; build -> test; test -> publish [style=dashed] }`))
+    if err != nil {
+        t.Fatalf("ImportDOT failed: %v", err)
+    }
+    if got := graph.Unbound(); fmt.Sprint(got) != "[build lint publish test]" {
+        t.Errorf("expected every imported node to be unbound, got %v", got)
+    }
+    noop := func(context.Context) error { return nil }
+    graph.Bind("build", noop)
+    graph.Bind("test", noop)
+    if got := graph.Unbound(); fmt.Sprint(got) != "[lint publish]" {
+        t.Errorf("unexpected unbound nodes %v", got)
+    }
+    if err := graph.Bind("deploy", noop); err == nil {
+        t.Errorf("expected binding a missing node to fail")
+    }
+
+    graph.SetAutoCreate(true)
+    graph.Precede("publish", "announce")
+    if got := graph.Unbound(); fmt.Sprint(got) != "[announce lint publish]" {
+        t.Errorf("expected the placeholder to be unbound, got %v", got)
+    }
+}
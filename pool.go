@@ -0,0 +1,410 @@
+package leo
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Worker is a slot in a WorkerPool that runs one task at a time. Labels
+// describe the worker's capabilities, such as {"has-gpu": "", "site": "syd"},
+// for tasks that require them, see RequireWorker.
+type Worker struct {
+    Name   string
+    Labels map[string]string
+}
+
+// matches reports whether w satisfies every constraint, each either a label
+// name that must be present or name=value.
+func (w *Worker) matches(constraints []string) bool {
+    for _, c := range constraints {
+        key, value, hasValue := strings.Cut(c, "=")
+        got, ok := w.Labels[key]
+        if !ok || hasValue && got != value {
+            return false
+        }
+    }
+    return true
+}
+
+// RequireWorker restricts a node to workers whose labels satisfy every
+// constraint: "has-gpu" requires the label to be present, and "site=syd"
+// requires it to have that value. A node with requirements can only run on
+// an executor with a WorkerPool, see Executor.SetWorkerPool.
+func RequireWorker(constraints ...string) NodeOption {
+    return func(n *Node) {
+        n.requires = append(n.requires, constraints...)
+    }
+}
+
+type workerKey struct{}
+
+// CurrentWorker returns the worker running the task that ctx was passed to,
+// if the executor has a WorkerPool.
+func CurrentWorker(ctx context.Context) (Worker, bool) {
+    w, ok := ctx.Value(workerKey{}).(Worker)
+    return w, ok
+}
+
+// WorkerPool is a fixed set of workers that run the tasks of one or more
+// executors, at most one task per worker at a time, so that a service running
+// many pipelines bounds its work by the size of the pool rather than by the
+// number of pipelines. Ready tasks wait for an idle worker that satisfies
+// their requirements.
+//
+// When executors share a pool, a worker that frees up serves the executor
+// with the fewest tasks running on the pool, so a pipeline with a large
+// backlog does not hold up the others. Among that executor's waiting tasks
+// it takes the one the executor's SchedulingPolicy ranks first, then the one
+// that has waited longest. With aging (see SetAging), tasks that have waited
+// too long go first. A WorkerPool is safe for concurrent use.
+//
+// A busy worker runs the tasks it is given one after another on a goroutine
+// of its own, which exits once the worker is idle. Pools created with
+// NewLazyWorkerPool add and retire workers as needed, instead of having a
+// fixed set, and keep the goroutine of an idle worker until it retires.
+type WorkerPool struct {
+    mu      sync.Mutex
+    workers []*Worker
+    idle    map[*Worker]bool
+    running map[*Executor]int
+    queue   []*job
+    seq     int
+    aging   time.Duration
+
+    joined  map[*Worker]time.Time
+    busy    map[*Worker]time.Duration
+    since   map[*Worker]time.Time
+
+    // For lazy pools, see NewLazyWorkerPool.
+    template    *Worker
+    max         int
+    idleTimeout time.Duration
+    spawned     int
+    waiting     map[*Worker]chan *job
+}
+
+// job is a ready node waiting for a worker.
+type job struct {
+    run    *Run
+    node   *Node
+    rank   rank
+    seq    int
+    queued time.Time
+}
+
+// NewWorkerPool returns a pool of the given workers. Workers without a name
+// are named after their position, starting from "worker-1".
+func NewWorkerPool(workers ...Worker) *WorkerPool {
+    p := &WorkerPool{
+        idle:    make(map[*Worker]bool),
+        running: make(map[*Executor]int),
+        joined:  make(map[*Worker]time.Time),
+        busy:    make(map[*Worker]time.Duration),
+        since:   make(map[*Worker]time.Time),
+    }
+    for i := range workers {
+        w := workers[i]
+        if w.Name == "" {
+            w.Name = fmt.Sprintf("worker-%d", i+1)
+        }
+        p.add(&w)
+    }
+    return p
+}
+
+// add adds w to the pool, idle. The caller must hold p.mu, unless the pool
+// is being created.
+func (p *WorkerPool) add(w *Worker) {
+    p.workers = append(p.workers, w)
+    p.idle[w] = true
+    p.joined[w] = time.Now()
+}
+
+// SetAging makes tasks that have waited for a worker for at least d start
+// before any task that has not, oldest first, whatever their priority or
+// executor. Under a steady stream of high-priority work, low-priority tasks
+// would otherwise wait until it dries up. 0, the default, disables aging.
+func (p *WorkerPool) SetAging(d time.Duration) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    p.aging = d
+}
+
+// Workers returns the pool's workers.
+func (p *WorkerPool) Workers() []Worker {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    out := make([]Worker, len(p.workers))
+    for i, w := range p.workers {
+        out[i] = *w
+    }
+    return out
+}
+
+// WorkerStats is the utilization of a worker of a WorkerPool.
+type WorkerStats struct {
+    Name string
+    // Busy is set while the worker runs a task.
+    Busy     bool
+    // BusyTime is the time the worker has spent running tasks, including
+    // the current one, and IdleTime the rest of the time since the worker
+    // joined the pool.
+    BusyTime time.Duration
+    IdleTime time.Duration
+}
+
+// PoolStats is a snapshot of the utilization of a WorkerPool, for sizing
+// pools from data: a long queue means too few workers, and workers that are
+// mostly idle mean too many.
+type PoolStats struct {
+    // Queued is the number of ready tasks waiting for a worker.
+    Queued  int
+    Workers []WorkerStats
+}
+
+// Stats returns the pool's current utilization.
+func (p *WorkerPool) Stats() PoolStats {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    now := time.Now()
+    stats := PoolStats{Queued: len(p.queue), Workers: make([]WorkerStats, len(p.workers))}
+    for i, w := range p.workers {
+        busy := p.busy[w]
+        if since, ok := p.since[w]; ok {
+            busy += now.Sub(since)
+        }
+        stats.Workers[i] = WorkerStats{
+            Name:     w.Name,
+            Busy:     !p.idle[w],
+            BusyTime: busy,
+            IdleTime: now.Sub(p.joined[w]) - busy,
+        }
+    }
+    return stats
+}
+
+// SetWorkerPool runs the executor's tasks on the workers of p, one at a time
+// per worker. A pool may be shared by several executors. The concurrency
+// limit (see SetConcurrency) does not apply to pooled runs.
+func (e *Executor) SetWorkerPool(p *WorkerPool) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.pool = p
+}
+
+func (e *Executor) getWorkerPool() *WorkerPool {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.pool
+}
+
+// checkWorkers returns an error if a node of the run has requirements that no
+// worker of p, which may be nil, satisfies.
+func (r *Run) checkWorkers(p *WorkerPool) error {
+    var names []string
+    for name, n := range r.graph.nodes {
+        if len(n.requires) > 0 {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        n := r.graph.nodes[name]
+        if p == nil {
+            return fmt.Errorf("node %s requires a worker but the executor has no worker pool", name)
+        }
+        if !p.canRun(n) {
+            return fmt.Errorf("node %s: no worker matches %s", name, strings.Join(n.requires, ", "))
+        }
+    }
+    return nil
+}
+
+func (p *WorkerPool) canRun(n *Node) bool {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if p.template != nil && p.template.matches(n.requires) {
+        return true
+    }
+    for _, w := range p.workers {
+        if w.matches(n.requires) {
+            return true
+        }
+    }
+    return false
+}
+
+// runPooled submits the run's ready nodes to p until ready is closed.
+func (r *Run) runPooled(ready <-chan *Node, p *WorkerPool, policy SchedulingPolicy) {
+    ranks := r.policyRanks(policy)
+    for n := range ready {
+        // Submit everything that is already ready together, so that the
+        // policy chooses among all of it.
+        jobs := []*job{{run: r, node: n, rank: ranks[n]}}
+        for more := true; more; {
+            select {
+            case n := <-ready:
+                jobs = append(jobs, &job{run: r, node: n, rank: ranks[n]})
+            default:
+                more = false
+            }
+        }
+        p.submit(jobs)
+    }
+}
+
+// submit starts each job on an idle worker that can run it, best first, and
+// queues the rest.
+func (p *WorkerPool) submit(jobs []*job) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    now := time.Now()
+    for _, j := range jobs {
+        j.seq = p.seq
+        j.queued = now
+        p.seq++
+    }
+    sort.SliceStable(jobs, func(a, b int) bool { return jobs[a].before(jobs[b]) })
+    for _, j := range jobs {
+        if w := p.idleWorker(j); w != nil {
+            p.start(w, j)
+        } else if w := p.spawn(j); w != nil {
+            p.start(w, j)
+        } else {
+            p.queue = append(p.queue, j)
+        }
+    }
+}
+
+// idleWorker returns an idle worker that can run j, or nil. The caller must
+// hold p.mu.
+func (p *WorkerPool) idleWorker(j *job) *Worker {
+    for _, w := range p.workers {
+        if p.idle[w] && w.matches(j.node.requires) {
+            return w
+        }
+    }
+    return nil
+}
+
+// start runs j on w: on w's goroutine if it is waiting for a job, or else on
+// a new one. The caller must hold p.mu.
+func (p *WorkerPool) start(w *Worker, j *job) {
+    p.assign(w, j)
+    if ch, ok := p.waiting[w]; ok {
+        delete(p.waiting, w)
+        ch <- j
+        return
+    }
+    go p.serve(w, j)
+}
+
+// assign records that w runs j. The caller must hold p.mu.
+func (p *WorkerPool) assign(w *Worker, j *job) {
+    p.idle[w] = false
+    p.since[w] = time.Now()
+    p.running[j.run.executor]++
+    j.run.setWorker(j.node, w)
+}
+
+// serve runs j on w's goroutine, then each job w is given after it, until w
+// has nothing left to run.
+func (p *WorkerPool) serve(w *Worker, j *job) {
+    for j != nil {
+        j.run.execute(j.node)
+        j = p.release(w, j)
+    }
+}
+
+// release records that w has finished done and returns the next queued job
+// it can run. If there is none, w becomes idle, and release returns nil, or
+// in a lazy pool waits for w to be given a job, see idled.
+func (p *WorkerPool) release(w *Worker, done *job) *job {
+    p.mu.Lock()
+    if p.running[done.run.executor]--; p.running[done.run.executor] == 0 {
+        delete(p.running, done.run.executor)
+    }
+    p.busy[w] += time.Since(p.since[w])
+    delete(p.since, w)
+    best := -1
+    now := time.Now()
+    for i, j := range p.queue {
+        if w.matches(j.node.requires) && (best < 0 || p.fairer(j, p.queue[best], now)) {
+            best = i
+        }
+    }
+    if best < 0 {
+        p.idle[w] = true
+        wait := p.idled(w)
+        p.mu.Unlock()
+        if wait == nil {
+            return nil
+        }
+        return p.await(w, wait)
+    }
+    j := p.queue[best]
+    p.queue = append(p.queue[:best], p.queue[best+1:]...)
+    p.assign(w, j)
+    p.mu.Unlock()
+    return j
+}
+
+// fairer reports whether j should start before other at now. Jobs that have
+// aged go first, oldest first. Otherwise jobs of different executors are
+// ordered by the number of tasks their executors have running, then by age;
+// jobs of the same executor by before. The caller must hold p.mu.
+func (p *WorkerPool) fairer(j, other *job, now time.Time) bool {
+    if p.aging > 0 {
+        aged, otherAged := now.Sub(j.queued) >= p.aging, now.Sub(other.queued) >= p.aging
+        if aged != otherAged {
+            return aged
+        }
+        if aged {
+            return j.seq < other.seq
+        }
+    }
+    if j.run.executor != other.run.executor {
+        a, b := p.running[j.run.executor], p.running[other.run.executor]
+        if a != b {
+            return a < b
+        }
+        return j.seq < other.seq
+    }
+    return j.before(other)
+}
+
+// before reports whether j should start before other.
+func (j *job) before(other *job) bool {
+    if j.rank.remaining != other.rank.remaining {
+        return j.rank.remaining > other.rank.remaining
+    }
+    if j.rank.depth != other.rank.depth {
+        return j.rank.depth > other.rank.depth
+    }
+    return j.seq < other.seq
+}
+
+// setWorker records the worker running n.
+func (r *Run) setWorker(n *Node, w *Worker) {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    if r.workers == nil {
+        r.workers = make(map[*Node]*Worker)
+    }
+    r.workers[n] = w
+}
+
+// workerContext adds the worker running n, if any, to ctx.
+func (r *Run) workerContext(ctx context.Context, n *Node) context.Context {
+    r.stateMu.Lock()
+    w := r.workers[n]
+    r.stateMu.Unlock()
+    if w == nil {
+        return ctx
+    }
+    return context.WithValue(ctx, workerKey{}, *w)
+}
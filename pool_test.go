@@ -0,0 +1,229 @@
+package leo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerAffinity(t *testing.T) {
+    pool := NewWorkerPool(
+        Worker{Name: "gpu", Labels: map[string]string{"has-gpu": "", "site": "mel"}},
+        Worker{Name: "syd", Labels: map[string]string{"site": "syd"}},
+        Worker{},
+    )
+    if ws := pool.Workers(); ws[2].Name != "worker-3" {
+        t.Errorf("expected an unnamed worker to be named worker-3, got %q", ws[2].Name)
+    }
+
+    var mu sync.Mutex
+    ranOn := make(map[string]string)
+    record := func(name string) TaskCtxFunc {
+        return func(ctx context.Context) error {
+            w, ok := CurrentWorker(ctx)
+            if !ok {
+                return fmt.Errorf("no worker in context")
+            }
+            mu.Lock()
+            ranOn[name] = w.Name
+            mu.Unlock()
+            time.Sleep(5 * time.Millisecond)
+            return nil
+        }
+    }
+
+    graph := TaskGraph()
+    for i := 0; i < 3; i++ {
+        train, upload := fmt.Sprintf("train%d", i), fmt.Sprintf("upload%d", i)
+        graph.AddCtx(train, record(train), RequireWorker("has-gpu"))
+        graph.AddCtx(upload, record(upload), RequireWorker("site=syd"))
+    }
+    graph.AddCtx("anywhere", record("anywhere"))
+
+    executor := NewExecutor(graph)
+    executor.SetWorkerPool(pool)
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    for name, worker := range ranOn {
+        if strings.HasPrefix(name, "train") && worker != "gpu" || strings.HasPrefix(name, "upload") && worker != "syd" {
+            t.Errorf("%s ran on %s", name, worker)
+        }
+    }
+    if len(ranOn) != 7 {
+        t.Errorf("expected every task to run, got %v", ranOn)
+    }
+}
+
+func TestWorkerPoolStats(t *testing.T) {
+    pool := NewWorkerPool(Worker{Name: "busy", Labels: map[string]string{"has-gpu": ""}}, Worker{Name: "idle"})
+    started, release := make(chan struct{}), make(chan struct{})
+    graph := TaskGraph()
+    graph.Add("work", func() error {
+        close(started)
+        <-release
+        return nil
+    }, RequireWorker("has-gpu"))
+
+    executor := NewExecutor(graph)
+    executor.SetWorkerPool(pool)
+    done := make(chan error)
+    go func() { done <- executor.Execute() }()
+    <-started
+    time.Sleep(10 * time.Millisecond)
+    stats := pool.Stats()
+    if !stats.Workers[0].Busy || stats.Workers[1].Busy || stats.Queued != 0 {
+        t.Errorf("expected only the first worker to be busy, got %+v", stats)
+    }
+    close(release)
+    if err := <-done; err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    stats = pool.Stats()
+    busy, idle := stats.Workers[0], stats.Workers[1]
+    if busy.Busy || busy.BusyTime < 10*time.Millisecond {
+        t.Errorf("expected the first worker to have been busy for at least 10ms, got %+v", busy)
+    }
+    if idle.BusyTime != 0 || idle.IdleTime < 10*time.Millisecond {
+        t.Errorf("expected the second worker to have been idle, got %+v", idle)
+    }
+}
+
+func TestWorkerRequirementErrors(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("train", func() error { return nil }, RequireWorker("has-gpu"))
+
+    executor := NewExecutor(graph)
+    if err := executor.Execute(); err == nil || !strings.Contains(err.Error(), "no worker pool") {
+        t.Errorf("expected an error without a pool, got %v", err)
+    }
+    executor.SetWorkerPool(NewWorkerPool(Worker{Labels: map[string]string{"site": "syd"}}))
+    if err := executor.Execute(); err == nil || !strings.Contains(err.Error(), "no worker matches has-gpu") {
+        t.Errorf("expected an unsatisfiable requirement error, got %v", err)
+    }
+}
+
+func TestWorkerPoolCapacity(t *testing.T) {
+    var mu sync.Mutex
+    running, peak := 0, 0
+    graph := TaskGraph()
+    for i := 0; i < 6; i++ {
+        graph.Add(fmt.Sprintf("t%d", i), func() error {
+            mu.Lock()
+            running++
+            if running > peak {
+                peak = running
+            }
+            mu.Unlock()
+            time.Sleep(5 * time.Millisecond)
+            mu.Lock()
+            running--
+            mu.Unlock()
+            return nil
+        })
+    }
+
+    executor := NewExecutor(graph)
+    executor.SetWorkerPool(NewWorkerPool(Worker{}, Worker{}))
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if peak != 2 {
+        t.Errorf("expected at most 2 tasks at once, saw %d", peak)
+    }
+}
+
+func TestSharedWorkerPool(t *testing.T) {
+    pool := NewWorkerPool(Worker{}, Worker{})
+    task := func() error {
+        time.Sleep(10 * time.Millisecond)
+        return nil
+    }
+
+    busy := TaskGraph()
+    for i := 0; i < 20; i++ {
+        busy.Add(fmt.Sprintf("b%d", i), task)
+    }
+    small := TaskGraph()
+    small.Add("s1", task)
+    small.Add("s2", task)
+
+    busyExec := NewExecutor(busy)
+    busyExec.SetWorkerPool(pool)
+    smallExec := NewExecutor(small)
+    smallExec.SetWorkerPool(pool)
+
+    done := make(chan error)
+    go func() { done <- busyExec.Execute() }()
+    time.Sleep(5 * time.Millisecond)
+
+    start := time.Now()
+    if err := smallExec.Execute(); err != nil {
+        t.Fatalf("small pipeline failed: %v", err)
+    }
+    if elapsed := time.Since(start); elapsed > 60*time.Millisecond {
+        t.Errorf("expected the small pipeline not to wait for the busy one's backlog, took %s", elapsed)
+    }
+    if err := <-done; err != nil {
+        t.Fatalf("busy pipeline failed: %v", err)
+    }
+}
+
+func TestWorkerPoolAging(t *testing.T) {
+    // position queues a cheap report behind a blocker on the main worker,
+    // while a kickoff on a side worker releases twenty urgent tasks for the
+    // main worker, and returns how many urgent tasks started before the
+    // report. The report is queued first, so it is the first to age.
+    position := func(aging time.Duration) int {
+        var mu sync.Mutex
+        var order []string
+        record := func(name string, d time.Duration) TaskFunc {
+            return func() error {
+                mu.Lock()
+                order = append(order, name)
+                mu.Unlock()
+                time.Sleep(d)
+                return nil
+            }
+        }
+        graph := TaskGraph()
+        graph.Add("blocker", record("blocker", 20*time.Millisecond), RequireWorker("main"), WithCost(2*time.Hour))
+        graph.Add("report", record("report", 5*time.Millisecond), RequireWorker("main"))
+        graph.Add("kickoff", record("kickoff", 0), RequireWorker("side"))
+        for i := 0; i < 20; i++ {
+            name := fmt.Sprintf("urgent%d", i)
+            graph.Add(name, record(name, 5*time.Millisecond), RequireWorker("main"), WithCost(time.Hour))
+            graph.Precede("kickoff", name)
+        }
+
+        pool := NewWorkerPool(
+            Worker{Name: "main", Labels: map[string]string{"main": ""}},
+            Worker{Name: "side", Labels: map[string]string{"side": ""}},
+        )
+        pool.SetAging(aging)
+        executor := NewExecutor(graph, WithWorkerPool(pool), WithScheduling(ScheduleCriticalPath))
+        if err := executor.Execute(); err != nil {
+            t.Fatalf("Execute failed: %v", err)
+        }
+        urgent := 0
+        for _, name := range order {
+            switch {
+            case name == "report":
+                return urgent
+            case strings.HasPrefix(name, "urgent"):
+                urgent++
+            }
+        }
+        return -1
+    }
+
+    if got := position(0); got != 20 {
+        t.Errorf("expected the report to start last without aging, started after %d tasks", got)
+    }
+    if got := position(12 * time.Millisecond); got != 0 {
+        t.Errorf("expected the report to start first once it aged, started after %d tasks", got)
+    }
+}
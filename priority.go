@@ -0,0 +1,306 @@
+package leo
+
+import (
+    "container/heap"
+    "time"
+)
+
+// SchedulingPolicy decides which ready task starts first when the number of
+// running tasks is limited, see Executor.SetConcurrency.
+type SchedulingPolicy int
+
+const (
+    // ScheduleFIFO starts ready tasks in the order they became ready. This
+    // is the default.
+    ScheduleFIFO SchedulingPolicy = iota
+    // ScheduleCriticalPath starts the ready task with the longest remaining
+    // path first, measured by the estimated cost of the task and everything
+    // that depends on it. Keeping the critical path moving shortens runs of
+    // unbalanced graphs. Costs come from the executor's history if it has
+    // one, then WithCost, then WithExpectedDuration.
+    ScheduleCriticalPath
+    // ScheduleLongestFirst starts the ready task with the largest estimated
+    // cost first, regardless of what depends on it. Packing the longest
+    // tasks first onto a limited number of slots keeps short tasks for the
+    // end, where they fill the gaps, which shortens runs of many
+    // independent tasks of uneven cost. Costs are estimated as for
+    // ScheduleCriticalPath.
+    ScheduleLongestFirst
+)
+
+func (p SchedulingPolicy) String() string {
+    switch p {
+    case ScheduleFIFO:
+        return "fifo"
+    case ScheduleCriticalPath:
+        return "critical path"
+    case ScheduleLongestFirst:
+        return "longest first"
+    }
+    return "unknown"
+}
+
+// WithCost declares a task's estimated duration, for scheduling and ETAs.
+// Unlike WithExpectedDuration, exceeding it is not a violation.
+func WithCost(d time.Duration) NodeOption {
+    return func(n *Node) {
+        n.cost = d
+    }
+}
+
+// SetConcurrency limits the number of tasks of a run that execute at once.
+// Ready tasks beyond the limit wait, and the scheduling policy decides which
+// starts next. A negative n means no limit. 0, the default, limits runs to
+// runtime.GOMAXPROCS(0) tasks, enough to keep every processor busy with
+// tasks that compute, or to 8 times that for executors created WithIOBound,
+// whose tasks mostly wait. Consumers of a streaming edge (see Stream) start
+// alongside their producer without waiting for a slot; other tasks that wait
+// for each other while running need a limit of at least the number of them
+// that run together.
+func (e *Executor) SetConcurrency(n int) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.concurrency = n
+}
+
+// SetScheduling sets the policy for starting ready tasks under a
+// concurrency limit or on a worker pool. Report.Scheduling records the
+// policy a run used, so that policies can be compared.
+func (e *Executor) SetScheduling(p SchedulingPolicy) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.scheduling = p
+}
+
+// getConcurrency returns the executor's concurrency limit, 0 for none, and
+// scheduling policy.
+func (e *Executor) getConcurrency() (int, SchedulingPolicy) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    switch {
+    case e.concurrency < 0:
+        return 0, e.scheduling
+    case e.concurrency == 0:
+        return defaultConcurrency(e.ioBound), e.scheduling
+    }
+    return e.concurrency, e.scheduling
+}
+
+// rank is a ready node's place in the queue: longer remaining paths first,
+// then deeper remaining chains, then arrival order.
+type rank struct {
+    remaining time.Duration
+    depth     int
+}
+
+// criticalPaths returns the rank of every node: its estimated cost plus the
+// largest rank among its successors.
+func (r *Run) criticalPaths() map[*Node]rank {
+    return r.graph.ranks(r.estimate)
+}
+
+// policyRanks returns the rank of every node under policy, or nil to start
+// ready nodes in arrival order.
+func (r *Run) policyRanks(policy SchedulingPolicy) map[*Node]rank {
+    if p := r.plan(); p != nil && r.estimates == nil {
+        return p.ranks[policy]
+    }
+    switch policy {
+    case ScheduleCriticalPath:
+        return r.criticalPaths()
+    case ScheduleLongestFirst:
+        return r.graph.longestFirst(r.estimate)
+    }
+    return nil
+}
+
+// longestFirst returns the rank of every node of g under
+// ScheduleLongestFirst, with costs from estimate.
+func (g *Graph) longestFirst(estimate func(n *Node) time.Duration) map[*Node]rank {
+    ranks := make(map[*Node]rank, len(g.nodes))
+    for _, node := range g.nodes {
+        ranks[node] = rank{remaining: estimate(node)}
+    }
+    return ranks
+}
+
+// ranks returns the rank of every node of g, with costs from estimate.
+func (g *Graph) ranks(estimate func(n *Node) time.Duration) map[*Node]rank {
+    ranks := make(map[*Node]rank, len(g.nodes))
+    var visit func(n *Node) rank
+    visit = func(n *Node) rank {
+        if rk, ok := ranks[n]; ok {
+            return rk
+        }
+        var best rank
+        for _, s := range n.successors() {
+            if rk := visit(s); rk.longer(best) {
+                best = rk
+            }
+        }
+        rk := rank{remaining: best.remaining + estimate(n), depth: best.depth + 1}
+        ranks[n] = rk
+        return rk
+    }
+    for _, node := range g.nodes {
+        visit(node)
+    }
+    return ranks
+}
+
+func (rk rank) longer(other rank) bool {
+    return rk.remaining > other.remaining || rk.remaining == other.remaining && rk.depth > other.depth
+}
+
+// CriticalPath returns the graph's longest path and its total cost: the
+// chain of dependencies that bounds how fast a run can finish however many
+// tasks run at once. Each node costs its WithCost, or else its
+// WithExpectedDuration, or else nothing; among paths of equal cost the one
+// with the most nodes is chosen, then the one with the first names.
+func (g *Graph) CriticalPath() ([]string, time.Duration) {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    ranks := g.ranks(func(n *Node) time.Duration {
+        if n.cost > 0 {
+            return n.cost
+        }
+        return n.expected
+    })
+    next := func(candidates []*Node) *Node {
+        var best *Node
+        for _, n := range candidates {
+            if best == nil || ranks[n].longer(ranks[best]) ||
+                ranks[n] == ranks[best] && n.name < best.name {
+                best = n
+            }
+        }
+        return best
+    }
+
+    var roots []*Node
+    for _, node := range g.nodes {
+        if len(node.predecessors()) == 0 {
+            roots = append(roots, node)
+        }
+    }
+    var path []string
+    n := next(roots)
+    if n == nil {
+        return nil, 0
+    }
+    total := ranks[n].remaining
+    for ; n != nil; n = next(n.successors()) {
+        path = append(path, n.name)
+    }
+    return path, total
+}
+
+// readyQueue orders ready nodes for a limited run.
+type readyQueue struct {
+    items   []queued
+    arrived int
+    ranks   map[*Node]rank
+}
+
+// queued is a node in a readyQueue and its place in the order of arrival.
+type queued struct {
+    node *Node
+    seq  int
+}
+
+func (q *readyQueue) Len() int { return len(q.items) }
+
+func (q *readyQueue) Less(i, j int) bool {
+    a, b := q.items[i], q.items[j]
+    if q.ranks != nil {
+        ra, rb := q.ranks[a.node], q.ranks[b.node]
+        if ra.remaining != rb.remaining {
+            return ra.remaining > rb.remaining
+        }
+        if ra.depth != rb.depth {
+            return ra.depth > rb.depth
+        }
+    }
+    return a.seq < b.seq
+}
+
+func (q *readyQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *readyQueue) Push(x any) {
+    q.items = append(q.items, queued{node: x.(*Node), seq: q.arrived})
+    q.arrived++
+}
+
+func (q *readyQueue) Pop() any {
+    it := q.items[len(q.items)-1]
+    q.items = q.items[:len(q.items)-1]
+    return it.node
+}
+
+// runLimited executes ready nodes with at most limit running at once, or
+// as many as the executor's adaptive controller allows if it has one,
+// starting them in the order of policy, until ready is closed and they have
+// all returned.
+func (r *Run) runLimited(ready <-chan *Node, limit int, policy SchedulingPolicy) {
+    q := &readyQueue{items: make([]queued, 0, len(r.graph.nodes)), ranks: r.policyRanks(policy)}
+    adaptive := r.executor.getAdaptive()
+    if adaptive != nil {
+        limit = adaptive.current()
+    }
+    // Goroutines that have executed a node wait on work for another, so
+    // that it reuses their stack instead of starting a goroutine of its own.
+    done, work := make(chan *Node), make(chan *Node)
+    defer close(work)
+    running, idle := 0, 0
+    push := func(n *Node) {
+        if n.streamed() {
+            // A stream consumer runs alongside its producer, which may
+            // hold the last slot until the consumer reads from it.
+            go r.execute(n)
+            return
+        }
+        heap.Push(q, n)
+    }
+    for ready != nil || running > 0 {
+        for running < limit && q.Len() > 0 {
+            n := heap.Pop(q).(*Node)
+            running++
+            if idle > 0 {
+                idle--
+                work <- n
+                continue
+            }
+            go func(n *Node) {
+                for ok := true; ok; n, ok = <-work {
+                    r.execute(n)
+                    done <- n
+                }
+            }(n)
+        }
+        select {
+        case n, ok := <-ready:
+            if !ok {
+                ready = nil
+                continue
+            }
+            push(n)
+            // Take everything else that is already ready, so that the
+            // policy chooses among all of it.
+            for more := true; more; {
+                select {
+                case n := <-ready:
+                    push(n)
+                default:
+                    more = false
+                }
+            }
+        case n := <-done:
+            running--
+            idle++
+            if adaptive != nil {
+                r.adapt(adaptive, n)
+                limit = adaptive.current()
+            }
+        }
+    }
+}
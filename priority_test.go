@@ -0,0 +1,184 @@
+package leo
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+    var mu sync.Mutex
+    running, peak := 0, 0
+    graph := TaskGraph()
+    for i := 0; i < 8; i++ {
+        graph.Add(fmt.Sprintf("t%d", i), func() error {
+            mu.Lock()
+            running++
+            if running > peak {
+                peak = running
+            }
+            mu.Unlock()
+            time.Sleep(5 * time.Millisecond)
+            mu.Lock()
+            running--
+            mu.Unlock()
+            return nil
+        })
+    }
+
+    executor := NewExecutor(graph)
+    executor.SetConcurrency(3)
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if peak != 3 {
+        t.Errorf("expected at most 3 tasks at once, saw %d", peak)
+    }
+}
+
+func TestCriticalPathScheduling(t *testing.T) {
+    // A chain of three tasks next to four independent ones. With two slots,
+    // the run takes four units if the chain keeps one slot busy throughout,
+    // and up to five if the independent tasks go first.
+    const unit = 40 * time.Millisecond
+    sleep := func() error {
+        time.Sleep(unit)
+        return nil
+    }
+    graph := TaskGraph()
+    for _, name := range []string{"a", "b", "c", "d"} {
+        graph.Add(name, sleep, WithCost(unit))
+    }
+    graph.Add("z1", sleep, WithCost(unit))
+    graph.Add("z2", sleep, WithCost(unit))
+    graph.Add("z3", sleep, WithCost(unit))
+    graph.Precede("z1", "z2")
+    graph.Precede("z2", "z3")
+
+    executor := NewExecutor(graph)
+    executor.SetConcurrency(2)
+    executor.SetScheduling(ScheduleCriticalPath)
+    start := time.Now()
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if elapsed := time.Since(start); elapsed >= 4*unit+unit/2 {
+        t.Errorf("expected the chain to be prioritised, run took %s", elapsed)
+    }
+    if start := executor.Report().Nodes["z1"].Start.Sub(executor.Report().Start); start > unit/2 {
+        t.Errorf("expected z1 to start first, started after %s", start)
+    }
+}
+
+func TestCriticalPath(t *testing.T) {
+    graph := TaskGraph()
+    noop := func() error { return nil }
+    graph.Add("fetch", noop, WithCost(time.Second))
+    graph.Add("compile", noop, WithCost(5*time.Second))
+    graph.Add("docs", noop, WithExpectedDuration(2*time.Second))
+    graph.Add("lint", noop)
+    graph.Add("package", noop, WithCost(time.Second))
+    graph.Precede("fetch", "compile")
+    graph.Precede("fetch", "docs")
+    graph.Precede("compile", "package")
+    graph.Precede("docs", "package")
+    graph.Precede("lint", "package")
+
+    path, total := graph.CriticalPath()
+    if want := []string{"fetch", "compile", "package"}; fmt.Sprint(path) != fmt.Sprint(want) || total != 7*time.Second {
+        t.Errorf("CriticalPath = %v, %s, want %v, 7s", path, total, want)
+    }
+
+    if path, total := TaskGraph().CriticalPath(); path != nil || total != 0 {
+        t.Errorf("CriticalPath of an empty graph = %v, %s", path, total)
+    }
+}
+
+// unevenGraph returns independent tasks of uneven cost, listed shortest
+// first, that sleep for their cost scaled by unit.
+func unevenGraph(unit time.Duration) *Graph {
+    graph := TaskGraph()
+    for i, units := range []int{1, 1, 1, 1, 2, 2, 3, 6} {
+        d := time.Duration(units) * unit
+        graph.Add(fmt.Sprintf("t%d", i), func() error {
+            time.Sleep(d)
+            return nil
+        }, WithCost(d))
+    }
+    return graph
+}
+
+func TestLongestFirstScheduling(t *testing.T) {
+    // 17 units of work on three slots. Longest first packs them into six
+    // units; in arrival order the six unit task starts last and the run
+    // takes nine.
+    const unit = 20 * time.Millisecond
+    order := func(policy SchedulingPolicy) (time.Duration, *Report) {
+        graph := unevenGraph(unit)
+        // Make every task ready together in the listed order.
+        graph.Add("start", func() error { return nil })
+        for i := 0; i < 8; i++ {
+            graph.Precede("start", fmt.Sprintf("t%d", i))
+        }
+        executor := NewExecutor(graph)
+        executor.SetConcurrency(3)
+        executor.SetScheduling(policy)
+        start := time.Now()
+        if err := executor.Execute(); err != nil {
+            t.Fatalf("Execute failed: %v", err)
+        }
+        return time.Since(start), executor.Report()
+    }
+
+    elapsed, report := order(ScheduleLongestFirst)
+    if report.Scheduling != ScheduleLongestFirst {
+        t.Errorf("Report().Scheduling = %s, want longest first", report.Scheduling)
+    }
+    if elapsed >= 7*unit+unit/2 {
+        t.Errorf("expected longest first to take about 6 units, took %s", elapsed)
+    }
+    if start := report.Nodes["t7"].Start.Sub(report.Nodes["t0"].Start); start > 0 {
+        t.Errorf("expected the longest task to start before the shortest")
+    }
+
+    if _, report := order(ScheduleFIFO); report.Scheduling != ScheduleFIFO {
+        t.Errorf("Report().Scheduling = %s, want fifo", report.Scheduling)
+    }
+}
+
+func BenchmarkScheduling(b *testing.B) {
+    for _, policy := range []SchedulingPolicy{ScheduleFIFO, ScheduleCriticalPath, ScheduleLongestFirst} {
+        b.Run(policy.String(), func(b *testing.B) {
+            for i := 0; i < b.N; i++ {
+                executor := NewExecutor(unevenGraph(time.Millisecond))
+                executor.SetConcurrency(3)
+                executor.SetScheduling(policy)
+                if err := executor.Execute(); err != nil {
+                    b.Fatalf("Execute failed: %v", err)
+                }
+            }
+        })
+    }
+}
+
+func TestDefaultConcurrency(t *testing.T) {
+    procs := runtime.GOMAXPROCS(0)
+    limit := func(e *Executor) int {
+        n, _ := e.getConcurrency()
+        return n
+    }
+    graph := TaskGraph()
+    if got := limit(NewExecutor(graph)); got != procs {
+        t.Errorf("default limit = %d, want GOMAXPROCS %d", got, procs)
+    }
+    if got := limit(NewExecutor(graph, WithIOBound())); got != 8*procs {
+        t.Errorf("IO-bound default limit = %d, want %d", got, 8*procs)
+    }
+    executor := NewExecutor(graph, WithIOBound())
+    executor.SetConcurrency(-1)
+    if got := limit(executor); got != 0 {
+        t.Errorf("expected no limit, got %d", got)
+    }
+}
@@ -0,0 +1,146 @@
+package leo
+
+import (
+    "fmt"
+    "io"
+    "strings"
+    "sync"
+    "time"
+)
+
+// ProgressBar draws a single-line progress bar for a run from its events,
+// for command-line tools wrapping leo:
+//
+//    [#########-----------] 9/20 (1 failed, 2 skipped) ETA 1m30s running: build, test
+//
+// Each event redraws the line in place using a carriage return, so w should
+// be a terminal. Use ShowProgress to attach a ProgressBar to an executor.
+type ProgressBar struct {
+    // Width is the maximum line length. Defaults to 80.
+    Width int
+
+    w       io.Writer
+    total   int
+    run     *Run
+    done    int
+    failed  int
+    skipped int
+    running []string
+    eta     time.Duration
+    mu      sync.Mutex
+}
+
+// NewProgressBar returns a progress bar for runs of total nodes.
+func NewProgressBar(w io.Writer, total int) *ProgressBar {
+    return &ProgressBar{w: w, total: total}
+}
+
+// ShowProgress draws a progress bar on w for each run of e until the
+// returned function is called, which draws the remaining events and finishes
+// the line.
+func ShowProgress(e *Executor, w io.Writer) (stop func()) {
+    p := NewProgressBar(w, len(e.graph.nodes))
+    sub := e.Subscribe()
+    finished := make(chan struct{})
+    go func() {
+        defer close(finished)
+        for ev := range sub.C {
+            p.Handle(ev)
+        }
+    }()
+    return func() {
+        sub.closeWhenDrained()
+        <-finished
+        p.Finish()
+    }
+}
+
+// Handle updates the bar with ev and redraws it. Events of a new run reset
+// the counts.
+func (p *ProgressBar) Handle(ev Event) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    if ev.Run != p.run {
+        p.run = ev.Run
+        p.done, p.failed, p.skipped = 0, 0, 0
+        p.running = nil
+    }
+    if ev.Type != EventTaskQueued && ev.Type != EventRunFinished {
+        p.eta = ev.ETA
+    }
+    switch ev.Type {
+    case EventTaskStarted:
+        p.running = append(p.running, ev.Node)
+    case EventTaskFinished:
+        p.done++
+        p.stopped(ev.Node)
+    case EventTaskFailed:
+        p.done++
+        p.failed++
+        p.stopped(ev.Node)
+    case EventTaskSkipped:
+        p.done++
+        p.skipped++
+    case EventTaskQueued:
+        return
+    }
+    fmt.Fprint(p.w, "\r"+p.line()+"\x1b[K")
+}
+
+// Finish ends the progress line.
+func (p *ProgressBar) Finish() {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if p.run != nil {
+        fmt.Fprintln(p.w)
+    }
+}
+
+func (p *ProgressBar) stopped(name string) {
+    for i, n := range p.running {
+        if n == name {
+            p.running = append(p.running[:i], p.running[i+1:]...)
+            return
+        }
+    }
+}
+
+// line renders the bar without the carriage return.
+func (p *ProgressBar) line() string {
+    const barWidth = 20
+    filled := 0
+    if p.total > 0 {
+        filled = barWidth * p.done / p.total
+    }
+    if filled > barWidth {
+        filled = barWidth
+    }
+
+    var b strings.Builder
+    fmt.Fprintf(&b, "[%s%s] %d/%d", strings.Repeat("#", filled), strings.Repeat("-", barWidth-filled), p.done, p.total)
+    switch {
+    case p.failed > 0 && p.skipped > 0:
+        fmt.Fprintf(&b, " (%d failed, %d skipped)", p.failed, p.skipped)
+    case p.failed > 0:
+        fmt.Fprintf(&b, " (%d failed)", p.failed)
+    case p.skipped > 0:
+        fmt.Fprintf(&b, " (%d skipped)", p.skipped)
+    }
+    if eta := p.eta.Round(time.Second); eta > 0 && p.done < p.total {
+        fmt.Fprintf(&b, " ETA %s", eta)
+    }
+    if len(p.running) > 0 {
+        b.WriteString(" running: " + strings.Join(p.running, ", "))
+    }
+
+    width := p.Width
+    if width <= 0 {
+        width = 80
+    }
+    line := b.String()
+    if len(line) > width {
+        line = line[:width-3] + "..."
+    }
+    return line
+}
@@ -0,0 +1,50 @@
+package leo
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestShowProgress(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("build", func() error { return nil })
+    graph.Add("test", func() error { return errors.New("flaky") })
+    graph.Add("deploy", func() error { return nil })
+    graph.Precede("build", "test")
+    graph.Precede("test", "deploy")
+
+    var out bytes.Buffer
+    executor := NewExecutor(graph)
+    stop := ShowProgress(executor, &out)
+    executor.Execute()
+    stop()
+
+    frames := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\r")
+    last := strings.TrimSuffix(frames[len(frames)-1], "\x1b[K")
+    if last != "[####################] 3/3 (1 failed, 1 skipped)" {
+        t.Errorf("unexpected final frame %q", last)
+    }
+    if !strings.Contains(out.String(), "running: build") {
+        t.Errorf("expected the running task to be shown, got %q", out.String())
+    }
+}
+
+func TestProgressBarWidth(t *testing.T) {
+    var out bytes.Buffer
+    p := NewProgressBar(&out, 10)
+    p.Width = 40
+    run := &Run{}
+    for _, name := range []string{"a-long-task-name", "another-long-task-name"} {
+        p.Handle(Event{Type: EventTaskStarted, Run: run, Node: name})
+    }
+    if line := p.line(); len(line) != 40 || !strings.HasSuffix(line, "...") {
+        t.Errorf("expected a truncated line, got %q", line)
+    }
+
+    p.Handle(Event{Type: EventTaskFinished, Run: &Run{}, Node: "x"})
+    if line := p.line(); line != "[##------------------] 1/10" {
+        t.Errorf("expected a new run to reset the bar, got %q", line)
+    }
+}
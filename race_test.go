@@ -0,0 +1,7 @@
+//go:build race
+
+package leo
+
+// raceEnabled is set when the race detector is on, which changes how much
+// code allocates.
+const raceEnabled = true
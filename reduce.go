@@ -0,0 +1,81 @@
+package leo
+
+// TransitiveReduction removes edges that are implied by a longer path, e.g.
+// A -> C when A -> B -> C also exists, and duplicate edges. Execution order is
+// unchanged. Edges configured with options such as OnParentFailure are kept,
+// since removing them could change failure handling, and only paths of edges
+// that skip the child when the parent fails, as the removed edge would, imply
+// one: with A -> B released by OnParentFailure(EdgeRelease), a failing A
+// would no longer skip C. It returns the number of edges removed, and
+// ErrFrozen if the graph is frozen.
+func (g *Graph) TransitiveReduction() (int, error) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return 0, ErrFrozen
+    }
+    removed := 0
+
+    for _, node := range g.nodes {
+        // Everything reachable from node through at least two edges that
+        // skip their child.
+        indirect := make(map[*Node]bool)
+        for _, child := range node.children {
+            if !node.edgeTo(child).skips() {
+                continue
+            }
+            for _, grandchild := range child.children {
+                if child.edgeTo(grandchild).skips() {
+                    markReachable(grandchild, indirect)
+                }
+            }
+        }
+
+        kept := node.children[:0]
+        seen := make(map[*Node]bool)
+        for _, child := range node.children {
+            _, configured := node.edges[child]
+            if !configured && (indirect[child] || seen[child]) {
+                child.removeParent(node)
+                removed++
+                continue
+            }
+            seen[child] = true
+            kept = append(kept, child)
+        }
+        node.children = kept
+    }
+
+    return removed, nil
+}
+
+// markReachable marks n and everything reachable from it through edges that
+// skip their child.
+func markReachable(n *Node, visited map[*Node]bool) {
+    if visited[n] {
+        return
+    }
+    visited[n] = true
+    for _, child := range n.children {
+        if n.edgeTo(child).skips() {
+            markReachable(child, visited)
+        }
+    }
+}
+
+// skips reports whether the edge skips its child when the parent fails or is
+// skipped, once the parent has finished, as an edge added without options
+// does.
+func (e *edgeConfig) skips() bool {
+    return e.policy == EdgeSkip && !e.stream
+}
+
+// removeParent removes one occurrence of parent from n's parents.
+func (n *Node) removeParent(parent *Node) {
+    for i, p := range n.parents {
+        if p == parent {
+            n.parents = append(n.parents[:i], n.parents[i+1:]...)
+            return
+        }
+    }
+}
@@ -0,0 +1,107 @@
+package leo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransitiveReduction(t *testing.T) {
+    graph := TaskGraph()
+    for _, name := range []string{"A", "B", "C", "D"} {
+        graph.Add(name, func() error { return nil })
+    }
+
+    graph.Precede("A", "B")
+    graph.Precede("B", "C")
+    graph.Precede("C", "D")
+    graph.Precede("A", "C") // implied by A -> B -> C
+    graph.Precede("A", "D") // implied by A -> B -> C -> D
+    graph.Precede("B", "D") // implied by B -> C -> D
+    graph.Precede("A", "B") // duplicate
+
+    if removed, err := graph.TransitiveReduction(); err != nil || removed != 4 {
+        t.Errorf("expected 4 edges removed, got %d (%v)", removed, err)
+    }
+
+    want := map[string][]string{
+        "A": {"B"},
+        "B": {"C"},
+        "C": {"D"},
+        "D": nil,
+    }
+    for name, children := range want {
+        node := graph.nodes[name]
+        if len(node.children) != len(children) {
+            t.Errorf("%s: expected children %v, got %d children", name, children, len(node.children))
+            continue
+        }
+        for i, child := range children {
+            if node.children[i].name != child {
+                t.Errorf("%s: expected child %s, got %s", name, child, node.children[i].name)
+            }
+        }
+    }
+    for _, name := range []string{"B", "C", "D"} {
+        if len(graph.nodes[name].parents) != 1 {
+            t.Errorf("%s: expected 1 parent, got %d", name, len(graph.nodes[name].parents))
+        }
+    }
+
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+}
+
+func TestTransitiveReductionKeepsConfiguredEdges(t *testing.T) {
+    graph := TaskGraph()
+    for _, name := range []string{"A", "B", "C"} {
+        graph.Add(name, func() error { return nil })
+    }
+
+    graph.Precede("A", "B")
+    graph.Precede("B", "C")
+    graph.Precede("A", "C", OnParentFailure(EdgeRelease))
+
+    if removed, err := graph.TransitiveReduction(); err != nil || removed != 0 {
+        t.Errorf("expected no edges removed, got %d (%v)", removed, err)
+    }
+}
+
+func TestTransitiveReductionFollowsSkippingPaths(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return errors.New("boom") })
+    for _, name := range []string{"B", "C"} {
+        graph.Add(name, func() error { return nil })
+    }
+
+    graph.Precede("A", "B", OnParentFailure(EdgeRelease))
+    graph.Precede("B", "C")
+    graph.Precede("A", "C")
+
+    // A -> C is what skips C when A fails, as B runs regardless.
+    if removed, err := graph.TransitiveReduction(); err != nil || removed != 0 {
+        t.Errorf("expected no edges removed, got %d (%v)", removed, err)
+    }
+    // Under FailAbort, B may still be running when Execute returns.
+    for _, mode := range []FailureMode{FailAbort, FailDrain} {
+        executor := NewExecutor(graph, WithFailureMode(mode))
+        if err := executor.Execute(); err == nil {
+            t.Fatalf("%s: expected Execute to fail", mode)
+        }
+        if nr := executor.Report().Nodes["C"]; nr.State != StateSkipped {
+            t.Errorf("%s: expected C to be skipped, got %s", mode, nr.State)
+        }
+    }
+
+    graph = TaskGraph()
+    for _, name := range []string{"A", "B", "C"} {
+        graph.Add(name, func() error { return nil })
+    }
+    graph.Precede("A", "B", Stream(0))
+    graph.Precede("B", "C")
+    graph.Precede("A", "C")
+    // B starts alongside A, so only A -> C orders C after A.
+    if removed, err := graph.TransitiveReduction(); err != nil || removed != 0 {
+        t.Errorf("expected no edges removed past a streaming edge, got %d (%v)", removed, err)
+    }
+}
@@ -0,0 +1,188 @@
+package leo
+
+import (
+    "context"
+    "fmt"
+    "time"
+)
+
+// RepeatPolicy controls how ExecuteN and ExecuteEvery respond to failed
+// iterations.
+type RepeatPolicy int
+
+const (
+    // StopOnFailure ends the loop after the first failed iteration and
+    // returns its error. This is the default.
+    StopOnFailure RepeatPolicy = iota
+    // ContinueOnFailure runs every iteration regardless of failures. The
+    // loop returns an error if any iteration failed.
+    ContinueOnFailure
+    // UntilSuccess ends the loop after the first successful iteration,
+    // repeating failed ones. The loop returns the last iteration's error if
+    // none succeeded.
+    UntilSuccess
+)
+
+func (p RepeatPolicy) String() string {
+    switch p {
+    case StopOnFailure:
+        return "stop on failure"
+    case ContinueOnFailure:
+        return "continue on failure"
+    case UntilSuccess:
+        return "until success"
+    }
+    return fmt.Sprintf("RepeatPolicy(%d)", int(p))
+}
+
+// SetRepeatPolicy sets the policy of subsequent calls to ExecuteN and
+// ExecuteEvery.
+func (e *Executor) SetRepeatPolicy(p RepeatPolicy) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.repeatPolicy = p
+}
+
+func (e *Executor) getRepeatPolicy() RepeatPolicy {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.repeatPolicy
+}
+
+// Iteration describes one run of the graph within ExecuteN or ExecuteEvery.
+type Iteration struct {
+    // Index counts iterations from 0.
+    Index    int
+    Start    time.Time
+    Duration time.Duration
+    Err      error
+    Report   *Report
+}
+
+// RepeatStats summarises the iterations of ExecuteN or ExecuteEvery.
+type RepeatStats struct {
+    Iterations int
+    Succeeded  int
+    Failed     int
+    Total      time.Duration
+    Min        time.Duration
+    Max        time.Duration
+}
+
+// Mean returns the average duration of an iteration, or 0 if none ran.
+func (s RepeatStats) Mean() time.Duration {
+    if s.Iterations == 0 {
+        return 0
+    }
+    return s.Total / time.Duration(s.Iterations)
+}
+
+func (s *RepeatStats) add(it Iteration) {
+    s.Iterations++
+    if it.Err != nil {
+        s.Failed++
+    } else {
+        s.Succeeded++
+    }
+    s.Total += it.Duration
+    if s.Iterations == 1 || it.Duration < s.Min {
+        s.Min = it.Duration
+    }
+    if it.Duration > s.Max {
+        s.Max = it.Duration
+    }
+}
+
+// ExecuteN runs the graph n times in sequence, calling the OnIterationStarted
+// and OnIterationFinished hooks around each run, and returns statistics over
+// the iterations that ran. Failed iterations are handled according to the
+// executor's RepeatPolicy. The loop also ends when ctx is cancelled, returning
+// ctx.Err(), or drained (see WithDrain) between iterations.
+func (e *Executor) ExecuteN(ctx context.Context, n int) (RepeatStats, error) {
+    return e.repeat(ctx, n, 0)
+}
+
+// ExecuteEvery runs the graph immediately and then every interval until ctx
+// is cancelled or drained, or the RepeatPolicy ends the loop, like ExecuteN.
+// An iteration that overruns the interval delays the next one rather than
+// overlapping it. Cancelling ctx ends the loop with ctx.Err(); draining it
+// ends the loop without an error of its own.
+func (e *Executor) ExecuteEvery(ctx context.Context, interval time.Duration) (RepeatStats, error) {
+    if interval <= 0 {
+        return RepeatStats{}, fmt.Errorf("interval must be positive")
+    }
+    return e.repeat(ctx, 0, interval)
+}
+
+// repeat runs the graph n times, or until stopped if n is 0, starting a run
+// every interval.
+func (e *Executor) repeat(ctx context.Context, n int, interval time.Duration) (RepeatStats, error) {
+    var stats RepeatStats
+    var lastErr error
+    policy := e.getRepeatPolicy()
+    drain := drainChan(ctx)
+
+    var tick <-chan time.Time
+    if interval > 0 {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        tick = ticker.C
+    }
+
+    for i := 0; n == 0 || i < n; i++ {
+        if i > 0 && tick != nil {
+            select {
+            case <-tick:
+            case <-ctx.Done():
+            case <-drain:
+            }
+        }
+        if err := ctx.Err(); err != nil {
+            return stats, err
+        }
+        if drained(drain) {
+            break
+        }
+
+        e.iterationStarted(i)
+        r := e.NewRun()
+        it := Iteration{Index: i, Start: time.Now()}
+        it.Err = r.ExecuteContext(ctx)
+        it.Duration = time.Since(it.Start)
+        it.Report = r.Report()
+        stats.add(it)
+        e.iterationFinished(it)
+
+        if err := ctx.Err(); err != nil {
+            return stats, err
+        }
+        if it.Err != nil {
+            lastErr = it.Err
+        }
+        if it.Err != nil && policy == StopOnFailure {
+            return stats, fmt.Errorf("iteration %d: %w", i, it.Err)
+        }
+        if it.Err == nil && policy == UntilSuccess {
+            return stats, nil
+        }
+    }
+
+    switch {
+    case lastErr == nil:
+        return stats, nil
+    case policy == UntilSuccess:
+        return stats, fmt.Errorf("no iteration succeeded: %w", lastErr)
+    default:
+        return stats, fmt.Errorf("%d of %d iterations failed, last: %w", stats.Failed, stats.Iterations, lastErr)
+    }
+}
+
+// drained reports whether drain, which may be nil, has been closed.
+func drained(drain <-chan struct{}) bool {
+    select {
+    case <-drain:
+        return true
+    default:
+        return false
+    }
+}
@@ -0,0 +1,85 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecuteN(t *testing.T) {
+    var calls atomic.Int32
+    graph := TaskGraph()
+    graph.Add("poll", func() error {
+        if calls.Add(1) == 2 {
+            return errors.New("flaky")
+        }
+        return nil
+    })
+
+    var finished []Iteration
+    executor := NewExecutor(graph)
+    executor.SetHooks(Hooks{
+        OnIterationFinished: func(it Iteration) { finished = append(finished, it) },
+    })
+
+    stats, err := executor.ExecuteN(context.Background(), 4)
+    if err == nil || stats.Iterations != 2 || stats.Failed != 1 {
+        t.Errorf("expected the loop to stop at the second iteration, got %+v, %v", stats, err)
+    }
+    if len(finished) != 2 || finished[1].Index != 1 || finished[1].Err == nil || finished[1].Report == nil {
+        t.Errorf("unexpected iterations %+v", finished)
+    }
+
+    calls.Store(0)
+    executor.SetRepeatPolicy(ContinueOnFailure)
+    stats, err = executor.ExecuteN(context.Background(), 4)
+    if err == nil || stats.Iterations != 4 || stats.Succeeded != 3 || stats.Failed != 1 {
+        t.Errorf("expected every iteration to run, got %+v, %v", stats, err)
+    }
+    if stats.Min > stats.Mean() || stats.Mean() > stats.Max {
+        t.Errorf("inconsistent durations %+v", stats)
+    }
+
+    calls.Store(1)
+    executor.SetRepeatPolicy(UntilSuccess)
+    stats, err = executor.ExecuteN(context.Background(), 4)
+    if err != nil || stats.Iterations != 2 {
+        t.Errorf("expected the loop to stop at the first success, got %+v, %v", stats, err)
+    }
+}
+
+func TestExecuteEvery(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("tick", func() error { return nil })
+    executor := NewExecutor(graph)
+
+    if _, err := executor.ExecuteEvery(context.Background(), 0); err == nil {
+        t.Errorf("expected a zero interval to be rejected")
+    }
+
+    drain := make(chan struct{})
+    ctx := WithDrain(context.Background(), drain)
+    executor.SetHooks(Hooks{
+        OnIterationFinished: func(it Iteration) {
+            if it.Index == 2 {
+                close(drain)
+            }
+        },
+    })
+    start := time.Now()
+    stats, err := executor.ExecuteEvery(ctx, 10*time.Millisecond)
+    if err != nil || stats.Iterations != 3 {
+        t.Errorf("expected the drain to stop the loop after three iterations, got %+v, %v", stats, err)
+    }
+    if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+        t.Errorf("expected iterations to be spaced by the interval, took %s", elapsed)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    executor.SetHooks(Hooks{OnIterationFinished: func(Iteration) { cancel() }})
+    if _, err := executor.ExecuteEvery(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+        t.Errorf("expected cancellation to end the loop, got %v", err)
+    }
+}
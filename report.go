@@ -0,0 +1,247 @@
+package leo
+
+import (
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+)
+
+// NodeState is the outcome of a node within a run.
+type NodeState int
+
+const (
+    // StatePending means the node had not run, or had not finished, when
+    // the report was taken.
+    StatePending NodeState = iota
+    StateSucceeded
+    StateFailed
+    // StateSkipped means the node's task was not called, either because it
+    // was disabled or because an upstream node failed or was skipped.
+    StateSkipped
+    // StateRunning and StateCached are only reported by Run.Status: reports
+    // are taken once nothing is running, and record cached nodes as
+    // succeeded with NodeReport.Cached set.
+    StateRunning
+    StateCached
+)
+
+func (s NodeState) String() string {
+    switch s {
+    case StatePending:
+        return "pending"
+    case StateSucceeded:
+        return "succeeded"
+    case StateFailed:
+        return "failed"
+    case StateSkipped:
+        return "skipped"
+    case StateRunning:
+        return "running"
+    case StateCached:
+        return "cached"
+    }
+    return fmt.Sprintf("NodeState(%d)", int(s))
+}
+
+// NodeReport describes a single task execution within a run.
+type NodeReport struct {
+    Name        string
+    State       NodeState
+    SkipReason  string
+    Start       time.Time
+    Duration    time.Duration
+    Err         error
+    // Handled is set on a failed node that has a fallback, see Graph.OnFailure.
+    Handled     bool
+    // Cached is set on a succeeded node whose result came from the cache,
+    // see WithCacheKey.
+    Cached      bool
+    Expected    time.Duration
+    SLAViolated bool
+    // Baseline is the node's slow task threshold from the history, and Slow
+    // is set if the task took longer, see Executor.SetSlowTaskPercentile.
+    Baseline    time.Duration
+    Slow        bool
+}
+
+// Report summarises a single execution of a graph.
+type Report struct {
+    // ID, Name and Labels are copied from the run, see Run.ID, Run.SetName
+    // and Run.SetLabels.
+    ID         string
+    Name       string
+    Labels     map[string]string
+    // Seed is the executor's seed for dispatch order, see Executor.SetSeed.
+    Seed       int64
+    // Scheduling is the policy that ordered ready tasks, see
+    // Executor.SetScheduling. It is ScheduleFIFO for runs that had neither a
+    // concurrency limit nor a worker pool.
+    Scheduling SchedulingPolicy
+    Start      time.Time
+    Duration   time.Duration
+    Nodes      map[string]*NodeReport
+    // Artifacts are the artifacts the run recorded, see RecordArtifact.
+    Artifacts  []Artifact
+    // Groups are the outcomes of the graph's atomic groups, see Graph.Group
+    // and Report.Group.
+    Groups     map[string]*GroupReport
+
+    mu sync.Mutex
+    // free holds the NodeReports not yet in Nodes, allocated together.
+    free []NodeReport
+    // final is set once the run has finished with the report, which then no
+    // longer changes, even if tasks the run abandoned finish later.
+    final bool
+}
+
+func newReport(start time.Time, size int) *Report {
+    return &Report{
+        Start: start,
+        Nodes: make(map[string]*NodeReport, size),
+        free:  make([]NodeReport, size),
+    }
+}
+
+// put stores nr as the report of its node, unless the report is final. The
+// caller must hold r.mu.
+func (r *Report) put(nr NodeReport) *NodeReport {
+    var p *NodeReport
+    if len(r.free) > 0 && !r.final {
+        p, r.free = &r.free[0], r.free[1:]
+    } else {
+        p = new(NodeReport)
+    }
+    *p = nr
+    if !r.final {
+        r.Nodes[nr.Name] = p
+    }
+    return p
+}
+
+func (r *Report) record(n *Node, start time.Time, d time.Duration, err error, cached bool, baseline time.Duration) *NodeReport {
+    state := StateSucceeded
+    if err != nil {
+        state = StateFailed
+    }
+
+    nr := NodeReport{
+        Name:        n.name,
+        State:       state,
+        Start:       start,
+        Duration:    d,
+        Err:         err,
+        Handled:     err != nil && len(n.fallbacks) > 0,
+        Cached:      cached,
+        Expected:    n.expected,
+        SLAViolated: n.expected > 0 && d > n.expected,
+        Baseline:    baseline,
+        Slow:        baseline > 0 && d > baseline && !cached,
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.put(nr)
+}
+
+func (r *Report) skip(n *Node, reason string) {
+    r.mu.Lock()
+    r.put(NodeReport{Name: n.name, State: StateSkipped, SkipReason: reason})
+    r.mu.Unlock()
+}
+
+// finish records the run's duration, up to now, marks nodes that never ran
+// or are still running as pending, and makes the report final.
+func (r *Report) finish(g *Graph, now time.Time) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.Duration = now.Sub(r.Start)
+    for name := range g.nodes {
+        if _, exists := r.Nodes[name]; !exists {
+            r.put(NodeReport{Name: name, State: StatePending})
+        }
+    }
+    r.final = true
+}
+
+func (r *Report) setArtifacts(artifacts []Artifact) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.Artifacts = artifacts
+}
+
+func (r *Report) setScheduling(p SchedulingPolicy) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.Scheduling = p
+}
+
+// Succeeded reports whether the run completed without unhandled failures.
+// Skipped nodes do not count as failures by themselves.
+func (r *Report) Succeeded() bool {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    for _, nr := range r.Nodes {
+        switch nr.State {
+        case StatePending:
+            return false
+        case StateFailed:
+            if !nr.Handled {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+// Completed returns the names of nodes that succeeded, sorted.
+func (r *Report) Completed() []string {
+    return r.inState(StateSucceeded)
+}
+
+// Pending returns the names of nodes that never ran, sorted, such as the
+// nodes that had not started when a run failed.
+func (r *Report) Pending() []string {
+    return r.inState(StatePending)
+}
+
+// Skipped returns the names of skipped nodes, sorted.
+func (r *Report) Skipped() []string {
+    return r.inState(StateSkipped)
+}
+
+// Failed returns the names of failed nodes, sorted.
+func (r *Report) Failed() []string {
+    return r.inState(StateFailed)
+}
+
+func (r *Report) inState(state NodeState) []string {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    var names []string
+    for name, nr := range r.Nodes {
+        if nr.State == state {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+// SLAViolations returns the violations recorded during the run, sorted by node name.
+func (r *Report) SLAViolations() []SLAViolation {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    var out []SLAViolation
+    for _, nr := range r.Nodes {
+        if nr.SLAViolated {
+            out = append(out, SLAViolation{Node: nr.Name, Expected: nr.Expected, Actual: nr.Duration})
+        }
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].Node < out[j].Node })
+    return out
+}
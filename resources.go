@@ -0,0 +1,138 @@
+package leo
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "sort"
+    "sync"
+)
+
+// Resources is an amount of machine capacity: CPU in cores, which may be
+// fractional, and Memory in bytes.
+type Resources struct {
+    CPU    float64
+    Memory int64
+}
+
+func (r Resources) String() string {
+    return fmt.Sprintf("%g CPU, %d bytes of memory", r.CPU, r.Memory)
+}
+
+// WithResources declares the CPU and memory a task uses while it runs. On an
+// executor with a capacity (see Executor.SetCapacity), a ready task waits
+// until its resources are available.
+func WithResources(cpu float64, memory int64) NodeOption {
+    return func(n *Node) {
+        n.resources = Resources{CPU: cpu, Memory: memory}
+    }
+}
+
+// SetCapacity limits the resources that tasks of the executor's runs,
+// declared with WithResources, use at once. A zero CPU or Memory leaves
+// that resource unlimited. The capacity is shared by runs that execute
+// concurrently, so that many memory-hungry tasks becoming ready together
+// run a few at a time rather than exhausting the machine. A run fails to
+// start if one of its tasks needs more than the capacity.
+func (e *Executor) SetCapacity(c Resources) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.capacity = &capacity{
+        cpu:     milli(c.CPU),
+        memory:  c.Memory,
+        changed: make(chan struct{}),
+    }
+}
+
+func (e *Executor) getCapacity() *capacity {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.capacity
+}
+
+// milli converts cores to thousandths of a core, so that adding and removing
+// fractional amounts does not accumulate rounding errors.
+func milli(cpu float64) int64 {
+    return int64(math.Round(cpu * 1000))
+}
+
+// capacity tracks the resources in use by running tasks.
+type capacity struct {
+    mu         sync.Mutex
+    cpu        int64
+    memory     int64
+    usedCPU    int64
+    usedMemory int64
+    // changed is closed and replaced whenever resources are released.
+    changed chan struct{}
+}
+
+// fits reports whether cpu and memory can ever be available at once.
+func (c *capacity) fits(cpu, memory int64) bool {
+    return (c.cpu == 0 || cpu <= c.cpu) && (c.memory == 0 || memory <= c.memory)
+}
+
+// acquire waits until cpu and memory are available and takes them. It returns
+// ctx.Err() if ctx is done first.
+func (c *capacity) acquire(ctx context.Context, cpu, memory int64) error {
+    for {
+        c.mu.Lock()
+        if (c.cpu == 0 || c.usedCPU+cpu <= c.cpu) && (c.memory == 0 || c.usedMemory+memory <= c.memory) {
+            c.usedCPU += cpu
+            c.usedMemory += memory
+            c.mu.Unlock()
+            return nil
+        }
+        changed := c.changed
+        c.mu.Unlock()
+
+        select {
+        case <-changed:
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+}
+
+func (c *capacity) release(cpu, memory int64) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.usedCPU -= cpu
+    c.usedMemory -= memory
+    close(c.changed)
+    c.changed = make(chan struct{})
+}
+
+// checkCapacity returns an error if a node of the run needs more resources
+// than c, which may be nil, provides.
+func (r *Run) checkCapacity(c *capacity) error {
+    if c == nil {
+        return nil
+    }
+    var names []string
+    for name := range r.graph.nodes {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        n := r.graph.nodes[name]
+        if !c.fits(milli(n.resources.CPU), n.resources.Memory) {
+            return fmt.Errorf("node %s needs %s, more than the executor's capacity", name, n.resources)
+        }
+    }
+    return nil
+}
+
+// acquireResources waits for the resources n declares, if the executor has a
+// capacity, and returns a function that releases them.
+func (r *Run) acquireResources(ctx context.Context, n *Node) (release func(), err error) {
+    c := r.executor.getCapacity()
+    cpu, memory := milli(n.resources.CPU), n.resources.Memory
+    if c == nil || cpu == 0 && memory == 0 {
+        return func() {}, nil
+    }
+    if err := c.acquire(ctx, cpu, memory); err != nil {
+        return nil, err
+    }
+    return func() { c.release(cpu, memory) }, nil
+}
@@ -0,0 +1,83 @@
+package leo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCapacity(t *testing.T) {
+    const gib = 1 << 30
+    var mu sync.Mutex
+    var used, peak int64
+    graph := TaskGraph()
+    for i := 0; i < 6; i++ {
+        graph.Add(fmt.Sprintf("load%d", i), func() error {
+            mu.Lock()
+            used += 2 * gib
+            if used > peak {
+                peak = used
+            }
+            mu.Unlock()
+            time.Sleep(5 * time.Millisecond)
+            mu.Lock()
+            used -= 2 * gib
+            mu.Unlock()
+            return nil
+        }, WithResources(0.5, 2*gib))
+    }
+    graph.Add("light", func() error { return nil })
+
+    executor := NewExecutor(graph)
+    executor.SetConcurrency(-1)
+    executor.SetCapacity(Resources{CPU: 4, Memory: 5 * gib})
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if peak != 4*gib {
+        t.Errorf("expected at most 4 GiB in use at once, saw %d", peak)
+    }
+
+    graph.Add("huge", func() error { return nil }, WithResources(8, gib))
+    err := executor.Execute()
+    if err == nil || !strings.Contains(err.Error(), "node huge needs 8 CPU") {
+        t.Errorf("expected an error for a task exceeding the capacity, got %v", err)
+    }
+}
+
+func TestCapacityCancelled(t *testing.T) {
+    graph := TaskGraph()
+    block := make(chan struct{})
+    graph.Add("first", func() error { <-block; return nil }, WithResources(1, 0))
+    graph.Add("second", func() error { return nil }, WithResources(1, 0))
+
+    executor := NewExecutor(graph)
+    executor.SetCapacity(Resources{CPU: 1})
+    ctx, cancel := context.WithCancel(context.Background())
+    go func() {
+        time.Sleep(10 * time.Millisecond)
+        cancel()
+        close(block)
+    }()
+    if err := executor.ExecuteContext(ctx); err == nil {
+        t.Fatalf("expected the run to fail when cancelled")
+    }
+    // The run returns on cancellation without waiting for first, which
+    // releases its CPU once it returns.
+    c := executor.getCapacity()
+    inUse := func() int64 {
+        c.mu.Lock()
+        defer c.mu.Unlock()
+        return c.usedCPU
+    }
+    deadline := time.Now().Add(time.Second)
+    for inUse() != 0 && time.Now().Before(deadline) {
+        time.Sleep(time.Millisecond)
+    }
+    if got := inUse(); got != 0 {
+        t.Errorf("expected all resources to be released, %d milli-CPU in use", got)
+    }
+}
@@ -0,0 +1,126 @@
+package leo
+
+import (
+    "context"
+    "errors"
+    "time"
+)
+
+// ErrUnconfirmed is returned for a task that is not idempotent when it would
+// have been repeated without the executor's confirmation, see SetConfirm.
+var ErrUnconfirmed = errors.New("repeating a task that is not idempotent was not confirmed")
+
+// AttemptKind says why a task would run again.
+type AttemptKind int
+
+const (
+    // AttemptRetry is a retry after the task failed, see WithRetry.
+    AttemptRetry AttemptKind = iota
+    // AttemptHedge is a second, speculative attempt, see WithHedge.
+    AttemptHedge
+    // AttemptResume is a rerun of a task that had started, and not
+    // succeeded, in a run being recovered, see Recover.
+    AttemptResume
+)
+
+func (k AttemptKind) String() string {
+    switch k {
+    case AttemptRetry:
+        return "retry"
+    case AttemptHedge:
+        return "hedge"
+    case AttemptResume:
+        return "resume"
+    }
+    return "unknown"
+}
+
+// Idempotent marks whether a node's task may safely run more than once.
+// Retries, hedging and rerunning the task when a run is recovered apply to
+// a task marked Idempotent(false) only if the executor's ConfirmFunc allows
+// each of them; otherwise a retry or resume fails the node with
+// ErrUnconfirmed, and a hedge is not started. Tasks that are not marked are
+// assumed to be idempotent.
+func Idempotent(ok bool) NodeOption {
+    return func(n *Node) {
+        n.notIdempotent = !ok
+    }
+}
+
+// ConfirmFunc decides whether a task that is not idempotent may run again,
+// for example by asking an operator. It is called each time, with the run's
+// or task's context.
+type ConfirmFunc func(ctx context.Context, node string, kind AttemptKind) bool
+
+// SetConfirm sets the function that allows repeating tasks that are not
+// idempotent. Without one, such tasks are never repeated.
+func (e *Executor) SetConfirm(confirm ConfirmFunc) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.confirm = confirm
+}
+
+func (e *Executor) getConfirm() ConfirmFunc {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.confirm
+}
+
+// WithRetry retries a failed task up to attempts more times, waiting delay
+// before the first retry and twice as long before each following one. The
+// task is not retried once its context is done, or if its error is not
+// retryable, see Permanent and RetryIf.
+func WithRetry(attempts int, delay time.Duration) NodeOption {
+    return func(n *Node) {
+        n.retries = attempts
+        n.retryDelay = delay
+    }
+}
+
+// mayRepeat reports whether n's task may run again for kind, in the run that
+// ctx belongs to.
+func (n *Node) mayRepeat(ctx context.Context, kind AttemptKind) bool {
+    if !n.notIdempotent {
+        return true
+    }
+    r := RunFromContext(ctx)
+    if r == nil {
+        return false
+    }
+    confirm := r.executor.getConfirm()
+    return confirm != nil && confirm(ctx, n.name, kind)
+}
+
+// runRetried runs n's task, retrying it as configured by WithRetry.
+func (n *Node) runRetried(ctx context.Context) error {
+    err := n.attempt(ctx)
+    delay := n.retryDelay
+    for i := 0; err != nil && i < n.retries; i++ {
+        if ctx.Err() != nil || !n.retryable(ctx, err) {
+            return err
+        }
+        if !n.mayRepeat(ctx, AttemptRetry) {
+            return errors.Join(err, ErrUnconfirmed)
+        }
+        timer := time.NewTimer(delay)
+        select {
+        case <-timer.C:
+        case <-ctx.Done():
+            timer.Stop()
+            return err
+        }
+        delay *= 2
+        err = n.attempt(ctx)
+    }
+    return err
+}
+
+// attempt runs n's task once, hedged if configured by WithHedge.
+func (n *Node) attempt(ctx context.Context) error {
+    if n.hedge > 0 {
+        return runHedged(ctx, n.task, n.hedge, func() bool {
+            return n.mayRepeat(ctx, AttemptHedge)
+        })
+    }
+    return n.task(ctx)
+}
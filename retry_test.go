@@ -0,0 +1,154 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+    graph := TaskGraph()
+
+    var times []time.Time
+    graph.Add("flaky", func() error {
+        times = append(times, time.Now())
+        if len(times) < 3 {
+            return errors.New("not yet")
+        }
+        return nil
+    }, WithRetry(3, 10*time.Millisecond))
+
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if len(times) != 3 {
+        t.Fatalf("expected 3 attempts, got %d", len(times))
+    }
+    // The delay doubles before each retry.
+    if d := times[2].Sub(times[1]); d < 20*time.Millisecond {
+        t.Errorf("expected the second retry to wait at least 20ms, waited %v", d)
+    }
+}
+
+func TestRetryNotIdempotent(t *testing.T) {
+    newGraph := func(attempts *int32) *Graph {
+        graph := TaskGraph()
+        graph.Add("charge", func() error {
+            atomic.AddInt32(attempts, 1)
+            return errors.New("timeout")
+        }, WithRetry(2, time.Millisecond), Idempotent(false))
+        return graph
+    }
+
+    var attempts int32
+    err := NewExecutor(newGraph(&attempts)).Execute()
+    if !errors.Is(err, ErrUnconfirmed) {
+        t.Errorf("expected ErrUnconfirmed, got %v", err)
+    }
+    if attempts != 1 {
+        t.Errorf("expected 1 attempt without confirmation, got %d", attempts)
+    }
+
+    attempts = 0
+    var kinds []AttemptKind
+    executor := NewExecutor(newGraph(&attempts))
+    executor.SetConfirm(func(ctx context.Context, node string, kind AttemptKind) bool {
+        kinds = append(kinds, kind)
+        return node == "charge"
+    })
+    err = executor.Execute()
+    if err == nil || errors.Is(err, ErrUnconfirmed) {
+        t.Errorf("expected the task's own error, got %v", err)
+    }
+    if attempts != 3 {
+        t.Errorf("expected 3 confirmed attempts, got %d", attempts)
+    }
+    if len(kinds) != 2 || kinds[0] != AttemptRetry || kinds[1] != AttemptRetry {
+        t.Errorf("expected two retry confirmations, got %v", kinds)
+    }
+}
+
+func TestHedgeNotIdempotent(t *testing.T) {
+    graph := TaskGraph()
+
+    var attempts int32
+    graph.Add("send", func() error {
+        atomic.AddInt32(&attempts, 1)
+        time.Sleep(30 * time.Millisecond)
+        return nil
+    }, WithHedge(time.Millisecond), Idempotent(false))
+
+    var mu sync.Mutex
+    var kinds []AttemptKind
+    executor := NewExecutor(graph)
+    executor.SetConfirm(func(ctx context.Context, node string, kind AttemptKind) bool {
+        mu.Lock()
+        defer mu.Unlock()
+        kinds = append(kinds, kind)
+        return false
+    })
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if got := atomic.LoadInt32(&attempts); got != 1 {
+        t.Errorf("expected the hedge not to start, got %d attempts", got)
+    }
+    mu.Lock()
+    defer mu.Unlock()
+    if len(kinds) != 1 || kinds[0] != AttemptHedge {
+        t.Errorf("expected one hedge confirmation, got %v", kinds)
+    }
+}
+
+func TestRecoverNotIdempotent(t *testing.T) {
+    var ran int32
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil })
+    graph.Add("pay", func() error { atomic.AddInt32(&ran, 1); return nil }, Idempotent(false))
+    graph.Precede("A", "pay")
+
+    path := filepath.Join(t.TempDir(), "journal.jsonl")
+    journal := &FileJournal{Path: path}
+    defer journal.Close()
+    now := time.Now()
+    for _, e := range []JournalEntry{
+        {Run: "crashed", Time: now, State: JournalRunStarted},
+        {Run: "crashed", Time: now, Node: "A", State: JournalStarted},
+        {Run: "crashed", Time: now, Node: "A", State: JournalSucceeded},
+        {Run: "crashed", Time: now, Node: "pay", State: JournalStarted},
+    } {
+        journal.Append(e)
+    }
+
+    executor := NewExecutor(graph)
+    run, err := Recover(journal, executor)
+    if err != nil || run == nil {
+        t.Fatalf("Recover failed: %v, %v", run, err)
+    }
+    if err := run.Execute(); !errors.Is(err, ErrUnconfirmed) {
+        t.Errorf("expected ErrUnconfirmed, got %v", err)
+    }
+    if ran != 0 {
+        t.Errorf("expected pay not to run again without confirmation")
+    }
+
+    var kind AttemptKind = -1
+    executor.SetConfirm(func(ctx context.Context, node string, k AttemptKind) bool {
+        kind = k
+        return true
+    })
+    run, err = Recover(journal, executor)
+    if err != nil || run == nil {
+        t.Fatalf("Recover failed: %v, %v", run, err)
+    }
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if ran != 1 || kind != AttemptResume {
+        t.Errorf("expected pay to be resumed once, got %d runs, kind %v", ran, kind)
+    }
+}
@@ -0,0 +1,799 @@
+package leo
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "maps"
+    "runtime"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// Run is a single execution of an executor's graph. It holds per-execution
+// settings, such as disabled nodes, without modifying the graph itself.
+type Run struct {
+    executor *Executor
+    graph    *Graph
+    reverse  bool
+    id       string
+    disabled map[*Node]bool
+    params   map[string]string
+    name     string
+    labels   map[string]string
+    report   *Report
+
+    stepMu    sync.Mutex
+    stateMu   sync.Mutex
+    state     map[string]any
+    results   map[string]any
+    artifacts []Artifact
+    secrets   []string
+    keys      map[string]string
+    committed map[string]bool
+    cached    map[*Node]bool
+    workers   map[*Node]*Worker
+
+    ctx          context.Context
+    wg           sync.WaitGroup
+    mu           sync.Mutex
+    inDegree     map[*Node]int
+    triggered    map[*Node]bool
+    skippedNodes map[*Node]bool
+    aborted      string
+    abortErr     error
+    interrupted  bool
+    failedStop   bool
+    mustRun      map[*Node]bool
+    running      map[*Node]*taskContext
+    contexts     []taskContext
+    nextContext  atomic.Int32
+    completed    map[*Node]bool
+    inDoubt      map[*Node]bool
+    via          map[*Node]*Node
+    tiebreak     map[*Node]int
+
+    started   map[*Node]time.Time
+    queuedAt  map[*Node]time.Time
+    latency   summary
+    estimates map[string]time.Duration
+    baselines map[string]time.Duration
+    waves     *waves
+    stages    *stages
+    groups    *groups
+
+    streams       map[streamKey]*stream
+    streamStarted map[*Node]bool
+    streamsDone   map[*Node]bool
+
+    ready     chan *Node
+    executors *executors
+    notices   *[]notice
+    errs      chan error
+
+    async *async
+}
+
+// NewRun prepares a new execution of the graph. Call Execute or
+// ExecuteContext on the returned Run to start it.
+func (e *Executor) NewRun() *Run {
+    return &Run{
+        executor: e,
+        graph:    e.graph,
+        id:       newRunID(),
+        disabled: make(map[*Node]bool),
+    }
+}
+
+// Disable skips the named nodes for this run only. A disabled node's task is
+// not called, and its children are handled according to their edge policies,
+// as for any skipped node: only children along OnParentFailure(EdgeRelease)
+// edges run.
+func (r *Run) Disable(names ...string) error {
+    for _, name := range names {
+        node, exists := r.graph.lookup(name)
+        if !exists {
+            return fmt.Errorf("node %s does not exist", name)
+        }
+        r.disabled[node] = true
+    }
+    return nil
+}
+
+// Disabled disables a node in every run, like Run.Disable, such as a task
+// that a configuration turns off.
+func Disabled() NodeOption {
+    return func(n *Node) {
+        n.disabled = true
+    }
+}
+
+// Report returns the run's report, or nil if the run has not started. The
+// report of a failed run records what succeeded before it failed, see
+// Report.Completed, Results and Rerun.
+func (r *Run) Report() *Report {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.report
+}
+
+// Execute executes the run.
+func (r *Run) Execute() error {
+    return r.ExecuteContext(context.Background())
+}
+
+// ExecuteContext executes the run, passing ctx to context-aware tasks. It
+// returns ctx.Err() if ctx is cancelled before the graph completes.
+func (r *Run) ExecuteContext(ctx context.Context) (err error) {
+    e := r.executor
+    if err := e.checkOptions(); err != nil {
+        return err
+    }
+    if err := r.graph.checkBound(); err != nil {
+        return err
+    }
+    if err := r.journal("", JournalRunStarted, nil); err != nil {
+        return err
+    }
+    if j := e.getJournal(); j != nil && !r.reverse {
+        if err := r.loadCommitted(j); err != nil {
+            return fmt.Errorf("journal: %w", err)
+        }
+    }
+    pool := e.getWorkerPool()
+    if err := r.checkWorkers(pool); err != nil {
+        return err
+    }
+    if err := r.checkCapacity(e.getCapacity()); err != nil {
+        return err
+    }
+    r.waves = nil
+    if e.getMode() == ModeWave {
+        if err := r.initWaves(); err != nil {
+            return err
+        }
+    }
+
+    r.ctx = context.WithValue(e.withServices(withParams(ctx, r.params)), runKey{}, r)
+    // Sized for every node, so that small graphs run without growing them.
+    size := len(r.graph.nodes)
+    r.inDegree = make(map[*Node]int, size)
+    r.via = make(map[*Node]*Node, size)
+    // Most runs skip nothing and have no fallbacks or streams, so these
+    // sets are only allocated once a node is added to them, see mark.
+    r.triggered = nil
+    r.skippedNodes = nil
+    r.mustRun = nil
+    r.streamStarted = nil
+    r.streamsDone = nil
+    r.aborted = ""
+    r.abortErr = nil
+    r.failedStop = false
+    r.interrupted = false
+    r.streams = nil
+    r.contexts = make([]taskContext, size)
+    r.nextContext.Store(0)
+    r.ready = make(chan *Node, len(r.graph.nodes))
+    r.errs = make(chan error, 1)
+    rep := newReport(e.now(), size)
+    rep.ID = r.id
+    rep.Name = r.name
+    rep.Labels = r.labels
+    r.mu.Lock()
+    r.report = rep
+    r.started = make(map[*Node]time.Time, size)
+    r.running = make(map[*Node]*taskContext)
+    r.queuedAt = make(map[*Node]time.Time, size)
+    r.latency = summary{}
+    r.mu.Unlock()
+    r.initTiebreak()
+    r.estimates = nil
+    if h := e.getHistory(); h != nil && !r.reverse {
+        // Estimates only feed ETA, so a history that cannot be read does
+        // not stop the run.
+        r.estimates, _ = estimateDurations(h)
+    }
+    r.baselines = nil
+    if h := e.getHistory(); h != nil {
+        if p := e.getSlowPercentile(); p > 0 {
+            // Like estimates, baselines are best effort.
+            r.baselines, _ = durationBaselines(h, p)
+        }
+    }
+    finished := make(chan struct{})
+
+    if p := r.plan(); p != nil {
+        maps.Copy(r.inDegree, p.inDegree)
+    } else {
+        for _, node := range r.graph.nodes {
+            r.inDegree[node] = node.inDegree()
+        }
+    }
+    if err := r.initGroups(); err != nil {
+        return err
+    }
+    if err := r.initStages(); err != nil {
+        return err
+    }
+    defer r.stopStages()
+
+    e.runStarted(r)
+    var x *executors
+    if limit, policy := e.getConcurrency(); pool != nil {
+        r.report.setScheduling(policy)
+        go r.runPooled(r.ready, pool, policy)
+    } else if limit > 0 || e.getAdaptive() != nil {
+        r.report.setScheduling(policy)
+        go r.runLimited(r.ready, limit, policy)
+    } else {
+        x = &executors{ready: r.ready, max: runtime.GOMAXPROCS(0)}
+    }
+
+    r.mu.Lock()
+    r.executors = x
+    for _, node := range r.tieOrder(r.roots()) {
+        if r.inDegree[node] == 0 {
+            r.wg.Add(1)
+            r.queued(node)
+            r.enqueue(node)
+        }
+    }
+    r.beginStages()
+    // No goroutine is idle yet, so each root starts one, once r.mu is
+    // released so as not to contend for it with the roots already started.
+    r.unlock()
+
+    // Nodes are only queued while another is executing or being queued
+    // above, so once every one has been executed, nothing more will be, and
+    // closing the queue stops the goroutines that read it.
+    go func(ready chan *Node) {
+        r.wg.Wait()
+        close(ready)
+        close(finished)
+    }(r.ready)
+
+    if drain := drainChan(ctx); drain != nil {
+        go func() {
+            select {
+            case <-drain:
+                r.mu.Lock()
+                r.stop("shutting down")
+                r.mu.Unlock()
+            case <-finished:
+            }
+        }()
+    }
+
+    defer func() {
+        r.report.finish(r.graph, e.now())
+        r.report.setArtifacts(r.Artifacts())
+        e.setReport(r.report)
+        err = r.snapshotFailure(err)
+        if jerr := r.journal("", JournalRunFinished, err); jerr != nil && err == nil {
+            err = jerr
+        }
+        if h := e.getHistory(); h != nil && !r.reverse {
+            if saveErr := h.Save(r.report.Record()); saveErr != nil && err == nil {
+                err = fmt.Errorf("saving history: %w", saveErr)
+            }
+        }
+        r.publish(Event{Type: EventRunFinished, Duration: r.report.Duration, Err: err})
+        e.runFinished(r.report, err)
+    }()
+
+    select {
+    case <-finished:
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        if r.interrupted {
+            return r.abortError()
+        }
+        return nil
+    case err := <-r.errs:
+        return r.fail(err, finished)
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// execute runs a single ready node and releases its successors.
+func (r *Run) execute(n *Node) {
+    defer r.wg.Done()
+    e := r.executor
+
+    r.mu.Lock()
+    r.dispatched(n)
+    if r.stopped(n) {
+        r.interrupted = true
+        r.skip(n, r.aborted)
+        r.unlock()
+        return
+    }
+    if reason := r.groupFailure(n); reason != "" {
+        r.skip(n, reason)
+        r.unlock()
+        return
+    }
+    r.mu.Unlock()
+
+    if r.completed[n] {
+        r.finish(n, e.now(), nil)
+        return
+    }
+    if r.inDoubt[n] && !n.mayRepeat(r.ctx, AttemptResume) {
+        r.finish(n, e.now(), fmt.Errorf("%w: the task was interrupted or failed before the run was recovered", ErrUnconfirmed))
+        return
+    }
+
+    if r.disabled[n] || n.disabled {
+        r.mu.Lock()
+        r.skip(n, skipDisabled)
+        r.unlock()
+        return
+    }
+
+    if n.condition != nil {
+        ok, err := n.condition(r.ctx)
+        if err != nil {
+            r.finish(n, e.now(), fmt.Errorf("evaluating condition: %w", err))
+            return
+        }
+        if !ok {
+            r.mu.Lock()
+            r.skip(n, skipCondition)
+            r.unlock()
+            return
+        }
+    }
+    if upToDate, err := n.upToDate(); err != nil {
+        r.finish(n, e.now(), err)
+        return
+    } else if upToDate {
+        r.bypass(n, skipUpToDate)
+        return
+    }
+
+    stageCtx := r.stageContext(n)
+    if stageCtx.Err() != nil {
+        r.finish(n, e.now(), context.Cause(stageCtx))
+        return
+    }
+    ctx := r.workerContext(stageCtx, n)
+    if n.idempotencyKey != nil {
+        if key := n.idempotencyKey(r.ctx); key != "" {
+            if r.isCommitted(key) {
+                r.finish(n, e.now(), nil)
+                return
+            }
+            r.setKey(n, key)
+            ctx = context.WithValue(ctx, idempotencyKeyCtx{}, key)
+        }
+    }
+    taskCtx := r.taskContext(ctx, n)
+
+    hit, digest := r.fromCache(n)
+    if hit {
+        r.setCached(n)
+        r.finish(n, e.now(), nil)
+        return
+    }
+
+    if err := r.step(n); err != nil {
+        r.finish(n, e.now(), err)
+        return
+    }
+
+    if err := r.prepareGroup(n); err != nil {
+        r.finish(n, e.now(), err)
+        return
+    }
+
+    releaseResources, err := r.acquireResources(taskCtx, n)
+    if err != nil {
+        r.finish(n, e.now(), err)
+        return
+    }
+
+    if err := r.journal(n.name, JournalStarted, nil); err != nil {
+        releaseResources()
+        r.finish(n, e.now(), err)
+        return
+    }
+
+    r.startStreams(n)
+    defer taskCtx.cancel(nil)
+    start := e.now()
+    r.mu.Lock()
+    r.running[n] = taskCtx
+    r.started[n] = start
+    eta := r.progressETA()
+    r.mu.Unlock()
+    r.publish(Event{Type: EventTaskStarted, Node: n.name, Time: start, ETA: eta})
+    stopWatching := r.watchSlow(n)
+    err = e.call(taskCtx, n)
+    r.mu.Lock()
+    delete(r.running, n)
+    r.mu.Unlock()
+    stopWatching()
+    releaseResources()
+    if err == nil {
+        r.recordOutputs(n)
+    }
+    if err == nil && digest != "" {
+        r.toCache(n, digest)
+    }
+    r.finish(n, start, err)
+}
+
+// finish records the outcome of n's task and releases its successors.
+func (r *Run) finish(n *Node, start time.Time, err error) {
+    e := r.executor
+    err = r.redactError(err)
+    state := JournalSucceeded
+    if err != nil {
+        state = JournalFailed
+    }
+    if jerr := r.journal(n.name, state, err); jerr != nil && err == nil {
+        err = jerr
+    }
+    if key := r.key(n.name); key != "" && err == nil {
+        r.commit(key)
+    }
+    nr := r.report.record(n, start, e.since(start), err, r.isCached(n), r.baselines[n.name])
+    if nr.SLAViolated {
+        e.violation(SLAViolation{
+            Node:     n.name,
+            Expected: nr.Expected,
+            Actual:   nr.Duration,
+        })
+    }
+
+    r.mu.Lock()
+    eta := r.progressETA()
+    r.mu.Unlock()
+    if err != nil {
+        r.publish(Event{Type: EventTaskFailed, Node: n.name, Duration: nr.Duration, Err: err, ETA: eta})
+    } else {
+        r.publish(Event{Type: EventTaskFinished, Node: n.name, Duration: nr.Duration, ETA: eta})
+    }
+
+    r.release(n, err)
+
+    if err != nil && len(n.fallbacks) == 0 {
+        select {
+        case r.errs <- r.taskError(n, err):
+        default:
+            // If an error is already recorded, we ignore subsequent errors
+        }
+    }
+}
+
+// release updates the successors of n once it has finished with err. Children
+// are dispatched on success and handled according to their edge policy on
+// failure; fallbacks are dispatched only if n (or another node they protect)
+// failed.
+func (r *Run) release(n *Node, err error) {
+    r.mu.Lock()
+    defer r.unlock()
+
+    r.closeStreams(n)
+    if err != nil {
+        r.failGroup(n, err)
+    }
+    for _, child := range r.tieOrder(n.children) {
+        if r.streamStarted[n] && n.edgeTo(child).stream {
+            continue
+        }
+        if err != nil {
+            reason, cause := fmt.Sprintf("upstream %s failed", n.name), r.newTaskError(n, err)
+            if !r.holdEdge(n, child, false, reason, cause) {
+                r.unsatisfied(n, child, reason, cause)
+            }
+            continue
+        }
+        if !r.holdEdge(n, child, true, "", nil) {
+            r.satisfy(child, n)
+        }
+    }
+
+    for _, fallback := range r.tieOrder(n.fallbacks) {
+        r.releaseFallback(n, fallback, err != nil, fmt.Sprintf("not needed: %s succeeded", n.name))
+    }
+    r.groupResolved(n, false)
+    r.stageResolved(n, err)
+    r.resolved(n)
+}
+
+// satisfy records that one of n's dependencies, via if it is a node, is met
+// and dispatches n once all of them are. The caller must hold r.mu.
+func (r *Run) satisfy(n, via *Node) {
+    if via != nil {
+        r.via[n] = via
+    }
+    r.inDegree[n]--
+    if r.inDegree[n] == 0 {
+        r.dispatch(n)
+    }
+}
+
+// unsatisfied handles the edge from parent to child when parent failed, with
+// err, or was skipped, according to the edge's policy. The caller must hold
+// r.mu.
+func (r *Run) unsatisfied(parent, child *Node, reason string, err error) {
+    switch parent.edgeTo(child).policy {
+    case EdgeRelease:
+        mark(&r.mustRun, child)
+        r.satisfy(child, parent)
+    case EdgeBlock:
+        r.abort(reason, err)
+        r.skip(child, reason)
+    default:
+        r.skip(child, reason)
+    }
+}
+
+// releaseFallback records that protected, one of the nodes protected by
+// fallback, has finished. The caller must hold r.mu.
+func (r *Run) releaseFallback(protected, fallback *Node, failed bool, reason string) {
+    if failed {
+        mark(&r.triggered, fallback)
+        r.via[fallback] = protected
+    }
+    r.inDegree[fallback]--
+    if r.inDegree[fallback] == 0 {
+        if r.triggered[fallback] {
+            r.dispatch(fallback)
+        } else {
+            r.skip(fallback, reason)
+        }
+    }
+}
+
+// abort stops the run from starting any further tasks, failing it with an
+// error that wraps cause, if it is not nil. The caller must hold r.mu.
+func (r *Run) abort(reason string, cause error) {
+    if r.aborted != "" {
+        return
+    }
+    r.aborted = "run aborted: " + reason
+    if cause != nil {
+        r.abortErr = fmt.Errorf("%s: %w", r.aborted, cause)
+    }
+    select {
+    case r.errs <- r.abortError():
+    default:
+    }
+}
+
+// abortError returns the error of a run that was aborted or stopped. The
+// caller must hold r.mu.
+func (r *Run) abortError() error {
+    if r.abortErr != nil {
+        return r.abortErr
+    }
+    return errors.New(r.aborted)
+}
+
+// stop stops the run from starting any further tasks, without failing it
+// immediately: tasks already running finish, and the run then fails if any
+// task was prevented from starting. The caller must hold r.mu.
+func (r *Run) stop(reason string) {
+    if r.aborted == "" {
+        r.aborted = "run stopped: " + reason
+    }
+}
+
+// dispatch queues n for execution unless it has already been skipped or the
+// run has been aborted. The caller must hold r.mu.
+func (r *Run) dispatch(n *Node) {
+    if r.skippedNodes[n] {
+        return
+    }
+    if r.stopped(n) {
+        r.interrupted = true
+        r.skip(n, r.aborted)
+        return
+    }
+    if reason := r.groupFailure(n); reason != "" {
+        r.skip(n, reason)
+        return
+    }
+    if r.hold(n) || r.holdForStage(n) {
+        return
+    }
+    r.wg.Add(1)
+    r.queued(n)
+    r.enqueue(n)
+}
+
+// executors are the goroutines that execute the ready nodes of a run without
+// a concurrency limit or worker pool. Once a goroutine has executed a node,
+// it waits for another, so that the next node reuses its stack, already
+// grown by the first, rather than starting a goroutine of its own. At most
+// max wait, as no more than that can run at once.
+type executors struct {
+    ready chan *Node
+    // idle is the number of goroutines waiting for a node, less the nodes
+    // already sent to them.
+    idle  int
+    max   int
+}
+
+// enqueue queues n, which is ready, for execution: on an idle goroutine of
+// a run without a limit, or a new one if none is idle, and otherwise on the
+// queue of the run's limit or worker pool. n is handed over once r.mu is
+// released, after its EventTaskQueued is published, so that no event of its
+// task precedes it. The caller must hold r.mu.
+func (r *Run) enqueue(n *Node) {
+    nt := notice{kind: noticeQueued, event: Event{Type: EventTaskQueued, Node: n.name}, node: n}
+    if x := r.executors; x != nil {
+        nt.x = x
+        if x.idle == 0 {
+            nt.spawn = true
+        } else {
+            x.idle--
+        }
+    }
+    r.post(nt)
+}
+
+// mark adds n to the set *m, allocating it if it is nil.
+func mark(m *map[*Node]bool, n *Node) {
+    if *m == nil {
+        *m = make(map[*Node]bool)
+    }
+    (*m)[n] = true
+}
+
+// noticeKind identifies the call a notice makes.
+type noticeKind int
+
+const (
+    // noticeQueued publishes event and hands node over for execution.
+    noticeQueued noticeKind = iota
+    // noticeSkipped journals the skip of event.Node, calls OnTaskSkipped and
+    // publishes event.
+    noticeSkipped
+    // noticeStageStarted calls OnStageStarted for the stage event.Node.
+    noticeStageStarted
+    // noticeStageFinished calls OnStageFinished for the stage event.Node,
+    // with event.Duration and event.Err.
+    noticeStageFinished
+)
+
+// A notice is a call that the run collects while holding r.mu and makes once
+// it has released it, so that hooks, journals and subscribers may call back
+// into the run, such as Run.Status, without deadlocking it.
+type notice struct {
+    kind  noticeKind
+    event Event
+    node  *Node
+    x     *executors
+    spawn bool
+}
+
+// noticeBuffers recycles the notices of runs, so that a run does not
+// allocate them each time it releases r.mu.
+var noticeBuffers = sync.Pool{New: func() any { return new([]notice) }}
+
+// post collects nt, to be made once r.mu is released. The caller must hold
+// r.mu.
+func (r *Run) post(nt notice) {
+    if r.notices == nil {
+        r.notices = noticeBuffers.Get().(*[]notice)
+    }
+    *r.notices = append(*r.notices, nt)
+}
+
+// unlock releases r.mu, then makes the calls collected while it was held, in
+// the order they were collected.
+func (r *Run) unlock() {
+    buf := r.notices
+    r.notices = nil
+    r.mu.Unlock()
+    if buf == nil {
+        return
+    }
+    notices := *buf
+    for i := range notices {
+        r.notify(&notices[i])
+    }
+    clear(notices)
+    *buf = notices[:0]
+    noticeBuffers.Put(buf)
+}
+
+func (r *Run) notify(nt *notice) {
+    e := r.executor
+    ev := nt.event
+    switch nt.kind {
+    case noticeQueued:
+        r.publish(ev)
+        switch {
+        case nt.x == nil:
+            r.ready <- nt.node
+        case nt.spawn:
+            go r.executeAll(nt.x, nt.node)
+        default:
+            nt.x.ready <- nt.node
+        }
+    case noticeSkipped:
+        // Skips are recomputed when a run is recovered, so a journal failure
+        // here does not need to stop the run.
+        r.journal(ev.Node, JournalSkipped, nil)
+        e.skipped(ev.Node, ev.Reason)
+        r.publish(ev)
+    case noticeStageStarted:
+        e.stageStarted(ev.Node)
+    case noticeStageFinished:
+        e.stageFinished(ev.Node, ev.Duration, ev.Err)
+    }
+}
+
+// executeAll executes n, then every node x gives it, until the run ends.
+func (r *Run) executeAll(x *executors, n *Node) {
+    for {
+        r.execute(n)
+        r.mu.Lock()
+        if x.idle >= x.max {
+            r.mu.Unlock()
+            return
+        }
+        x.idle++
+        r.mu.Unlock()
+        var ok bool
+        if n, ok = <-x.ready; !ok {
+            return
+        }
+    }
+}
+
+const (
+    skipDisabled  = "disabled"
+    skipCondition = "condition not met"
+    skipUpToDate  = "up to date"
+)
+
+// bypass skips n with reason without skipping its children, which are
+// released as if n had succeeded, for a node whose outputs are already up to
+// date.
+func (r *Run) bypass(n *Node, reason string) {
+    r.report.skip(n, reason)
+    r.executor.skipped(n.name, reason)
+    r.mu.Lock()
+    eta := r.progressETA()
+    r.mu.Unlock()
+    r.publish(Event{Type: EventTaskSkipped, Node: n.name, Reason: reason, ETA: eta})
+    r.release(n, nil)
+}
+
+// skip marks n as skipped and propagates the skip to its children according
+// to their edge policies. n gets reason; deeper nodes are attributed to the
+// skipped node that reached them. The skip is journalled, and OnTaskSkipped
+// called, once r.mu is released. The caller must hold r.mu.
+func (r *Run) skip(n *Node, reason string) {
+    if r.skippedNodes[n] {
+        return
+    }
+    mark(&r.skippedNodes, n)
+    r.closeStreams(n)
+    r.report.skip(n, reason)
+    r.post(notice{
+        kind:  noticeSkipped,
+        event: Event{Type: EventTaskSkipped, Node: n.name, Reason: reason, ETA: r.progressETA()},
+    })
+
+    reason = fmt.Sprintf("upstream %s skipped", n.name)
+    for _, child := range r.tieOrder(n.children) {
+        if !r.holdEdge(n, child, false, reason, nil) {
+            r.unsatisfied(n, child, reason, nil)
+        }
+    }
+    for _, fallback := range r.tieOrder(n.fallbacks) {
+        r.releaseFallback(n, fallback, false, fmt.Sprintf("not needed: %s skipped", n.name))
+    }
+    r.groupResolved(n, true)
+    r.stageResolved(n, nil)
+    r.resolved(n)
+}
@@ -0,0 +1,334 @@
+package leo
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunDisable(t *testing.T) {
+    graph := TaskGraph()
+
+    var mu sync.Mutex
+    executed := make(map[string]bool)
+    task := func(name string) TaskFunc {
+        return func() error {
+            mu.Lock()
+            defer mu.Unlock()
+            executed[name] = true
+            return nil
+        }
+    }
+
+    graph.Add("A", task("A"))
+    graph.Add("B", task("B"))
+    graph.Add("C", task("C"))
+    graph.Add("D", task("D"))
+    graph.Precede("A", "B")
+    graph.Precede("B", "C")
+    graph.Precede("B", "D", OnParentFailure(EdgeRelease))
+
+    executor := NewExecutor(graph)
+
+    run := executor.NewRun()
+    if err := run.Disable("B"); err != nil {
+        t.Fatalf("Disable failed: %v", err)
+    }
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    if !executed["A"] || executed["B"] || executed["C"] || !executed["D"] {
+        t.Errorf("expected A and D to run and B and C to be skipped, got %v", executed)
+    }
+    if nr := run.Report().Nodes["B"]; nr == nil || nr.State != StateSkipped || nr.SkipReason != "disabled" {
+        t.Errorf("expected B to be reported as disabled, got %+v", nr)
+    }
+    if nr := run.Report().Nodes["C"]; nr == nil || nr.State != StateSkipped || nr.SkipReason != "upstream B skipped" {
+        t.Errorf("expected the skip to propagate to C, got %+v", nr)
+    }
+
+    // Disabling is per run; the next execution runs everything.
+    executed = make(map[string]bool)
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if !executed["B"] || !executed["C"] {
+        t.Errorf("B and C should run when B is not disabled")
+    }
+
+    if err := executor.NewRun().Disable("missing"); err == nil {
+        t.Errorf("Disable should fail for an unknown node")
+    }
+
+    // A blocking edge aborts the run when its parent is disabled.
+    executed = make(map[string]bool)
+    graph = TaskGraph()
+    graph.Add("A", task("A"))
+    graph.Add("B", task("B"))
+    graph.Precede("A", "B", OnParentFailure(EdgeBlock))
+    run = NewExecutor(graph).NewRun()
+    run.Disable("A")
+    if err := run.Execute(); err == nil {
+        t.Errorf("expected a blocking edge from a disabled node to fail the run")
+    }
+    if executed["A"] || executed["B"] {
+        t.Errorf("expected neither task to run, got %v", executed)
+    }
+}
+
+func TestDisabledNode(t *testing.T) {
+    var ran []string
+    graph := TaskGraph()
+    graph.Add("A", func() error { ran = append(ran, "A"); return nil }, Disabled())
+    graph.Add("B", func() error { ran = append(ran, "B"); return nil })
+    graph.Precede("A", "B", OnParentFailure(EdgeRelease))
+
+    executor := NewExecutor(graph)
+    for i := 0; i < 2; i++ {
+        ran = nil
+        if err := executor.Execute(); err != nil {
+            t.Fatalf("Execute failed: %v", err)
+        }
+        if len(ran) != 1 || ran[0] != "B" {
+            t.Errorf("run %d: expected only B to run, got %v", i, ran)
+        }
+        if nr := executor.Report().Nodes["A"]; nr.State != StateSkipped || nr.SkipReason != "disabled" {
+            t.Errorf("run %d: expected A to be reported as disabled, got %+v", i, nr)
+        }
+    }
+}
+
+func TestSkipPropagation(t *testing.T) {
+    graph := TaskGraph()
+
+    graph.Add("A", func() error { return errors.New("boom") })
+    graph.Add("B", func() error { return nil })
+    graph.Add("C", func() error { return nil })
+    graph.Precede("A", "B")
+    graph.Precede("B", "C")
+
+    var mu sync.Mutex
+    reasons := make(map[string]string)
+
+    executor := NewExecutor(graph)
+    executor.SetHooks(Hooks{
+        OnTaskSkipped: func(name, reason string) {
+            mu.Lock()
+            defer mu.Unlock()
+            reasons[name] = reason
+        },
+    })
+
+    if err := executor.Execute(); err == nil {
+        t.Fatalf("expected Execute to fail")
+    }
+
+    want := map[string]string{
+        "B": "upstream A failed",
+        "C": "upstream B skipped",
+    }
+    report := executor.Report()
+    for name, reason := range want {
+        if reasons[name] != reason {
+            t.Errorf("hook reason for %s: got %q, want %q", name, reasons[name], reason)
+        }
+        nr := report.Nodes[name]
+        if nr.State != StateSkipped || nr.SkipReason != reason {
+            t.Errorf("report for %s: got %s (%q), want skipped (%q)", name, nr.State, nr.SkipReason, reason)
+        }
+    }
+    if report.Succeeded() {
+        t.Errorf("report should not be successful")
+    }
+    if failed := report.Failed(); len(failed) != 1 || failed[0] != "A" {
+        t.Errorf("expected only A to fail, got %v", failed)
+    }
+}
+
+// callbackJournal calls onAppend before appending each entry.
+type callbackJournal struct {
+    Journal
+    onAppend func(JournalEntry)
+}
+
+func (j callbackJournal) Append(entry JournalEntry) error {
+    j.onAppend(entry)
+    return j.Journal.Append(entry)
+}
+
+func TestSkipCallbacksOutsideLock(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return errors.New("boom") })
+    graph.Add("B", func() error { return nil })
+    graph.Add("C", func() error { return nil })
+    graph.Precede("A", "B")
+    graph.Precede("B", "C")
+
+    executor := NewExecutor(graph)
+    run := executor.NewRun()
+
+    // The hook and the journal both call back into the run, which
+    // deadlocks if the run calls them while holding its lock.
+    var mu sync.Mutex
+    var skipped, journalled []string
+    executor.SetHooks(Hooks{
+        OnTaskSkipped: func(name, reason string) {
+            run.ETA()
+            mu.Lock()
+            defer mu.Unlock()
+            skipped = append(skipped, name)
+        },
+    })
+    journal := &FileJournal{Path: filepath.Join(t.TempDir(), "journal.jsonl")}
+    defer journal.Close()
+    executor.SetJournal(callbackJournal{Journal: journal, onAppend: func(entry JournalEntry) {
+        run.ETA()
+        if entry.State == JournalSkipped {
+            mu.Lock()
+            defer mu.Unlock()
+            journalled = append(journalled, entry.Node)
+        }
+    }})
+
+    done := make(chan error, 1)
+    go func() { done <- run.Execute() }()
+    select {
+    case err := <-done:
+        if err == nil {
+            t.Fatalf("expected Execute to fail")
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatalf("the run deadlocked")
+    }
+    if fmt.Sprint(skipped) != "[B C]" || fmt.Sprint(journalled) != "[B C]" {
+        t.Errorf("expected B and C to be skipped and journalled, got %v and %v", skipped, journalled)
+    }
+}
+
+// BenchmarkExecuteSmallGraph measures the allocations of running a small
+// frozen graph, as services that run such graphs at a high rate do. Tasks
+// allocate nothing of their own unless they use their context, but each run
+// allocates its Report, which callers may keep, and its scheduling state,
+// see TestExecuteAllocations.
+func BenchmarkExecuteSmallGraph(b *testing.B) {
+    graph := treeGraph(b, 16)
+    for _, limit := range []int{0, -1} {
+        executor := NewExecutor(graph, WithConcurrency(limit))
+        b.Run(fmt.Sprintf("limit=%d", limit), func(b *testing.B) {
+            b.ReportAllocs()
+            for i := 0; i < b.N; i++ {
+                if err := executor.Execute(); err != nil {
+                    b.Fatalf("Execute failed: %v", err)
+                }
+            }
+        })
+    }
+}
+
+// treeGraph returns a frozen graph of size no-op nodes, each depending on
+// the node at half its index.
+func treeGraph(tb testing.TB, size int) *Graph {
+    graph := TaskGraph()
+    for i := 0; i < size; i++ {
+        graph.Add(fmt.Sprintf("n%d", i), func() error { return nil })
+        if i > 0 {
+            graph.Precede(fmt.Sprintf("n%d", i/2), fmt.Sprintf("n%d", i))
+        }
+    }
+    if _, err := graph.Freeze(); err != nil {
+        tb.Fatalf("Freeze failed: %v", err)
+    }
+    return graph
+}
+
+// TestExecuteAllocations checks that a run allocates the same whatever the
+// number of its tasks, and less than the 51 times runs of 16 tasks once did.
+func TestExecuteAllocations(t *testing.T) {
+    if raceEnabled {
+        t.Skip("the race detector allocates")
+    }
+    allocs := func(size int) float64 {
+        executor := NewExecutor(treeGraph(t, size), WithConcurrency(1))
+        return testing.AllocsPerRun(100, func() {
+            if err := executor.Execute(); err != nil {
+                t.Fatalf("Execute failed: %v", err)
+            }
+        })
+    }
+    small, large := allocs(16), allocs(256)
+    if large != small {
+        t.Errorf("expected runs of 16 and 256 tasks to allocate the same, got %v and %v", small, large)
+    }
+    if small >= 51 {
+        t.Errorf("expected fewer than 51 allocations per run, got %v", small)
+    }
+}
+
+// layeredGraph returns a graph of size no-op nodes in layers of width, each
+// node depending on the node at the same position in the layer above.
+func layeredGraph(size, width int) *Graph {
+    graph := TaskGraph()
+    for i := 0; i < size; i++ {
+        graph.Add(fmt.Sprintf("n%d", i), func() error { return nil })
+        if i >= width {
+            graph.Precede(fmt.Sprintf("n%d", i-width), fmt.Sprintf("n%d", i))
+        }
+    }
+    return graph
+}
+
+// BenchmarkDispatch runs 10,000-node graphs, from fully independent to
+// chains, reporting the mean time a node waits between being queued and
+// being dispatched.
+func BenchmarkDispatch(b *testing.B) {
+    for _, width := range []int{10000, 100, 1} {
+        graph := layeredGraph(10000, width)
+        for _, limit := range []int{-1, 4} {
+            b.Run(fmt.Sprintf("width=%d/limit=%d", width, limit), func(b *testing.B) {
+                executor := NewExecutor(graph, WithConcurrency(limit))
+                var latency time.Duration
+                for i := 0; i < b.N; i++ {
+                    run := executor.NewRun()
+                    if err := run.Execute(); err != nil {
+                        b.Fatalf("Execute failed: %v", err)
+                    }
+                    latency += run.Utilization().DispatchLatency
+                }
+                b.ReportMetric(float64(latency.Nanoseconds())/float64(b.N), "ns/dispatch")
+            })
+        }
+    }
+}
+
+func TestRunGoroutinesExit(t *testing.T) {
+    for _, tc := range []struct {
+        name string
+        opts []ExecutorOption
+    }{
+        {"unlimited", []ExecutorOption{WithConcurrency(-1)}},
+        {"limited", []ExecutorOption{WithConcurrency(2)}},
+        {"pooled", []ExecutorOption{WithWorkerPool(NewWorkerPool(Worker{}, Worker{}))}},
+    } {
+        t.Run(tc.name, func(t *testing.T) {
+            before := runtime.NumGoroutine()
+            executor := NewExecutor(layeredGraph(100, 10), tc.opts...)
+            for i := 0; i < 10; i++ {
+                if err := executor.Execute(); err != nil {
+                    t.Fatalf("Execute failed: %v", err)
+                }
+            }
+            deadline := time.Now().Add(2 * time.Second)
+            for runtime.NumGoroutine() > before {
+                if time.Now().After(deadline) {
+                    t.Fatalf("expected %d goroutines after the runs, got %d", before, runtime.NumGoroutine())
+                }
+                time.Sleep(time.Millisecond)
+            }
+        })
+    }
+}
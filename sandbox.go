@@ -0,0 +1,89 @@
+package leo
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os/exec"
+    "strconv"
+    "time"
+)
+
+// Sandbox runs commands with resource limits and a restricted environment, so
+// that third-party pipeline steps cannot take down the host. The zero value
+// applies no restrictions beyond running the command in its own process
+// group, which is killed as a whole when the task is cancelled.
+//
+// CPUTime and Memory are applied with the shell's ulimit builtin, so sh must
+// be available (inside Chroot, if one is set). Control groups are not
+// managed; run the executor itself under a cgroup to cap a whole pipeline.
+type Sandbox struct {
+    // Timeout kills the command if it runs longer than this.
+    Timeout time.Duration
+    // CPUTime limits the CPU time the process may consume (RLIMIT_CPU).
+    CPUTime time.Duration
+    // Memory limits the process's virtual memory in bytes (RLIMIT_AS).
+    Memory int64
+    // Env, if non-nil, replaces the inherited environment.
+    Env []string
+    // Dir is the working directory. Defaults to the executor's.
+    Dir string
+    // Chroot runs the command with this root directory. It requires
+    // privileges and is only supported on Unix.
+    Chroot string
+}
+
+// Command returns a task that runs the named program with args inside the
+// sandbox.
+func (s Sandbox) Command(name string, args ...string) TaskCtxFunc {
+    return func(ctx context.Context) error {
+        return s.run(ctx, name, args)
+    }
+}
+
+// Shell returns a task that runs script with "sh -c" inside the sandbox.
+func (s Sandbox) Shell(script string) TaskCtxFunc {
+    return s.Command("sh", "-c", script)
+}
+
+func (s Sandbox) run(ctx context.Context, name string, args []string) error {
+    if s.Timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+        defer cancel()
+    }
+
+    if limits := s.ulimits(); limits != "" {
+        args = append([]string{"-c", limits + ` && exec "$@"`, "sh", name}, args...)
+        name = "sh"
+    }
+    cmd := exec.CommandContext(ctx, name, args...)
+    cmd.Env = s.Env
+    cmd.Dir = s.Dir
+    if err := isolate(cmd, s.Chroot); err != nil {
+        return err
+    }
+
+    err := runCommand(ctx, cmd)
+    var cmdErr *CommandError
+    if errors.As(err, &cmdErr) && s.Timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+        cmdErr.Err = fmt.Errorf("time limit %s exceeded: %w", s.Timeout, context.DeadlineExceeded)
+    }
+    return err
+}
+
+// ulimits returns the shell commands applying the CPU and memory limits.
+func (s Sandbox) ulimits() string {
+    var limits string
+    if s.CPUTime > 0 {
+        secs := int64((s.CPUTime + time.Second - 1) / time.Second)
+        limits = "ulimit -t " + strconv.FormatInt(secs, 10)
+    }
+    if s.Memory > 0 {
+        if limits != "" {
+            limits += " && "
+        }
+        limits += "ulimit -v " + strconv.FormatInt((s.Memory+1023)/1024, 10)
+    }
+    return limits
+}
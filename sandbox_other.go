@@ -0,0 +1,24 @@
+//go:build !unix
+
+package leo
+
+import (
+    "errors"
+    "os/exec"
+    "time"
+)
+
+// isolate is limited to killing the process itself on platforms without
+// process groups.
+func isolate(cmd *exec.Cmd, chroot string) error {
+    if chroot != "" {
+        return errors.New("sandbox: chroot is not supported on this platform")
+    }
+    cmd.WaitDelay = time.Second
+    return nil
+}
+
+// checkUmask returns an error if CommandSpec.Umask is not supported.
+func checkUmask() error {
+    return errors.New("command: umask is not supported on this platform")
+}
@@ -0,0 +1,64 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSandboxTimeout(t *testing.T) {
+    if runtime.GOOS == "windows" {
+        t.Skip("requires sh")
+    }
+    sb := Sandbox{Timeout: 100 * time.Millisecond}
+
+    start := time.Now()
+    // The background sleep keeps the output pipe open unless the whole
+    // process group is killed.
+    err := sb.Shell("sleep 5 & sleep 5")(context.Background())
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("expected the time limit to be reported, got %v", err)
+    }
+    if elapsed := time.Since(start); elapsed > 2*time.Second {
+        t.Errorf("sandboxed command ran for %s", elapsed)
+    }
+}
+
+func TestSandboxLimits(t *testing.T) {
+    if runtime.GOOS == "windows" {
+        t.Skip("requires sh")
+    }
+
+    err := Sandbox{Memory: 1 << 20}.Command("sh", "-c", "true")(context.Background())
+    if err == nil {
+        t.Errorf("expected a 1 MiB memory limit to stop the command")
+    }
+
+    if err := (Sandbox{Memory: 1 << 30, CPUTime: time.Minute}).Shell("true")(context.Background()); err != nil {
+        t.Errorf("generous limits should not fail the command: %v", err)
+    }
+
+    err = Sandbox{CPUTime: time.Second, Timeout: 10 * time.Second}.Shell("while :; do :; done")(context.Background())
+    if err == nil || errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("expected the CPU limit to stop the command, got %v", err)
+    }
+}
+
+func TestSandboxEnv(t *testing.T) {
+    if runtime.GOOS == "windows" {
+        t.Skip("requires sh")
+    }
+    t.Setenv("LEO_TEST_INHERITED", "yes")
+
+    err := Sandbox{Env: []string{"ONLY=1"}}.Shell(`test -z "$LEO_TEST_INHERITED" && test "$ONLY" = 1`)(context.Background())
+    if err != nil {
+        t.Errorf("expected a restricted environment: %v", err)
+    }
+
+    err = Sandbox{Chroot: "/nonexistent"}.Shell("true")(context.Background())
+    if err == nil {
+        t.Errorf("expected an unusable chroot to fail")
+    }
+}
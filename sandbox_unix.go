@@ -0,0 +1,25 @@
+//go:build unix
+
+package leo
+
+import (
+    "os/exec"
+    "syscall"
+    "time"
+)
+
+// isolate runs cmd in its own process group, killed as a whole on
+// cancellation, and optionally chrooted.
+func isolate(cmd *exec.Cmd, chroot string) error {
+    cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Chroot: chroot}
+    cmd.Cancel = func() error {
+        return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+    }
+    cmd.WaitDelay = time.Second
+    return nil
+}
+
+// checkUmask returns an error if CommandSpec.Umask is not supported.
+func checkUmask() error {
+    return nil
+}
@@ -0,0 +1,173 @@
+package leo
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+)
+
+// Scheduler runs executors repeatedly, each on its own interval, as a
+// long-lived service. A run is not started while the previous run of the same
+// pipeline is still in progress.
+type Scheduler struct {
+    mu        sync.Mutex
+    pipelines map[string]*scheduled
+    running   bool
+    started   time.Time
+    ctx       context.Context
+    wg        sync.WaitGroup
+}
+
+type scheduled struct {
+    name     string
+    executor *Executor
+    interval time.Duration
+    busy     bool
+    health   PipelineHealth
+}
+
+// NewScheduler returns an empty scheduler.
+func NewScheduler() *Scheduler {
+    return &Scheduler{pipelines: make(map[string]*scheduled)}
+}
+
+// Every schedules e to run every interval under name. The first run starts
+// one interval after the scheduler starts. Pipelines must be added before
+// Run is called.
+func (s *Scheduler) Every(name string, e *Executor, interval time.Duration) error {
+    if interval <= 0 {
+        return fmt.Errorf("pipeline %s: interval must be positive", name)
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.running {
+        return errors.New("scheduler is already running")
+    }
+    if _, exists := s.pipelines[name]; exists {
+        return fmt.Errorf("pipeline %s is already scheduled", name)
+    }
+    s.pipelines[name] = &scheduled{name: name, executor: e, interval: interval}
+    return nil
+}
+
+// Run starts the scheduled pipelines and blocks until ctx is cancelled or
+// drained (see WithDrain). Runs in progress receive the cancellation or drain
+// through their context; Run waits for them to return.
+func (s *Scheduler) Run(ctx context.Context) error {
+    s.mu.Lock()
+    if s.running {
+        s.mu.Unlock()
+        return errors.New("scheduler is already running")
+    }
+    s.running = true
+    s.started = time.Now()
+    s.ctx = ctx
+    for _, p := range s.pipelines {
+        p.health.NextRun = s.started.Add(p.interval)
+        s.wg.Add(1)
+        go s.loop(ctx, p)
+    }
+    s.mu.Unlock()
+
+    select {
+    case <-ctx.Done():
+    case <-drainChan(ctx):
+    }
+    s.mu.Lock()
+    s.running = false
+    s.mu.Unlock()
+    s.wg.Wait()
+    return nil
+}
+
+// Trigger starts a run of the named pipeline now, outside its schedule. It
+// returns an error if the scheduler is not running or the pipeline is
+// already running.
+func (s *Scheduler) Trigger(name string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    p, ok := s.pipelines[name]
+    switch {
+    case !ok:
+        return fmt.Errorf("pipeline %s is not scheduled", name)
+    case !s.running:
+        return errors.New("scheduler is not running")
+    case p.busy:
+        return fmt.Errorf("pipeline %s is already running", name)
+    }
+    p.busy = true
+    ctx := s.ctx
+    s.wg.Add(1)
+    go func() {
+        defer s.wg.Done()
+        s.execute(ctx, p)
+    }()
+    return nil
+}
+
+func (s *Scheduler) loop(ctx context.Context, p *scheduled) {
+    defer s.wg.Done()
+    ticker := time.NewTicker(p.interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-drainChan(ctx):
+            return
+        case now := <-ticker.C:
+            s.mu.Lock()
+            p.health.NextRun = now.Add(p.interval)
+            s.mu.Unlock()
+            if s.start(p) {
+                s.execute(ctx, p)
+            }
+        }
+    }
+}
+
+// start marks p as running, reporting false if it already was.
+func (s *Scheduler) start(p *scheduled) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if p.busy {
+        return false
+    }
+    p.busy = true
+    return true
+}
+
+func (s *Scheduler) execute(ctx context.Context, p *scheduled) {
+    start := time.Now()
+    err := p.executor.ExecuteContext(ctx)
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    p.busy = false
+    h := &p.health
+    h.Runs++
+    h.LastRun = start
+    h.LastDuration = time.Since(start)
+    h.LastError = ""
+    if err != nil {
+        h.LastError = err.Error()
+        h.ConsecutiveFailures++
+    } else {
+        h.ConsecutiveFailures = 0
+    }
+}
+
+// Pipelines returns the names of the scheduled pipelines, sorted.
+func (s *Scheduler) Pipelines() []string {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    names := make([]string, 0, len(s.pipelines))
+    for name := range s.pipelines {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
@@ -0,0 +1,102 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+    var ticks atomic.Int32
+    graph := TaskGraph()
+    graph.Add("tick", func() error {
+        ticks.Add(1)
+        return nil
+    })
+
+    s := NewScheduler()
+    if err := s.Every("ticker", NewExecutor(graph), 10*time.Millisecond); err != nil {
+        t.Fatalf("Every failed: %v", err)
+    }
+    if err := s.Every("ticker", NewExecutor(graph), time.Second); err == nil {
+        t.Errorf("expected a duplicate name to be rejected")
+    }
+    if err := s.Trigger("ticker"); err == nil {
+        t.Errorf("expected Trigger to fail before Run")
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error)
+    go func() { done <- s.Run(ctx) }()
+
+    deadline := time.Now().Add(5 * time.Second)
+    for ticks.Load() < 3 && time.Now().Before(deadline) {
+        time.Sleep(5 * time.Millisecond)
+    }
+    cancel()
+    if err := <-done; err != nil {
+        t.Fatalf("Run failed: %v", err)
+    }
+    if ticks.Load() < 3 {
+        t.Errorf("expected at least 3 runs, got %d", ticks.Load())
+    }
+}
+
+func TestSchedulerTriggerBusy(t *testing.T) {
+    release := make(chan struct{})
+    graph := TaskGraph()
+    graph.Add("wait", func() error {
+        <-release
+        return nil
+    })
+
+    s := NewScheduler()
+    s.Every("slow", NewExecutor(graph), time.Hour)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error)
+    go func() { done <- s.Run(ctx) }()
+    for !s.Health().Alive {
+        time.Sleep(time.Millisecond)
+    }
+
+    if err := s.Trigger("slow"); err != nil {
+        t.Fatalf("Trigger failed: %v", err)
+    }
+    if err := s.Trigger("slow"); err == nil {
+        t.Errorf("expected a second trigger to be refused while running")
+    }
+    if !s.Health().Pipelines["slow"].Running {
+        t.Errorf("expected the pipeline to be reported as running")
+    }
+    if err := s.Trigger("missing"); err == nil {
+        t.Errorf("expected an unknown pipeline to be refused")
+    }
+
+    close(release)
+    cancel()
+    <-done
+}
+
+func TestSchedulerFailures(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("fail", func() error { return errors.New("down") })
+
+    s := NewScheduler()
+    s.Every("flaky", NewExecutor(graph), 5*time.Millisecond)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    go s.Run(ctx)
+
+    deadline := time.Now().Add(5 * time.Second)
+    for s.Health().Pipelines["flaky"].ConsecutiveFailures < 2 && time.Now().Before(deadline) {
+        time.Sleep(5 * time.Millisecond)
+    }
+    h := s.Health()
+    if h.Pipelines["flaky"].ConsecutiveFailures < 2 || h.Pipelines["flaky"].LastError == "" {
+        t.Errorf("expected consecutive failures to be tracked, got %+v", h.Pipelines["flaky"])
+    }
+}
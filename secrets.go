@@ -0,0 +1,223 @@
+package leo
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// SecretsProvider looks up secret values by path. Tasks reference secrets
+// with secret:// URIs, which ResolveSecrets replaces using the
+// SecretsProvider provided to the executor with Provide.
+type SecretsProvider interface {
+    Secret(ctx context.Context, path string) (string, error)
+}
+
+// Secrets combines providers by name: the first segment of a path selects the
+// provider, which receives the rest. With
+//
+//    Provide[SecretsProvider](executor, Secrets{"env": EnvSecrets{}, "vault": vault})
+//
+// secret://env/API_TOKEN reads an environment variable and
+// secret://vault/secret/data/app#password reads from Vault.
+type Secrets map[string]SecretsProvider
+
+func (s Secrets) Secret(ctx context.Context, path string) (string, error) {
+    name, rest, _ := strings.Cut(path, "/")
+    p, ok := s[name]
+    if !ok {
+        return "", fmt.Errorf("unknown secrets provider %q", name)
+    }
+    return p.Secret(ctx, rest)
+}
+
+// EnvSecrets reads secrets from environment variables named by the path.
+type EnvSecrets struct{}
+
+func (EnvSecrets) Secret(_ context.Context, name string) (string, error) {
+    v, ok := os.LookupEnv(name)
+    if !ok {
+        return "", fmt.Errorf("environment variable %s is not set", name)
+    }
+    return v, nil
+}
+
+// FileSecrets reads secrets from files under Dir, such as mounted Kubernetes
+// or Docker secrets. A single trailing newline is removed.
+type FileSecrets struct {
+    Dir string
+}
+
+func (f FileSecrets) Secret(_ context.Context, path string) (string, error) {
+    clean := filepath.Clean("/" + path)
+    data, err := os.ReadFile(filepath.Join(f.Dir, clean))
+    if err != nil {
+        return "", err
+    }
+    s := strings.TrimSuffix(string(data), "\n")
+    return strings.TrimSuffix(s, "\r"), nil
+}
+
+// VaultSecrets reads secrets from a HashiCorp Vault server over its HTTP API.
+// The path is the API path after /v1/ followed by #field, for example
+// secret/data/app#password; both KV version 1 and 2 responses are understood.
+// The field may be omitted if the secret has a single field.
+type VaultSecrets struct {
+    // Addr is the server address. Defaults to $VAULT_ADDR.
+    Addr string
+    // Token authenticates requests. Defaults to $VAULT_TOKEN.
+    Token string
+    // Client sends requests. Defaults to http.DefaultClient.
+    Client *http.Client
+}
+
+func (v VaultSecrets) Secret(ctx context.Context, path string) (string, error) {
+    addr, token, client := v.Addr, v.Token, v.Client
+    if addr == "" {
+        addr = os.Getenv("VAULT_ADDR")
+    }
+    if token == "" {
+        token = os.Getenv("VAULT_TOKEN")
+    }
+    if client == nil {
+        client = http.DefaultClient
+    }
+    if addr == "" {
+        return "", errors.New("vault: no address configured")
+    }
+
+    path, field, _ := strings.Cut(path, "#")
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+path, nil)
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("X-Vault-Token", token)
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return "", fmt.Errorf("vault: %w", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("vault: %s: %s", path, resp.Status)
+    }
+
+    var body struct {
+        Data map[string]any `json:"data"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+        return "", fmt.Errorf("vault: %s: %w", path, err)
+    }
+    data := body.Data
+    if inner, ok := data["data"].(map[string]any); ok {
+        data = inner
+    }
+
+    if field == "" {
+        if len(data) != 1 {
+            return "", fmt.Errorf("vault: %s has %d fields; name one with #field", path, len(data))
+        }
+        for k := range data {
+            field = k
+        }
+    }
+    s, ok := data[field].(string)
+    if !ok {
+        return "", fmt.Errorf("vault: %s has no string field %s", path, field)
+    }
+    return s, nil
+}
+
+var secretURI = regexp.MustCompile(`secret://[A-Za-z0-9_.\-/#]+`)
+
+// ResolveSecrets replaces each secret:// URI in s with the secret's value,
+// looked up with the SecretsProvider provided to the executor. The values are
+// remembered by the run, which redacts them from task errors and command
+// output so that they do not appear in reports, hooks or logs.
+func ResolveSecrets(ctx context.Context, s string) (string, error) {
+    if !strings.Contains(s, "secret://") {
+        return s, nil
+    }
+    provider, ok := Service[SecretsProvider](ctx)
+    if !ok {
+        return "", errors.New("secret reference found but no SecretsProvider provided")
+    }
+    run := RunFromContext(ctx)
+
+    var firstErr error
+    out := secretURI.ReplaceAllStringFunc(s, func(uri string) string {
+        if firstErr != nil {
+            return uri
+        }
+        v, err := provider.Secret(ctx, strings.TrimPrefix(uri, "secret://"))
+        if err != nil {
+            firstErr = fmt.Errorf("resolving %s: %w", uri, err)
+            return uri
+        }
+        if run != nil {
+            run.addSecret(v)
+        }
+        return v
+    })
+    if firstErr != nil {
+        return "", firstErr
+    }
+    return out, nil
+}
+
+// addSecret records a resolved secret value for redaction.
+func (r *Run) addSecret(v string) {
+    if v == "" {
+        return
+    }
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    for _, s := range r.secrets {
+        if s == v {
+            return
+        }
+    }
+    r.secrets = append(r.secrets, v)
+    // Replace longer values first so that a secret containing another is
+    // redacted whole.
+    sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+// redact replaces the run's resolved secret values in s.
+func (r *Run) redact(s string) string {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    for _, v := range r.secrets {
+        s = strings.ReplaceAll(s, v, "[REDACTED]")
+    }
+    return s
+}
+
+// redactError returns err with the run's secret values removed from its
+// message. The original error is still available through errors.Is and
+// errors.As.
+func (r *Run) redactError(err error) error {
+    if err == nil {
+        return nil
+    }
+    msg := err.Error()
+    if redacted := r.redact(msg); redacted != msg {
+        return &redactedError{msg: redacted, err: err}
+    }
+    return err
+}
+
+type redactedError struct {
+    msg string
+    err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
@@ -0,0 +1,89 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretsProviders(t *testing.T) {
+    dir := t.TempDir()
+    os.WriteFile(filepath.Join(dir, "db"), []byte("s3cret\n"), 0o600)
+
+    vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("X-Vault-Token") != "root" {
+            http.Error(w, "denied", http.StatusForbidden)
+            return
+        }
+        if r.URL.Path != "/v1/secret/data/app" {
+            http.NotFound(w, r)
+            return
+        }
+        w.Write([]byte(`{"data": {"data": {"password": "pa55"}, "metadata": {}}}`))
+    }))
+    defer vault.Close()
+
+    t.Setenv("LEO_TEST_TOKEN", "tok")
+    provider := Secrets{
+        "env":   EnvSecrets{},
+        "file":  FileSecrets{Dir: dir},
+        "vault": VaultSecrets{Addr: vault.URL, Token: "root"},
+    }
+
+    graph := TaskGraph()
+    var got string
+    graph.AddCtx("use", func(ctx context.Context) error {
+        var err error
+        got, err = ResolveSecrets(ctx, "secret://env/LEO_TEST_TOKEN secret://file/db secret://vault/secret/data/app#password")
+        return err
+    })
+    executor := NewExecutor(graph)
+    Provide[SecretsProvider](executor, provider)
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if got != "tok s3cret pa55" {
+        t.Errorf("unexpected resolution %q", got)
+    }
+
+    for _, path := range []string{"env/LEO_TEST_MISSING", "file/missing", "vault/secret/data/other", "vault/secret/data/app#user", "other/x"} {
+        if _, err := provider.Secret(context.Background(), path); err == nil {
+            t.Errorf("expected an error for %s", path)
+        }
+    }
+}
+
+func TestSecretRedaction(t *testing.T) {
+    t.Setenv("LEO_TEST_TOKEN", "tok-123")
+
+    graph := TaskGraph()
+    graph.AddCtx("leak", func(ctx context.Context) error {
+        v, err := ResolveSecrets(ctx, "secret://LEO_TEST_TOKEN")
+        if err != nil {
+            return err
+        }
+        return errors.New("bad token " + v)
+    })
+    executor := NewExecutor(graph)
+    Provide[SecretsProvider](executor, EnvSecrets{})
+
+    err := executor.Execute()
+    if err == nil || strings.Contains(err.Error(), "tok-123") {
+        t.Errorf("expected a redacted error, got %v", err)
+    }
+    if msg := executor.Report().Nodes["leak"].Err.Error(); msg != "bad token [REDACTED]" {
+        t.Errorf("unexpected report error %q", msg)
+    }
+
+    if _, err := ResolveSecrets(context.Background(), "secret://x"); err == nil {
+        t.Errorf("expected an error without a provider")
+    }
+    if s, err := ResolveSecrets(context.Background(), "plain"); err != nil || s != "plain" {
+        t.Errorf("plain strings should pass through, got %q, %v", s, err)
+    }
+}
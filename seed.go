@@ -0,0 +1,53 @@
+package leo
+
+import (
+    "math/rand"
+    "sort"
+)
+
+// SetSeed makes the order in which a run dispatches tasks that become ready
+// together reproducible: tasks without dependencies, the children released
+// by the same task, and the tasks of a stage or wave are dispatched in an
+// order chosen by seed, so two runs with the same seed dispatch in the same
+// order while different seeds explore different orders. Tasks still run in
+// parallel, so to reproduce an ordering bug exactly, combine a seed with
+// SetConcurrency(1). The seed is recorded in the run's report.
+func (e *Executor) SetSeed(seed int64) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.seed = &seed
+}
+
+func (e *Executor) getSeed() *int64 {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.seed
+}
+
+// initTiebreak assigns each node its place among nodes that become ready
+// together, if the executor has a seed.
+func (r *Run) initTiebreak() {
+    r.tiebreak = nil
+    seed := r.executor.getSeed()
+    if seed == nil {
+        return
+    }
+    r.report.Seed = *seed
+    names := sortedNodeNames(r.graph)
+    perm := rand.New(rand.NewSource(*seed)).Perm(len(names))
+    r.tiebreak = make(map[*Node]int, len(names))
+    for i, name := range names {
+        r.tiebreak[r.graph.nodes[name]] = perm[i]
+    }
+}
+
+// tieOrder returns nodes in the order to dispatch them: as given, or in the
+// seeded order if the executor has a seed.
+func (r *Run) tieOrder(nodes []*Node) []*Node {
+    if r.tiebreak == nil || len(nodes) < 2 {
+        return nodes
+    }
+    out := append([]*Node(nil), nodes...)
+    sort.Slice(out, func(i, j int) bool { return r.tiebreak[out[i]] < r.tiebreak[out[j]] })
+    return out
+}
@@ -0,0 +1,57 @@
+package leo
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSeededDispatchOrder(t *testing.T) {
+    var mu sync.Mutex
+    var order []string
+    graph := TaskGraph()
+    graph.Add("root", func() error { return nil })
+    for i := 0; i < 8; i++ {
+        for _, prefix := range []string{"start", "child"} {
+            name := fmt.Sprintf("%s-%d", prefix, i)
+            graph.Add(name, func() error {
+                mu.Lock()
+                defer mu.Unlock()
+                order = append(order, name)
+                return nil
+            })
+            if prefix == "child" {
+                graph.Precede("root", name)
+            }
+        }
+    }
+
+    runWithSeed := func(seed int64) []string {
+        mu.Lock()
+        order = nil
+        mu.Unlock()
+        executor := NewExecutor(graph)
+        executor.SetConcurrency(1)
+        executor.SetSeed(seed)
+        if err := executor.Execute(); err != nil {
+            t.Fatalf("Execute failed: %v", err)
+        }
+        if got := executor.Report().Seed; got != seed {
+            t.Errorf("Report().Seed = %d, want %d", got, seed)
+        }
+        mu.Lock()
+        defer mu.Unlock()
+        return append([]string(nil), order...)
+    }
+
+    first := runWithSeed(42)
+    for i := 0; i < 5; i++ {
+        if again := runWithSeed(42); !reflect.DeepEqual(again, first) {
+            t.Fatalf("runs with the same seed dispatched differently:\n%v\n%v", first, again)
+        }
+    }
+    if other := runWithSeed(7); reflect.DeepEqual(other, first) {
+        t.Errorf("seeds 42 and 7 dispatched in the same order %v", first)
+    }
+}
@@ -0,0 +1,64 @@
+package leo
+
+import (
+    "context"
+    "fmt"
+    "reflect"
+)
+
+type servicesKey struct{}
+
+// Provide registers a shared service, such as a database handle or API
+// client, on the executor. Tasks retrieve it by type with Service, so
+// pipeline definitions do not need to capture services in closures. Providing
+// a second value of the same type replaces the first. To provide a value as
+// an interface type, instantiate Provide explicitly, e.g.
+// Provide[Store](executor, db).
+func Provide[T any](e *Executor, v T) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    if e.services == nil {
+        e.services = make(map[reflect.Type]any)
+    }
+    e.services[typeOf[T]()] = v
+}
+
+// Service returns the service of type T provided to the executor running the
+// task that received ctx.
+func Service[T any](ctx context.Context) (T, bool) {
+    services, _ := ctx.Value(servicesKey{}).(map[reflect.Type]any)
+    v, ok := services[typeOf[T]()]
+    if !ok {
+        var zero T
+        return zero, false
+    }
+    return v.(T), true
+}
+
+// MustService is like Service but panics if no service of type T was
+// provided.
+func MustService[T any](ctx context.Context) T {
+    v, ok := Service[T](ctx)
+    if !ok {
+        panic(fmt.Sprintf("leo: no service of type %s provided", typeOf[T]()))
+    }
+    return v
+}
+
+func typeOf[T any]() reflect.Type {
+    return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// withServices adds a snapshot of the executor's services to ctx.
+func (e *Executor) withServices(ctx context.Context) context.Context {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    if len(e.services) == 0 {
+        return ctx
+    }
+    services := make(map[reflect.Type]any, len(e.services))
+    for t, v := range e.services {
+        services[t] = v
+    }
+    return context.WithValue(ctx, servicesKey{}, services)
+}
@@ -0,0 +1,53 @@
+package leo
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeStore interface {
+    Get(key string) string
+}
+
+type mapStore map[string]string
+
+func (m mapStore) Get(key string) string { return m[key] }
+
+type apiClient struct{ endpoint string }
+
+func TestServices(t *testing.T) {
+    graph := TaskGraph()
+
+    var endpoint, value string
+    var missing bool
+    graph.AddCtx("A", func(ctx context.Context) error {
+        endpoint = MustService[*apiClient](ctx).endpoint
+        value = MustService[fakeStore](ctx).Get("k")
+        _, ok := Service[string](ctx)
+        missing = !ok
+        return nil
+    })
+
+    executor := NewExecutor(graph)
+    Provide(executor, &apiClient{endpoint: "https://api"})
+    Provide[fakeStore](executor, mapStore{"k": "v"})
+
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if endpoint != "https://api" || value != "v" {
+        t.Errorf("unexpected services: endpoint %q, value %q", endpoint, value)
+    }
+    if !missing {
+        t.Errorf("Service should report unprovided types as missing")
+    }
+}
+
+func TestMustServicePanics(t *testing.T) {
+    defer func() {
+        if recover() == nil {
+            t.Errorf("MustService should panic for a missing service")
+        }
+    }()
+    MustService[*apiClient](context.Background())
+}
@@ -0,0 +1,71 @@
+package leo
+
+import (
+    "context"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
+)
+
+type drainKey struct{}
+
+// WithDrain returns a copy of ctx that asks runs and schedulers using it to
+// shut down gracefully once drain is closed: runs stop starting new tasks and
+// return after the running ones finish, failing if any task was not started,
+// and schedulers stop starting runs and return once their runs have
+// returned. Cancel ctx to stop running tasks as well.
+func WithDrain(ctx context.Context, drain <-chan struct{}) context.Context {
+    return context.WithValue(ctx, drainKey{}, drain)
+}
+
+// drainChan returns the drain channel of ctx, or nil if it has none.
+func drainChan(ctx context.Context) <-chan struct{} {
+    drain, _ := ctx.Value(drainKey{}).(<-chan struct{})
+    return drain
+}
+
+// ShutdownOnSignal returns a context for executors and schedulers that shuts
+// them down gracefully when the process receives one of signals (SIGINT and
+// SIGTERM by default). The first signal drains the context, see WithDrain;
+// grace later, or on a second signal, the context is cancelled, which
+// cancels the running tasks. Call stop to release the signal handler.
+//
+// Because runs save their history before they return, a service can simply
+// return once its executors and scheduler have:
+//
+//    ctx, stop := leo.ShutdownOnSignal(context.Background(), 30*time.Second)
+//    defer stop()
+//    return scheduler.Run(ctx)
+func ShutdownOnSignal(parent context.Context, grace time.Duration, signals ...os.Signal) (ctx context.Context, stop context.CancelFunc) {
+    if len(signals) == 0 {
+        signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+    }
+    cancelCtx, cancel := context.WithCancel(parent)
+    drain := make(chan struct{})
+    sigs := make(chan os.Signal, 1)
+    signal.Notify(sigs, signals...)
+
+    go func() {
+        select {
+        case <-sigs:
+        case <-cancelCtx.Done():
+            return
+        }
+        close(drain)
+
+        timer := time.NewTimer(grace)
+        defer timer.Stop()
+        select {
+        case <-sigs:
+        case <-timer.C:
+        case <-cancelCtx.Done():
+        }
+        cancel()
+    }()
+
+    return WithDrain(cancelCtx, drain), func() {
+        signal.Stop(sigs)
+        cancel()
+    }
+}
@@ -0,0 +1,87 @@
+package leo
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+    started := make(chan struct{})
+    release := make(chan struct{})
+    graph := TaskGraph()
+    graph.Add("A", func() error {
+        close(started)
+        <-release
+        return nil
+    })
+    graph.Add("B", func() error { return nil })
+    graph.Precede("A", "B")
+
+    drain := make(chan struct{})
+    executor := NewExecutor(graph)
+    done := make(chan error)
+    go func() { done <- executor.ExecuteContext(WithDrain(context.Background(), drain)) }()
+
+    <-started
+    close(drain)
+    time.Sleep(10 * time.Millisecond)
+    select {
+    case err := <-done:
+        t.Fatalf("run returned before its running task finished: %v", err)
+    default:
+    }
+    close(release)
+
+    if err := <-done; err == nil {
+        t.Errorf("expected a drained run with unstarted tasks to fail")
+    }
+    report := executor.Report()
+    if report.Nodes["A"].State != StateSucceeded || report.Nodes["B"].State != StateSkipped {
+        t.Errorf("expected A to finish and B to be skipped, got %s and %s", report.Nodes["A"].State, report.Nodes["B"].State)
+    }
+}
+
+func TestDrainAfterCompletion(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil })
+
+    drain := make(chan struct{})
+    ctx := WithDrain(context.Background(), drain)
+    if err := NewExecutor(graph).ExecuteContext(ctx); err != nil {
+        t.Errorf("expected an undrained run to succeed, got %v", err)
+    }
+    close(drain)
+}
+
+func TestShutdownOnSignal(t *testing.T) {
+    if runtime.GOOS == "windows" {
+        t.Skip("cannot send signals to self")
+    }
+    ctx, stop := ShutdownOnSignal(context.Background(), 20*time.Millisecond, os.Interrupt)
+    defer stop()
+
+    s := NewScheduler()
+    s.Every("idle", NewExecutor(TaskGraph()), time.Hour)
+    done := make(chan error)
+    go func() { done <- s.Run(ctx) }()
+    for !s.Health().Alive {
+        time.Sleep(time.Millisecond)
+    }
+
+    self, _ := os.FindProcess(os.Getpid())
+    self.Signal(os.Interrupt)
+
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatalf("scheduler did not stop after the signal")
+    }
+    select {
+    case <-ctx.Done():
+    case <-time.After(5 * time.Second):
+        t.Errorf("context was not cancelled after the grace period")
+    }
+}
@@ -0,0 +1,26 @@
+package leo
+
+import (
+    "fmt"
+    "time"
+)
+
+// SLAViolation records a task that ran longer than its expected duration.
+type SLAViolation struct {
+    Node     string
+    Expected time.Duration
+    Actual   time.Duration
+}
+
+func (v SLAViolation) String() string {
+    return fmt.Sprintf("node %s took %s, expected at most %s", v.Node, v.Actual, v.Expected)
+}
+
+// WithExpectedDuration declares how long a task is expected to take. Runs that
+// exceed it are reported through Hooks.OnSLAViolation and the run's Report.
+// The task is not interrupted.
+func WithExpectedDuration(d time.Duration) NodeOption {
+    return func(n *Node) {
+        n.expected = d
+    }
+}
@@ -0,0 +1,46 @@
+package leo
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSLAViolation(t *testing.T) {
+    graph := TaskGraph()
+
+    graph.Add("fast", func() error { return nil }, WithExpectedDuration(time.Second))
+    graph.Add("slow", func() error {
+        time.Sleep(20 * time.Millisecond)
+        return nil
+    }, WithExpectedDuration(time.Millisecond))
+    graph.Precede("fast", "slow")
+
+    var mu sync.Mutex
+    var hooked []SLAViolation
+
+    executor := NewExecutor(graph)
+    executor.SetHooks(Hooks{
+        OnSLAViolation: func(v SLAViolation) {
+            mu.Lock()
+            defer mu.Unlock()
+            hooked = append(hooked, v)
+        },
+    })
+
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    if len(hooked) != 1 || hooked[0].Node != "slow" {
+        t.Fatalf("expected one violation for 'slow' from the hook, got %v", hooked)
+    }
+
+    violations := executor.Report().SLAViolations()
+    if len(violations) != 1 || violations[0].Node != "slow" {
+        t.Fatalf("expected one violation for 'slow' in the report, got %v", violations)
+    }
+    if violations[0].Actual <= violations[0].Expected {
+        t.Errorf("violation actual %s should exceed expected %s", violations[0].Actual, violations[0].Expected)
+    }
+}
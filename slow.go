@@ -0,0 +1,74 @@
+package leo
+
+import (
+    "fmt"
+    "math"
+    "time"
+)
+
+// slowTaskMinSamples is the number of successful executions a node needs in
+// the history before its executions can be flagged as slow, so that a few
+// lucky runs do not make for a baseline.
+const slowTaskMinSamples = 5
+
+// SetSlowTaskPercentile flags tasks that take longer than the given
+// percentile of their successful durations in the executor's history, such
+// as 95, to catch performance regressions: once a running task exceeds its
+// baseline, an EventTaskSlow is published, without interrupting the task,
+// and its NodeReport is marked Slow when it finishes. Nodes with fewer than
+// five successful executions in the history are not flagged. A percentile of
+// 0, the default, disables detection.
+func (e *Executor) SetSlowTaskPercentile(p float64) error {
+    if p < 0 || p > 100 {
+        return fmt.Errorf("slow task percentile %v is not between 0 and 100", p)
+    }
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.slowPct = p
+    return nil
+}
+
+func (e *Executor) getSlowPercentile() float64 {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.slowPct
+}
+
+// durationBaselines returns the p-th percentile of each node's successful
+// durations in the most recent runs in h, for nodes with enough of them.
+func durationBaselines(h HistoryStore, p float64) (map[string]time.Duration, error) {
+    samples, err := successfulDurations(h)
+    if err != nil {
+        return nil, err
+    }
+    baselines := make(map[string]time.Duration, len(samples))
+    for name, ds := range samples {
+        if len(ds) < slowTaskMinSamples {
+            continue
+        }
+        // Nearest rank: the smallest duration at least p percent of the
+        // samples do not exceed.
+        rank := int(math.Ceil(p / 100 * float64(len(ds))))
+        baselines[name] = ds[max(rank, 1)-1]
+    }
+    return baselines, nil
+}
+
+// watchSlow publishes EventTaskSlow if n, which started its task, is still
+// running once its baseline has elapsed. The returned function stops
+// watching and must be called when the task returns.
+func (r *Run) watchSlow(n *Node) (stop func()) {
+    baseline, ok := r.baselines[n.name]
+    if !ok {
+        return func() {}
+    }
+    timer := time.AfterFunc(baseline, func() {
+        r.mu.Lock()
+        _, running := r.running[n]
+        r.mu.Unlock()
+        if running {
+            r.publish(Event{Type: EventTaskSlow, Node: n.name, Duration: baseline})
+        }
+    })
+    return func() { timer.Stop() }
+}
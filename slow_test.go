@@ -0,0 +1,95 @@
+package leo
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowTask(t *testing.T) {
+    history := NewMemoryHistory(0)
+    for i := 1; i <= 10; i++ {
+        history.Save(RunRecord{Succeeded: true, Nodes: map[string]NodeRecord{
+            "build": {State: "succeeded", Duration: time.Duration(i) * time.Millisecond},
+            "lint":  {State: "succeeded", Duration: time.Second},
+            "new":   {State: "failed", Duration: time.Millisecond},
+        }})
+    }
+
+    release := make(chan struct{})
+    graph := TaskGraph()
+    graph.Add("build", func() error {
+        <-release
+        return nil
+    })
+    graph.Add("lint", func() error { return nil })
+    graph.Add("new", func() error {
+        time.Sleep(20 * time.Millisecond)
+        return nil
+    })
+
+    executor := NewExecutor(graph, WithSlowTaskPercentile(95))
+    executor.SetHistory(history)
+    sub := executor.SubscribeFilter(EventFilter{Types: []EventType{EventTaskSlow}})
+    defer sub.Close()
+
+    done := make(chan error)
+    go func() { done <- executor.Execute() }()
+    // build's p95 is 10ms; the event arrives while it is still running.
+    ev := <-sub.C
+    close(release)
+    if err := <-done; err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if ev.Node != "build" || ev.Duration != 10*time.Millisecond {
+        t.Errorf("expected build to exceed its 10ms baseline, got %v after %s", ev, ev.Duration)
+    }
+
+    rep := executor.Report()
+    if nr := rep.Nodes["build"]; !nr.Slow || nr.Baseline != 10*time.Millisecond {
+        t.Errorf("expected build to be reported slow, got %+v", nr)
+    }
+    // lint is faster than its baseline, and new has no successful history.
+    for _, name := range []string{"lint", "new"} {
+        if nr := rep.Nodes[name]; nr.Slow {
+            t.Errorf("expected %s not to be slow, got %+v", name, nr)
+        }
+    }
+    select {
+    case ev := <-sub.C:
+        t.Errorf("unexpected event %v", ev)
+    default:
+    }
+}
+
+func TestSlowTaskNeedsHistory(t *testing.T) {
+    history := NewMemoryHistory(0)
+    for i := 0; i < slowTaskMinSamples-1; i++ {
+        history.Save(RunRecord{Nodes: map[string]NodeRecord{"build": {State: "succeeded", Duration: time.Millisecond}}})
+    }
+    graph := TaskGraph()
+    graph.Add("build", func() error {
+        time.Sleep(10 * time.Millisecond)
+        return nil
+    })
+    executor := NewExecutor(graph, WithSlowTaskPercentile(50))
+    executor.SetHistory(history)
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if nr := executor.Report().Nodes["build"]; nr.Slow || nr.Baseline != 0 {
+        t.Errorf("expected no baseline from %d samples, got %+v", slowTaskMinSamples-1, nr)
+    }
+
+    if err := executor.SetSlowTaskPercentile(101); err == nil || !strings.Contains(err.Error(), "between 0 and 100") {
+        t.Errorf("expected an out of range error, got %v", err)
+    }
+
+    ran := false
+    graph = TaskGraph()
+    graph.Add("build", func() error { ran = true; return nil })
+    err := NewExecutor(graph, WithSlowTaskPercentile(-1)).Execute()
+    if err == nil || !strings.Contains(err.Error(), "between 0 and 100") || ran {
+        t.Errorf("expected the run to fail with the option's error before starting, got %v", err)
+    }
+}
@@ -0,0 +1,168 @@
+package leo
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "time"
+)
+
+// Snapshot is the state of a failed run, written as JSON for offline
+// debugging when the executor has a snapshot directory, see
+// Executor.SetSnapshotDir. Parameter values, run state and errors are
+// redacted of the run's secrets.
+type Snapshot struct {
+    ID       string            `json:"id"`
+    Name     string            `json:"name,omitempty"`
+    Labels   map[string]string `json:"labels,omitempty"`
+    Params   map[string]string `json:"params,omitempty"`
+    State    map[string]string `json:"state,omitempty"`
+    Start    time.Time         `json:"start"`
+    Duration time.Duration     `json:"duration"`
+    Err      string            `json:"error"`
+    Nodes    []SnapshotNode    `json:"nodes"`
+}
+
+// SnapshotNode is a node of a Snapshot: its place in the graph, its settings
+// (see Diff) and its outcome. State is "running" for a node whose task had
+// started but not finished when the run failed.
+type SnapshotNode struct {
+    Name       string            `json:"name"`
+    Children   []string          `json:"children,omitempty"`
+    Fallbacks  []string          `json:"fallbacks,omitempty"`
+    Metadata   map[string]string `json:"metadata,omitempty"`
+    State      string            `json:"state"`
+    SkipReason string            `json:"skip_reason,omitempty"`
+    Worker     string            `json:"worker,omitempty"`
+    Start      *time.Time        `json:"start,omitempty"`
+    Duration   time.Duration     `json:"duration,omitempty"`
+    Err        string            `json:"error,omitempty"`
+}
+
+// SetSnapshotDir makes runs that fail write a Snapshot to dir, named after
+// the run's ID with a .json extension. An empty dir, the default, disables
+// snapshots. A snapshot that cannot be written is reported alongside the
+// run's error.
+func (e *Executor) SetSnapshotDir(dir string) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.snapshotDir = dir
+}
+
+func (e *Executor) getSnapshotDir() string {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.snapshotDir
+}
+
+// snapshot returns the state of the run, which failed with err.
+func (r *Run) snapshot(err error) *Snapshot {
+    rep := r.report
+    s := &Snapshot{
+        ID:     r.id,
+        Name:   r.name,
+        Labels: r.labels,
+        Err:    r.redact(err.Error()),
+    }
+    if len(r.params) > 0 {
+        s.Params = make(map[string]string, len(r.params))
+        for k, v := range r.params {
+            s.Params[k] = r.redact(v)
+        }
+    }
+
+    r.stateMu.Lock()
+    state := make(map[string]string, len(r.state))
+    for k, v := range r.state {
+        state[k] = fmt.Sprint(v)
+    }
+    workers := make(map[*Node]string, len(r.workers))
+    for n, w := range r.workers {
+        workers[n] = w.Name
+    }
+    r.stateMu.Unlock()
+    for k, v := range state {
+        state[k] = r.redact(v)
+    }
+    if len(state) > 0 {
+        s.State = state
+    }
+
+    r.mu.Lock()
+    started := make(map[*Node]time.Time, len(r.started))
+    for n, t := range r.started {
+        started[n] = t
+    }
+    r.mu.Unlock()
+
+    rep.mu.Lock()
+    defer rep.mu.Unlock()
+    s.Start = rep.Start
+    s.Duration = rep.Duration
+    for _, name := range sortedNodeNames(r.graph) {
+        n := r.graph.nodes[name]
+        sn := SnapshotNode{
+            Name:     name,
+            Metadata: n.metadata(),
+            Worker:   workers[n],
+            State:    StatePending.String(),
+        }
+        if len(sn.Metadata) == 0 {
+            sn.Metadata = nil
+        }
+        for _, child := range n.children {
+            sn.Children = append(sn.Children, child.name)
+        }
+        for _, fallback := range n.fallbacks {
+            sn.Fallbacks = append(sn.Fallbacks, fallback.name)
+        }
+        sort.Strings(sn.Children)
+        sort.Strings(sn.Fallbacks)
+        if nr := rep.Nodes[name]; nr != nil && nr.State != StatePending {
+            sn.State = nr.State.String()
+            sn.SkipReason = nr.SkipReason
+            sn.Duration = nr.Duration
+            if !nr.Start.IsZero() {
+                start := nr.Start
+                sn.Start = &start
+            }
+            if nr.Err != nil {
+                sn.Err = r.redact(nr.Err.Error())
+            }
+        } else if t, ok := started[n]; ok {
+            sn.State = "running"
+            sn.Start = &t
+            sn.Duration = r.executor.since(t)
+        }
+        s.Nodes = append(s.Nodes, sn)
+    }
+    return s
+}
+
+// writeSnapshot writes the state of the run, which failed with err, to dir.
+func (r *Run) writeSnapshot(dir string, err error) error {
+    data, merr := json.MarshalIndent(r.snapshot(err), "", "  ")
+    if merr != nil {
+        return merr
+    }
+    if werr := os.MkdirAll(dir, 0o755); werr != nil {
+        return werr
+    }
+    return os.WriteFile(filepath.Join(dir, r.id+".json"), append(data, '\n'), 0o644)
+}
+
+// snapshotFailure writes a snapshot of the run if it failed with err and the
+// executor has a snapshot directory, returning err with any error writing it.
+func (r *Run) snapshotFailure(err error) error {
+    dir := r.executor.getSnapshotDir()
+    if err == nil || dir == "" {
+        return err
+    }
+    if werr := r.writeSnapshot(dir, err); werr != nil {
+        return errors.Join(err, fmt.Errorf("writing snapshot: %w", werr))
+    }
+    return err
+}
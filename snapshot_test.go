@@ -0,0 +1,95 @@
+package leo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFailureSnapshot(t *testing.T) {
+    dir := t.TempDir()
+    indexing, release := make(chan struct{}), make(chan struct{})
+    defer close(release)
+
+    graph := TaskGraph()
+    graph.Add("fetch", func() error { return nil }, WithTags("io"))
+    graph.AddCtx("upload", func(ctx context.Context) error {
+        <-indexing
+        RunFromContext(ctx).Set("bucket", "s3://hunter2/logs")
+        return errors.New("denied for token hunter2")
+    })
+    graph.Add("notify", func() error { return nil })
+    graph.Add("index", func() error {
+        close(indexing)
+        <-release
+        return nil
+    })
+    graph.Precede("fetch", "upload")
+    graph.Precede("upload", "notify")
+    graph.Precede("fetch", "index")
+
+    executor := NewExecutor(graph)
+    executor.SetConcurrency(-1)
+    executor.SetSnapshotDir(dir)
+    run := executor.NewRun()
+    run.SetParams(map[string]string{"token": "hunter2", "env": "prod"})
+    run.addSecret("hunter2")
+    if err := run.Execute(); err == nil {
+        t.Fatal("Execute succeeded, want the upload failure")
+    }
+
+    data, err := os.ReadFile(filepath.Join(dir, run.ID()+".json"))
+    if err != nil {
+        t.Fatalf("reading snapshot: %v", err)
+    }
+    if strings.Contains(string(data), "hunter2") {
+        t.Errorf("snapshot contains a secret:\n%s", data)
+    }
+    var s Snapshot
+    if err := json.Unmarshal(data, &s); err != nil {
+        t.Fatalf("decoding snapshot: %v", err)
+    }
+    if s.ID != run.ID() || !strings.Contains(s.Err, "denied for token [REDACTED]") {
+        t.Errorf("snapshot run = %s, error %q", s.ID, s.Err)
+    }
+    if s.Params["env"] != "prod" || s.State["bucket"] != "s3://[REDACTED]/logs" {
+        t.Errorf("snapshot params = %v, state = %v", s.Params, s.State)
+    }
+
+    nodes := make(map[string]SnapshotNode)
+    for _, n := range s.Nodes {
+        nodes[n.Name] = n
+    }
+    if n := nodes["fetch"]; n.State != "succeeded" || n.Start == nil || n.Metadata["tags"] != "io" ||
+        !reflect.DeepEqual(n.Children, []string{"index", "upload"}) {
+        t.Errorf("fetch = %+v", n)
+    }
+    if n := nodes["upload"]; n.State != "failed" || n.Err == "" {
+        t.Errorf("upload = %+v", n)
+    }
+    if n := nodes["notify"]; n.State != "skipped" || n.SkipReason != "upstream upload failed" {
+        t.Errorf("notify = %+v", n)
+    }
+    if n := nodes["index"]; n.State != "running" || n.Start == nil {
+        t.Errorf("index = %+v", n)
+    }
+}
+
+func TestNoSnapshotOnSuccess(t *testing.T) {
+    dir := t.TempDir()
+    graph := TaskGraph()
+    graph.Add("A", func() error { return nil })
+    executor := NewExecutor(graph)
+    executor.SetSnapshotDir(dir)
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+        t.Errorf("a successful run wrote %d snapshots", len(entries))
+    }
+}
@@ -0,0 +1,373 @@
+package leo
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+)
+
+// stage is a named group of nodes declared with Graph.Stage.
+type stage struct {
+    name        string
+    timeout     time.Duration
+    concurrency int
+}
+
+// StageOption configures a stage declared with Graph.Stage.
+type StageOption func(*stage)
+
+// StageTimeout limits how long a stage may run, from the moment its barrier
+// opens until its last node finishes. When the timeout expires, the contexts
+// of the stage's tasks are cancelled, tasks that have not started fail, and
+// later stages are skipped.
+func StageTimeout(d time.Duration) StageOption {
+    return func(s *stage) {
+        s.timeout = d
+    }
+}
+
+// StageConcurrency limits the number of the stage's tasks that run at once
+// to n, such as deploying to at most 5 devices at a time, on top of the
+// executor's concurrency limit. Tasks of other stages are not limited by it.
+func StageConcurrency(n int) StageOption {
+    return func(s *stage) {
+        s.concurrency = n
+    }
+}
+
+// Stage declares a named stage, such as "prepare", "deploy" or "verify".
+// Stages run in the order they are declared, with an implicit barrier between
+// them: no node of a stage starts until every node of the previous stage has
+// finished or been skipped. If a node of a stage fails without a fallback, the
+// nodes of later stages are skipped. Nodes are placed in a stage with InStage;
+// nodes outside any stage are scheduled by their edges alone.
+func (g *Graph) Stage(name string, opts ...StageOption) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    if name == "" {
+        return errors.New("stage needs a name")
+    }
+    for _, s := range g.stages {
+        if s.name == name {
+            return fmt.Errorf("stage %s already exists", name)
+        }
+    }
+    s := &stage{name: name}
+    for _, opt := range opts {
+        opt(s)
+    }
+    g.stages = append(g.stages, s)
+    return nil
+}
+
+// Stages returns the names of the graph's stages in order.
+func (g *Graph) Stages() []string {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    names := make([]string, len(g.stages))
+    for i, s := range g.stages {
+        names[i] = s.name
+    }
+    return names
+}
+
+// InStage places a node in the named stage, which must be declared with
+// Graph.Stage before the graph runs.
+func InStage(name string) NodeOption {
+    return func(n *Node) {
+        n.stage = name
+    }
+}
+
+// stageIndex returns the position of every staged node's stage, checking that
+// the stages exist and that no edge leads from a stage back to an earlier one,
+// directly or through unstaged nodes.
+func (g *Graph) stageIndex() (map[*Node]int, error) {
+    positions := make(map[string]int, len(g.stages))
+    for i, s := range g.stages {
+        positions[s.name] = i
+    }
+    index := make(map[*Node]int)
+    members := make([][]*Node, len(g.stages))
+    for _, node := range g.nodes {
+        if node.stage == "" {
+            continue
+        }
+        i, ok := positions[node.stage]
+        if !ok {
+            return nil, fmt.Errorf("node %s is in undeclared stage %s", node.name, node.stage)
+        }
+        index[node] = i
+        members[i] = append(members[i], node)
+    }
+    if len(g.stages) == 0 {
+        return index, nil
+    }
+
+    // Walk the graph with a barrier vertex in front of each stage after the
+    // first, reached from every node of the previous stage. A cycle through a
+    // barrier means the run could never finish.
+    visited := make(map[any]bool)
+    onStack := make(map[any]bool)
+    var visit func(v any) bool
+    visit = func(v any) bool {
+        if onStack[v] {
+            return true
+        }
+        if visited[v] {
+            return false
+        }
+        visited[v] = true
+        onStack[v] = true
+        defer delete(onStack, v)
+
+        var next []any
+        switch v := v.(type) {
+        case *Node:
+            for _, s := range v.successors() {
+                next = append(next, s)
+            }
+            if i, ok := index[v]; ok && i+1 < len(g.stages) {
+                next = append(next, i+1)
+            }
+        case int:
+            for _, n := range members[v] {
+                next = append(next, n)
+            }
+            if len(members[v]) == 0 && v+1 < len(g.stages) {
+                next = append(next, v+1)
+            }
+        }
+        for _, w := range next {
+            if visit(w) {
+                return true
+            }
+        }
+        return false
+    }
+    for _, node := range g.nodes {
+        if visit(node) {
+            return nil, errors.New("stage order conflicts with the graph's edges")
+        }
+    }
+    return index, nil
+}
+
+// stages tracks the progress of a run through the graph's stages.
+type stages struct {
+    list    []*stage
+    index   map[*Node]int
+    members [][]*Node
+    pending []int
+    err     []error
+    current int
+    failed  string
+    ctx     context.Context
+    cancel  context.CancelFunc
+    started time.Time
+    busy    bool
+    running []int
+    waiting [][]*Node
+    slots   map[*Node]bool
+}
+
+// initStages prepares the run's stages, adding a barrier dependency to every
+// node outside the first stage. It must be called after the in-degrees are
+// set and before any node is dispatched.
+func (r *Run) initStages() error {
+    g := r.graph
+    r.stages = nil
+    var index map[*Node]int
+    if g.plan != nil {
+        index = g.plan.stages
+    } else {
+        var err error
+        if index, err = g.stageIndex(); err != nil {
+            return err
+        }
+    }
+    if len(g.stages) == 0 {
+        return nil
+    }
+    s := &stages{
+        list:    g.stages,
+        index:   index,
+        members: make([][]*Node, len(g.stages)),
+        pending: make([]int, len(g.stages)),
+        err:     make([]error, len(g.stages)),
+        running: make([]int, len(g.stages)),
+        waiting: make([][]*Node, len(g.stages)),
+        slots:   make(map[*Node]bool),
+    }
+    for node, i := range index {
+        s.members[i] = append(s.members[i], node)
+        s.pending[i]++
+        if i > 0 {
+            r.inDegree[node]++
+        }
+    }
+    r.stages = s
+    return nil
+}
+
+// beginStages starts the first stage and any empty stages after it. The caller
+// must hold r.mu.
+func (r *Run) beginStages() {
+    if r.stages == nil {
+        return
+    }
+    r.beginStage()
+    r.advanceStages()
+}
+
+// stageContext returns the context for n's task: the context of n's stage if
+// it has one, otherwise the run's.
+func (r *Run) stageContext(n *Node) context.Context {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    s := r.stages
+    if s == nil {
+        return r.ctx
+    }
+    if i, ok := s.index[n]; ok && i == s.current && s.ctx != nil {
+        return s.ctx
+    }
+    return r.ctx
+}
+
+// stageResolved records that n has finished with err or been skipped, ending
+// its stage once every node in it has. Failures that have a fallback do not
+// count against the stage. The caller must hold r.mu.
+func (r *Run) stageResolved(n *Node, err error) {
+    s := r.stages
+    if s == nil {
+        return
+    }
+    i, ok := s.index[n]
+    if !ok {
+        return
+    }
+    if err != nil && len(n.fallbacks) == 0 && s.err[i] == nil {
+        s.err[i] = fmt.Errorf("node %s failed: %w", n.name, err)
+    }
+    s.pending[i]--
+    if s.slots[n] {
+        delete(s.slots, n)
+        s.running[i]--
+        if len(s.waiting[i]) > 0 {
+            next := s.waiting[i][0]
+            s.waiting[i] = s.waiting[i][1:]
+            r.dispatch(next)
+        }
+    }
+    r.advanceStages()
+}
+
+// holdForStage reports whether n must wait for one of its stage's tasks to
+// finish because the stage's concurrency limit is reached, queueing it if
+// so, and otherwise takes a slot of the stage for n. The caller must hold
+// r.mu.
+func (r *Run) holdForStage(n *Node) bool {
+    s := r.stages
+    if s == nil {
+        return false
+    }
+    i, ok := s.index[n]
+    if !ok || s.list[i].concurrency <= 0 {
+        return false
+    }
+    if s.running[i] >= s.list[i].concurrency {
+        s.waiting[i] = append(s.waiting[i], n)
+        return true
+    }
+    s.running[i]++
+    s.slots[n] = true
+    return false
+}
+
+// advanceStages ends the current stage and opens the next one's barrier for as
+// long as the current stage has no pending nodes. The caller must hold r.mu.
+func (r *Run) advanceStages() {
+    s := r.stages
+    if s.busy {
+        // Skipping a stage's nodes resolves them while the barrier is being
+        // opened; the outer call picks up where they leave off.
+        return
+    }
+    s.busy = true
+    defer func() { s.busy = false }()
+    for s.current < len(s.list) && s.pending[s.current] == 0 {
+        r.endStage()
+        s.current++
+        if s.current < len(s.list) {
+            r.beginStage()
+        }
+    }
+}
+
+// beginStage opens the barrier of the current stage, or skips its nodes if an
+// earlier stage failed. OnStageStarted is called once r.mu is released. The
+// caller must hold r.mu.
+func (r *Run) beginStage() {
+    s := r.stages
+    st := s.list[s.current]
+    if s.failed != "" {
+        reason := fmt.Sprintf("stage %s failed", s.failed)
+        for _, node := range s.members[s.current] {
+            r.skip(node, reason)
+        }
+        return
+    }
+
+    s.started = r.executor.now()
+    s.ctx, s.cancel = r.ctx, nil
+    if st.timeout > 0 {
+        s.ctx, s.cancel = context.WithTimeoutCause(r.ctx, st.timeout,
+            fmt.Errorf("stage %s timed out after %s", st.name, st.timeout))
+    }
+    r.post(notice{kind: noticeStageStarted, event: Event{Node: st.name}})
+    if s.current == 0 {
+        return
+    }
+    for _, node := range r.tieOrder(s.members[s.current]) {
+        r.satisfy(node, nil)
+    }
+}
+
+// endStage finishes the current stage, if it was started. OnStageFinished is
+// called once r.mu is released. The caller must hold r.mu.
+func (r *Run) endStage() {
+    s := r.stages
+    if s.ctx == nil {
+        return
+    }
+    st := s.list[s.current]
+    err := s.err[s.current]
+    if s.cancel != nil {
+        if cause := context.Cause(s.ctx); cause != nil && s.ctx.Err() == context.DeadlineExceeded {
+            err = cause
+        }
+        s.cancel()
+    }
+    s.ctx, s.cancel = nil, nil
+    if err != nil && s.failed == "" {
+        s.failed = st.name
+    }
+    r.post(notice{
+        kind:  noticeStageFinished,
+        event: Event{Node: st.name, Duration: r.executor.since(s.started), Err: err},
+    })
+}
+
+// stopStages releases the current stage's timer once the run has returned.
+func (r *Run) stopStages() {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if s := r.stages; s != nil && s.cancel != nil {
+        s.cancel()
+    }
+}
@@ -0,0 +1,180 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStageBarriers(t *testing.T) {
+    var mu sync.Mutex
+    var order []string
+    record := func(name string) {
+        mu.Lock()
+        order = append(order, name)
+        mu.Unlock()
+    }
+    task := func(name string, d time.Duration) TaskFunc {
+        return func() error {
+            time.Sleep(d)
+            record(name)
+            return nil
+        }
+    }
+
+    graph := TaskGraph()
+    graph.Stage("prepare")
+    graph.Stage("deploy")
+    graph.Stage("verify")
+    graph.Add("fetch", task("fetch", 30*time.Millisecond), InStage("prepare"))
+    graph.Add("build", task("build", 0), InStage("prepare"))
+    graph.Add("push", task("push", 0), InStage("deploy"))
+    graph.Add("check", task("check", 0), InStage("verify"))
+    if err := graph.Stage("deploy"); err == nil {
+        t.Errorf("expected a duplicate stage to be rejected")
+    }
+    if got := graph.Stages(); !reflect.DeepEqual(got, []string{"prepare", "deploy", "verify"}) {
+        t.Errorf("unexpected stages %v", got)
+    }
+
+    executor := NewExecutor(graph)
+    executor.SetConcurrency(-1)
+    executor.SetHooks(Hooks{
+        OnStageStarted: func(name string) { record("start " + name) },
+        OnStageFinished: func(name string, d time.Duration, err error) {
+            if err != nil {
+                t.Errorf("stage %s failed: %v", name, err)
+            }
+            record("end " + name)
+        },
+    })
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    want := []string{
+        "start prepare", "build", "fetch", "end prepare",
+        "start deploy", "push", "end deploy",
+        "start verify", "check", "end verify",
+    }
+    if !reflect.DeepEqual(order, want) {
+        t.Errorf("unexpected order\n got %v\nwant %v", order, want)
+    }
+}
+
+func TestStageFailureSkipsLaterStages(t *testing.T) {
+    graph := TaskGraph()
+    graph.Stage("prepare")
+    graph.Stage("deploy")
+    graph.Add("fetch", func() error { return errors.New("no network") }, InStage("prepare"))
+    graph.Add("push", func() error { return nil }, InStage("deploy"))
+
+    var started []string
+    executor := NewExecutor(graph)
+    executor.SetHooks(Hooks{
+        OnStageStarted: func(name string) { started = append(started, name) },
+    })
+    if err := executor.Execute(); err == nil {
+        t.Fatalf("expected the run to fail")
+    }
+    if nr := executor.Report().Nodes["push"]; nr.State != StateSkipped || nr.SkipReason != "stage prepare failed" {
+        t.Errorf("expected push to be skipped, got %v (%s)", nr.State, nr.SkipReason)
+    }
+    if !reflect.DeepEqual(started, []string{"prepare"}) {
+        t.Errorf("expected only prepare to start, got %v", started)
+    }
+}
+
+func TestStageTimeout(t *testing.T) {
+    graph := TaskGraph()
+    graph.Stage("deploy", StageTimeout(20*time.Millisecond))
+    graph.AddCtx("push", func(ctx context.Context) error {
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(5 * time.Second):
+            return nil
+        }
+    }, InStage("deploy"))
+
+    var stageErr error
+    executor := NewExecutor(graph)
+    executor.SetHooks(Hooks{
+        OnStageFinished: func(name string, d time.Duration, err error) { stageErr = err },
+    })
+    if err := executor.Execute(); err == nil {
+        t.Fatalf("expected the stage timeout to fail the run")
+    }
+    if stageErr == nil || !strings.Contains(stageErr.Error(), "stage deploy timed out") {
+        t.Errorf("expected a stage timeout, got %v", stageErr)
+    }
+}
+
+func TestStageErrors(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("a", func() error { return nil }, InStage("missing"))
+    if err := NewExecutor(graph).Execute(); err == nil || !strings.Contains(err.Error(), "undeclared stage") {
+        t.Errorf("expected an undeclared stage error, got %v", err)
+    }
+
+    graph = TaskGraph()
+    graph.Stage("prepare")
+    graph.Stage("verify")
+    graph.Add("fetch", func() error { return nil }, InStage("prepare"))
+    graph.Add("check", func() error { return nil }, InStage("verify"))
+    graph.Add("between", func() error { return nil })
+    graph.Precede("check", "between")
+    graph.Precede("between", "fetch")
+    if err := NewExecutor(graph).Execute(); err == nil || !strings.Contains(err.Error(), "conflicts") {
+        t.Errorf("expected a stage order conflict, got %v", err)
+    }
+}
+
+func TestStageConcurrency(t *testing.T) {
+    var mu sync.Mutex
+    running, peak := 0, 0
+    device := func() error {
+        mu.Lock()
+        running++
+        if running > peak {
+            peak = running
+        }
+        mu.Unlock()
+        time.Sleep(5 * time.Millisecond)
+        mu.Lock()
+        running--
+        mu.Unlock()
+        return nil
+    }
+    graph := TaskGraph()
+    graph.Stage("build")
+    graph.Stage("deploy", StageConcurrency(2))
+    for i := 0; i < 4; i++ {
+        graph.Add(fmt.Sprintf("compile-%d", i), func() error { time.Sleep(5 * time.Millisecond); return nil }, InStage("build"))
+    }
+    for i := 0; i < 6; i++ {
+        graph.Add(fmt.Sprintf("device-%d", i), device, InStage("deploy"))
+    }
+
+    start := time.Now()
+    executor := NewExecutor(graph, WithConcurrency(-1))
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if peak != 2 {
+        t.Errorf("expected at most 2 devices at a time, got %d", peak)
+    }
+    if len(executor.Report().Completed()) != 10 {
+        t.Errorf("expected every task to run, got %v", executor.Report().Completed())
+    }
+    // The build stage runs its 4 tasks together and the deploy stage in 3
+    // rounds of 2.
+    if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+        t.Errorf("expected the deploy stage to run in rounds, took %v", elapsed)
+    }
+}
@@ -0,0 +1,111 @@
+package leo
+
+import (
+    "context"
+    "sync"
+)
+
+type (
+    runKey  struct{}
+    nodeKey struct{}
+)
+
+// RunFromContext returns the run executing the task that received ctx, or nil
+// if ctx did not come from a run.
+func RunFromContext(ctx context.Context) *Run {
+    r, _ := ctx.Value(runKey{}).(*Run)
+    return r
+}
+
+// Set stores a value in the run's state, which is shared by all tasks of the
+// run and isolated from other runs. It is safe to call from concurrent tasks.
+// Values set before the run starts are visible to its tasks.
+func (r *Run) Set(key string, value any) {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    if r.state == nil {
+        r.state = make(map[string]any)
+    }
+    r.state[key] = value
+}
+
+// Get returns a value stored with Set.
+func (r *Run) Get(key string) (any, bool) {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    v, ok := r.state[key]
+    return v, ok
+}
+
+// runNode returns the run and node executing the task that received ctx.
+func runNode(ctx context.Context) (*Run, *Node) {
+    n, _ := ctx.Value(nodeKey{}).(*Node)
+    return RunFromContext(ctx), n
+}
+
+// taskContext is the context a task receives: parent with the task's node
+// and a cancellation of its own, see Run.taskContext. Most tasks never look
+// at their context, so the cancelable context that serves everything but
+// the node is only derived from parent when it is first used.
+type taskContext struct {
+    context.Context
+    node *Node
+
+    mu            sync.Mutex
+    derived       context.Context
+    cancelDerived context.CancelCauseFunc
+    // cancelled and cause record a cancellation before derived exists.
+    cancelled bool
+    cause     error
+}
+
+// taskContext returns the context for running n under parent, from the
+// run's preallocated contexts while they last.
+func (r *Run) taskContext(parent context.Context, n *Node) *taskContext {
+    var c *taskContext
+    if i := int(r.nextContext.Add(1)) - 1; i < len(r.contexts) {
+        c = &r.contexts[i]
+    } else {
+        c = new(taskContext)
+    }
+    c.Context, c.node = parent, n
+    return c
+}
+
+func (c *taskContext) Value(key any) any {
+    if key == (nodeKey{}) {
+        return c.node
+    }
+    return c.ctx().Value(key)
+}
+
+func (c *taskContext) Done() <-chan struct{} { return c.ctx().Done() }
+
+func (c *taskContext) Err() error { return c.ctx().Err() }
+
+// ctx returns the cancelable context derived from the parent, deriving it
+// on first use.
+func (c *taskContext) ctx() context.Context {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.derived == nil {
+        c.derived, c.cancelDerived = context.WithCancelCause(c.Context)
+        if c.cancelled {
+            c.cancelDerived(c.cause)
+        }
+    }
+    return c.derived
+}
+
+// cancel cancels the context with cause, like a context.CancelCauseFunc.
+func (c *taskContext) cancel(cause error) {
+    c.mu.Lock()
+    cancel := c.cancelDerived
+    if cancel == nil && !c.cancelled {
+        c.cancelled, c.cause = true, cause
+    }
+    c.mu.Unlock()
+    if cancel != nil {
+        cancel(cause)
+    }
+}
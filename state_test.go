@@ -0,0 +1,79 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunState(t *testing.T) {
+    graph := TaskGraph()
+
+    graph.AddCtx("produce", func(ctx context.Context) error {
+        RunFromContext(ctx).Set("artifact", "build-42")
+        return nil
+    })
+    var got any
+    graph.AddCtx("consume", func(ctx context.Context) error {
+        got, _ = RunFromContext(ctx).Get("artifact")
+        return nil
+    })
+    graph.Precede("produce", "consume")
+
+    executor := NewExecutor(graph)
+
+    first := executor.NewRun()
+    if err := first.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if got != "build-42" {
+        t.Errorf("consume saw %v, want build-42", got)
+    }
+    if v, _ := first.Get("artifact"); v != "build-42" {
+        t.Errorf("state should be readable after the run, got %v", v)
+    }
+
+    second := executor.NewRun()
+    if _, ok := second.Get("artifact"); ok {
+        t.Errorf("state should not leak between runs")
+    }
+
+    if RunFromContext(context.Background()) != nil {
+        t.Errorf("expected no run outside an execution")
+    }
+}
+
+func TestTaskContextCancelledBeforeUse(t *testing.T) {
+    fail := errors.New("boom")
+    release := make(chan struct{})
+    checked := make(chan error, 1)
+    graph := TaskGraph()
+    graph.Add("fail", func() error {
+        time.Sleep(10 * time.Millisecond)
+        return fail
+    })
+    graph.AddCtx("wait", func(ctx context.Context) error {
+        // The run is aborted before the task first looks at its context.
+        <-release
+        if _, ok := ctx.Value(nodeKey{}).(*Node); !ok {
+            checked <- errors.New("expected the context to carry the node")
+            return nil
+        }
+        select {
+        case <-ctx.Done():
+            checked <- context.Cause(ctx)
+        default:
+            checked <- errors.New("expected the context to be done")
+        }
+        return nil
+    })
+
+    if err := NewExecutor(graph, WithConcurrency(-1)).Execute(); err == nil {
+        t.Fatalf("expected the run to fail")
+    }
+    close(release)
+    if cause := <-checked; !errors.Is(cause, fail) {
+        t.Errorf("expected the run's error as the cause, got %v", cause)
+    }
+}
@@ -0,0 +1,112 @@
+package leo
+
+import "time"
+
+// NodeStatus is a node's state at one point during or after a run, see
+// Run.Status.
+type NodeStatus struct {
+    // State is StatePending, StateRunning, StateSucceeded, StateCached,
+    // StateFailed or StateSkipped.
+    State NodeState
+    // Start is when the node's task started, and End when it finished.
+    // They are zero for nodes that have not started or finished, and End
+    // is zero for skipped nodes.
+    Start time.Time
+    End   time.Time
+    // Err is set for failed nodes, and SkipReason for skipped ones.
+    Err        error
+    SkipReason string
+}
+
+// Status returns the status of every node of the run by name. It may be
+// called while the run executes, from any goroutine, as well as after it has
+// returned; every node is pending before the run starts.
+func (r *Run) Status() map[string]NodeStatus {
+    r.mu.Lock()
+    rep := r.report
+    started := make(map[string]time.Time, len(r.started))
+    for n, t := range r.started {
+        started[n.name] = t
+    }
+    r.mu.Unlock()
+
+    out := make(map[string]NodeStatus, len(r.graph.nodes))
+    for name := range r.graph.nodes {
+        out[name] = NodeStatus{State: StatePending}
+    }
+    for name, t := range started {
+        out[name] = NodeStatus{State: StateRunning, Start: t}
+    }
+    if rep == nil {
+        return out
+    }
+
+    rep.mu.Lock()
+    defer rep.mu.Unlock()
+    for name, nr := range rep.Nodes {
+        st := NodeStatus{
+            State:      nr.State,
+            Err:        nr.Err,
+            SkipReason: nr.SkipReason,
+        }
+        switch nr.State {
+        case StatePending:
+            continue
+        case StateSucceeded, StateFailed:
+            st.Start = nr.Start
+            st.End = nr.Start.Add(nr.Duration)
+            if nr.Cached {
+                st.State = StateCached
+            }
+        }
+        out[name] = st
+    }
+    return out
+}
+
+// Utilization is a snapshot of a run's scheduling, see Run.Utilization.
+type Utilization struct {
+    // Queued is the number of nodes whose dependencies are met but that
+    // have not been dispatched yet, because the concurrency limit or the
+    // worker pool is saturated, and Running the number of tasks running.
+    Queued  int
+    Running int
+    // Dispatched is the number of nodes dispatched so far. DispatchLatency
+    // is their mean time between being queued and being dispatched, and
+    // MaxDispatchLatency the longest.
+    Dispatched         int
+    DispatchLatency    time.Duration
+    MaxDispatchLatency time.Duration
+}
+
+// Utilization returns the run's current queue depth, running tasks and
+// dispatch latency. Like Status, it may be called from any goroutine while
+// the run executes and after it has returned.
+func (r *Run) Utilization() Utilization {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    u := Utilization{
+        Queued:             len(r.queuedAt),
+        Running:            len(r.running),
+        Dispatched:         int(r.latency.count),
+        MaxDispatchLatency: r.latency.max,
+    }
+    if r.latency.count > 0 {
+        u.DispatchLatency = r.latency.sum / time.Duration(r.latency.count)
+    }
+    return u
+}
+
+// queued records that n was queued. The caller must hold r.mu.
+func (r *Run) queued(n *Node) {
+    r.queuedAt[n] = r.executor.now()
+}
+
+// dispatched records that n was taken off the queue. The caller must hold
+// r.mu.
+func (r *Run) dispatched(n *Node) {
+    if t, ok := r.queuedAt[n]; ok {
+        delete(r.queuedAt, n)
+        r.latency.add(r.executor.now().Sub(t))
+    }
+}
@@ -0,0 +1,150 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunStatus(t *testing.T) {
+    var started, release chan struct{}
+    graph := TaskGraph()
+    graph.AddFunc("fetch", func() int { return 7 }, WithCacheKey(func(ctx context.Context) string { return "v1" }))
+    graph.Add("build", func() error {
+        if started != nil {
+            close(started)
+            <-release
+        }
+        return nil
+    })
+    graph.Add("test", func() error { return errors.New("flaky") })
+    graph.Add("ship", func() error { return nil })
+    graph.Precede("fetch", "build")
+    graph.Precede("build", "test")
+    graph.Precede("test", "ship")
+
+    // The first run caches fetch.
+    executor := NewExecutor(graph)
+    executor.SetCache(&MemoryCache{})
+    executor.Execute()
+
+    run := executor.NewRun()
+    if st := run.Status()["build"]; st.State != StatePending {
+        t.Errorf("expected build to be pending before the run, got %s", st.State)
+    }
+    started, release = make(chan struct{}), make(chan struct{})
+    done := make(chan error)
+    go func() { done <- run.Execute() }()
+
+    <-started
+    status := run.Status()
+    if st := status["build"]; st.State != StateRunning || st.Start.IsZero() || !st.End.IsZero() {
+        t.Errorf("expected build to be running, got %+v", st)
+    }
+    if st := status["fetch"]; st.State != StateCached || st.End.IsZero() {
+        t.Errorf("expected fetch to come from the cache, got %+v", st)
+    }
+    if st := status["ship"]; st.State != StatePending {
+        t.Errorf("expected ship to be pending, got %+v", st)
+    }
+    close(release)
+    if err := <-done; err == nil {
+        t.Fatalf("expected test to fail the run")
+    }
+
+    status = run.Status()
+    if st := status["build"]; st.State != StateSucceeded || st.End.Before(st.Start) {
+        t.Errorf("expected build to have succeeded, got %+v", st)
+    }
+    if st := status["test"]; st.State != StateFailed || st.Err == nil {
+        t.Errorf("expected test to have failed, got %+v", st)
+    }
+    if st := status["ship"]; st.State != StateSkipped || st.SkipReason != "upstream test failed" {
+        t.Errorf("expected ship to be skipped, got %+v", st)
+    }
+}
+
+func TestRunStatusFromHooks(t *testing.T) {
+    graph := TaskGraph()
+    graph.Stage("prepare")
+    graph.Stage("deploy")
+    graph.Add("fetch", func() error { return errors.New("no network") }, InStage("prepare"))
+    graph.Add("push", func() error { return nil }, InStage("deploy"))
+
+    executor := NewExecutor(graph)
+    run := executor.NewRun()
+    // Hooks may poll the run they are called for.
+    var mu sync.Mutex
+    seen := make(map[string]NodeState)
+    record := func(hook, node string) {
+        state := run.Status()[node].State
+        mu.Lock()
+        defer mu.Unlock()
+        seen[hook] = state
+    }
+    executor.SetHooks(Hooks{
+        OnStageStarted:  func(name string) { record("started "+name, "fetch") },
+        OnStageFinished: func(name string, d time.Duration, err error) { record("finished "+name, "fetch") },
+        OnTaskSkipped:   func(name, reason string) { record("skipped", name) },
+    })
+
+    done := make(chan error, 1)
+    go func() { done <- run.Execute() }()
+    select {
+    case err := <-done:
+        if err == nil {
+            t.Fatalf("expected the run to fail")
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatalf("the run deadlocked")
+    }
+    want := map[string]NodeState{
+        "started prepare":  StatePending,
+        "finished prepare": StateFailed,
+        "skipped":          StateSkipped,
+    }
+    if !reflect.DeepEqual(seen, want) {
+        t.Errorf("expected hooks to see %v, got %v", want, seen)
+    }
+}
+
+func TestRunUtilization(t *testing.T) {
+    started, release := make(chan struct{}), make(chan struct{})
+    graph := TaskGraph()
+    graph.Add("slow", func() error {
+        close(started)
+        <-release
+        return nil
+    })
+    graph.Add("waiting", func() error { return nil })
+    graph.Precede("slow", "waiting")
+    for _, name := range []string{"a", "b"} {
+        graph.Add(name, func() error { return nil })
+        graph.Precede(name, "waiting")
+    }
+
+    executor := NewExecutor(graph, WithConcurrency(1))
+    run := executor.NewRun()
+    done := make(chan error)
+    go func() { done <- run.Execute() }()
+    <-started
+    // slow runs alone, while a and b wait for the single slot in some order.
+    u := run.Utilization()
+    if u.Running != 1 || u.Queued+u.Dispatched != 3 {
+        t.Errorf("expected one running task and the others queued or dispatched, got %+v", u)
+    }
+    close(release)
+    if err := <-done; err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    u = run.Utilization()
+    if u.Running != 0 || u.Queued != 0 || u.Dispatched != 4 {
+        t.Errorf("expected every node to be dispatched, got %+v", u)
+    }
+    if u.MaxDispatchLatency < u.DispatchLatency {
+        t.Errorf("expected the max latency to be at least the mean, got %+v", u)
+    }
+}
@@ -0,0 +1,137 @@
+package leo
+
+import (
+    "context"
+    "fmt"
+)
+
+// Stream makes the edge a dataflow channel: the child is started as soon as
+// the parent's task starts, rather than when it finishes, and the two tasks
+// exchange items over a channel obtained with StreamTo and StreamFrom. The
+// channel holds up to buffer items and is closed when the parent's task
+// returns, whatever its outcome. If the parent is skipped before it starts,
+// the edge behaves like an ordinary one.
+func Stream(buffer int) EdgeOption {
+    return func(e *edgeConfig) {
+        e.stream = true
+        e.buffer = buffer
+    }
+}
+
+type streamKey struct {
+    from, to *Node
+}
+
+type stream struct {
+    ch     any
+    close  func()
+    closed bool
+}
+
+// StreamTo returns the sending side of the streaming edge from the task that
+// received ctx to child. The parent and child must agree on T.
+func StreamTo[T any](ctx context.Context, child string) (chan<- T, error) {
+    r, n := runNode(ctx)
+    if r == nil {
+        return nil, fmt.Errorf("leo: StreamTo called outside a running task")
+    }
+    to, ok := r.graph.nodes[child]
+    if !ok {
+        return nil, fmt.Errorf("leo: node %s does not exist", child)
+    }
+    return streamChan[T](r, n, to)
+}
+
+// StreamFrom returns the receiving side of the streaming edge from parent to
+// the task that received ctx. The parent and child must agree on T.
+func StreamFrom[T any](ctx context.Context, parent string) (<-chan T, error) {
+    r, n := runNode(ctx)
+    if r == nil {
+        return nil, fmt.Errorf("leo: StreamFrom called outside a running task")
+    }
+    from, ok := r.graph.nodes[parent]
+    if !ok {
+        return nil, fmt.Errorf("leo: node %s does not exist", parent)
+    }
+    return streamChan[T](r, from, n)
+}
+
+// streamChan returns the run's channel for the streaming edge from -> to,
+// creating it on first use.
+func streamChan[T any](r *Run, from, to *Node) (chan T, error) {
+    ed := from.edgeTo(to)
+    if !from.hasChild(to) || !ed.stream {
+        return nil, fmt.Errorf("leo: %s -> %s is not a streaming edge", from.name, to.name)
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    key := streamKey{from, to}
+    s, ok := r.streams[key]
+    if !ok {
+        ch := make(chan T, ed.buffer)
+        s = &stream{ch: ch, close: func() { close(ch) }}
+        if r.streams == nil {
+            r.streams = make(map[streamKey]*stream)
+        }
+        r.streams[key] = s
+        if r.streamsDone[from] {
+            s.close()
+            s.closed = true
+        }
+    }
+    ch, ok := s.ch.(chan T)
+    if !ok {
+        return nil, fmt.Errorf("leo: %s -> %s streams %T, not chan %s", from.name, to.name, s.ch, typeOf[T]())
+    }
+    return ch, nil
+}
+
+// streamed reports whether n consumes a streaming edge.
+func (n *Node) streamed() bool {
+    for _, p := range n.parents {
+        if p.edgeTo(n).stream {
+            return true
+        }
+    }
+    return false
+}
+
+// streaming reports whether n streams to a child.
+func (n *Node) streaming() bool {
+    for _, c := range n.children {
+        if n.edgeTo(c).stream {
+            return true
+        }
+    }
+    return false
+}
+
+// startStreams dispatches the children n streams to, now that its task is
+// about to start.
+func (r *Run) startStreams(n *Node) {
+    r.mu.Lock()
+    defer r.unlock()
+    for _, child := range n.children {
+        if n.edgeTo(child).stream {
+            mark(&r.streamStarted, n)
+            r.satisfy(child, n)
+        }
+    }
+}
+
+// closeStreams closes the channels of n's streaming edges. The caller must
+// hold r.mu.
+func (r *Run) closeStreams(n *Node) {
+    if !n.streaming() {
+        return
+    }
+    mark(&r.streamsDone, n)
+    for key, s := range r.streams {
+        if key.from == n && !s.closed {
+            s.close()
+            s.closed = true
+        }
+    }
+}
@@ -0,0 +1,128 @@
+package leo
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+    graph := TaskGraph()
+
+    var consumerStarted atomic.Bool
+    var sawConsumerFirst bool
+    graph.AddCtx("produce", func(ctx context.Context) error {
+        out, err := StreamTo[int](ctx, "consume")
+        if err != nil {
+            return err
+        }
+        for i := 1; i <= 5; i++ {
+            out <- i
+            if i == 1 {
+                // The channel is unbuffered, so the consumer is running.
+                sawConsumerFirst = consumerStarted.Load()
+            }
+        }
+        return nil
+    })
+
+    var sum int
+    graph.AddCtx("consume", func(ctx context.Context) error {
+        consumerStarted.Store(true)
+        in, err := StreamFrom[int](ctx, "produce")
+        if err != nil {
+            return err
+        }
+        for v := range in {
+            sum += v
+        }
+        return nil
+    })
+
+    var after bool
+    graph.Add("after", func() error {
+        after = true
+        return nil
+    })
+
+    if err := graph.Precede("produce", "consume", Stream(0)); err != nil {
+        t.Fatalf("Precede failed: %v", err)
+    }
+    graph.Precede("produce", "after")
+
+    if err := NewExecutor(graph).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if !sawConsumerFirst {
+        t.Errorf("expected the consumer to start before the producer finished")
+    }
+    if sum != 15 {
+        t.Errorf("expected sum 15, got %d", sum)
+    }
+    if !after {
+        t.Errorf("expected the ordinary child to run")
+    }
+}
+
+func TestStreamDisabledProducer(t *testing.T) {
+    graph := TaskGraph()
+    graph.AddCtx("produce", func(ctx context.Context) error { return nil })
+
+    var received int
+    graph.AddCtx("consume", func(ctx context.Context) error {
+        in, err := StreamFrom[string](ctx, "produce")
+        if err != nil {
+            return err
+        }
+        for range in {
+            received++
+        }
+        return nil
+    })
+    graph.Precede("produce", "consume", Stream(1), OnParentFailure(EdgeRelease))
+
+    run := NewExecutor(graph).NewRun()
+    run.Disable("produce")
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if received != 0 {
+        t.Errorf("expected a closed, empty stream, got %d items", received)
+    }
+}
+
+func TestStreamErrors(t *testing.T) {
+    graph := TaskGraph()
+    graph.AddCtx("a", func(ctx context.Context) error {
+        _, err := StreamTo[int](ctx, "b")
+        return err
+    })
+    graph.Add("b", func() error { return nil })
+    graph.Precede("a", "b")
+
+    if err := NewExecutor(graph).Execute(); err == nil {
+        t.Errorf("expected an error for an ordinary edge")
+    }
+
+    graph = TaskGraph()
+    graph.AddCtx("a", func(ctx context.Context) error {
+        out, err := StreamTo[int](ctx, "b")
+        if err == nil {
+            out <- 1
+        }
+        return err
+    })
+    graph.AddCtx("b", func(ctx context.Context) error {
+        _, err := StreamFrom[string](ctx, "a")
+        return err
+    })
+    graph.Precede("a", "b", Stream(1))
+
+    if err := NewExecutor(graph).Execute(); err == nil {
+        t.Errorf("expected an error for mismatched stream types")
+    }
+
+    if _, err := StreamTo[int](context.Background(), "b"); err == nil {
+        t.Errorf("expected an error outside a run")
+    }
+}
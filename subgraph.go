@@ -0,0 +1,155 @@
+package leo
+
+import (
+    "fmt"
+    "sort"
+)
+
+// Ancestors returns the names of every node that name depends on, directly or
+// indirectly, sorted. Fallback edges count as dependencies.
+func (g *Graph) Ancestors(name string) ([]string, error) {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    node, exists := g.lookup(name)
+    if !exists {
+        return nil, fmt.Errorf("node %s does not exist", name)
+    }
+
+    visited := make(map[*Node]bool)
+    var walk func(n *Node)
+    walk = func(n *Node) {
+        for _, p := range n.predecessors() {
+            if !visited[p] {
+                visited[p] = true
+                walk(p)
+            }
+        }
+    }
+    walk(node)
+
+    return sortedNames(visited), nil
+}
+
+// Descendants returns the names of every node that depends on name, directly
+// or indirectly, sorted. Fallback edges count as dependencies.
+func (g *Graph) Descendants(name string) ([]string, error) {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    node, exists := g.lookup(name)
+    if !exists {
+        return nil, fmt.Errorf("node %s does not exist", name)
+    }
+
+    visited := make(map[*Node]bool)
+    var walk func(n *Node)
+    walk = func(n *Node) {
+        for _, c := range n.successors() {
+            if !visited[c] {
+                visited[c] = true
+                walk(c)
+            }
+        }
+    }
+    walk(node)
+
+    return sortedNames(visited), nil
+}
+
+// Subgraph returns a new graph containing only the named nodes and the edges
+// between them. Tasks and node options are shared with g; edges to nodes
+// outside the subgraph are dropped, and aliases of included nodes are kept.
+func (g *Graph) Subgraph(names ...string) (*Graph, error) {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    include := make(map[*Node]bool, len(names))
+    for _, name := range names {
+        node, exists := g.lookup(name)
+        if !exists {
+            return nil, fmt.Errorf("node %s does not exist", name)
+        }
+        include[node] = true
+    }
+
+    sub := TaskGraph()
+    sub.stages = g.stages
+    for _, node := range g.startNodes {
+        if include[node] {
+            c := node.clone()
+            sub.nodes[c.name] = c
+            sub.startNodes = append(sub.startNodes, c)
+        }
+    }
+
+    for node := range include {
+        from := sub.nodes[node.name]
+        for _, child := range node.children {
+            if !include[child] {
+                continue
+            }
+            to := sub.nodes[child.name]
+            from.children = append(from.children, to)
+            to.parents = append(to.parents, from)
+            if ed, ok := node.edges[child]; ok {
+                if from.edges == nil {
+                    from.edges = make(map[*Node]*edgeConfig)
+                }
+                cp := *ed
+                from.edges[to] = &cp
+            }
+        }
+        for _, fallback := range node.fallbacks {
+            if !include[fallback] {
+                continue
+            }
+            fb := sub.nodes[fallback.name]
+            from.fallbacks = append(from.fallbacks, fb)
+            fb.fallbackFor = append(fb.fallbackFor, from)
+        }
+    }
+    for group, tx := range g.transactions {
+        if sub.transactions == nil {
+            sub.transactions = make(map[string]*Transaction)
+        }
+        sub.transactions[group] = tx
+    }
+    for alias, name := range g.aliases {
+        if _, ok := sub.nodes[name]; ok {
+            if sub.aliases == nil {
+                sub.aliases = make(map[string]string)
+            }
+            sub.aliases[alias] = name
+        }
+    }
+
+    return sub, nil
+}
+
+// clone returns a copy of n's task and settings without any edges.
+func (n *Node) clone() *Node {
+    c := *n
+    c.children = make([]*Node, 0)
+    c.parents = make([]*Node, 0)
+    c.fallbacks = nil
+    c.fallbackFor = nil
+    c.edges = nil
+    return &c
+}
+
+// predecessors returns the nodes with an edge of any kind to n.
+func (n *Node) predecessors() []*Node {
+    if len(n.fallbackFor) == 0 {
+        return n.parents
+    }
+    out := make([]*Node, 0, len(n.parents)+len(n.fallbackFor))
+    out = append(out, n.parents...)
+    return append(out, n.fallbackFor...)
+}
+
+func sortedNames(nodes map[*Node]bool) []string {
+    names := make([]string, 0, len(nodes))
+    for n := range nodes {
+        names = append(names, n.name)
+    }
+    sort.Strings(names)
+    return names
+}
@@ -0,0 +1,73 @@
+package leo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func diamond() *Graph {
+    graph := TaskGraph()
+    for _, name := range []string{"A", "B", "C", "D", "E"} {
+        graph.Add(name, func() error { return nil })
+    }
+    graph.Precede("A", "B")
+    graph.Precede("A", "C")
+    graph.Succeed("D", "B")
+    graph.Succeed("D", "C")
+    graph.Precede("D", "E")
+    return graph
+}
+
+func TestAncestorsAndDescendants(t *testing.T) {
+    graph := diamond()
+
+    ancestors, err := graph.Ancestors("D")
+    if err != nil {
+        t.Fatalf("Ancestors failed: %v", err)
+    }
+    if want := []string{"A", "B", "C"}; !reflect.DeepEqual(ancestors, want) {
+        t.Errorf("Ancestors(D) = %v, want %v", ancestors, want)
+    }
+
+    descendants, err := graph.Descendants("B")
+    if err != nil {
+        t.Fatalf("Descendants failed: %v", err)
+    }
+    if want := []string{"D", "E"}; !reflect.DeepEqual(descendants, want) {
+        t.Errorf("Descendants(B) = %v, want %v", descendants, want)
+    }
+
+    if _, err := graph.Ancestors("missing"); err == nil {
+        t.Errorf("Ancestors should fail for an unknown node")
+    }
+}
+
+func TestSubgraph(t *testing.T) {
+    graph := diamond()
+
+    sub, err := graph.Subgraph("B", "D", "E")
+    if err != nil {
+        t.Fatalf("Subgraph failed: %v", err)
+    }
+
+    if len(sub.nodes) != 3 {
+        t.Fatalf("expected 3 nodes, got %d", len(sub.nodes))
+    }
+    if len(sub.nodes["D"].parents) != 1 || sub.nodes["D"].parents[0] != sub.nodes["B"] {
+        t.Errorf("D should only depend on B in the subgraph")
+    }
+    if sub.nodes["B"] == graph.nodes["B"] {
+        t.Errorf("subgraph should not share nodes with the original graph")
+    }
+    if len(graph.nodes["D"].parents) != 2 {
+        t.Errorf("original graph should be unchanged")
+    }
+
+    if err := NewExecutor(sub).Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+
+    if _, err := graph.Subgraph("A", "missing"); err == nil {
+        t.Errorf("Subgraph should fail for an unknown node")
+    }
+}
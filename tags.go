@@ -0,0 +1,44 @@
+package leo
+
+import (
+    "sort"
+    "strings"
+)
+
+// WithTags labels a node, for example by team or resource, so that event
+// subscribers can select the nodes they care about. See EventFilter.
+func WithTags(tags ...string) NodeOption {
+    return func(n *Node) {
+        n.tags = append(n.tags, tags...)
+    }
+}
+
+// Tagged returns the names of the nodes with tag, sorted.
+func (g *Graph) Tagged(tag string) []string {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    var names []string
+    for _, node := range g.nodes {
+        if node.hasTag(tag) {
+            names = append(names, node.name)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+func (n *Node) hasTag(tag string) bool {
+    for _, t := range n.tags {
+        if t == tag {
+            return true
+        }
+    }
+    return false
+}
+
+// tagList returns the node's tags sorted and comma-separated, for metadata.
+func (n *Node) tagList() string {
+    tags := append([]string(nil), n.tags...)
+    sort.Strings(tags)
+    return strings.Join(tags, ",")
+}
@@ -0,0 +1,27 @@
+package leo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("a", func() error { return nil }, WithTags("db", "critical"))
+    graph.Add("b", func() error { return nil }, WithTags("critical"))
+    graph.Add("c", func() error { return nil })
+
+    if got := graph.Tagged("critical"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+        t.Errorf("unexpected tagged nodes %v", got)
+    }
+
+    other := TaskGraph()
+    other.Add("a", func() error { return nil }, WithTags("critical", "db"))
+    other.Add("b", func() error { return nil })
+    other.Add("c", func() error { return nil })
+
+    diff := Diff(graph, other)
+    if len(diff.Changed) != 1 || diff.Changed[0].Node != "b" || diff.Changed[0].Key != "tags" {
+        t.Errorf("expected only b's tags to change, got %v", diff)
+    }
+}
@@ -0,0 +1,82 @@
+package leo
+
+import "strings"
+
+// TaskError is the error a run returns when a task fails. Besides the task's
+// own error it records the run and the chain of nodes that led to the failed
+// one, so that a failure deep in a pipeline can be traced from a single log
+// line. Use errors.As to extract it:
+//
+//    var taskErr *leo.TaskError
+//    if errors.As(err, &taskErr) {
+//        log.Printf("run %s: %s failed after %v", taskErr.RunID, taskErr.Node, taskErr.Chain)
+//    }
+type TaskError struct {
+    RunID string
+    Node  string
+    // Chain lists the ancestors that led to Node, from a node without
+    // dependencies to the one that released Node: each node in the chain is
+    // the last dependency of the next to finish, or the failed node that
+    // triggered a fallback.
+    Chain []string
+    Err   error
+}
+
+func (e *TaskError) Error() string {
+    var b strings.Builder
+    b.WriteString("error executing node ")
+    b.WriteString(e.Node)
+    if len(e.Chain) > 0 {
+        b.WriteString(" (via ")
+        b.WriteString(strings.Join(e.Chain, " → "))
+        b.WriteString(")")
+    }
+    b.WriteString(" in run ")
+    b.WriteString(e.RunID)
+    b.WriteString(": ")
+    b.WriteString(e.Err.Error())
+    return b.String()
+}
+
+func (e *TaskError) Unwrap() error {
+    return e.Err
+}
+
+// NodeName returns the name of the node whose task failed.
+func (e *TaskError) NodeName() string {
+    return e.Node
+}
+
+// NodeError is an error attributed to a node of a graph, such as a
+// *TaskError. Errors returned by a run wrap the errors of the nodes that
+// caused them, including when a blocking edge aborts the run (see
+// EdgeBlock), so errors.As finds the node and errors.Is the task's own
+// error:
+//
+//    var nodeErr leo.NodeError
+//    if errors.As(err, &nodeErr) && errors.Is(err, sql.ErrConnDone) {
+//        log.Printf("%s lost its database connection", nodeErr.NodeName())
+//    }
+type NodeError interface {
+    error
+    NodeName() string
+}
+
+// taskError returns the error reported for n failing with err.
+func (r *Run) taskError(n *Node, err error) *TaskError {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.newTaskError(n, err)
+}
+
+// newTaskError is taskError for callers that hold r.mu.
+func (r *Run) newTaskError(n *Node, err error) *TaskError {
+    var chain []string
+    for p := r.via[n]; p != nil; p = r.via[p] {
+        chain = append(chain, p.name)
+    }
+    for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+        chain[i], chain[j] = chain[j], chain[i]
+    }
+    return &TaskError{RunID: r.id, Node: n.name, Chain: chain, Err: err}
+}
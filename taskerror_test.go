@@ -0,0 +1,94 @@
+package leo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskErrorChain(t *testing.T) {
+    boom := errors.New("boom")
+    graph := TaskGraph()
+    graph.Add("fetch", func() error { return nil })
+    graph.Add("slow", func() error {
+        time.Sleep(20 * time.Millisecond)
+        return nil
+    })
+    graph.Add("build", func() error { return nil })
+    graph.Add("deploy", func() error { return boom })
+    graph.Precede("fetch", "build")
+    graph.Precede("slow", "build")
+    graph.Precede("build", "deploy")
+
+    // fetch and slow run at once, so that slow finishes last.
+    run := NewExecutor(graph, WithConcurrency(-1)).NewRun()
+    err := run.Execute()
+    if !errors.Is(err, boom) {
+        t.Fatalf("Execute = %v, want boom", err)
+    }
+    var taskErr *TaskError
+    if !errors.As(err, &taskErr) {
+        t.Fatalf("Execute = %v, want a *TaskError", err)
+    }
+    if taskErr.Node != "deploy" || taskErr.RunID != run.ID() {
+        t.Errorf("TaskError = %+v, want node deploy in run %s", taskErr, run.ID())
+    }
+    if want := []string{"slow", "build"}; !reflect.DeepEqual(taskErr.Chain, want) {
+        t.Errorf("Chain = %v, want %v", taskErr.Chain, want)
+    }
+    if want := "error executing node deploy (via slow → build) in run " + run.ID() + ": boom"; err.Error() != want {
+        t.Errorf("Error() = %q, want %q", err.Error(), want)
+    }
+}
+
+func TestTaskErrorFallbackChain(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("migrate", func() error { return errors.New("locked") })
+    graph.Add("rollback", func() error { return errors.New("rollback failed") })
+    graph.OnFailure("migrate", "rollback")
+
+    err := NewExecutor(graph).Execute()
+    var taskErr *TaskError
+    if !errors.As(err, &taskErr) {
+        t.Fatalf("Execute = %v, want a *TaskError", err)
+    }
+    if taskErr.Node != "rollback" || !reflect.DeepEqual(taskErr.Chain, []string{"migrate"}) {
+        t.Errorf("TaskError = %+v, want rollback via migrate", taskErr)
+    }
+    if !strings.Contains(err.Error(), "rollback failed") {
+        t.Errorf("Error() = %q does not include the task's error", err.Error())
+    }
+}
+
+type quotaError struct {
+    limit int
+}
+
+func (e *quotaError) Error() string { return fmt.Sprintf("quota of %d exceeded", e.limit) }
+
+func TestNodeError(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("check", func() error { return fmt.Errorf("checking capacity: %w", &quotaError{limit: 3}) })
+    graph.Add("restore", func() error { return nil })
+    graph.Add("apply", func() error { return nil })
+    graph.Precede("check", "apply", OnParentFailure(EdgeBlock))
+    graph.OnFailure("check", "restore")
+
+    // The fallback handles check's failure, so the run fails because the
+    // blocking edge aborts it, with an error that still leads to check.
+    err := NewExecutor(graph).Execute()
+    if err == nil || !strings.HasPrefix(err.Error(), "run aborted: upstream check failed: ") {
+        t.Fatalf("Execute = %v, want the run to be aborted", err)
+    }
+    var nodeErr NodeError
+    if !errors.As(err, &nodeErr) || nodeErr.NodeName() != "check" {
+        t.Errorf("expected a NodeError for check, got %v", nodeErr)
+    }
+    var quota *quotaError
+    if !errors.As(err, &quota) || quota.limit != 3 {
+        t.Errorf("expected the task's own error type, got %v", quota)
+    }
+}
@@ -0,0 +1,67 @@
+package leo
+
+import "context"
+
+// WithTeardown sets the task that undoes a node's work, for example deleting
+// what the node created. Teardown tasks run only in reverse runs, see
+// Executor.ExecuteReverse.
+func WithTeardown(task TaskCtxFunc) NodeOption {
+    return func(n *Node) {
+        n.teardown = task
+    }
+}
+
+// ExecuteReverse tears down what Execute brings up: it runs each node's
+// teardown task in reverse dependency order, so that a node is torn down only
+// after every node that depends on it. Nodes without a teardown task succeed
+// immediately. A failed teardown skips the teardown of the node's
+// dependencies, leaving them in place for whatever still uses them.
+//
+// Stages run in reverse order. Fallback edges, edge options and node
+// settings other than tags and stage do not apply to reverse runs, which are
+// also not recorded in the executor's history or journal.
+func (e *Executor) ExecuteReverse() error {
+    return e.ExecuteReverseContext(context.Background())
+}
+
+// ExecuteReverseContext is ExecuteReverse with a context for the teardown
+// tasks.
+func (e *Executor) ExecuteReverseContext(ctx context.Context) error {
+    return e.NewReverseRun().ExecuteContext(ctx)
+}
+
+// NewReverseRun prepares a reverse run of the graph, see ExecuteReverse.
+func (e *Executor) NewReverseRun() *Run {
+    r := e.NewRun()
+    r.graph = e.graph.reversed()
+    r.reverse = true
+    return r
+}
+
+// reversed returns a graph with the same nodes, running their teardown tasks,
+// and every dependency edge pointing the other way.
+func (g *Graph) reversed() *Graph {
+    rev := TaskGraph()
+    for _, node := range g.startNodes {
+        task := node.teardown
+        if task == nil {
+            task = func(context.Context) error { return nil }
+        }
+        rev.AddCtx(node.name, task)
+        rn := rev.nodes[node.name]
+        rn.tags = node.tags
+        rn.stage = node.stage
+    }
+    for _, node := range g.nodes {
+        to := rev.nodes[node.name]
+        for _, child := range node.children {
+            from := rev.nodes[child.name]
+            from.children = append(from.children, to)
+            to.parents = append(to.parents, from)
+        }
+    }
+    for i := len(g.stages) - 1; i >= 0; i-- {
+        rev.stages = append(rev.stages, g.stages[i])
+    }
+    return rev
+}
@@ -0,0 +1,74 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestExecuteReverse(t *testing.T) {
+    var mu sync.Mutex
+    var order []string
+    record := func(name string) TaskCtxFunc {
+        return func(context.Context) error {
+            mu.Lock()
+            defer mu.Unlock()
+            order = append(order, name)
+            return nil
+        }
+    }
+
+    graph := TaskGraph()
+    graph.AddCtx("network", record("up network"), WithTeardown(record("down network")))
+    graph.AddCtx("db", record("up db"), WithTeardown(record("down db")))
+    graph.AddCtx("migrate", record("up migrate"))
+    graph.AddCtx("app", record("up app"), WithTeardown(record("down app")))
+    graph.Precede("network", "db")
+    graph.Precede("db", "migrate")
+    graph.Precede("migrate", "app")
+
+    executor := NewExecutor(graph)
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if want := []string{"up network", "up db", "up migrate", "up app"}; !reflect.DeepEqual(order, want) {
+        t.Errorf("unexpected bring-up order %v", order)
+    }
+
+    order = nil
+    if err := executor.ExecuteReverse(); err != nil {
+        t.Fatalf("ExecuteReverse failed: %v", err)
+    }
+    if want := []string{"down app", "down db", "down network"}; !reflect.DeepEqual(order, want) {
+        t.Errorf("unexpected teardown order %v", order)
+    }
+    if !executor.Report().Succeeded() || len(executor.Report().Nodes) != 4 {
+        t.Errorf("expected a successful teardown report of every node")
+    }
+}
+
+func TestExecuteReverseFailure(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("db", func() error { return nil }, WithTeardown(func(context.Context) error { return nil }))
+    graph.Add("app", func() error { return nil }, WithTeardown(func(context.Context) error {
+        return errors.New("still serving")
+    }))
+    graph.Precede("db", "app")
+
+    executor := NewExecutor(graph)
+    if err := executor.ExecuteReverse(); err == nil {
+        t.Fatalf("expected the teardown to fail")
+    }
+    if nr := executor.Report().Nodes["db"]; nr.State != StateSkipped {
+        t.Errorf("expected db to be left in place, got %v", nr.State)
+    }
+
+    history := NewMemoryHistory(10)
+    executor.SetHistory(history)
+    executor.ExecuteReverse()
+    if runs, _ := history.Runs(0); len(runs) != 0 {
+        t.Errorf("expected reverse runs to stay out of the history, got %d", len(runs))
+    }
+}
@@ -0,0 +1,256 @@
+package leo
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Span is a trace record of a run or of one of its tasks, in the shape used
+// by tracing systems but without depending on one. TraceID is the run's ID;
+// a run's span has the run's ID as its SpanID, and its tasks' spans have it
+// as their ParentID. Status is "ok", "error" or, for tasks, "skipped".
+type Span struct {
+    TraceID    string            `json:"trace_id"`
+    SpanID     string            `json:"span_id"`
+    ParentID   string            `json:"parent_id,omitempty"`
+    Name       string            `json:"name"`
+    Start      time.Time         `json:"start"`
+    End        time.Time         `json:"end"`
+    Status     string            `json:"status"`
+    Error      string            `json:"error,omitempty"`
+    Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Duration returns the span's duration.
+func (s Span) Duration() time.Duration {
+    return s.End.Sub(s.Start)
+}
+
+// Tracer writes a Span for each finished run and task as a line of JSON, for
+// users without a tracing collector. Read the spans back with ReadTrace, or
+// view them as a timeline with WriteTimeline or the leo command's trace
+// subcommand:
+//
+//    f, _ := os.Create("trace.jsonl")
+//    defer leo.NewTracer(f).Observe(executor)()
+//
+// Attributes record the run's name and labels on the run's span and the
+// task's tags and skip reason on a task's span.
+type Tracer struct {
+    mu  sync.Mutex
+    enc *json.Encoder
+    err error
+}
+
+// NewTracer returns a tracer writing to w.
+func NewTracer(w io.Writer) *Tracer {
+    return &Tracer{enc: json.NewEncoder(w)}
+}
+
+// Observe traces the runs of e until the returned function is called. A
+// Tracer may observe several executors.
+func (t *Tracer) Observe(e *Executor) (stop func()) {
+    sub := e.SubscribeFilter(EventFilter{Types: []EventType{
+        EventTaskFinished, EventTaskFailed, EventTaskSkipped, EventRunFinished,
+    }})
+    finished := make(chan struct{})
+    go func() {
+        defer close(finished)
+        for ev := range sub.C {
+            t.Handle(ev)
+        }
+    }()
+    return func() {
+        sub.closeWhenDrained()
+        <-finished
+    }
+}
+
+// Handle writes the span that ev ends, if any.
+func (t *Tracer) Handle(ev Event) {
+    if ev.Run == nil {
+        return
+    }
+    span := Span{
+        TraceID: ev.Run.id,
+        Start:   ev.Time.Add(-ev.Duration),
+        End:     ev.Time,
+        Status:  "ok",
+    }
+    if ev.Err != nil {
+        span.Status = "error"
+        span.Error = ev.Err.Error()
+    }
+    attrs := make(map[string]string)
+    switch ev.Type {
+    case EventRunFinished:
+        span.SpanID = ev.Run.id
+        span.Name = ev.Run.name
+        if span.Name == "" {
+            span.Name = "run"
+        }
+        for k, v := range ev.Run.labels {
+            attrs["label."+k] = v
+        }
+    case EventTaskFinished, EventTaskFailed, EventTaskSkipped:
+        span.SpanID = ev.Run.id + "/" + ev.Node
+        span.ParentID = ev.Run.id
+        span.Name = ev.Node
+        if n := ev.Run.graph.nodes[ev.Node]; n != nil && len(n.tags) > 0 {
+            attrs["tags"] = n.tagList()
+        }
+        if ev.Type == EventTaskSkipped {
+            span.Status = "skipped"
+            attrs["skip_reason"] = ev.Reason
+        }
+    default:
+        return
+    }
+    if len(attrs) > 0 {
+        span.Attributes = attrs
+    }
+
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if err := t.enc.Encode(span); err != nil && t.err == nil {
+        t.err = err
+    }
+}
+
+// Err returns the first error writing a span.
+func (t *Tracer) Err() error {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    return t.err
+}
+
+// ReadTrace reads the spans written by a Tracer. Blank lines are ignored.
+func ReadTrace(r io.Reader) ([]Span, error) {
+    var spans []Span
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(nil, 1<<20)
+    for line := 1; scanner.Scan(); line++ {
+        if strings.TrimSpace(scanner.Text()) == "" {
+            continue
+        }
+        var s Span
+        if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+            return nil, fmt.Errorf("line %d: %w", line, err)
+        }
+        spans = append(spans, s)
+    }
+    return spans, scanner.Err()
+}
+
+// WriteTimeline renders spans as a text timeline, one chart per run, with a
+// row per task showing when it ran relative to the start of the run, scaled
+// to width columns:
+//
+//    deploy 3f2a9c1e0b7d4a65, 1.2s, ok
+//    build   |██████████                              | 300ms
+//    test    |          ███████████████████████████   | 810ms
+//    notify  |                                     ·  | skipped
+//
+// Runs are in the order they first appear in spans, and tasks in order of
+// their start, then name. A run's chart spans its tasks even if the run's own span is missing,
+// as it is for a run that was still going when the trace was read.
+func WriteTimeline(w io.Writer, spans []Span, width int) error {
+    if width < 10 {
+        width = 10
+    }
+    runs := make(map[string]*Span)
+    tasks := make(map[string][]Span)
+    seen := make(map[string]bool)
+    var order []string
+    for i := range spans {
+        s := spans[i]
+        if !seen[s.TraceID] {
+            seen[s.TraceID] = true
+            order = append(order, s.TraceID)
+        }
+        if s.ParentID == "" {
+            runs[s.TraceID] = &s
+        } else {
+            tasks[s.TraceID] = append(tasks[s.TraceID], s)
+        }
+    }
+
+    bw := bufio.NewWriter(w)
+    for _, id := range order {
+        run := runs[id]
+        ts := tasks[id]
+        sort.SliceStable(ts, func(i, j int) bool {
+            if !ts[i].Start.Equal(ts[j].Start) {
+                return ts[i].Start.Before(ts[j].Start)
+            }
+            return ts[i].Name < ts[j].Name
+        })
+
+        var start, end time.Time
+        if run != nil {
+            start, end = run.Start, run.End
+        }
+        for _, s := range ts {
+            if start.IsZero() || s.Start.Before(start) {
+                start = s.Start
+            }
+            if s.End.After(end) {
+                end = s.End
+            }
+        }
+        total := end.Sub(start)
+
+        switch {
+        case run != nil && run.Error != "":
+            fmt.Fprintf(bw, "%s %s, %s, %s: %s\n", run.Name, id, total, run.Status, run.Error)
+        case run != nil:
+            fmt.Fprintf(bw, "%s %s, %s, %s\n", run.Name, id, total, run.Status)
+        default:
+            fmt.Fprintf(bw, "run %s, %s, unfinished\n", id, total)
+        }
+
+        nameWidth := 0
+        for _, s := range ts {
+            if len(s.Name) > nameWidth {
+                nameWidth = len(s.Name)
+            }
+        }
+        column := func(t time.Time) int {
+            if total <= 0 {
+                return 0
+            }
+            c := int(int64(width) * int64(t.Sub(start)) / int64(total))
+            if c >= width {
+                c = width - 1
+            }
+            return c
+        }
+        for _, s := range ts {
+            bar := []rune(strings.Repeat(" ", width))
+            from, to := column(s.Start), column(s.End)
+            mark := '█'
+            if s.Status == "skipped" {
+                mark = '·'
+            }
+            for c := from; c <= to; c++ {
+                bar[c] = mark
+            }
+            outcome := s.Duration().String()
+            switch s.Status {
+            case "skipped":
+                outcome = "skipped"
+            case "error":
+                outcome += " failed: " + s.Error
+            }
+            fmt.Fprintf(bw, "%-*s |%s| %s\n", nameWidth, s.Name, string(bar), outcome)
+        }
+        fmt.Fprintln(bw)
+    }
+    return bw.Flush()
+}
@@ -0,0 +1,89 @@
+package leo
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracer(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("ok", func() error { return nil }, WithTags("io"))
+    graph.Add("bad", func() error { return errors.New("boom") })
+    graph.Add("after", func() error { return nil })
+    graph.Precede("ok", "bad")
+    graph.Precede("bad", "after")
+
+    var buf bytes.Buffer
+    executor := NewExecutor(graph)
+    tracer := NewTracer(&buf)
+    stop := tracer.Observe(executor)
+    run := executor.NewRun()
+    run.SetName("nightly")
+    run.SetLabels(map[string]string{"env": "prod"})
+    run.Execute()
+    stop()
+    if err := tracer.Err(); err != nil {
+        t.Fatalf("Err = %v", err)
+    }
+
+    spans, err := ReadTrace(&buf)
+    if err != nil {
+        t.Fatalf("ReadTrace failed: %v", err)
+    }
+    byName := make(map[string]Span)
+    for _, s := range spans {
+        if s.TraceID != run.ID() {
+            t.Errorf("span %s has trace %s, want %s", s.Name, s.TraceID, run.ID())
+        }
+        byName[s.Name] = s
+    }
+    if len(byName) != 4 {
+        t.Fatalf("got spans %v, want 4", spans)
+    }
+    if s := byName["nightly"]; s.ParentID != "" || s.Status != "error" || s.Attributes["label.env"] != "prod" {
+        t.Errorf("run span = %+v", s)
+    }
+    if s := byName["ok"]; s.ParentID != run.ID() || s.Status != "ok" || s.Attributes["tags"] != "io" || s.End.Before(s.Start) {
+        t.Errorf("ok span = %+v", s)
+    }
+    if s := byName["bad"]; s.Status != "error" || s.Error != "boom" {
+        t.Errorf("bad span = %+v", s)
+    }
+    if s := byName["after"]; s.Status != "skipped" || s.Attributes["skip_reason"] != "upstream bad failed" {
+        t.Errorf("after span = %+v", s)
+    }
+
+    if _, err := ReadTrace(strings.NewReader("{}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+        t.Errorf("ReadTrace of a bad line = %v", err)
+    }
+}
+
+func TestWriteTimeline(t *testing.T) {
+    start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+    spans := []Span{
+        {TraceID: "r1", SpanID: "r1/build", ParentID: "r1", Name: "build", Start: at(0), End: at(400), Status: "ok"},
+        {TraceID: "r1", SpanID: "r1/test", ParentID: "r1", Name: "test", Start: at(400), End: at(1000), Status: "error", Error: "boom"},
+        {TraceID: "r1", SpanID: "r1/notify", ParentID: "r1", Name: "notify", Start: at(1000), End: at(1000), Status: "skipped"},
+        {TraceID: "r1", SpanID: "r1", Name: "deploy", Start: at(0), End: at(1000), Status: "error", Error: "test failed"},
+        {TraceID: "r2", SpanID: "r2/build", ParentID: "r2", Name: "build", Start: at(2000), End: at(2500), Status: "ok"},
+    }
+    var buf bytes.Buffer
+    if err := WriteTimeline(&buf, spans, 10); err != nil {
+        t.Fatalf("WriteTimeline failed: %v", err)
+    }
+    want := "deploy r1, 1s, error: test failed\n" +
+        "build  |█████     | 400ms\n" +
+        "test   |    ██████| 600ms failed: boom\n" +
+        "notify |         ·| skipped\n" +
+        "\n" +
+        "run r2, 500ms, unfinished\n" +
+        "build |██████████| 500ms\n" +
+        "\n"
+    if buf.String() != want {
+        t.Errorf("timeline =\n%s\nwant\n%s", buf.String(), want)
+    }
+}
@@ -0,0 +1,131 @@
+package leo
+
+import (
+    "context"
+    "errors"
+    "fmt"
+)
+
+// Transaction is a two-phase commit for the side effects of an atomic group,
+// such as a configuration pushed to a fleet of devices that must only take
+// effect once every device has accepted it. Any of its functions may be nil.
+type Transaction struct {
+    // Prepare runs once, before the first member of the group starts. If it
+    // fails, the member fails with its error, and so does the group.
+    Prepare func(ctx context.Context) error
+    // Commit runs once every member has finished or been skipped, if the
+    // group was prepared and no member failed. If it fails, the group fails
+    // with its error.
+    Commit func(ctx context.Context) error
+    // Abort runs instead of Commit if the group was prepared and failed,
+    // with the group's error, once every member has finished or been
+    // skipped.
+    Abort func(ctx context.Context, cause error) error
+}
+
+// Transact attaches tx to group, see Group. The edges leaving the group are
+// released once Commit or Abort has returned, so that nodes outside the group
+// only see committed effects. A failed Commit or Abort fails the run, even if
+// the group has a compensation; a compensation runs alongside Abort.
+//
+// Runs executed with FailAbort, the default, may return before Abort has
+// run, as they do before the tasks still running have finished; use
+// FailDrain to wait for it.
+func (g *Graph) Transact(group string, tx Transaction) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    if len(g.groupMembers(group)) == 0 {
+        return fmt.Errorf("group %s does not exist", group)
+    }
+    if g.transactions == nil {
+        g.transactions = make(map[string]*Transaction)
+    }
+    g.transactions[group] = &tx
+    return nil
+}
+
+// preparation is the progress of a group's Prepare in a run.
+type preparation struct {
+    done chan struct{}
+    err  error
+}
+
+// prepareGroup runs the Prepare of n's group's transaction if n is the first
+// member to start, or waits for it otherwise, and returns its error.
+func (r *Run) prepareGroup(n *Node) error {
+    tx := r.graph.transactions[n.group]
+    if r.groups == nil || n.group == "" || tx == nil {
+        return nil
+    }
+    r.mu.Lock()
+    p, started := r.groups.prepared[n.group]
+    if !started {
+        p = &preparation{done: make(chan struct{})}
+        r.groups.prepared[n.group] = p
+    }
+    r.mu.Unlock()
+
+    if started {
+        <-p.done
+    } else {
+        if tx.Prepare != nil {
+            p.err = tx.Prepare(r.ctx)
+        }
+        close(p.done)
+    }
+    if p.err != nil {
+        return fmt.Errorf("preparing group %s: %w", n.group, p.err)
+    }
+    return nil
+}
+
+// completeTransaction commits or aborts the transaction of the group name,
+// which has finished with err, and then ends the group. The caller must have
+// added it to r.wg.
+func (r *Run) completeTransaction(name string, tx *Transaction, p *preparation, err error) {
+    defer r.wg.Done()
+    <-p.done
+    var committed, aborted bool
+    var txErr error
+    if err == nil {
+        if tx.Commit != nil {
+            if cerr := tx.Commit(r.ctx); cerr != nil {
+                txErr = fmt.Errorf("committing group %s: %w", name, cerr)
+                err = txErr
+            }
+        }
+        committed = err == nil
+    } else {
+        if tx.Abort != nil {
+            if aerr := tx.Abort(r.ctx, err); aerr != nil {
+                txErr = fmt.Errorf("aborting group %s: %w", name, aerr)
+                err = errors.Join(err, txErr)
+            }
+        }
+        aborted = true
+    }
+    if txErr != nil {
+        select {
+        case r.errs <- txErr:
+        default:
+        }
+    }
+
+    r.mu.Lock()
+    defer r.unlock()
+    r.report.transacted(name, committed, aborted)
+    r.endGroup(name, err)
+}
+
+func (r *Report) transacted(name string, committed, aborted bool) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.final {
+        return
+    }
+    r.Groups[name].Committed = committed
+    r.Groups[name].Aborted = aborted
+}
@@ -0,0 +1,118 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fleet returns a graph pushing a configuration to devices in group "push",
+// followed by a "verify" node outside it, and the log of what ran.
+func fleet(devices map[string]error) (*Graph, func() []string, func(string)) {
+    var mu sync.Mutex
+    var log []string
+    record := func(s string) {
+        mu.Lock()
+        log = append(log, s)
+        mu.Unlock()
+    }
+    graph := TaskGraph()
+    var members []string
+    for name, err := range devices {
+        name, err := name, err
+        graph.Add(name, func() error { record(name); return err })
+        members = append(members, name)
+    }
+    graph.Add("verify", func() error { record("verify"); return nil })
+    for _, name := range members {
+        graph.Precede(name, "verify")
+    }
+    graph.Group("push", members...)
+    return graph, func() []string {
+        mu.Lock()
+        defer mu.Unlock()
+        return append([]string(nil), log...)
+    }, record
+}
+
+func TestTransact(t *testing.T) {
+    graph, log, record := fleet(map[string]error{"r1": nil, "r2": nil, "r3": nil})
+    err := graph.Transact("push", Transaction{
+        Prepare: func(context.Context) error { record("prepare"); return nil },
+        Commit:  func(context.Context) error { record("commit"); return nil },
+        Abort:   func(context.Context, error) error { record("abort"); return nil },
+    })
+    if err != nil {
+        t.Fatalf("Transact failed: %v", err)
+    }
+    executor := NewExecutor(graph, WithConcurrency(-1))
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    got := log()
+    if len(got) != 6 || got[0] != "prepare" || got[4] != "commit" || got[5] != "verify" {
+        t.Errorf("expected prepare, the devices, commit and verify, got %v", got)
+    }
+    if group, _ := executor.Report().Group("push"); group.State != StateSucceeded || !group.Committed || group.Aborted {
+        t.Errorf("unexpected group report %+v", group)
+    }
+}
+
+func TestTransactAborts(t *testing.T) {
+    graph, log, record := fleet(map[string]error{"r1": nil, "r2": errors.New("rejected")})
+    var cause error
+    graph.Transact("push", Transaction{
+        Commit: func(context.Context) error { record("commit"); return nil },
+        Abort:  func(_ context.Context, err error) error { cause = err; record("abort"); return nil },
+    })
+    executor := NewExecutor(graph, WithFailureMode(FailDrain))
+    if err := executor.Execute(); err == nil || !strings.Contains(err.Error(), "rejected") {
+        t.Fatalf("expected the device's failure, got %v", err)
+    }
+    got := strings.Join(log(), " ")
+    if strings.Contains(got, "commit") || strings.Contains(got, "verify") || !strings.HasSuffix(got, "abort") {
+        t.Errorf("expected only an abort after the devices, got %s", got)
+    }
+    if cause == nil || !strings.Contains(cause.Error(), "rejected") {
+        t.Errorf("expected Abort to receive the failure, got %v", cause)
+    }
+    if group, _ := executor.Report().Group("push"); group.State != StateFailed || !group.Aborted || group.Committed {
+        t.Errorf("unexpected group report %+v", group)
+    }
+}
+
+func TestTransactPrepareAndCommitFailures(t *testing.T) {
+    graph, log, record := fleet(map[string]error{"r1": nil, "r2": nil})
+    graph.Transact("push", Transaction{
+        Prepare: func(context.Context) error { return errors.New("lock held") },
+        Abort:   func(context.Context, error) error { record("abort"); return nil },
+    })
+    err := NewExecutor(graph, WithFailureMode(FailDrain), WithConcurrency(1)).Execute()
+    if err == nil || !strings.Contains(err.Error(), "preparing group push: lock held") {
+        t.Errorf("expected the prepare error, got %v", err)
+    }
+    if got := log(); len(got) != 1 || got[0] != "abort" {
+        t.Errorf("expected no device to run and the group to abort, got %v", got)
+    }
+
+    graph, log, _ = fleet(map[string]error{"r1": nil})
+    graph.Transact("push", Transaction{
+        Commit: func(context.Context) error { return errors.New("quorum lost") },
+    })
+    executor := NewExecutor(graph, WithFailureMode(FailDrain))
+    if err := executor.Execute(); err == nil || !strings.Contains(err.Error(), "committing group push: quorum lost") {
+        t.Errorf("expected the commit error, got %v", err)
+    }
+    if nr := executor.Report().Nodes["verify"]; nr.State != StateSkipped || nr.SkipReason != "group push failed" {
+        t.Errorf("expected verify to be skipped, got %+v", nr)
+    }
+    if got := log(); len(got) != 1 {
+        t.Errorf("unexpected log %v", got)
+    }
+
+    if err := graph.Transact("nope", Transaction{}); err == nil {
+        t.Errorf("expected an unknown group to fail")
+    }
+}
@@ -0,0 +1,150 @@
+package leo
+
+import (
+    "errors"
+    "sort"
+)
+
+// ExecutionMode controls how an executor schedules ready tasks.
+type ExecutionMode int
+
+const (
+    // ModeConcurrent starts each task as soon as its dependencies are met.
+    // This is the default.
+    ModeConcurrent ExecutionMode = iota
+    // ModeWave runs the graph level by level: every task of a level (see
+    // Graph.Levels) finishes or is skipped before any task of the next level
+    // starts. Graphs with streaming edges cannot run in this mode.
+    ModeWave
+)
+
+func (m ExecutionMode) String() string {
+    switch m {
+    case ModeConcurrent:
+        return "concurrent"
+    case ModeWave:
+        return "wave"
+    }
+    return "unknown"
+}
+
+// SetMode sets the execution mode of subsequent runs.
+func (e *Executor) SetMode(m ExecutionMode) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.mode = m
+}
+
+func (e *Executor) getMode() ExecutionMode {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.mode
+}
+
+// Levels returns the graph's nodes grouped by topological level, with names
+// sorted within each level. Nodes without dependencies are at level 0, and
+// every other node is one level below its deepest dependency, counting
+// fallback edges.
+func (g *Graph) Levels() [][]string {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    var levels [][]string
+    for node, level := range g.levels() {
+        for len(levels) <= level {
+            levels = append(levels, nil)
+        }
+        levels[level] = append(levels[level], node.name)
+    }
+    for _, names := range levels {
+        sort.Strings(names)
+    }
+    return levels
+}
+
+// levels returns the topological level of every node.
+func (g *Graph) levels() map[*Node]int {
+    levels := make(map[*Node]int, len(g.nodes))
+    var visit func(n *Node) int
+    visit = func(n *Node) int {
+        if l, ok := levels[n]; ok {
+            return l
+        }
+        l := 0
+        for _, p := range n.predecessors() {
+            if pl := visit(p) + 1; pl > l {
+                l = pl
+            }
+        }
+        levels[n] = l
+        return l
+    }
+    for _, node := range g.nodes {
+        visit(node)
+    }
+    return levels
+}
+
+// waves tracks the progress of a run in ModeWave.
+type waves struct {
+    level   map[*Node]int
+    pending []int
+    held    [][]*Node
+    current int
+}
+
+// initWaves prepares the run for ModeWave.
+func (r *Run) initWaves() error {
+    g := r.graph
+    for _, node := range g.nodes {
+        for _, ed := range node.edges {
+            if ed.stream {
+                return errors.New("wave mode does not support streaming edges")
+            }
+        }
+    }
+
+    w := &waves{}
+    if g.plan != nil {
+        w.level = g.plan.levels
+    } else {
+        w.level = g.levels()
+    }
+    for _, l := range w.level {
+        for len(w.pending) <= l {
+            w.pending = append(w.pending, 0)
+            w.held = append(w.held, nil)
+        }
+        w.pending[l]++
+    }
+    r.waves = w
+    return nil
+}
+
+// hold defers n until its level is reached, reporting whether it did. The
+// caller must hold r.mu.
+func (r *Run) hold(n *Node) bool {
+    w := r.waves
+    if w == nil || w.level[n] <= w.current {
+        return false
+    }
+    w.held[w.level[n]] = append(w.held[w.level[n]], n)
+    return true
+}
+
+// resolved records that n has finished or been skipped, starting the next
+// level once every node of the current one has. The caller must hold r.mu.
+func (r *Run) resolved(n *Node) {
+    w := r.waves
+    if w == nil {
+        return
+    }
+    w.pending[w.level[n]]--
+    for w.current < len(w.pending)-1 && w.pending[w.current] == 0 {
+        w.current++
+        held := w.held[w.current]
+        w.held[w.current] = nil
+        for _, h := range r.tieOrder(held) {
+            r.dispatch(h)
+        }
+    }
+}
@@ -0,0 +1,78 @@
+package leo
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWaveMode(t *testing.T) {
+    var mu sync.Mutex
+    var order []string
+    record := func(name string, d time.Duration) TaskFunc {
+        return func() error {
+            time.Sleep(d)
+            mu.Lock()
+            order = append(order, name)
+            mu.Unlock()
+            return nil
+        }
+    }
+
+    graph := TaskGraph()
+    graph.Add("slow", record("slow", 30*time.Millisecond))
+    graph.Add("fast", record("fast", 0))
+    graph.Add("next", record("next", 0))
+    graph.Add("last", record("last", 0))
+    graph.Precede("fast", "next")
+    graph.Precede("next", "last")
+    graph.Precede("slow", "last")
+
+    if got := graph.Levels(); !reflect.DeepEqual(got, [][]string{{"fast", "slow"}, {"next"}, {"last"}}) {
+        t.Errorf("unexpected levels %v", got)
+    }
+
+    executor := NewExecutor(graph)
+    executor.SetConcurrency(-1)
+    executor.Execute()
+    if order[1] != "next" {
+        t.Errorf("expected next to run before slow finished in concurrent mode, got %v", order)
+    }
+
+    order = nil
+    executor.SetMode(ModeWave)
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if !reflect.DeepEqual(order[1:], []string{"slow", "next", "last"}) {
+        t.Errorf("expected next to wait for the first level, got %v", order)
+    }
+}
+
+func TestWaveModeSkips(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("a", func() error { return nil })
+    graph.Add("b", func() error { return nil })
+    graph.Add("c", func() error { return nil })
+    graph.Precede("a", "b", OnParentFailure(EdgeRelease))
+    graph.Precede("b", "c")
+
+    executor := NewExecutor(graph)
+    executor.SetMode(ModeWave)
+    run := executor.NewRun()
+    run.Disable("a")
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if run.Report().Nodes["c"].State != StateSucceeded {
+        t.Errorf("expected every level to run")
+    }
+
+    graph.AddCtx("producer", nil)
+    graph.AddCtx("consumer", nil)
+    graph.Precede("producer", "consumer", Stream(0))
+    if err := executor.Execute(); err == nil {
+        t.Errorf("expected streaming edges to be rejected in wave mode")
+    }
+}
@@ -0,0 +1,290 @@
+package leo
+
+import (
+    "bufio"
+    "crypto/sha1"
+    "encoding/base64"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// EventMessage is the JSON form of an Event sent by WebSocketHandler.
+type EventMessage struct {
+    // Sequence numbers the messages of a connection from 1.
+    Sequence  uint64            `json:"sequence"`
+    Type      string            `json:"type"`
+    Time      time.Time         `json:"time"`
+    RunID     string            `json:"run_id,omitempty"`
+    RunName   string            `json:"run_name,omitempty"`
+    RunLabels map[string]string `json:"run_labels,omitempty"`
+    Node      string            `json:"node,omitempty"`
+    Duration  time.Duration     `json:"duration,omitempty"`
+    Err       string            `json:"error,omitempty"`
+    Reason    string            `json:"reason,omitempty"`
+    ETA       time.Duration     `json:"eta,omitempty"`
+}
+
+// newEventMessage returns the message for ev, with its error redacted of the
+// run's secrets.
+func newEventMessage(seq uint64, ev Event) EventMessage {
+    m := EventMessage{
+        Sequence: seq,
+        Type:     ev.Type.String(),
+        Time:     ev.Time,
+        Node:     ev.Node,
+        Duration: ev.Duration,
+        Reason:   ev.Reason,
+        ETA:      ev.ETA,
+    }
+    if r := ev.Run; r != nil {
+        m.RunID = r.id
+        m.RunName = r.name
+        m.RunLabels = r.labels
+    }
+    if ev.Err != nil {
+        m.Err = ev.Err.Error()
+        if ev.Run != nil {
+            m.Err = ev.Run.redact(m.Err)
+        }
+    }
+    return m
+}
+
+// websocketGUID is appended to the client's key to compute the accept key,
+// see RFC 6455.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes.
+const (
+    wsText  = 0x1
+    wsClose = 0x8
+    wsPing  = 0x9
+    wsPong  = 0xA
+)
+
+// maxWebSocketFrame bounds the size of frames read from clients, which only
+// send control frames.
+const maxWebSocketFrame = 1 << 16
+
+// WebSocketHandler returns an HTTP handler that upgrades requests to
+// WebSocket connections and pushes the executor's events to them as JSON
+// text messages, one EventMessage each, in the order they were published,
+// so that dashboards update live without polling. Query parameters select
+// events like an EventFilter and may be repeated: type (such as
+// TaskStarted), node (a glob pattern), tag, and label (name=value). The
+// handler accepts any origin; wrap it to restrict cross-origin browsers.
+func (e *Executor) WebSocketHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+            http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+            return
+        }
+        if r.Header.Get("Sec-WebSocket-Version") != "13" {
+            w.Header().Set("Sec-WebSocket-Version", "13")
+            http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+            return
+        }
+        key := r.Header.Get("Sec-WebSocket-Key")
+        if key == "" {
+            http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+            return
+        }
+        filter, err := parseEventQuery(r.URL.Query())
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        hijacker, ok := w.(http.Hijacker)
+        if !ok {
+            http.Error(w, "WebSocket upgrade is not supported by this server", http.StatusInternalServerError)
+            return
+        }
+
+        sub := e.SubscribeFilter(filter)
+        defer sub.Close()
+        conn, rw, err := hijacker.Hijack()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        sum := sha1.Sum([]byte(key + websocketGUID))
+        fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+            base64.StdEncoding.EncodeToString(sum[:]))
+        if err := rw.Flush(); err != nil {
+            return
+        }
+
+        ws := &wsConn{conn: conn}
+        closed := make(chan struct{})
+        go func() {
+            defer close(closed)
+            ws.readControl(rw.Reader)
+        }()
+        var seq uint64
+        for {
+            select {
+            case ev := <-sub.C:
+                seq++
+                data, err := json.Marshal(newEventMessage(seq, ev))
+                if err != nil {
+                    continue
+                }
+                if err := ws.write(wsText, data); err != nil {
+                    return
+                }
+            case <-closed:
+                return
+            case <-r.Context().Done():
+                ws.write(wsClose, nil)
+                return
+            }
+        }
+    })
+}
+
+// wsConn is the server side of a WebSocket connection.
+type wsConn struct {
+    conn net.Conn
+    mu   sync.Mutex
+}
+
+// write sends an unfragmented, unmasked frame.
+func (c *wsConn) write(opcode byte, payload []byte) error {
+    header := []byte{0x80 | opcode}
+    switch n := len(payload); {
+    case n < 126:
+        header = append(header, byte(n))
+    case n <= 0xFFFF:
+        header = append(header, 126)
+        header = binary.BigEndian.AppendUint16(header, uint16(n))
+    default:
+        header = append(header, 127)
+        header = binary.BigEndian.AppendUint64(header, uint64(n))
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+    _, err := c.conn.Write(append(header, payload...))
+    return err
+}
+
+// readControl reads the client's frames until it closes the connection,
+// answering pings and echoing the close frame. Data frames are ignored.
+func (c *wsConn) readControl(r *bufio.Reader) {
+    for {
+        opcode, payload, err := readWebSocketFrame(r)
+        if err != nil {
+            return
+        }
+        switch opcode {
+        case wsPing:
+            c.write(wsPong, payload)
+        case wsClose:
+            if len(payload) >= 2 {
+                payload = payload[:2]
+            }
+            c.write(wsClose, payload)
+            return
+        }
+    }
+}
+
+// readWebSocketFrame reads a frame sent by a client, which must be masked,
+// and returns its opcode and unmasked payload.
+func readWebSocketFrame(r *bufio.Reader) (opcode byte, payload []byte, err error) {
+    var head [2]byte
+    if _, err := io.ReadFull(r, head[:]); err != nil {
+        return 0, nil, err
+    }
+    opcode = head[0] & 0x0F
+    if head[1]&0x80 == 0 {
+        return 0, nil, errors.New("websocket: unmasked client frame")
+    }
+    size := uint64(head[1] & 0x7F)
+    switch size {
+    case 126:
+        var ext [2]byte
+        if _, err := io.ReadFull(r, ext[:]); err != nil {
+            return 0, nil, err
+        }
+        size = uint64(binary.BigEndian.Uint16(ext[:]))
+    case 127:
+        var ext [8]byte
+        if _, err := io.ReadFull(r, ext[:]); err != nil {
+            return 0, nil, err
+        }
+        size = binary.BigEndian.Uint64(ext[:])
+    }
+    if size > maxWebSocketFrame {
+        return 0, nil, fmt.Errorf("websocket: frame of %d bytes is too large", size)
+    }
+    var mask [4]byte
+    if _, err := io.ReadFull(r, mask[:]); err != nil {
+        return 0, nil, err
+    }
+    payload = make([]byte, size)
+    if _, err := io.ReadFull(r, payload); err != nil {
+        return 0, nil, err
+    }
+    for i := range payload {
+        payload[i] ^= mask[i%4]
+    }
+    return opcode, payload, nil
+}
+
+// headerContains reports whether the comma-separated header name contains
+// token, ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+    for _, v := range h.Values(name) {
+        for _, t := range strings.Split(v, ",") {
+            if strings.EqualFold(strings.TrimSpace(t), token) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// parseEventQuery returns the EventFilter described by query parameters:
+// type, node, tag and label (name=value), each of which may be repeated.
+func parseEventQuery(q map[string][]string) (EventFilter, error) {
+    var f EventFilter
+    for _, name := range q["type"] {
+        t, err := parseEventType(name)
+        if err != nil {
+            return f, err
+        }
+        f.Types = append(f.Types, t)
+    }
+    f.Nodes = q["node"]
+    f.Tags = q["tag"]
+    for _, label := range q["label"] {
+        k, v, ok := strings.Cut(label, "=")
+        if !ok {
+            return f, fmt.Errorf("label %q is not name=value", label)
+        }
+        if f.Labels == nil {
+            f.Labels = make(map[string]string)
+        }
+        f.Labels[k] = v
+    }
+    return f, nil
+}
+
+// parseEventType returns the EventType whose String is name.
+func parseEventType(name string) (EventType, error) {
+    for t := EventTaskQueued; t <= EventRunFinished; t++ {
+        if t.String() == name {
+            return t, nil
+        }
+    }
+    return 0, fmt.Errorf("unknown event type %q", name)
+}
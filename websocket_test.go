@@ -0,0 +1,130 @@
+package leo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebSocketHandler(t *testing.T) {
+    graph := TaskGraph()
+    graph.Add("build", func() error { return nil })
+    graph.Add("test", func() error { return errors.New("flaky") })
+    graph.Precede("build", "test")
+    executor := NewExecutor(graph)
+
+    srv := httptest.NewServer(executor.WebSocketHandler())
+    defer srv.Close()
+
+    conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer conn.Close()
+    io.WriteString(conn, "GET /?type=TaskFinished&type=TaskFailed&type=RunFinished HTTP/1.1\r\n"+
+        "Host: leo\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+        "Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+    r := bufio.NewReader(conn)
+    resp, err := http.ReadResponse(r, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if resp.StatusCode != http.StatusSwitchingProtocols {
+        t.Fatalf("expected 101, got %s", resp.Status)
+    }
+    // The accept key for the sample nonce of RFC 6455.
+    if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+        t.Errorf("unexpected Sec-WebSocket-Accept %q", accept)
+    }
+
+    if err := executor.Execute(); err == nil {
+        t.Fatalf("expected the run to fail")
+    }
+    var got []EventMessage
+    for len(got) == 0 || got[len(got)-1].Type != "RunFinished" {
+        opcode, payload := readServerFrame(t, r)
+        if opcode != wsText {
+            t.Fatalf("expected a text frame, got opcode %d", opcode)
+        }
+        var m EventMessage
+        if err := json.Unmarshal(payload, &m); err != nil {
+            t.Fatalf("decoding %s: %v", payload, err)
+        }
+        got = append(got, m)
+    }
+    if len(got) != 3 || got[0].Node != "build" || got[1].Node != "test" || got[1].Err != "flaky" {
+        t.Fatalf("unexpected events %+v", got)
+    }
+    for i, m := range got {
+        if m.Sequence != uint64(i+1) || m.RunID != executor.Report().ID {
+            t.Errorf("event %d: unexpected sequence or run in %+v", i, m)
+        }
+    }
+
+    // The server echoes a masked close frame from the client.
+    mask := []byte{1, 2, 3, 4}
+    frame := append([]byte{0x80 | wsClose, 0x80 | 2}, mask...)
+    code := binary.BigEndian.AppendUint16(nil, 1000)
+    for i := range code {
+        code[i] ^= mask[i%4]
+    }
+    conn.Write(append(frame, code...))
+    if opcode, payload := readServerFrame(t, r); opcode != wsClose || binary.BigEndian.Uint16(payload) != 1000 {
+        t.Errorf("expected the close to be echoed, got opcode %d %v", opcode, payload)
+    }
+}
+
+func TestWebSocketHandlerRejects(t *testing.T) {
+    srv := httptest.NewServer(NewExecutor(TaskGraph()).WebSocketHandler())
+    defer srv.Close()
+
+    resp, err := http.Get(srv.URL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusBadRequest {
+        t.Errorf("expected a plain request to be rejected, got %s", resp.Status)
+    }
+
+    req, _ := http.NewRequest(http.MethodGet, srv.URL+"?type=Nope", nil)
+    req.Header.Set("Connection", "Upgrade")
+    req.Header.Set("Upgrade", "websocket")
+    req.Header.Set("Sec-WebSocket-Version", "13")
+    req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+    resp, err = http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusBadRequest {
+        t.Errorf("expected an unknown event type to be rejected, got %s", resp.Status)
+    }
+}
+
+// readServerFrame reads a short, unmasked frame sent by the server.
+func readServerFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+    t.Helper()
+    var head [2]byte
+    if _, err := io.ReadFull(r, head[:]); err != nil {
+        t.Fatalf("reading frame: %v", err)
+    }
+    size := int(head[1] & 0x7F)
+    if size == 126 {
+        var ext [2]byte
+        io.ReadFull(r, ext[:])
+        size = int(binary.BigEndian.Uint16(ext[:]))
+    }
+    payload := make([]byte, size)
+    if _, err := io.ReadFull(r, payload); err != nil {
+        t.Fatalf("reading frame: %v", err)
+    }
+    return head[0] & 0x0F, payload
+}
@@ -0,0 +1,243 @@
+package leo
+
+import (
+    "context"
+    "fmt"
+    "reflect"
+    "sort"
+    "strings"
+)
+
+var (
+    contextType = typeOf[context.Context]()
+    errorType   = typeOf[error]()
+)
+
+// AddFunc adds a node whose dependencies are inferred from fn's signature.
+// fn may take a context.Context as its first argument, followed by any
+// number of arguments of distinct types, and may return a value, an error, or
+// a value and an error:
+//
+//    graph.AddFunc("deploy", func(cfg ParsedConfig, creds Creds) (Deployment, error) { ... })
+//
+// After adding all nodes, call AutoWire to connect each argument to the node
+// producing a value of that type. The value a node returns is available from
+// Run.Result under the node's name.
+func (g *Graph) AddFunc(name string, fn any, opts ...NodeOption) error {
+    v := reflect.ValueOf(fn)
+    t := v.Type()
+    if t.Kind() != reflect.Func {
+        return fmt.Errorf("AddFunc %s: expected a function, got %s", name, t)
+    }
+    if t.IsVariadic() {
+        return fmt.Errorf("AddFunc %s: variadic functions are not supported", name)
+    }
+
+    withCtx := t.NumIn() > 0 && t.In(0) == contextType
+    var consumes []reflect.Type
+    seen := make(map[reflect.Type]bool)
+    for i := 0; i < t.NumIn(); i++ {
+        if i == 0 && withCtx {
+            continue
+        }
+        in := t.In(i)
+        if seen[in] {
+            return fmt.Errorf("AddFunc %s: more than one argument of type %s", name, in)
+        }
+        seen[in] = true
+        consumes = append(consumes, in)
+    }
+
+    var produces reflect.Type
+    switch {
+    case t.NumOut() == 0:
+    case t.NumOut() == 1 && t.Out(0) == errorType:
+    case t.NumOut() == 1:
+        produces = t.Out(0)
+    case t.NumOut() == 2 && t.Out(1) == errorType:
+        produces = t.Out(0)
+    default:
+        return fmt.Errorf("AddFunc %s: must return a value, an error, or a value and an error", name)
+    }
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    if _, exists := g.nodes[name]; exists {
+        return fmt.Errorf("AddFunc %s: %w", name, ErrNodeExists)
+    }
+
+    var node *Node
+    g.addNode(name, func(ctx context.Context) error {
+        // The running node differs from node once the graph has been merged
+        // into another, see Graph.Merge.
+        n := node
+        if _, running := runNode(ctx); running != nil {
+            n = running
+        }
+        return n.callFunc(ctx, v, withCtx)
+    }, opts)
+    node = g.nodes[name]
+    node.produces = produces
+    node.consumes = consumes
+    node.inputs = make(map[reflect.Type]*Node)
+    return nil
+}
+
+// AutoWire adds an edge to each function node added with AddFunc from the
+// node producing each of its argument types. An argument type that no node
+// produces is looked up among the executor's services (see Provide) when the
+// task runs. It is an error for more than one node to produce a type that
+// another node consumes.
+func (g *Graph) AutoWire() error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if g.plan != nil {
+        return ErrFrozen
+    }
+    producers := make(map[reflect.Type][]*Node)
+    for _, node := range g.nodes {
+        if node.produces != nil {
+            producers[node.produces] = append(producers[node.produces], node)
+        }
+    }
+
+    names := make([]string, 0, len(g.nodes))
+    for name := range g.nodes {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    for _, name := range names {
+        node := g.nodes[name]
+        for _, t := range node.consumes {
+            candidates := producers[t]
+            switch len(candidates) {
+            case 0:
+                continue
+            case 1:
+            default:
+                var names []string
+                for _, c := range candidates {
+                    names = append(names, c.name)
+                }
+                sort.Strings(names)
+                return fmt.Errorf("AutoWire %s: type %s is produced by %s", name, t, strings.Join(names, ", "))
+            }
+
+            producer := candidates[0]
+            if producer == node {
+                return fmt.Errorf("AutoWire %s: consumes its own output type %s", name, t)
+            }
+            if node.inputs[t] == producer {
+                continue
+            }
+            if !producer.hasChild(node) {
+                if err := g.precede(producer.name, name, nil); err != nil {
+                    return fmt.Errorf("AutoWire %s -> %s: %w", producer.name, name, err)
+                }
+            }
+            node.inputs[t] = producer
+        }
+    }
+    return nil
+}
+
+// Result returns the value produced by the named node in this run, for nodes
+// added with AddFunc.
+func (r *Run) Result(name string) (any, bool) {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    v, ok := r.results[name]
+    return v, ok
+}
+
+// SinkResults returns the values produced in this run by the function nodes
+// without children, by node name. Sinks are usually a pipeline's final
+// products. Nodes that produce no value, or have not succeeded, are left out.
+func (r *Run) SinkResults() map[string]any {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    out := make(map[string]any)
+    for name, v := range r.results {
+        if n := r.graph.nodes[name]; n != nil && len(n.children) == 0 {
+            out[name] = v
+        }
+    }
+    return out
+}
+
+// SinkResultsOf returns the sink results of r (see Run.SinkResults) that are
+// of type T.
+func SinkResultsOf[T any](r *Run) map[string]T {
+    out := make(map[string]T)
+    for name, v := range r.SinkResults() {
+        if t, ok := v.(T); ok {
+            out[name] = t
+        }
+    }
+    return out
+}
+
+func (r *Run) setResult(name string, v any) {
+    r.stateMu.Lock()
+    defer r.stateMu.Unlock()
+    if r.results == nil {
+        r.results = make(map[string]any)
+    }
+    r.results[name] = v
+}
+
+// callFunc calls a function added with AddFunc, passing the results of its
+// producers or the executor's services as arguments.
+func (n *Node) callFunc(ctx context.Context, fn reflect.Value, withCtx bool) error {
+    run := RunFromContext(ctx)
+    services, _ := ctx.Value(servicesKey{}).(map[reflect.Type]any)
+
+    args := make([]reflect.Value, 0, len(n.consumes)+1)
+    if withCtx {
+        args = append(args, reflect.ValueOf(&ctx).Elem())
+    }
+    for _, t := range n.consumes {
+        var v any
+        var ok bool
+        if producer := n.inputs[t]; producer != nil && run != nil {
+            v, ok = run.Result(producer.name)
+        } else {
+            v, ok = services[t]
+        }
+        if !ok {
+            return fmt.Errorf("no value of type %s available", t)
+        }
+        arg := reflect.New(t).Elem()
+        if v != nil {
+            arg.Set(reflect.ValueOf(v))
+        }
+        args = append(args, arg)
+    }
+
+    out := fn.Call(args)
+    if len(out) > 0 {
+        if last := out[len(out)-1]; last.Type() == errorType {
+            if !last.IsNil() {
+                return last.Interface().(error)
+            }
+            out = out[:len(out)-1]
+        }
+    }
+    if len(out) == 1 && run != nil {
+        run.setResult(n.name, out[0].Interface())
+    }
+    return nil
+}
+
+func (n *Node) hasChild(child *Node) bool {
+    for _, c := range n.children {
+        if c == child {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,132 @@
+package leo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type parsedConfig struct{ target string }
+type creds struct{ token string }
+type deployment struct{ id string }
+
+func TestAutoWire(t *testing.T) {
+    graph := TaskGraph()
+
+    err := graph.AddFunc("deploy", func(ctx context.Context, cfg parsedConfig, c creds) (deployment, error) {
+        return deployment{id: cfg.target + "/" + c.token}, nil
+    })
+    if err != nil {
+        t.Fatalf("AddFunc failed: %v", err)
+    }
+    graph.AddFunc("parse", func() parsedConfig { return parsedConfig{target: "prod"} })
+    graph.AddFunc("login", func() (creds, error) { return creds{token: "t0k"}, nil })
+
+    var verified string
+    graph.AddFunc("verify", func(d deployment) error {
+        verified = d.id
+        return nil
+    })
+
+    if err := graph.AutoWire(); err != nil {
+        t.Fatalf("AutoWire failed: %v", err)
+    }
+
+    parents := map[string]bool{}
+    for _, p := range graph.nodes["deploy"].parents {
+        parents[p.name] = true
+    }
+    if !parents["parse"] || !parents["login"] || len(parents) != 2 {
+        t.Errorf("deploy should depend on parse and login, got %v", parents)
+    }
+
+    run := NewExecutor(graph).NewRun()
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if verified != "prod/t0k" {
+        t.Errorf("verify saw %q", verified)
+    }
+    if d, _ := run.Result("deploy"); d.(deployment).id != "prod/t0k" {
+        t.Errorf("unexpected deploy result %v", d)
+    }
+}
+
+func TestAutoWireServices(t *testing.T) {
+    graph := TaskGraph()
+
+    var got string
+    graph.AddFunc("use-creds", func(c creds) { got = c.token })
+    if err := graph.AutoWire(); err != nil {
+        t.Fatalf("AutoWire failed: %v", err)
+    }
+
+    executor := NewExecutor(graph)
+    Provide(executor, creds{token: "from-service"})
+    if err := executor.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    if got != "from-service" {
+        t.Errorf("expected the service value, got %q", got)
+    }
+
+    if err := NewExecutor(graph).Execute(); err == nil {
+        t.Errorf("expected an error when no value is available")
+    }
+}
+
+func TestAutoWireErrors(t *testing.T) {
+    graph := TaskGraph()
+    graph.AddFunc("a", func() creds { return creds{} })
+    graph.AddFunc("b", func() creds { return creds{} })
+    graph.AddFunc("c", func(creds) {})
+    if err := graph.AutoWire(); err == nil {
+        t.Errorf("expected an ambiguity error")
+    }
+
+    for _, fn := range []any{
+        42,
+        func(a, b creds) {},
+        func() (creds, creds) { return creds{}, creds{} },
+        func(...creds) {},
+    } {
+        if err := TaskGraph().AddFunc("x", fn); err == nil {
+            t.Errorf("expected AddFunc to reject %T", fn)
+        }
+    }
+}
+
+func TestAddFuncError(t *testing.T) {
+    graph := TaskGraph()
+    graph.AddFunc("fail", func() (creds, error) { return creds{}, errors.New("denied") })
+
+    if err := NewExecutor(graph).Execute(); err == nil {
+        t.Errorf("expected the function's error to fail the run")
+    }
+}
+
+type executable struct{ arch string }
+
+func TestSinkResults(t *testing.T) {
+    graph := TaskGraph()
+    graph.AddFunc("config", func() parsedConfig { return parsedConfig{"prod"} })
+    graph.AddFunc("amd64", func(cfg parsedConfig) (executable, error) { return executable{"amd64"}, nil })
+    graph.AddFunc("docs", func() string { return "manual.pdf" })
+    graph.Add("notify", func() error { return nil })
+    if err := graph.AutoWire(); err != nil {
+        t.Fatal(err)
+    }
+
+    run := NewExecutor(graph).NewRun()
+    if err := run.Execute(); err != nil {
+        t.Fatalf("Execute failed: %v", err)
+    }
+    want := map[string]any{"amd64": executable{"amd64"}, "docs": "manual.pdf"}
+    if got := run.SinkResults(); !reflect.DeepEqual(got, want) {
+        t.Errorf("SinkResults() = %v, want %v", got, want)
+    }
+    if got := SinkResultsOf[executable](run); !reflect.DeepEqual(got, map[string]executable{"amd64": {"amd64"}}) {
+        t.Errorf("SinkResultsOf[executable] = %v", got)
+    }
+}